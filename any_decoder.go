@@ -0,0 +1,67 @@
+package jscandec
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/romshark/jscan/v2"
+)
+
+// AnyDecoder is a reusable decoder instance that, unlike Decoder,
+// isn't bound to a single type and decodes into values of any type
+// determined at the time of the call.
+// The type stack of every type is built once on its first use and reused
+// for all following calls, which costs a map lookup per call compared to
+// a Decoder. AnyDecoder must not be used concurrently!
+type AnyDecoder[S []byte | string] struct {
+	tokenizer *jscan.Tokenizer[S]
+	options   *InitOptions
+
+	// decoders are the decoders of all types decoded so far.
+	// Their type parameter T is irrelevant since they're only used
+	// through decode.
+	decoders map[reflect.Type]*Decoder[S, any]
+}
+
+// NewAnyDecoder creates a new reusable decoder instance that decodes
+// into values of any type using the given tokenizer and options.
+// Like with NewDecoder, tokenizer may be shared with other decoders,
+// yet the decoders must not be used concurrently!
+func NewAnyDecoder[S []byte | string](
+	tokenizer *jscan.Tokenizer[S], options *InitOptions,
+) *AnyDecoder[S] {
+	return &AnyDecoder[S]{
+		tokenizer: tokenizer,
+		options:   options,
+		decoders:  map[reflect.Type]*Decoder[S, any]{},
+	}
+}
+
+// Decode decodes s into the value dst points to like *Decoder[S, T].Decode
+// would for T being the type dst points to.
+// Returns ErrNilDest if dst is nil or a nil pointer and ErrUnsupportedType
+// if dst isn't a pointer. Errors returned by NewDecoder for the type
+// are returned with index 0.
+func (d *AnyDecoder[S]) Decode(
+	s S, dst any, options *DecodeOptions,
+) (errIndex int, err error) {
+	if dst == nil {
+		return 0, ErrNilDest
+	}
+	v := reflect.ValueOf(dst)
+	if v.Kind() != reflect.Pointer {
+		return 0, fmt.Errorf("%w: non-pointer destination %T", ErrUnsupportedType, dst)
+	}
+	if v.IsNil() {
+		return 0, ErrNilDest
+	}
+	t := v.Type().Elem()
+	dec, ok := d.decoders[t]
+	if !ok {
+		if dec, err = newDecoder[S, any](d.tokenizer, t, d.options); err != nil {
+			return 0, err
+		}
+		d.decoders[t] = dec
+	}
+	return dec.decode(s, v.UnsafePointer(), options)
+}
@@ -18,7 +18,59 @@ func appendTypeToStack[S []byte | string](
 			Size:             unsafe.Sizeof(struct{ typ, dat uintptr }{}),
 			ParentFrameIndex: noParentFrame,
 		}), nil
-	} else if s := determineJSONUnmarshalerSupport(t); s != interfaceSupportNone {
+	} else if f, ok := options.ContainerFactories[t]; ok {
+		return appendContainerToStack(stack, t, f, options)
+	} else if t.Kind() == reflect.Struct && t.Implements(tpOptional) {
+		// Optional[T] implements json.Unmarshaler only for
+		// compatibility with encoding/json and is decoded natively.
+		parentIndex := uint32(len(stack))
+		stack = append(stack, stackFrame[S]{
+			Type:             ExpectTypeOptional,
+			Typ:              getTyp(t),
+			Size:             t.Size(),
+			ParentFrameIndex: noParentFrame,
+		})
+		newAtIndex := len(stack)
+		value, _ := t.FieldByName("Value")
+		var err error
+		if stack, err = appendTypeToStack(stack, value.Type, options); err != nil {
+			return nil, err
+		}
+		stack[newAtIndex].Offset = value.Offset
+		stack[newAtIndex].ParentFrameIndex = parentIndex
+		return stack, nil
+	} else if t.Kind() == reflect.Struct && t.Implements(tpStringed) {
+		// Stringed[T] implements json.Unmarshaler only for
+		// compatibility with encoding/json and is decoded natively
+		// like T with the `string` tag option.
+		newAtIndex := len(stack)
+		value, _ := t.FieldByName("Value")
+		var err error
+		if stack, err = appendTypeToStack(stack, value.Type, options); err != nil {
+			return nil, err
+		}
+		st, ok := stack[newAtIndex].Type.stringTagType()
+		if !ok {
+			return nil, fmt.Errorf("%w: %v", ErrUnsupportedType, t)
+		}
+		// Value is the only field, the layout of Stringed[T] equals T's.
+		stack[newAtIndex].Type = st
+		stack[newAtIndex].Typ = getTyp(t)
+		return stack, nil
+	} else if t == tpBigRat {
+		// big.Rat implements encoding.TextUnmarshaler but is decoded
+		// natively to also accept numbers.
+		return append(stack, stackFrame[S]{
+			Type:             ExpectTypeBigRat,
+			Typ:              getTyp(t),
+			Size:             t.Size(),
+			ParentFrameIndex: noParentFrame,
+		}), nil
+	} else if s := determineJSONUnmarshalerSupport(t); s != interfaceSupportNone &&
+		t.Kind() != reflect.Ptr {
+		// Pointers to types implementing the unmarshaler interfaces
+		// are excluded and handled by the pointer frame like in encoding/json,
+		// where null sets the pointer to nil.
 		return append(stack, stackFrame[S]{
 			Type:             ExpectTypeJSONUnmarshaler,
 			Typ:              getTyp(t),
@@ -26,7 +78,9 @@ func appendTypeToStack[S []byte | string](
 			Size:             t.Size(),
 			ParentFrameIndex: noParentFrame,
 		}), nil
-	} else if s := determineTextUnmarshalerSupport(t); s != interfaceSupportNone {
+	} else if s := determineTextUnmarshalerSupport(t); s != interfaceSupportNone &&
+		t.Kind() != reflect.Ptr {
+		// Pointers are handled by the pointer frame like above.
 		return append(stack, stackFrame[S]{
 			Type:             ExpectTypeTextUnmarshaler,
 			Typ:              getTyp(t),
@@ -37,9 +91,20 @@ func appendTypeToStack[S []byte | string](
 	}
 	switch t.Kind() {
 	case reflect.Interface:
+		if t == tpError {
+			// Strings are decoded into errors.New values.
+			return append(stack, stackFrame[S]{
+				Type:             ExpectTypeError,
+				Typ:              getTyp(t),
+				Size:             t.Size(),
+				ParentFrameIndex: noParentFrame,
+			}), nil
+		}
 		if t.NumMethod() != 0 {
-			// TODO:
-			panic("not yet supported")
+			if c, ok := options.ConcreteTypes[t]; ok {
+				return appendInterfaceToStack(stack, t, c, options)
+			}
+			return nil, fmt.Errorf("%w: %v", ErrUnsupportedInterface, t)
 		}
 		return append(stack, stackFrame[S]{
 			Type:             ExpectTypeAny,
@@ -70,12 +135,41 @@ func appendTypeToStack[S []byte | string](
 			return nil, err
 		}
 
+		if len(stack) == newAtIndex+1 {
+			// Use a fast path for arrays of scalars,
+			// which doesn't need a separate element frame.
+			fastPath := ExpectTypeArray
+			switch stack[newAtIndex].Type {
+			case ExpectTypeBool:
+				fastPath = ExpectTypeArrayBool
+			case ExpectTypeStr:
+				fastPath = ExpectTypeArrayStr
+			case ExpectTypeFloat32:
+				fastPath = ExpectTypeArrayFloat32
+			case ExpectTypeFloat64:
+				fastPath = ExpectTypeArrayFloat64
+			}
+			if fastPath != ExpectTypeArray {
+				stack = stack[:newAtIndex]
+				stack[parentIndex].Type = fastPath
+				return stack, nil
+			}
+		}
+
 		// Link array element to the array frame.
 		stack[newAtIndex].ParentFrameIndex = parentIndex
 		stack[newAtIndex].Cap = t.Len()
 
 	case reflect.Slice:
 		elem := t.Elem()
+		if elem == tpTime {
+			return append(stack, stackFrame[S]{
+				Type:             ExpectTypeSliceTime,
+				Typ:              getTyp(t),
+				Size:             t.Size(),
+				ParentFrameIndex: noParentFrame,
+			}), nil
+		}
 		switch elem.Kind() {
 		case reflect.Struct:
 			if elem.Size() < 1 {
@@ -88,7 +182,7 @@ func appendTypeToStack[S []byte | string](
 			}
 			// Check for recursion
 			for i := range stack {
-				if stack[i].RType == elem {
+				if isRecursiveStructFrame(stack, i, elem) {
 					// Recursion of type stack[i] detected.
 					// Link recursive frame to the recursion frame.
 					stack[i].Type = ExpectTypeStructRecur
@@ -110,7 +204,10 @@ func appendTypeToStack[S []byte | string](
 				ParentFrameIndex: noParentFrame,
 			}), nil
 		case reflect.String:
-			if elem == tpNumber {
+			if elem == tpNumber || elem == tpJSONNumber {
+				break
+			}
+			if isEnum(options, elem) {
 				break
 			}
 			return append(stack, stackFrame[S]{
@@ -220,6 +317,7 @@ func appendTypeToStack[S []byte | string](
 
 		// Link slice element to the slice frame.
 		stack[newAtIndex].ParentFrameIndex = parentIndex
+		stack[parentIndex].FlatStruct = isFlatStruct(stack, newAtIndex)
 
 	case reflect.Map:
 		parentIndex := uint32(len(stack))
@@ -227,7 +325,7 @@ func appendTypeToStack[S []byte | string](
 		if elem.Kind() == reflect.Struct && elem.Size() > 0 {
 			// Check for recursion
 			for i := range stack {
-				if stack[i].RType == elem {
+				if isRecursiveStructFrame(stack, i, elem) {
 					// Recursion of type stack[i] detected.
 					// Link recursive frame to the recursion frame.
 					stack[i].Type = ExpectTypeStructRecur
@@ -235,6 +333,7 @@ func appendTypeToStack[S []byte | string](
 					stack = append(stack, stackFrame[S]{
 						Type:                   ExpectTypeMapRecur,
 						Typ:                    getTyp(t),
+						RType:                  t,
 						Size:                   t.Size(),
 						MapValueType:           getTyp(t.Elem()),
 						MapCanUseAssignFaststr: canUseAssignFaststr(t),
@@ -244,7 +343,7 @@ func appendTypeToStack[S []byte | string](
 					{
 						newAtIndex := len(stack)
 						var err error
-						stack, err = appendTypeToStack(stack, t.Key(), options)
+						stack, err = appendMapKeyToStack(stack, t.Key(), options)
 						if err != nil {
 							return nil, err
 						}
@@ -256,7 +355,8 @@ func appendTypeToStack[S []byte | string](
 			}
 		}
 
-		if t.Key().Kind() == reflect.String && t.Elem().Kind() == reflect.String {
+		if t.Key().Kind() == reflect.String && t.Elem().Kind() == reflect.String &&
+			!isEnum(options, t.Key()) && !isEnum(options, t.Elem()) {
 			return append(stack, stackFrame[S]{
 				Type:             ExpectTypeMapStringString,
 				Typ:              getTyp(t),
@@ -266,6 +366,29 @@ func appendTypeToStack[S []byte | string](
 			}), nil
 		}
 
+		if t.Key().Kind() == reflect.String && t.Elem() == tpRawMessage &&
+			!isEnum(options, t.Key()) {
+			return append(stack, stackFrame[S]{
+				Type:             ExpectTypeMapStringRawMessage,
+				Typ:              getTyp(t),
+				RType:            t,
+				Size:             t.Size(),
+				ParentFrameIndex: noParentFrame,
+			}), nil
+		}
+
+		if levels, leaf := nestedMapLevels[S](t, options); levels != nil {
+			return append(stack, stackFrame[S]{
+				Type:             ExpectTypeMapNested,
+				Typ:              getTyp(t),
+				RType:            t,
+				Size:             t.Size(),
+				MapLevels:        levels,
+				MapLeaf:          leaf,
+				ParentFrameIndex: noParentFrame,
+			}), nil
+		}
+
 		stack = append(stack, stackFrame[S]{
 			Type:                   ExpectTypeMap,
 			Typ:                    getTyp(t),
@@ -278,7 +401,7 @@ func appendTypeToStack[S []byte | string](
 		{
 			newAtIndex := len(stack)
 			var err error
-			if stack, err = appendTypeToStack(stack, t.Key(), options); err != nil {
+			if stack, err = appendMapKeyToStack(stack, t.Key(), options); err != nil {
 				return nil, err
 			}
 			// Link map key to the map frame.
@@ -305,39 +428,70 @@ func appendTypeToStack[S []byte | string](
 				ParentFrameIndex: noParentFrame,
 			}), nil
 		}
+		fields, err := structFields(t, options.FallbackTagKeys)
+		if err != nil {
+			return nil, err
+		}
 		stack = append(stack, stackFrame[S]{
 			Type:             ExpectTypeStruct,
 			Typ:              getTyp(t),
 			RType:            t,
 			Size:             t.Size(),
-			Fields:           make([]fieldStackFrame, 0, numFields),
+			Fields:           make([]fieldStackFrame, 0, len(fields)),
 			ParentFrameIndex: noParentFrame,
 		})
 
-		for i := 0; i < numFields; i++ {
-			f := t.Field(i)
-			name := f.Name
-			optionString := false
+		// aliases collects the `jsonalias` tags of fields matched by name.
+		var aliases []fieldStackFrame
+		for _, f := range fields {
+			name, _, _ := fieldJSONName(f, options.FallbackTagKeys)
+			optionString, optionRest, optionRaw := false, false, false
+			optionPairs, optionBytes := false, false
 			if jsonTag := f.Tag.Get("json"); jsonTag != "" {
-				name = jsonTag
 				if i := strings.IndexByte(jsonTag, ','); i != -1 {
-					name = jsonTag[:i]
 					optionName := jsonTag[i+1:]
 					optionString = optionName == "string"
+					optionRest = optionName == "rest"
+					optionRaw = optionName == "raw"
+					optionPairs = optionName == "pairs"
+					optionBytes = optionName == "bytes"
+				}
+			}
+
+			if optionRest {
+				// This field receives all unknown fields and
+				// can't be matched by name.
+				if f.Type != tpMapStringAny {
+					return nil, ErrRestTagOptionOnUnsupportedType
+				}
+				if stack[parentIndex].HasRest {
+					return nil, ErrMultipleRestFields
+				}
+				stack[parentIndex].HasRest = true
+				stack[parentIndex].RestOffset = f.Offset
+				continue
+			}
+
+			if optionRaw {
+				// This field receives the verbatim bytes of the object
+				// and can't be matched by name.
+				if f.Type.Kind() != reflect.Slice || f.Type.Elem().Kind() != reflect.Uint8 {
+					return nil, ErrRawTagOptionOnUnsupportedType
 				}
-				switch name {
-				case "":
-					// Either there was no tag or no name specified in it.
-					name = f.Name
-				case "-":
-					// Ignore this field.
-					continue
+				if stack[parentIndex].HasRaw {
+					return nil, ErrMultipleRawFields
 				}
+				stack[parentIndex].HasRaw = true
+				stack[parentIndex].RawOffset = f.Offset
+				continue
 			}
 
 			newAtIndex := uint32(len(stack))
-			var err error
-			stack, err = appendTypeToStack(stack, f.Type, options)
+			if optionPairs {
+				stack, err = appendPairsToStack(stack, f.Type, options)
+			} else {
+				stack, err = appendTypeToStack(stack, f.Type, options)
+			}
 			if err != nil {
 				return nil, err
 			}
@@ -348,6 +502,12 @@ func appendTypeToStack[S []byte | string](
 					FrameIndex: newAtIndex,
 				},
 			)
+			if tag := f.Tag.Get("jsonalias"); tag != "" {
+				aliases = append(aliases, fieldStackFrame{
+					Name:       tag,
+					FrameIndex: newAtIndex,
+				})
+			}
 
 			// Assign static offset
 			stack[newAtIndex].Offset = f.Offset
@@ -355,44 +515,53 @@ func appendTypeToStack[S []byte | string](
 			// Link the field frame to the parent struct frame.
 			stack[newAtIndex].ParentFrameIndex = parentIndex
 
+			if tag, ok := f.Tag.Lookup("default"); ok {
+				value, err := parseDefault(f, stack[newAtIndex].Type, tag)
+				if err != nil {
+					return nil, err
+				}
+				stack[parentIndex].Defaults = append(
+					stack[parentIndex].Defaults,
+					fieldDefault{
+						FrameIndex: newAtIndex,
+						Offset:     f.Offset,
+						Typ:        getTyp(f.Type),
+						Value:      value,
+					},
+				)
+			}
+
+			if f.Tag.Get("required") == "true" {
+				stack[parentIndex].Required = append(
+					stack[parentIndex].Required, newAtIndex,
+				)
+			}
+
+			if optionBytes {
+				// The field receives the unescaped contents of a string
+				// instead of base64-decoded data or an array of numbers.
+				if stack[newAtIndex].Type != ExpectTypeSliceUint8 {
+					return nil, ErrBytesTagOptionOnUnsupportedType
+				}
+				stack[newAtIndex].Type = ExpectTypeBytesString
+			}
+
 			if optionString {
-				switch stack[newAtIndex].Type {
-				case ExpectTypeStr:
-					stack[newAtIndex].Type = ExpectTypeStrString
-				case ExpectTypeBool:
-					stack[newAtIndex].Type = ExpectTypeBoolString
-				case ExpectTypeFloat32:
-					stack[newAtIndex].Type = ExpectTypeFloat32String
-				case ExpectTypeFloat64:
-					stack[newAtIndex].Type = ExpectTypeFloat64String
-				case ExpectTypeInt:
-					stack[newAtIndex].Type = ExpectTypeIntString
-				case ExpectTypeInt8:
-					stack[newAtIndex].Type = ExpectTypeInt8String
-				case ExpectTypeInt16:
-					stack[newAtIndex].Type = ExpectTypeInt16String
-				case ExpectTypeInt32:
-					stack[newAtIndex].Type = ExpectTypeInt32String
-				case ExpectTypeInt64:
-					stack[newAtIndex].Type = ExpectTypeInt64String
-				case ExpectTypeUint:
-					stack[newAtIndex].Type = ExpectTypeUintString
-				case ExpectTypeUint8:
-					stack[newAtIndex].Type = ExpectTypeUint8String
-				case ExpectTypeUint16:
-					stack[newAtIndex].Type = ExpectTypeUint16String
-				case ExpectTypeUint32:
-					stack[newAtIndex].Type = ExpectTypeUint32String
-				case ExpectTypeUint64:
-					stack[newAtIndex].Type = ExpectTypeUint64String
-				default:
+				target := newAtIndex
+				if stack[target].Type == ExpectTypePtr && f.Type.Name() == "" {
+					// Like encoding/json, apply the option through
+					// a single level of unnamed pointer (like *int).
+					target++
+				}
+				if st, ok := stack[target].Type.stringTagType(); ok {
+					stack[target].Type = st
+				} else if options.DisallowStringTagOptOnUnsupportedTypes {
 					// Using tag option `string` on an unsupported type
-					if options.DisallowStringTagOptOnUnsupportedTypes {
-						return nil, ErrStringTagOptionOnUnsupportedType
-					}
+					return nil, ErrStringTagOptionOnUnsupportedType
 				}
 			}
 		}
+		appendFieldAliases(stack, parentIndex, aliases)
 
 	case reflect.Bool:
 		stack = append(stack, stackFrame[S]{
@@ -402,13 +571,21 @@ func appendTypeToStack[S []byte | string](
 			ParentFrameIndex: noParentFrame,
 		})
 	case reflect.String:
-		if t == tpNumber {
+		if t == tpNumber || t == tpJSONNumber {
 			stack = append(stack, stackFrame[S]{
 				Type:             ExpectTypeNumber,
 				Typ:              getTyp(t),
 				Size:             t.Size(),
 				ParentFrameIndex: noParentFrame,
 			})
+		} else if enum, ok := options.EnumConstraints[t]; ok {
+			stack = append(stack, stackFrame[S]{
+				Type:             ExpectTypeStrEnum,
+				Typ:              getTyp(t),
+				Size:             t.Size(),
+				Enum:             enum,
+				ParentFrameIndex: noParentFrame,
+			})
 		} else {
 			stack = append(stack, stackFrame[S]{
 				Type:             ExpectTypeStr,
@@ -508,7 +685,7 @@ func appendTypeToStack[S []byte | string](
 		if elem.Kind() == reflect.Struct && elem.Size() > 0 {
 			// Check for recursion
 			for i := range stack {
-				if stack[i].RType == elem {
+				if isRecursiveStructFrame(stack, i, elem) {
 					// Recursion of type stack[i] detected.
 					// Link recursive frame to the recursion frame.
 					stack[i].Type = ExpectTypeStructRecur
@@ -538,12 +715,205 @@ func appendTypeToStack[S []byte | string](
 		}
 		stack[newAtIndex].ParentFrameIndex = parentIndex
 
+	case reflect.Uintptr:
+		// Unlike encoding/json, uintptr isn't decoded like an unsigned integer
+		// on purpose because it's meant to hold memory addresses.
+		if t.PkgPath() != "" {
+			return nil, fmt.Errorf("%w: %v of kind uintptr", ErrUnsupportedType, t)
+		}
+		return nil, fmt.Errorf("%w: %v", ErrUnsupportedType, t)
+
 	default:
-		return nil, fmt.Errorf("unsupported type: %v", t)
+		return nil, fmt.Errorf("%w: %v", ErrUnsupportedType, t)
 	}
 	return stack, nil
 }
 
+// appendInterfaceToStack appends the frame of the non-empty interface type t
+// decoded into its registered concrete type c.
+// Both *E and non-pointer E are stored as a pointer to E in the data word of
+// the interface header, which is why the element is always decoded by
+// a pointer frame at the offset of the data word.
+func appendInterfaceToStack[S []byte | string](
+	stack []stackFrame[S], t, c reflect.Type, options *InitOptions,
+) ([]stackFrame[S], error) {
+	if c == nil || !c.Implements(t) {
+		return nil, fmt.Errorf("%w: %v doesn't implement %v", ErrUnsupportedType, c, t)
+	}
+	elem := c
+	if c.Kind() == reflect.Pointer {
+		elem = c.Elem()
+	} else if isPointerShaped(c) {
+		// Pointer-shaped values are stored directly in the data word.
+		return nil, fmt.Errorf("%w: concrete type %v of %v", ErrUnsupportedType, c, t)
+	}
+
+	// Compute the first word of the interface header for c.
+	v := reflect.New(t)
+	v.Elem().Set(reflect.New(c).Elem())
+	itab := (*ifaceHeader)(v.UnsafePointer()).Tab
+
+	parentIndex := uint32(len(stack))
+	stack = append(stack, stackFrame[S]{
+		Type:             ExpectTypeInterface,
+		Typ:              getTyp(t),
+		Itab:             itab,
+		Size:             t.Size(),
+		ParentFrameIndex: noParentFrame,
+	})
+	newAtIndex := len(stack)
+	var err error
+	if stack, err = appendTypeToStack(stack, reflect.PointerTo(elem), options); err != nil {
+		return nil, err
+	}
+	stack[newAtIndex].Offset = unsafe.Offsetof(ifaceHeader{}.Data)
+	stack[newAtIndex].ParentFrameIndex = parentIndex
+	return stack, nil
+}
+
+// appendPairsToStack appends the frame of the type t of a field tagged with
+// the `pairs` option to stack. t must be a slice of structs with the exported
+// fields Key of kind string and Value of any supported type, otherwise
+// ErrPairsTagOptionOnUnsupportedType is returned.
+func appendPairsToStack[S []byte | string](
+	stack []stackFrame[S], t reflect.Type, options *InitOptions,
+) ([]stackFrame[S], error) {
+	if t.Kind() != reflect.Slice || t.Elem().Kind() != reflect.Struct {
+		return nil, ErrPairsTagOptionOnUnsupportedType
+	}
+	elem := t.Elem()
+	key, okKey := elem.FieldByName("Key")
+	value, okValue := elem.FieldByName("Value")
+	if !okKey || !okValue || !key.IsExported() || !value.IsExported() ||
+		key.Type.Kind() != reflect.String || len(key.Index) != 1 || len(value.Index) != 1 {
+		return nil, ErrPairsTagOptionOnUnsupportedType
+	}
+
+	parentIndex := uint32(len(stack))
+	stack = append(stack, stackFrame[S]{
+		Type:             ExpectTypeSlicePairs,
+		Typ:              getTyp(t),
+		PairTyp:          getTyp(elem),
+		PairSize:         elem.Size(),
+		PairKeyOffset:    key.Offset,
+		Size:             t.Size(),
+		ParentFrameIndex: noParentFrame,
+	})
+	newAtIndex := len(stack)
+	var err error
+	if stack, err = appendTypeToStack(stack, value.Type, options); err != nil {
+		return nil, err
+	}
+	stack[newAtIndex].Offset = value.Offset
+	stack[newAtIndex].ParentFrameIndex = parentIndex
+	return stack, nil
+}
+
+// isPointerShaped returns true for types that are stored
+// in the data word of an interface directly.
+func isPointerShaped(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Pointer, reflect.Map, reflect.Chan,
+		reflect.Func, reflect.UnsafePointer:
+		return true
+	case reflect.Struct:
+		return t.NumField() == 1 && isPointerShaped(t.Field(0).Type)
+	case reflect.Array:
+		return t.Len() == 1 && isPointerShaped(t.Elem())
+	}
+	return false
+}
+
 func getTyp(t reflect.Type) *typ {
 	return (*typ)(((*emptyInterface)(unsafe.Pointer(&t))).ptr)
 }
+
+// appendMapKeyToStack appends the frame of the map key type t to stack.
+// Keys of types implementing encoding.TextUnmarshaler are always decoded
+// through UnmarshalText, regardless of the receiver type and even if the type
+// also implements json.Unmarshaler. Like in encoding/json, other key types
+// must be either strings or integers, otherwise ErrUnsupportedType is returned.
+func appendMapKeyToStack[S []byte | string](
+	stack []stackFrame[S], t reflect.Type, options *InitOptions,
+) ([]stackFrame[S], error) {
+	if t.Kind() != reflect.Ptr &&
+		determineTextUnmarshalerSupport(t) != interfaceSupportNone {
+		return append(stack, stackFrame[S]{
+			Type:             ExpectTypeTextUnmarshaler,
+			Typ:              getTyp(t),
+			RType:            t,
+			Size:             t.Size(),
+			ParentFrameIndex: noParentFrame,
+		}), nil
+	}
+	switch t.Kind() {
+	case reflect.String,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return appendTypeToStack(stack, t, options)
+	}
+	return nil, fmt.Errorf("%w: map key %v", ErrUnsupportedType, t)
+}
+
+// fallbackTagName returns the name specified by the first struct tag
+// of the given keys that specifies one, or "" if none does.
+// Options and the name "-" are ignored, fields can only be ignored
+// by the json struct tag.
+func fallbackTagName(tag reflect.StructTag, keys []string) string {
+	for _, k := range keys {
+		name := tag.Get(k)
+		if i := strings.IndexByte(name, ','); i != -1 {
+			name = name[:i]
+		}
+		if name != "" && name != "-" {
+			return name
+		}
+	}
+	return ""
+}
+
+// stringTagType returns the type decoding t from a string like
+// the `string` tag option does. Returns false if t doesn't support it.
+func (t ExpectType) stringTagType() (ExpectType, bool) {
+	switch t {
+	case ExpectTypeStr:
+		return ExpectTypeStrString, true
+	case ExpectTypeBool:
+		return ExpectTypeBoolString, true
+	case ExpectTypeFloat32:
+		return ExpectTypeFloat32String, true
+	case ExpectTypeFloat64:
+		return ExpectTypeFloat64String, true
+	case ExpectTypeInt:
+		return ExpectTypeIntString, true
+	case ExpectTypeInt8:
+		return ExpectTypeInt8String, true
+	case ExpectTypeInt16:
+		return ExpectTypeInt16String, true
+	case ExpectTypeInt32:
+		return ExpectTypeInt32String, true
+	case ExpectTypeInt64:
+		return ExpectTypeInt64String, true
+	case ExpectTypeUint:
+		return ExpectTypeUintString, true
+	case ExpectTypeUint8:
+		return ExpectTypeUint8String, true
+	case ExpectTypeUint16:
+		return ExpectTypeUint16String, true
+	case ExpectTypeUint32:
+		return ExpectTypeUint32String, true
+	case ExpectTypeUint64:
+		return ExpectTypeUint64String, true
+	}
+	return 0, false
+}
+
+// isRecursiveStructFrame returns true if stack[i] is the frame of struct
+// type t. Frames of unmarshaler types also refer to their type but are
+// decoded through the unmarshaler interface and never recurse.
+func isRecursiveStructFrame[S []byte | string](
+	stack []stackFrame[S], i int, t reflect.Type,
+) bool {
+	return stack[i].RType == t && (stack[i].Type == ExpectTypeStruct ||
+		stack[i].Type == ExpectTypeStructRecur)
+}
@@ -1,7 +1,10 @@
 package jscandec
 
 import (
+	"encoding/json"
+	"net"
 	"runtime"
+	"strconv"
 	"testing"
 
 	"github.com/romshark/jscan/v2"
@@ -23,3 +26,116 @@ func BenchmarkNewDecoder(b *testing.B) {
 		runtime.KeepAlive(d)
 	})
 }
+
+func BenchmarkDecodeFlatStructSlice(b *testing.B) {
+	type FlatStruct struct {
+		ID      int64   `json:"id"`
+		Name    string  `json:"name"`
+		Active  bool    `json:"active"`
+		Score   float64 `json:"score"`
+		Count   uint32  `json:"count"`
+		Comment string  `json:"comment"`
+	}
+	in := []byte{'['}
+	for i := 0; i < 10_000; i++ {
+		if i > 0 {
+			in = append(in, ',')
+		}
+		in = append(in, `{"id":123456,"name":"flat struct","active":true,`+
+			`"score":3.1415,"count":42,"comment":"some text"}`...)
+	}
+	in = append(in, ']')
+
+	for _, fastPath := range []bool{true, false} {
+		name := "generic"
+		if fastPath {
+			name = "flat"
+		}
+		b.Run(name, func(b *testing.B) {
+			tok := jscan.NewTokenizer[[]byte](64, len(in)/2)
+			d, err := NewDecoder[[]byte, []FlatStruct](tok, DefaultInitOptions)
+			if err != nil {
+				b.Fatal(err)
+			}
+			if !d.stackExp[0].FlatStruct {
+				b.Fatal("expected flat struct slice")
+			}
+			d.stackExp[0].FlatStruct = fastPath
+			var v []FlatStruct
+			b.SetBytes(int64(len(in)))
+			b.ResetTimer()
+			for n := 0; n < b.N; n++ {
+				if _, err := d.Decode(in, &v, DefaultOptions); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkDecodeSliceTextUnmarshaler(b *testing.B) {
+	in := []byte{'['}
+	for i := 0; i < 10_000; i++ {
+		if i > 0 {
+			in = append(in, ',')
+		}
+		in = append(in, '"')
+		in = append(in, net.IPv4(10, byte(i>>16), byte(i>>8), byte(i)).String()...)
+		in = append(in, '"')
+	}
+	in = append(in, ']')
+
+	b.Run("jscandec", func(b *testing.B) {
+		tok := jscan.NewTokenizer[[]byte](64, len(in)/2)
+		d, err := NewDecoder[[]byte, []net.IP](tok, DefaultInitOptions)
+		if err != nil {
+			b.Fatal(err)
+		}
+		var v []net.IP
+		b.SetBytes(int64(len(in)))
+		b.ResetTimer()
+		for n := 0; n < b.N; n++ {
+			if _, err := d.Decode(in, &v, DefaultOptions); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("encoding_json", func(b *testing.B) {
+		var v []net.IP
+		b.SetBytes(int64(len(in)))
+		b.ResetTimer()
+		for n := 0; n < b.N; n++ {
+			if err := json.Unmarshal(in, &v); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkDecodeSliceAnySmallIntegers(b *testing.B) {
+	in := []byte{'['}
+	for i := 0; i < 10_000; i++ {
+		if i > 0 {
+			in = append(in, ',')
+		}
+		in = strconv.AppendInt(in, int64(i%256), 10)
+	}
+	in = append(in, ']')
+
+	tok := jscan.NewTokenizer[[]byte](64, len(in)/2)
+	d, err := NewDecoder[[]byte, []any](tok, DefaultInitOptions)
+	if err != nil {
+		b.Fatal(err)
+	}
+	var v []any
+	b.SetBytes(int64(len(in)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		v = nil
+		if _, err := d.Decode(in, &v, DefaultOptions); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package jscandec
+
+import (
+	"math/bits"
+	"unsafe"
+
+	"github.com/romshark/jscan-experimental-decoder/internal/atoi"
+)
+
+// decodeBitmask expands the bits of the unsigned integer literal tv into
+// the []bool at p least significant bit first (see
+// DecodeOptions.BoolSliceFromBitmask). The length of the slice is
+// options.BoolSliceBitmaskWidth, or the number of bits up to the most
+// significant set bit if the width is zero.
+func decodeBitmask[S []byte | string](
+	tv S, p unsafe.Pointer, options *DecodeOptions, budget *allocBudget,
+) error {
+	if tv[0] == '-' {
+		return ErrUnexpectedValue
+	}
+	v, overflow := atoi.U64(tv)
+	if overflow {
+		return ErrIntegerOverflow
+	}
+	width := bits.Len64(v)
+	if w := options.BoolSliceBitmaskWidth; w > 0 {
+		if width > w {
+			// Set bits exceed the width.
+			return ErrIntegerOverflow
+		}
+		width = w
+	}
+
+	sl := *(*[]bool)(p)
+	if sl == nil || cap(sl) < width {
+		// Like with arrays, `0` with a width of zero yields an empty slice.
+		if !budget.alloc(uintptr(width)) {
+			return ErrAllocBudgetExceeded
+		}
+		sl = make([]bool, width)
+	} else {
+		sl = sl[:width]
+	}
+	for i := range sl {
+		sl[i] = i < 64 && v&(1<<i) != 0
+	}
+	*(*[]bool)(p) = sl
+	return nil
+}
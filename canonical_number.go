@@ -0,0 +1,85 @@
+package jscandec
+
+import "github.com/romshark/jscan/v2"
+
+// nonCanonicalNumberIndex returns the index of the first number token in tokens
+// that isn't in canonical form (see DecodeOptions.RequireCanonicalNumbers).
+// Returns -1 if all numbers are canonical.
+func nonCanonicalNumberIndex[S []byte | string](s S, tokens []jscan.Token[S]) int {
+	for i := range tokens {
+		switch tokens[i].Type {
+		case jscan.TokenTypeInteger, jscan.TokenTypeNumber:
+			if !isCanonicalNumber(s[tokens[i].Index:tokens[i].End]) {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// isCanonicalNumber returns true if the valid JSON number literal s
+// is in canonical form (see DecodeOptions.RequireCanonicalNumbers).
+func isCanonicalNumber[S []byte | string](s S) bool {
+	i := 0
+	if s[0] == '-' {
+		i++
+	}
+	intStart := i
+	for i < len(s) && isDigit(s[i]) {
+		i++
+	}
+	integer := s[intStart:i]
+	leadingFracZeros := 0
+	if i < len(s) && s[i] == '.' {
+		i++
+		fracStart := i
+		for i < len(s) && isDigit(s[i]) {
+			i++
+		}
+		if s[i-1] == '0' {
+			return false // Trailing zero
+		}
+		for s[fracStart+leadingFracZeros] == '0' {
+			leadingFracZeros++
+		}
+	}
+
+	if i == len(s) {
+		// No exponent
+		if integer[0] == '0' {
+			if i == intStart+1 {
+				// No fraction, negative zero isn't canonical.
+				return intStart == 0
+			}
+			// Magnitude less than 1e-6.
+			return leadingFracZeros < 6
+		}
+		// Magnitude less than 1e21.
+		return len(integer) <= 21
+	}
+
+	// Exponent
+	if s[i] == 'E' || len(integer) != 1 || integer[0] == '0' {
+		return false
+	}
+	i++
+	negative := false
+	switch s[i] {
+	case '+':
+		return false
+	case '-':
+		negative = true
+		i++
+	}
+	if s[i] == '0' {
+		return false // Zero or leading zero
+	}
+	exp := 0
+	for ; i < len(s) && exp < 1000; i++ {
+		exp = exp*10 + int(s[i]-'0')
+	}
+	if negative {
+		return exp >= 7
+	}
+	return exp >= 21
+}
@@ -0,0 +1,89 @@
+package jscandec
+
+import "github.com/romshark/jscan/v2"
+
+// DecodeArrayToChan decodes the elements of the top-level JSON array in s
+// and sends each of them on ch in order. ch is not closed when
+// DecodeArrayToChan returns. Every element is decoded into a new value of
+// type T which is sent by value, so the receiver may retain it.
+// Elements sent before an error is encountered remain sent.
+// If s isn't an array ErrUnexpectedValue is returned.
+// An element that fails to decode is reported like by Decode with the error
+// index relative to s.
+//
+// InitOptions.SlicePool must not be used since the pooled backing arrays
+// are reused by subsequent elements.
+func (d *Decoder[S, T]) DecodeArrayToChan(
+	s S, ch chan<- T, options *DecodeOptions,
+) (errIndex int, err error) {
+	return d.decodeArrayElements(s, options, func(v T, _ S, _ int) (int, error) {
+		ch <- v
+		return 0, nil
+	})
+}
+
+// decodeArrayElements decodes every element of the top-level JSON array in s
+// into a new value of type T and calls yield with the value, the source of
+// the element and its index in s. decodeArrayElements stops at the first
+// error returned by yield and returns it together with its error index.
+// If s isn't an array ErrUnexpectedValue is returned.
+// An element that fails to decode is reported like by Decode with the error
+// index relative to s.
+func (d *Decoder[S, T]) decodeArrayElements(
+	s S, options *DecodeOptions,
+	yield func(v T, src S, index int) (errIndex int, err error),
+) (errIndex int, err error) {
+	i := skipSpace(s, 0)
+	if i >= len(s) {
+		return i, syntaxError(s, i, jscan.ErrorCodeUnexpectedEOF)
+	}
+	if s[i] != '[' {
+		return i, ErrUnexpectedValue
+	}
+	if i = skipSpace(s, i+1); i < len(s) && s[i] == ']' {
+		return trailingSpace(s, i+1)
+	}
+	for {
+		if i >= len(s) {
+			return i, syntaxError(s, i, jscan.ErrorCodeUnexpectedEOF)
+		}
+		end := valueEnd(s, i)
+
+		var v T
+		if errIndex, err = d.Decode(s[i:end], &v, options); err != nil {
+			if errTok, ok := err.(jscan.Error[S]); ok {
+				errTok.Src, errTok.Index = s, errTok.Index+i
+				err = errTok
+			}
+			return errIndex + i, err
+		}
+		if errIndex, err = yield(v, s[i:end], i); err != nil {
+			return errIndex, err
+		}
+
+		if i = skipSpace(s, end); i >= len(s) {
+			return i, syntaxError(s, i, jscan.ErrorCodeUnexpectedEOF)
+		}
+		switch s[i] {
+		case ',':
+			i = skipSpace(s, i+1)
+		case ']':
+			return trailingSpace(s, i+1)
+		default:
+			return i, syntaxError(s, i, jscan.ErrorCodeUnexpectedToken)
+		}
+	}
+}
+
+// trailingSpace returns -1 and no error if s contains only whitespace
+// after index i, otherwise returns an unexpected token error.
+func trailingSpace[S []byte | string](s S, i int) (errIndex int, err error) {
+	if i = skipSpace(s, i); i < len(s) {
+		return i, syntaxError(s, i, jscan.ErrorCodeUnexpectedToken)
+	}
+	return -1, nil
+}
+
+func syntaxError[S []byte | string](s S, i int, code jscan.ErrorCode) error {
+	return jscan.Error[S]{Src: s, Index: i, Code: code}
+}
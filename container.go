@@ -0,0 +1,63 @@
+package jscandec
+
+import (
+	"fmt"
+	"reflect"
+	"unsafe"
+)
+
+// appendContainerToStack appends the frames of the custom container type t
+// created by factory (see InitOptions.ContainerFactories) to stack.
+// The element frame is guaranteed to be at an offset of 1 relative
+// to the container frame index.
+func appendContainerToStack[S []byte | string](
+	stack []stackFrame[S], t reflect.Type,
+	factory func(elems int) (dst unsafe.Pointer, add func(elem unsafe.Pointer)),
+	options *InitOptions,
+) ([]stackFrame[S], error) {
+	var elem reflect.Type
+	switch t.Kind() {
+	case reflect.Map:
+		elem = t.Key()
+	case reflect.Slice, reflect.Array:
+		elem = t.Elem()
+	default:
+		return nil, fmt.Errorf("%w: container %v", ErrUnsupportedType, t)
+	}
+	for i := range stack {
+		if stack[i].RType == elem && (stack[i].Type == ExpectTypeStruct ||
+			stack[i].Type == ExpectTypeStructRecur) {
+			// The element frame is reused for every element,
+			// which rules out recursion.
+			return nil, fmt.Errorf(
+				"%w: recursive container element %v", ErrUnsupportedType, elem,
+			)
+		}
+	}
+
+	parentIndex := uint32(len(stack))
+	stack = append(stack, stackFrame[S]{
+		Type:             ExpectTypeContainer,
+		Typ:              getTyp(t),
+		RType:            t,
+		Size:             t.Size(),
+		ContainerFactory: factory,
+		ParentFrameIndex: noParentFrame,
+	})
+	newAtIndex := len(stack)
+	var err error
+	if stack, err = appendTypeToStack(stack, elem, options); err != nil {
+		return nil, err
+	}
+	// Link container element to the container frame.
+	stack[newAtIndex].ParentFrameIndex = parentIndex
+	return stack, nil
+}
+
+// completeContainer copies the container created for the container frame si
+// to p once all of its elements were added.
+func (d *Decoder[S, T]) completeContainer(si uint32, p unsafe.Pointer) {
+	f := &d.stackExp[si]
+	typedmemmove(f.Typ, p, f.ContainerDst)
+	f.ContainerDst, f.ContainerAdd = nil, nil
+}
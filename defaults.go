@@ -0,0 +1,63 @@
+package jscandec
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"unsafe"
+
+	"github.com/romshark/jscan/v2"
+)
+
+// fieldDefault is the default value of a struct field
+// defined by the `default` struct tag.
+type fieldDefault struct {
+	// FrameIndex defines the stack index of the field's value frame.
+	FrameIndex uint32
+
+	// Offset is the offset of the field in the struct.
+	Offset uintptr
+
+	// Typ is the type of the field.
+	Typ *typ
+
+	// Value points to the decoded default value of type Typ.
+	Value unsafe.Pointer
+}
+
+// parseDefault decodes the `default` struct tag value tag of field f
+// with value frame type t. Strings are taken verbatim while all other
+// scalar types are decoded from tag as JSON, such as `default:"8080"`.
+func parseDefault(f reflect.StructField, t ExpectType, tag string) (unsafe.Pointer, error) {
+	if !t.isFlatScalar() {
+		return nil, fmt.Errorf(
+			"%w: field %s of type %v", ErrDefaultTagOnUnsupportedType, f.Name, f.Type,
+		)
+	}
+	v := reflect.New(f.Type)
+	if t == ExpectTypeStr {
+		v.Elem().SetString(tag)
+	} else if err := json.Unmarshal([]byte(tag), v.Interface()); err != nil {
+		return nil, fmt.Errorf("%w: field %s: %v", ErrInvalidDefault, f.Name, err)
+	}
+	return v.UnsafePointer(), nil
+}
+
+// applyDefaults assigns the default values of all fields of the struct frame
+// si that have none of their names present as keys of the object at
+// tokens[ti] to the struct at p. Keys with a null value count as present.
+// Merge patches leave absent fields unchanged and apply no defaults.
+func (d *Decoder[S, T]) applyDefaults(
+	s S, tokens []jscan.Token[S], ti int, si uint32, p unsafe.Pointer,
+	options *DecodeOptions,
+) {
+	if options.mergePatch {
+		return
+	}
+	for _, def := range d.stackExp[si].Defaults {
+		if d.hasField(s, tokens, ti, si, def.FrameIndex, options) {
+			continue
+		}
+		typedmemmove(def.Typ, unsafe.Add(p, def.Offset), def.Value)
+	}
+}
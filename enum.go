@@ -0,0 +1,21 @@
+package jscandec
+
+import "reflect"
+
+// enumValue returns the value of enum equal to v and true,
+// otherwise returns false. The value of enum is returned instead of v
+// to not retain the memory of the input v may be a slice of.
+func enumValue(enum []string, v string) (string, bool) {
+	for _, e := range enum {
+		if e == v {
+			return e, true
+		}
+	}
+	return "", false
+}
+
+// isEnum returns true if t is constrained by options.EnumConstraints.
+func isEnum(options *InitOptions, t reflect.Type) bool {
+	_, ok := options.EnumConstraints[t]
+	return ok
+}
@@ -0,0 +1,56 @@
+package jscandec
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/romshark/jscan-experimental-decoder/internal/unescape"
+
+	"github.com/romshark/jscan/v2"
+)
+
+// keyPaths returns the JSON Pointer (RFC 6901) of the value of every key token
+// in tokens, indexed by the token index of the key.
+// Entries of tokens other than TokenTypeKey are empty.
+func keyPaths[S []byte | string](s S, tokens []jscan.Token[S]) []string {
+	type level struct {
+		Path       string
+		IsArray    bool
+		ArrayIndex int
+	}
+	paths := make([]string, len(tokens))
+	stack := make([]level, 0, 16)
+	for i := range tokens {
+		var path string
+		switch tokens[i].Type {
+		case jscan.TokenTypeKey:
+			key := unescape.Valid[S, string](s[tokens[i].Index+1 : tokens[i].End-1])
+			paths[i] = stack[len(stack)-1].Path + "/" + escapeJSONPointer(key)
+			continue
+		case jscan.TokenTypeObjectEnd, jscan.TokenTypeArrayEnd:
+			stack = stack[:len(stack)-1]
+			continue
+		}
+		if i > 0 && tokens[i-1].Type == jscan.TokenTypeKey {
+			path = paths[i-1]
+		} else if l := len(stack); l > 0 && stack[l-1].IsArray {
+			path = stack[l-1].Path + "/" + strconv.Itoa(stack[l-1].ArrayIndex)
+			stack[l-1].ArrayIndex++
+		}
+		switch tokens[i].Type {
+		case jscan.TokenTypeObject:
+			stack = append(stack, level{Path: path})
+		case jscan.TokenTypeArray:
+			stack = append(stack, level{Path: path, IsArray: true})
+		}
+	}
+	return paths
+}
+
+// escapeJSONPointer escapes '~' and '/' in a JSON Pointer reference token.
+func escapeJSONPointer(s string) string {
+	if !strings.ContainsAny(s, "~/") {
+		return s
+	}
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(s)
+}
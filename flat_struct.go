@@ -0,0 +1,393 @@
+package jscandec
+
+import (
+	"unsafe"
+
+	"github.com/romshark/jscan-experimental-decoder/internal/atoi"
+	"github.com/romshark/jscan-experimental-decoder/internal/unescape"
+
+	"github.com/romshark/jscan/v2"
+)
+
+// isFlatScalar returns true for the scalar types supported by decodeScalar.
+func (t ExpectType) isFlatScalar() bool {
+	switch t {
+	case ExpectTypeBool,
+		ExpectTypeStr,
+		ExpectTypeFloat32,
+		ExpectTypeFloat64,
+		ExpectTypeInt,
+		ExpectTypeInt8,
+		ExpectTypeInt16,
+		ExpectTypeInt32,
+		ExpectTypeInt64,
+		ExpectTypeUint,
+		ExpectTypeUint8,
+		ExpectTypeUint16,
+		ExpectTypeUint32,
+		ExpectTypeUint64:
+		return true
+	}
+	return false
+}
+
+// isFlatStruct returns true if the struct frame at index i is followed
+// exclusively by frames of scalar fields, which allows slices of it
+// to be decoded by decodeFlatStructSlice.
+func isFlatStruct[S []byte | string](stack []stackFrame[S], i int) bool {
+	f := &stack[i]
+	if f.Type != ExpectTypeStruct || f.HasRest || f.HasRaw || len(f.Defaults) > 0 || len(f.Required) > 0 ||
+		len(f.Fields)-f.FieldAliases != len(stack)-i-1 {
+		return false
+	}
+	for _, fl := range f.Fields {
+		if !stack[fl.FrameIndex].Type.isFlatScalar() {
+			return false
+		}
+	}
+	return true
+}
+
+// decodeFlatStructSlice decodes the elements of the array at tokens[ti]
+// into the slice data dp of the slice frame si, which is a slice of
+// a flat struct (see isFlatStruct).
+// Compared to the generic path it avoids the frame transitions
+// for every element and field. Only the first elems elements are decoded.
+// Returns the index of the token following the array.
+func (d *Decoder[S, T]) decodeFlatStructSlice(
+	s S, tokens []jscan.Token[S], ti int, si uint32, dp unsafe.Pointer,
+	elems uintptr, options *DecodeOptions, budget *allocBudget,
+) (next, errIndex int, err error) {
+	structFrame := &d.stackExp[si+1]
+	fields, size := structFrame.Fields, structFrame.Size
+	exact := options.DisableCaseInsensitiveMatching || d.exactOnly
+	end := tokens[ti].End
+	for ti++; elems > 0; dp, elems = unsafe.Add(dp, size), elems-1 {
+		switch tokens[ti].Type {
+		case jscan.TokenTypeNull:
+			// Skip
+			ti++
+			continue
+		case jscan.TokenTypeObject:
+		default:
+			return 0, tokens[ti].Index, ErrUnexpectedValue
+		}
+		objEnd := tokens[ti].End
+		for ti++; ti < objEnd; {
+			key := s[tokens[ti].Index+1 : tokens[ti].End-1]
+			if !options.DisableFieldNameUnescaping {
+				key = unescape.Valid[S, S](key)
+			}
+			var frameIndex uint32
+			if exact {
+				frameIndex = fieldFrameIndexByNameExact(fields, key)
+			} else {
+				frameIndex = fieldFrameIndexByName(fields, key)
+			}
+			skip := frameIndex == noParentFrame
+			if skip {
+				if err := options.unknownField(
+					tokens[ti].Index,
+					tokens[ti+1].Type == jscan.TokenTypeObject ||
+						tokens[ti+1].Type == jscan.TokenTypeArray,
+				); err != nil {
+					return 0, tokens[ti].Index, err
+				}
+			} else if options.IgnoreFields != nil &&
+				options.IgnoreFields[fieldName(fields, frameIndex)] {
+				skip = true
+			} else if options.DuplicateKeyStrategy != DuplicateKeyLast &&
+				d.isDuplicateField(s, tokens, ti, fields, frameIndex, options) {
+				if options.DuplicateKeyStrategy == DuplicateKeyError {
+					return 0, tokens[ti].Index, ErrDuplicateKey
+				}
+				skip = true // Keep the first value
+			}
+			if skip {
+				// Skip value, go to the next key
+				switch tokens[ti+1].Type {
+				case jscan.TokenTypeObject, jscan.TokenTypeArray:
+					ti = tokens[ti+1].End + 1
+				default:
+					ti += 2
+				}
+				continue
+			}
+			f := &d.stackExp[frameIndex]
+			if err := d.decodeScalar(
+				s, tokens[ti+1], f.Type, unsafe.Add(dp, f.Offset), options, budget,
+			); err != nil {
+				return 0, tokens[ti+1].Index, err
+			}
+			ti += 2
+		}
+		ti = objEnd + 1
+	}
+	return end + 1, 0, nil
+}
+
+// isDuplicateField returns true if a key preceding the key at tokens[ti]
+// in the same object matches the field frame frameIndex in fields.
+// Preceding values are skipped backwards since the end token of arrays and
+// objects refers to their start token. Scanning stops at the nearest
+// preceding match, which keeps the total cost per field linear
+// in the number of tokens of the object.
+func (d *Decoder[S, T]) isDuplicateField(
+	s S, tokens []jscan.Token[S], ti int, fields []fieldStackFrame,
+	frameIndex uint32, options *DecodeOptions,
+) bool {
+	exact := options.DisableCaseInsensitiveMatching || d.exactOnly
+	for i := ti - 1; tokens[i].Type != jscan.TokenTypeObject; i-- {
+		// tokens[i] is the last token of the value of the preceding key.
+		switch tokens[i].Type {
+		case jscan.TokenTypeObjectEnd, jscan.TokenTypeArrayEnd:
+			i = tokens[i].End
+		}
+		i-- // The preceding key.
+		key := s[tokens[i].Index+1 : tokens[i].End-1]
+		if !options.DisableFieldNameUnescaping {
+			key = unescape.Valid[S, S](key)
+		}
+		if exact {
+			if fieldFrameIndexByNameExact(fields, key) == frameIndex {
+				return true
+			}
+		} else if fieldFrameIndexByName(fields, key) == frameIndex {
+			return true
+		}
+	}
+	return false
+}
+
+// hasField returns true if any of the keys of the object at tokens[ti]
+// resolves to the field with value frame frameIndex of the struct frame si
+// the same way keys are resolved when decoding the object.
+func (d *Decoder[S, T]) hasField(
+	s S, tokens []jscan.Token[S], ti int, si, frameIndex uint32,
+	options *DecodeOptions,
+) bool {
+	fields := d.stackExp[si].Fields
+	exact := options.DisableCaseInsensitiveMatching || d.exactOnly
+	for i, end := ti+1, tokens[ti].End; i < end; {
+		key := s[tokens[i].Index+1 : tokens[i].End-1]
+		if !options.DisableFieldNameUnescaping {
+			key = unescape.Valid[S, S](key)
+		}
+		if exact {
+			if fieldFrameIndexByNameExact(fields, key) == frameIndex {
+				return true
+			}
+		} else if fieldFrameIndexByName(fields, key) == frameIndex {
+			return true
+		}
+		// Skip the value, go to the next key.
+		switch i++; tokens[i].Type {
+		case jscan.TokenTypeObject, jscan.TokenTypeArray:
+			i = tokens[i].End + 1
+		default:
+			i++
+		}
+	}
+	return false
+}
+
+// fieldFrameIndexByNameExact is like fieldFrameIndexByName
+// but without the case-insensitive fallback.
+func fieldFrameIndexByNameExact[S []byte | string](
+	fields []fieldStackFrame, name S,
+) uint32 {
+	for i := range fields {
+		if string(fields[i].Name) == string(name) {
+			return fields[i].FrameIndex
+		}
+	}
+	return noParentFrame
+}
+
+// decodeScalar decodes the value of token tok into p of scalar type t
+// (see isFlatScalar) the same way the generic path of Decode does.
+func (d *Decoder[S, T]) decodeScalar(
+	s S, tok jscan.Token[S], t ExpectType, p unsafe.Pointer,
+	options *DecodeOptions, budget *allocBudget,
+) error {
+	switch tok.Type {
+	case jscan.TokenTypeNull:
+		switch t {
+		case ExpectTypeBool:
+			*(*bool)(p) = zeroBool
+		case ExpectTypeStr:
+			*(*string)(p) = zeroStr
+		case ExpectTypeFloat32:
+			*(*float32)(p) = zeroFloat32
+		case ExpectTypeFloat64:
+			*(*float64)(p) = zeroFloat64
+		case ExpectTypeInt:
+			*(*int)(p) = zeroInt
+		case ExpectTypeInt8:
+			*(*int8)(p) = zeroInt8
+		case ExpectTypeInt16:
+			*(*int16)(p) = zeroInt16
+		case ExpectTypeInt32:
+			*(*int32)(p) = zeroInt32
+		case ExpectTypeInt64:
+			*(*int64)(p) = zeroInt64
+		case ExpectTypeUint:
+			*(*uint)(p) = zeroUint
+		case ExpectTypeUint8:
+			*(*uint8)(p) = zeroUint8
+		case ExpectTypeUint16:
+			*(*uint16)(p) = zeroUint16
+		case ExpectTypeUint32:
+			*(*uint32)(p) = zeroUint32
+		case ExpectTypeUint64:
+			*(*uint64)(p) = zeroUint64
+		}
+		return nil
+
+	case jscan.TokenTypeTrue, jscan.TokenTypeFalse:
+		if t != ExpectTypeBool {
+			return ErrUnexpectedValue
+		}
+		*(*bool)(p) = tok.Type == jscan.TokenTypeTrue
+		return nil
+
+	case jscan.TokenTypeString:
+		if t == ExpectTypeBool && options.ExtendedBoolStrings {
+			v, ok := parseExtendedBool(s[tok.Index+1 : tok.End-1])
+			if !ok {
+				return ErrUnexpectedValue
+			}
+			*(*bool)(p) = v
+			return nil
+		}
+		if t != ExpectTypeStr {
+			return ErrUnexpectedValue
+		}
+		if options.DisallowEmptyString && tok.End-tok.Index == len(`""`) {
+			// Fields of flat structs are always struct fields.
+			return ErrEmptyString
+		}
+		if stringTooLong(s, tok, options) {
+			return ErrLimitExceeded
+		}
+		if invalidUTF8(s, tok, options) {
+			return ErrInvalidUTF8
+		}
+		if !allocString(budget, s, tok) {
+			return ErrAllocBudgetExceeded
+		}
+		*(*string)(p) = options.transformValue(
+			unescape.Valid[S, string](s[tok.Index+1 : tok.End-1]),
+		)
+		return nil
+
+	case jscan.TokenTypeInteger:
+		if len(d.rewriteSpans) > 0 && t.isNonIntegerNumber() &&
+			hexLiteral(d.rewriteSpans, tok.Index) {
+			return ErrUnexpectedValue
+		}
+		tv := s[tok.Index:tok.End]
+		var overflow bool
+		switch t {
+		case ExpectTypeFloat32:
+			v, err := d.parseFloat32(tv)
+			if err != nil {
+				return err
+			}
+			checkPrecision(options, tok.Index, tv, float64(v))
+			*(*float32)(p) = v
+			return nil
+		case ExpectTypeFloat64:
+			v, err := d.parseFloat64(tv)
+			if err != nil {
+				return err
+			}
+			checkPrecision(options, tok.Index, tv, v)
+			*(*float64)(p) = v
+			return nil
+		case ExpectTypeInt:
+			v, err := d.parseInt(tv)
+			if err != nil {
+				return err
+			}
+			*(*int)(p) = v
+			return nil
+		case ExpectTypeInt8:
+			*(*int8)(p), overflow = atoi.I8(tv)
+		case ExpectTypeInt16:
+			*(*int16)(p), overflow = atoi.I16(tv)
+		case ExpectTypeInt32:
+			*(*int32)(p), overflow = atoi.I32(tv)
+		case ExpectTypeInt64:
+			*(*int64)(p), overflow = atoi.I64(tv)
+		case ExpectTypeUint, ExpectTypeUint8, ExpectTypeUint16,
+			ExpectTypeUint32, ExpectTypeUint64:
+			if tv[0] == '-' {
+				return ErrUnexpectedValue
+			}
+			switch t {
+			case ExpectTypeUint:
+				v, err := d.parseUint(tv)
+				if err != nil {
+					return err
+				}
+				*(*uint)(p) = v
+			case ExpectTypeUint8:
+				*(*uint8)(p), overflow = atoi.U8(tv)
+			case ExpectTypeUint16:
+				*(*uint16)(p), overflow = atoi.U16(tv)
+			case ExpectTypeUint32:
+				*(*uint32)(p), overflow = atoi.U32(tv)
+			case ExpectTypeUint64:
+				*(*uint64)(p), overflow = atoi.U64(tv)
+			}
+		default:
+			return ErrUnexpectedValue
+		}
+		if overflow {
+			return ErrIntegerOverflow
+		}
+		return nil
+
+	case jscan.TokenTypeNumber:
+		tv := s[tok.Index:tok.End]
+		var err error
+		switch t {
+		case ExpectTypeFloat32:
+			*(*float32)(p), err = d.parseFloat32(tv)
+			return err
+		case ExpectTypeFloat64:
+			*(*float64)(p), err = d.parseFloat64(tv)
+			return err
+		case ExpectTypeBool, ExpectTypeStr:
+			return ErrUnexpectedValue
+		}
+		if !options.AllowFloatAsInt {
+			return ErrUnexpectedValue
+		}
+		switch t {
+		case ExpectTypeInt:
+			*(*int)(p), err = floatAsInt[int](tv)
+		case ExpectTypeInt8:
+			*(*int8)(p), err = floatAsInt[int8](tv)
+		case ExpectTypeInt16:
+			*(*int16)(p), err = floatAsInt[int16](tv)
+		case ExpectTypeInt32:
+			*(*int32)(p), err = floatAsInt[int32](tv)
+		case ExpectTypeInt64:
+			*(*int64)(p), err = floatAsInt[int64](tv)
+		case ExpectTypeUint:
+			*(*uint)(p), err = floatAsInt[uint](tv)
+		case ExpectTypeUint8:
+			*(*uint8)(p), err = floatAsInt[uint8](tv)
+		case ExpectTypeUint16:
+			*(*uint16)(p), err = floatAsInt[uint16](tv)
+		case ExpectTypeUint32:
+			*(*uint32)(p), err = floatAsInt[uint32](tv)
+		case ExpectTypeUint64:
+			*(*uint64)(p), err = floatAsInt[uint64](tv)
+		}
+		return err
+	}
+	return ErrUnexpectedValue
+}
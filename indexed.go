@@ -0,0 +1,93 @@
+package jscandec
+
+import (
+	"fmt"
+	"reflect"
+	"unsafe"
+
+	"github.com/romshark/jscan-experimental-decoder/internal/unescape"
+)
+
+// DecodeArrayIndexed decodes the elements of the top-level JSON array in s
+// into values of struct type V using d and inserts them into *dst keyed by
+// the value of their field keyField, such as `[{"id":"a"},{"id":"b"}]` into
+// a map[string]V keyed by "id". keyField is the JSON name of the field,
+// which must be of type K. A new map is allocated if *dst is nil, existing
+// entries are kept unless overwritten. Elements with duplicate keys
+// overwrite preceding ones.
+// Elements inserted before an error is encountered remain inserted.
+// Returns ErrMissingKeyField at the index of an element that doesn't
+// contain keyField and ErrUnsupportedType if V isn't a struct with
+// field keyField of type K. Other errors are reported like by
+// DecodeArrayToChan.
+//
+// InitOptions.SlicePool must not be used since the pooled backing arrays
+// are reused by subsequent elements.
+func DecodeArrayIndexed[S []byte | string, K comparable, V any](
+	d *Decoder[S, V], s S, keyField string, dst *map[K]V, options *DecodeOptions,
+) (errIndex int, err error) {
+	if dst == nil {
+		return 0, ErrNilDest
+	}
+	switch d.stackExp[0].Type {
+	case ExpectTypeStruct, ExpectTypeStructRecur:
+	default:
+		return 0, fmt.Errorf("%w: indexing non-struct %v", ErrUnsupportedType,
+			reflect.TypeOf((*V)(nil)).Elem())
+	}
+	fields := d.stackExp[0].Fields
+	keyFrame := fieldFrameIndexByNameExact(fields, keyField)
+	if keyFrame == noParentFrame {
+		return 0, fmt.Errorf("%w: %v has no field %q", ErrUnsupportedType,
+			reflect.TypeOf((*V)(nil)).Elem(), keyField)
+	}
+	if tk := reflect.TypeOf((*K)(nil)).Elem(); d.stackExp[keyFrame].Typ != getTyp(tk) {
+		return 0, fmt.Errorf("%w: field %q isn't of key type %v",
+			ErrUnsupportedType, keyField, tk)
+	}
+	offset := d.stackExp[keyFrame].Offset
+	exact := options.DisableCaseInsensitiveMatching || d.exactOnly
+
+	if *dst == nil {
+		*dst = map[K]V{}
+	}
+	m := *dst
+	return d.decodeArrayElements(s, options, func(v V, src S, index int) (int, error) {
+		if !hasObjectMember(src, func(name S) bool {
+			if !options.DisableFieldNameUnescaping {
+				name = unescape.Valid[S, S](name)
+			}
+			if exact {
+				return fieldFrameIndexByNameExact(fields, name) == keyFrame
+			}
+			return fieldFrameIndexByName(fields, name) == keyFrame
+		}) {
+			return index, ErrMissingKeyField
+		}
+		m[*(*K)(unsafe.Add(unsafe.Pointer(&v), offset))] = v
+		return 0, nil
+	})
+}
+
+// hasObjectMember returns true if s is a valid JSON object with a member
+// whose name, without the quotes and still escaped, satisfies match.
+func hasObjectMember[S []byte | string](s S, match func(name S) bool) bool {
+	i := skipSpace(s, 0)
+	if i >= len(s) || s[i] != '{' {
+		return false
+	}
+	for i = skipSpace(s, i+1); i < len(s) && s[i] == '"'; {
+		end := valueEnd(s, i)
+		if match(s[i+1 : end-1]) {
+			return true
+		}
+		i = skipSpace(s, end)            // Colon
+		i = skipSpace(s, i+1)            // Value
+		i = skipSpace(s, valueEnd(s, i)) // Comma or closing brace
+		if i >= len(s) || s[i] != ',' {
+			return false
+		}
+		i = skipSpace(s, i+1)
+	}
+	return false
+}
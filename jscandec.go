@@ -3,12 +3,17 @@ package jscandec
 import (
 	"bytes"
 	"encoding"
+	"encoding/base64"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"math"
+	"math/big"
 	"reflect"
 	"strconv"
 	"strings"
+	"time"
+	"unicode/utf8"
 	"unsafe"
 
 	"github.com/romshark/jscan-experimental-decoder/internal/atoi"
@@ -26,8 +31,96 @@ var (
 	ErrUnexpectedValue = errors.New("unexpected value")
 	ErrUnknownField    = errors.New("unknown field")
 	ErrIntegerOverflow = errors.New("integer overflow")
+
+	ErrAllocBudgetExceeded = errors.New("allocation budget exceeded")
+
+	ErrUnknownCompositeField = errors.New("unknown field with object or array value")
+
+	ErrRestTagOptionOnUnsupportedType = errors.New(
+		"invalid use of the `rest` tag option on type other than map[string]any",
+	)
+	ErrMultipleRestFields = errors.New("multiple fields with the `rest` tag option")
+
+	ErrRawTagOptionOnUnsupportedType = errors.New(
+		"invalid use of the `raw` tag option on type other than []byte",
+	)
+	ErrMultipleRawFields = errors.New("multiple fields with the `raw` tag option")
+
+	ErrPairsTagOptionOnUnsupportedType = errors.New(
+		"invalid use of the `pairs` tag option on type other than " +
+			"[]struct{Key string; Value V}",
+	)
+
+	ErrBytesTagOptionOnUnsupportedType = errors.New(
+		"invalid use of the `bytes` tag option on type other than []byte",
+	)
+
+	ErrDefaultTagOnUnsupportedType = errors.New(
+		"invalid use of the `default` struct tag on non-scalar type",
+	)
+	ErrInvalidDefault = errors.New("invalid `default` struct tag value")
+
+	// ErrMissingRequiredField is returned at the index of an object
+	// that's missing a field with the `required:"true"` struct tag.
+	ErrMissingRequiredField = errors.New("missing required field")
+
+	// ErrUnsupportedType is returned by NewDecoder for types that can't be
+	// decoded into, such as channels, functions, complex numbers, unsafe.Pointer
+	// and uintptr. Unlike encoding/json, uintptr and named types of kind uintptr
+	// are rejected on purpose since they're meant to hold memory addresses.
+	// Structs with fields promoted through embedded struct pointers
+	// are rejected too.
+	ErrUnsupportedType = errors.New("unsupported type")
+
+	ErrMaxDepthExceeded = errors.New("maximum depth exceeded")
+	ErrLimitExceeded    = errors.New("limit exceeded")
+
+	ErrInvalidUTF8 = errors.New("invalid UTF-8 in string")
+
+	ErrEmptyString = errors.New("empty string")
+
+	ErrDuplicateKey = errors.New("duplicate key")
+
+	// ErrMissingKeyField is returned by DecodeArrayIndexed for array
+	// elements that don't contain the key field.
+	ErrMissingKeyField = errors.New("missing key field")
+
+	ErrNonCanonicalNumber = errors.New("non-canonical number")
+
+	// ErrImpreciseNumber is reported through DecodeOptions.Warnings
+	// for integers that can't be represented exactly by the float
+	// they're decoded into.
+	ErrImpreciseNumber = errors.New("imprecise number")
+
+	// ErrSchemaMismatch is returned by Decode if the input lacks
+	// the field required by InitOptions.RequireField or its value differs.
+	ErrSchemaMismatch = errors.New("schema mismatch")
+
+	// ErrUnsupportedInterface is returned for interface types other than
+	// the empty interface since the concrete type to decode into is unknown,
+	// unless it's registered in InitOptions.ConcreteTypes.
+	// It wraps ErrUnsupportedType.
+	ErrUnsupportedInterface = fmt.Errorf(
+		"%w: non-empty interface", ErrUnsupportedType,
+	)
 )
 
+// SyntaxErrorCode returns the jscan error code and true if err
+// is a syntax error originating from tokenization, such as
+// jscan.ErrorCodeUnexpectedEOF for truncated input.
+// Returns false for semantic decode errors such as ErrUnexpectedValue.
+func SyntaxErrorCode(err error) (jscan.ErrorCode, bool) {
+	var errStr jscan.Error[string]
+	if errors.As(err, &errStr) {
+		return errStr.Code, true
+	}
+	var errBytes jscan.Error[[]byte]
+	if errors.As(err, &errBytes) {
+		return errBytes.Code, true
+	}
+	return 0, false
+}
+
 // Number represents a JSON number literal.
 type Number string
 
@@ -44,14 +137,21 @@ func (n Number) Int64() (int64, error) {
 	return strconv.ParseInt(string(n), 10, 64)
 }
 
-var tpNumber = reflect.TypeOf(Number(""))
+var (
+	tpNumber     = reflect.TypeOf(Number(""))
+	tpJSONNumber = reflect.TypeOf(json.Number(""))
+	tpTime       = reflect.TypeOf(time.Time{})
+	tpRawMessage = reflect.TypeOf(json.RawMessage(nil))
+	tpError      = reflect.TypeOf((*error)(nil)).Elem()
+	tpBigRat     = reflect.TypeOf(big.Rat{})
+)
 
 type ExpectType int8
 
 const (
 	_ ExpectType = iota
 
-	// ExpectTypeNumber is the type `jscandec.Number`
+	// ExpectTypeNumber is the type `jscandec.Number` or `encoding/json.Number`
 	ExpectTypeNumber
 
 	// ExpectTypeJSONUnmarshaler is any type that implements
@@ -68,15 +168,36 @@ const (
 	// ExpectTypePtrRecur is any recursive pointer type (used for recursive struct fields)
 	ExpectTypePtrRecur
 
+	// ExpectTypeOptional is any type `jscandec.Optional[T]`
+	ExpectTypeOptional
+
 	// ExpectTypeAny is type `any`
 	ExpectTypeAny
 
+	// ExpectTypeError is type `error`
+	ExpectTypeError
+
+	// ExpectTypeInterface is any non-empty interface type with a concrete type
+	// registered in InitOptions.ConcreteTypes
+	ExpectTypeInterface
+
+	// ExpectTypeBigRat is type `math/big.Rat`
+	ExpectTypeBigRat
+
 	// ExpectTypeMap is any map type
 	ExpectTypeMap
 
 	// ExpectTypeMapStringString is `map[string]string`
 	ExpectTypeMapStringString
 
+	// ExpectTypeMapStringRawMessage is `map[string]json.RawMessage`
+	ExpectTypeMapStringRawMessage
+
+	// ExpectTypeMapNested is a chain of at least two nested maps with string
+	// keys ending in a scalar value type, such as
+	// `map[string]map[string]int` (see decodeNestedMap)
+	ExpectTypeMapNested
+
 	// ExpectTypeMapRecur is any recursive map type (used for recursive struct fields)
 	ExpectTypeMapRecur
 
@@ -86,6 +207,20 @@ const (
 	// ExpectTypeArrayLen0 is any zero-length array type (like [0]int)
 	ExpectTypeArrayLen0
 
+	// ExpectTypeArrayBool is any non-zero-length array of bool (like [4]bool)
+	ExpectTypeArrayBool
+
+	// ExpectTypeArrayStr is any non-zero-length array of string (like [4]string)
+	ExpectTypeArrayStr
+
+	// ExpectTypeArrayFloat32 is any non-zero-length array of float32
+	// (like [4]float32)
+	ExpectTypeArrayFloat32
+
+	// ExpectTypeArrayFloat64 is any non-zero-length array of float64
+	// (like [4]float64)
+	ExpectTypeArrayFloat64
+
 	// ExpectTypeSlice is any slice type
 	ExpectTypeSlice
 
@@ -137,6 +272,17 @@ const (
 	// ExpectTypeSliceFloat64 is type `[]float64`
 	ExpectTypeSliceFloat64
 
+	// ExpectTypeSliceTime is type `[]time.Time`
+	ExpectTypeSliceTime
+
+	// ExpectTypeSlicePairs is any `[]struct{Key string; Value V}` type
+	// with `json:",pairs"` tag
+	ExpectTypeSlicePairs
+
+	// ExpectTypeContainer is any type registered in
+	// InitOptions.ContainerFactories
+	ExpectTypeContainer
+
 	// ExpectTypeStruct is any struct type except `struct{}`
 	ExpectTypeStruct
 
@@ -152,6 +298,10 @@ const (
 	// ExpectTypeStr is type `string`
 	ExpectTypeStr
 
+	// ExpectTypeStrEnum is any string type constrained
+	// by InitOptions.EnumConstraints
+	ExpectTypeStrEnum
+
 	// ExpectTypeFloat32 is type `float32`
 	ExpectTypeFloat32
 
@@ -229,6 +379,9 @@ const (
 
 	// ExpectTypeUint64String is type `uint64` with `json:",string"` tag
 	ExpectTypeUint64String
+
+	// ExpectTypeBytesString is type `[]byte` with `json:",bytes"` tag
+	ExpectTypeBytesString
 )
 
 func (t ExpectType) String() string {
@@ -243,18 +396,38 @@ func (t ExpectType) String() string {
 		return "*"
 	case ExpectTypePtrRecur:
 		return "*⟲"
+	case ExpectTypeOptional:
+		return "optional"
 	case ExpectTypeAny:
 		return "any"
+	case ExpectTypeError:
+		return "error"
+	case ExpectTypeInterface:
+		return "interface"
+	case ExpectTypeBigRat:
+		return "big.Rat"
 	case ExpectTypeMap:
 		return "map"
 	case ExpectTypeMapStringString:
 		return "map[string]string"
+	case ExpectTypeMapStringRawMessage:
+		return "map[string]json.RawMessage"
+	case ExpectTypeMapNested:
+		return "map[string]map[string]…"
 	case ExpectTypeMapRecur:
 		return "map⟲"
 	case ExpectTypeArray:
 		return "array"
 	case ExpectTypeArrayLen0:
 		return "[0]array"
+	case ExpectTypeArrayBool:
+		return "[N]bool"
+	case ExpectTypeArrayStr:
+		return "[N]string"
+	case ExpectTypeArrayFloat32:
+		return "[N]float32"
+	case ExpectTypeArrayFloat64:
+		return "[N]float64"
 	case ExpectTypeSlice:
 		return "slice"
 	case ExpectTypeSliceRecur:
@@ -289,6 +462,12 @@ func (t ExpectType) String() string {
 		return "[]float32"
 	case ExpectTypeSliceFloat64:
 		return "[]float64"
+	case ExpectTypeSliceTime:
+		return "[]time.Time"
+	case ExpectTypeSlicePairs:
+		return "pairs"
+	case ExpectTypeContainer:
+		return "container"
 	case ExpectTypeStruct:
 		return "struct"
 	case ExpectTypeStructRecur:
@@ -299,6 +478,8 @@ func (t ExpectType) String() string {
 		return "boolean"
 	case ExpectTypeStr:
 		return "string"
+	case ExpectTypeStrEnum:
+		return "string enum"
 	case ExpectTypeFloat32:
 		return "float32"
 	case ExpectTypeFloat64:
@@ -351,19 +532,86 @@ func (t ExpectType) String() string {
 		return "string(uint32)"
 	case ExpectTypeUint64String:
 		return "string(uint64)"
+	case ExpectTypeBytesString:
+		return "bytes"
 	}
 	return ""
 }
 
+// isStringTagType returns true for types with the `string` struct tag option
+// (like ExpectTypeIntString), otherwise returns false.
+func (t ExpectType) isStringTagType() bool {
+	switch t {
+	case ExpectTypeBoolString,
+		ExpectTypeStrString,
+		ExpectTypeFloat32String,
+		ExpectTypeFloat64String,
+		ExpectTypeIntString,
+		ExpectTypeInt8String,
+		ExpectTypeInt16String,
+		ExpectTypeInt32String,
+		ExpectTypeInt64String,
+		ExpectTypeUintString,
+		ExpectTypeUint8String,
+		ExpectTypeUint16String,
+		ExpectTypeUint32String,
+		ExpectTypeUint64String:
+		return true
+	}
+	return false
+}
+
+// isContainer returns true for slice, map and array types,
+// otherwise returns false.
+func (t ExpectType) isContainer() bool {
+	switch t {
+	case ExpectTypeMap,
+		ExpectTypeMapStringString,
+		ExpectTypeMapStringRawMessage,
+		ExpectTypeMapNested,
+		ExpectTypeMapRecur,
+		ExpectTypeArray,
+		ExpectTypeArrayLen0,
+		ExpectTypeArrayBool,
+		ExpectTypeArrayStr,
+		ExpectTypeArrayFloat32,
+		ExpectTypeArrayFloat64,
+		ExpectTypeSlice,
+		ExpectTypeSliceRecur,
+		ExpectTypeSliceEmptyStruct,
+		ExpectTypeSliceBool,
+		ExpectTypeSliceString,
+		ExpectTypeSliceInt,
+		ExpectTypeSliceInt8,
+		ExpectTypeSliceInt16,
+		ExpectTypeSliceInt32,
+		ExpectTypeSliceInt64,
+		ExpectTypeSliceUint,
+		ExpectTypeSliceUint8,
+		ExpectTypeSliceUint16,
+		ExpectTypeSliceUint32,
+		ExpectTypeSliceUint64,
+		ExpectTypeSliceFloat32,
+		ExpectTypeSliceFloat64,
+		ExpectTypeSliceTime,
+		ExpectTypeSlicePairs,
+		ExpectTypeContainer:
+		return true
+	}
+	return false
+}
+
 // isElemComposite returns false for non-composite slice item types,
 // otherwise returns true.
 func (t ExpectType) isElemComposite() bool {
 	switch t {
 	case ExpectTypePtr,
+		ExpectTypeInterface,
 		ExpectTypeArrayLen0, // Zero-length arrays require no memory
 		ExpectTypeEmptyStruct,
 		ExpectTypeBool,
 		ExpectTypeStr,
+		ExpectTypeStrEnum,
 		ExpectTypeFloat32,
 		ExpectTypeFloat64,
 		ExpectTypeInt,
@@ -401,6 +649,14 @@ type recursionStackFrame struct {
 
 	// ContainerFrame stores the index of the recursive container frame.
 	ContainerFrame uint32
+
+	// ContainerDest and ContainerOffset store the destination and offset
+	// for the ExpectTypePtrRecur container frame to be reset to.
+	// A pointer frame that is a slice or array element is shared with
+	// the nested levels of the recursion and would otherwise lose
+	// its position in the parent slice.
+	ContainerDest   unsafe.Pointer
+	ContainerOffset uintptr
 }
 
 type stackFrame[S []byte | string] struct {
@@ -417,6 +673,28 @@ type stackFrame[S []byte | string] struct {
 	// MapValueType is relevant to map frames only.
 	MapValueType *typ
 
+	// Enum is relevant to ExpectTypeStrEnum frames only and defines
+	// the allowed values.
+	Enum []string
+
+	// ContainerFactory is relevant to ExpectTypeContainer frames only.
+	ContainerFactory func(elems int) (dst unsafe.Pointer, add func(elem unsafe.Pointer))
+
+	// ContainerDst and ContainerAdd are relevant to ExpectTypeContainer frames
+	// only and hold the container being decoded created by ContainerFactory.
+	ContainerDst unsafe.Pointer
+	ContainerAdd func(elem unsafe.Pointer)
+
+	// MapLevels and MapLeaf are relevant to ExpectTypeMapNested frames only
+	// and define the maps of the chain from the outermost to the innermost
+	// and the scalar type of the values of the innermost map.
+	MapLevels []nestedMapLevel
+	MapLeaf   ExpectType
+
+	// Itab is relevant to ExpectTypeInterface frames only and defines the
+	// first word of the interface header for the registered concrete type.
+	Itab unsafe.Pointer
+
 	// Size defines the number of bytes the data would occupy in memory.
 	// Size caches reflect.Type.Size() for faster access.
 	Size uintptr
@@ -428,11 +706,28 @@ type stackFrame[S []byte | string] struct {
 	// Cap defines the capacity of the parent array for array item frames.
 	Cap int
 
+	// RestOffset is relevant to struct frames with HasRest only and defines
+	// the offset of the `map[string]any` field tagged with the `rest` option.
+	RestOffset uintptr
+
+	// RawOffset is relevant to struct frames with HasRaw only and defines
+	// the offset of the `[]byte` field tagged with the `raw` option.
+	RawOffset uintptr
+
+	// PairTyp, PairSize and PairKeyOffset are relevant to ExpectTypeSlicePairs
+	// frames only and define the pair struct type, its size and the offset
+	// of its Key field. The offset of the Value field is the static Offset
+	// of the value frame.
+	PairTyp       *typ
+	PairSize      uintptr
+	PairKeyOffset uintptr
+
 	// RecurFrame defines the index of the recursive ExpectTypeStructRecur frame and
 	// is relevant to ExpectTypePtrRecur, ExpectTypeMapRecur and ExpectTypeSliceRecur.
 	RecurFrame int
 
-	// Len is relevant to array frames only and defines their current length.
+	// Len is relevant to array and ExpectTypeSlicePairs frames only
+	// and defines their current length.
 	Len int // Overwritten at runtime
 
 	// Dest defines the destination memory to write the data to.
@@ -458,6 +753,41 @@ type stackFrame[S []byte | string] struct {
 	// MapCanUseAssignFaststr is only relevant to map frames and indicates whether
 	// mapassign_faststr can be used instead of mapassign.
 	MapCanUseAssignFaststr bool
+
+	// HasRest is relevant to struct frames only and indicates whether the struct
+	// has a field tagged with the `rest` option receiving all unknown fields.
+	HasRest bool
+
+	// HasRaw is relevant to struct frames only and indicates whether the struct
+	// has a field tagged with the `raw` option receiving the verbatim object.
+	HasRaw bool
+
+	// FieldAliases is relevant to struct frames only and defines the number
+	// of entries at the end of Fields that are alternative names of fields
+	// defined by the `jsonalias` struct tag.
+	FieldAliases int
+
+	// Defaults is relevant to struct frames only and defines the default
+	// values of fields with the `default` struct tag.
+	Defaults []fieldDefault
+
+	// Required is relevant to struct frames only and defines the value frame
+	// indexes of fields with the `required:"true"` struct tag.
+	Required []uint32
+
+	// Pool is relevant to ExpectTypeSlice frames only and retains
+	// the backing array of the slice if InitOptions.SlicePool is enabled.
+	Pool slicePool
+
+	// FlatStruct is relevant to ExpectTypeSlice frames only and indicates
+	// that the element type is a struct of only scalar fields,
+	// which is decoded by decodeFlatStructSlice.
+	FlatStruct bool
+
+	// Singleton is relevant to ExpectTypeSlice frames only and indicates
+	// that the object currently being decoded is the only element of
+	// the slice (see DecodeOptions.ObjectAsSingletonSlice).
+	Singleton bool
 }
 
 // noParentFrame uses math.MaxUint32 because the length of the decoder stack
@@ -486,7 +816,7 @@ func Unmarshal[S []byte | string, T any](s S, t *T) error {
 	}
 	stack := make([]stackFrame[S], 0, 4)
 	var err error
-	stack, err = appendTypeToStack(stack, reflect.TypeOf(*t), DefaultInitOptions)
+	stack, err = appendTypeToStack(stack, reflect.TypeOf(t).Elem(), DefaultInitOptions)
 	if err != nil {
 		return err
 	}
@@ -510,6 +840,23 @@ type Decoder[S []byte | string, T any] struct {
 	parseUint    func(s S) (uint, error)
 	parseFloat32 func(s S) (float32, error)
 	parseFloat64 func(s S) (float64, error)
+	rewriteSpans []rewriteSpan
+	mergeStack   []pendingMerge
+
+	// decodedFields is only used if DecodeOptions.OnFieldDecoded != nil.
+	decodedFields []decodedField
+
+	// exactOnly is set by InitOptions.PrecomputeExactOnly.
+	exactOnly bool
+
+	// slicePool is set by InitOptions.SlicePool.
+	slicePool bool
+
+	// fastFloat is set by InitOptions.FastFloatParsing.
+	fastFloat bool
+
+	// requireField is set by InitOptions.RequireField.
+	requireField RequiredField
 }
 
 // NewDecoder creates a new reusable decoder instance.
@@ -519,15 +866,27 @@ type Decoder[S []byte | string, T any] struct {
 func NewDecoder[S []byte | string, T any](
 	tokenizer *jscan.Tokenizer[S],
 	options *InitOptions,
+) (*Decoder[S, T], error) {
+	// Go through the pointer type since reflect.TypeOf of a nil interface is nil.
+	return newDecoder[S, T](tokenizer, reflect.TypeOf((*T)(nil)).Elem(), options)
+}
+
+// newDecoder creates a new decoder instance for type t, which is T
+// unless the decoder is used by AnyDecoder.
+func newDecoder[S []byte | string, T any](
+	tokenizer *jscan.Tokenizer[S], t reflect.Type, options *InitOptions,
 ) (*Decoder[S, T], error) {
 	d := &Decoder[S, T]{
-		tokenizer: tokenizer,
-		stackExp:  make([]stackFrame[S], 0, 4),
+		tokenizer:    tokenizer,
+		stackExp:     make([]stackFrame[S], 0, 4),
+		exactOnly:    options.PrecomputeExactOnly,
+		slicePool:    options.SlicePool,
+		fastFloat:    options.FastFloatParsing,
+		requireField: options.RequireField,
 	}
 
-	var z T
 	var err error
-	d.stackExp, err = appendTypeToStack(d.stackExp, reflect.TypeOf(z), options)
+	d.stackExp, err = appendTypeToStack(d.stackExp, t, options)
 	if err != nil {
 		return nil, err
 	}
@@ -536,6 +895,125 @@ func NewDecoder[S []byte | string, T any](
 	return d, nil
 }
 
+// Clone creates a copy of the decoder without rebuilding its type stack.
+// The clone gets its own tokenizer and shares no mutable state with d,
+// which allows using it concurrently with d.
+func (d *Decoder[S, T]) Clone() *Decoder[S, T] {
+	c := &Decoder[S, T]{
+		tokenizer: jscan.NewTokenizer[S](
+			jscan.DefaultStackSizeTokenizer, jscan.DefaultTokenBufferSize,
+		),
+		stackExp:     make([]stackFrame[S], len(d.stackExp)),
+		exactOnly:    d.exactOnly,
+		slicePool:    d.slicePool,
+		fastFloat:    d.fastFloat,
+		requireField: d.requireField,
+	}
+	copy(c.stackExp, d.stackExp)
+	for i := range c.stackExp {
+		f := &c.stackExp[i]
+		if f.Fields != nil {
+			f.Fields = append([]fieldStackFrame(nil), f.Fields...)
+		}
+		if f.RecursionStack != nil {
+			f.RecursionStack = make([]recursionStackFrame, 0, cap(f.RecursionStack))
+		}
+		f.Dest, f.Len, f.Pool = nil, 0, slicePool{}
+		f.ContainerDst, f.ContainerAdd = nil, nil
+	}
+	c.init()
+	return c
+}
+
+// Grow pre-allocates internal buffers for inputs of up to expectedTokens tokens
+// and a nesting depth of up to expectedDepth to avoid dynamic memory allocation
+// during the first calls to Decode.
+// The decoder's tokenizer is grown as well, which will affect all decoders sharing it.
+func (d *Decoder[S, T]) Grow(expectedTokens, expectedDepth int) {
+	for i := range d.stackExp {
+		if d.stackExp[i].Type != ExpectTypeStructRecur ||
+			cap(d.stackExp[i].RecursionStack) >= expectedDepth {
+			continue
+		}
+		d.stackExp[i].RecursionStack = make([]recursionStackFrame, 0, expectedDepth)
+	}
+
+	if expectedTokens < 1 && expectedDepth < 1 {
+		return
+	}
+	// jscan doesn't provide a way to grow the tokenizer buffers directly,
+	// therefore tokenize a synthetic input of the expected size and depth instead.
+	depth := expectedDepth
+	if depth < 1 {
+		depth = 1
+	}
+	values := expectedTokens - depth*2
+	b := make([]byte, 0, depth*2+values*2)
+	for i := 0; i < depth; i++ {
+		b = append(b, '[')
+	}
+	for i := 0; i < values; i++ {
+		if i > 0 {
+			b = append(b, ',')
+		}
+		b = append(b, '0')
+	}
+	for i := 0; i < depth; i++ {
+		b = append(b, ']')
+	}
+	_ = d.tokenizer.Tokenize(S(b), func([]jscan.Token[S]) bool { return false })
+}
+
+// InputType returns the type the decoder decodes into.
+func (d *Decoder[S, T]) InputType() reflect.Type {
+	return reflect.TypeOf((*T)(nil)).Elem()
+}
+
+// DescribeStack returns a human-readable description of every frame of the
+// decoder's compiled type stack, which is useful for diagnosing how a type
+// is decoded. Each line contains the frame index, the expected type,
+// the offset and the index of the parent frame (or "-" for the root frame).
+// Struct frames list the frame index of every field and recursive frames
+// (marked with ⟲) refer to the frame of the recursive struct.
+// The format is meant for humans and isn't guaranteed to be stable.
+func (d *Decoder[S, T]) DescribeStack() []string {
+	lines := make([]string, len(d.stackExp))
+	for i := range d.stackExp {
+		f := &d.stackExp[i]
+		var b strings.Builder
+		b.WriteString(strconv.Itoa(i))
+		b.WriteString(": ")
+		b.WriteString(f.Type.String())
+		b.WriteString(" offset=")
+		b.WriteString(strconv.FormatUint(uint64(f.Offset), 10))
+		b.WriteString(" parent=")
+		if f.ParentFrameIndex == noParentFrame {
+			b.WriteString("-")
+		} else {
+			b.WriteString(strconv.FormatUint(uint64(f.ParentFrameIndex), 10))
+		}
+		switch f.Type {
+		case ExpectTypePtrRecur, ExpectTypeMapRecur, ExpectTypeSliceRecur:
+			b.WriteString(" recur=")
+			b.WriteString(strconv.Itoa(f.RecurFrame))
+		}
+		if f.Fields != nil {
+			b.WriteString(" fields=[")
+			for fi, fl := range f.Fields {
+				if fi > 0 {
+					b.WriteByte(' ')
+				}
+				b.WriteString(strconv.Quote(fl.Name))
+				b.WriteByte(':')
+				b.WriteString(strconv.FormatUint(uint64(fl.FrameIndex), 10))
+			}
+			b.WriteByte(']')
+		}
+		lines[i] = b.String()
+	}
+	return lines
+}
+
 func (d *Decoder[S, T]) init() {
 	// 64-bit system
 	d.parseInt = func(s S) (int, error) {
@@ -604,10 +1082,27 @@ func (d *Decoder[S, T]) init() {
 			return v, nil
 		}
 	}
+
+	if d.fastFloat {
+		exact32, exact64 := d.parseFloat32, d.parseFloat64
+		d.parseFloat32 = func(s S) (float32, error) {
+			if v, ok := parseFloat64Fast(s); ok && !math.IsInf(float64(float32(v)), 0) {
+				return float32(v), nil
+			}
+			return exact32(s)
+		}
+		d.parseFloat64 = func(s S) (float64, error) {
+			if v, ok := parseFloat64Fast(s); ok {
+				return v, nil
+			}
+			return exact64(s)
+		}
+	}
 }
 
 var (
 	tpJSONUnmarshaler = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+	tpMapStringAny    = reflect.TypeOf(map[string]any(nil))
 	tpTextUnmarshaler = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
 )
 
@@ -657,14 +1152,174 @@ type InitOptions struct {
 	// ErrStringTagOptionOnUnsupportedType if a `json:",string"` struct tag option is
 	// used on an unsupported type.
 	DisallowStringTagOptOnUnsupportedTypes bool
+
+	// PrecomputeExactOnly makes the decoder match struct field names exactly
+	// and skip the case-insensitive fallback search entirely, regardless of
+	// DecodeOptions.DisableCaseInsensitiveMatching. This saves a second linear
+	// pass over the struct fields for every key that doesn't match exactly.
+	//
+	// For example, the following JSON input:
+	//
+	//   `{ "A": 42 }`
+	//
+	// will not match field A in the following type:
+	//
+	//   struct { A int `json:"a"` }
+	//
+	// and property "A" will be treated as an unknown field instead.
+	PrecomputeExactOnly bool
+
+	// ConcreteTypes maps non-empty interface types to the concrete types
+	// decoded into them. The concrete type must implement the interface
+	// and can either be a pointer type like *Circle or a non-pointer type
+	// like Circle, in which case the interface holds a Circle value.
+	// Non-empty interface types without a registered concrete type make
+	// NewDecoder return ErrUnsupportedInterface.
+	//
+	// For example, the following options:
+	//
+	//   &InitOptions{ConcreteTypes: map[reflect.Type]reflect.Type{
+	//     reflect.TypeOf((*Shape)(nil)).Elem(): reflect.TypeOf(&Circle{}),
+	//   }}
+	//
+	// will make the decoder decode JSON objects into *Circle values
+	// wherever the type Shape is expected, and null into a nil Shape.
+	// Like in encoding/json, an embedded Shape is a regular field named
+	// "Shape" whose fields aren't promoted to the embedding struct.
+	ConcreteTypes map[reflect.Type]reflect.Type
+
+	// SlicePool makes the decoder retain the backing arrays it allocates
+	// for slices and reuse them in subsequent calls to Decode instead of
+	// allocating new ones, which reduces GC pressure when decoding many
+	// transient values. Slices of scalar types like []int or []string
+	// are unaffected.
+	//
+	// WARNING: a slice decoded by a call to Decode may share its backing
+	// array with slices decoded by the next call to Decode on the same
+	// decoder, which will overwrite its contents. Don't retain decoded
+	// slices across calls to Decode or copy them before doing so.
+	SlicePool bool
+
+	// FallbackTagKeys defines the keys of struct tags consulted in order
+	// for the name of a struct field if its json struct tag doesn't specify
+	// one, such as `db` for `db:"user_id"`. The first tag specifying
+	// a name wins and the Go field name is used if none does.
+	// Options and the name "-" of fallback tags are ignored.
+	FallbackTagKeys []string
+
+	// FastFloatParsing makes the decoder parse numbers decoded into float32
+	// and float64 with a faster parser instead of strconv.ParseFloat.
+	// Numbers of up to 15 significant digits and a decimal exponent within
+	// [-22, 22] are still parsed exactly, but longer literals may differ
+	// from the correctly rounded result by a few units in the last place,
+	// which is a relative error of less than 1e-15 for float64.
+	// Numbers the fast parser can't handle, such as those with very large
+	// or small exponents, fall back to strconv.ParseFloat.
+	FastFloatParsing bool
+
+	// RequireField makes Decode check that the top-level value is an object
+	// containing a member with the name RequireField.Name and the string value
+	// RequireField.Value before anything is decoded. If the member is missing
+	// or its value differs Decode returns ErrSchemaMismatch at the index of
+	// the top-level value, which prevents misinterpreting input of one schema
+	// version as another. Names and values are matched exactly.
+	// The check is disabled if RequireField.Name is empty.
+	//
+	// For example, the following options:
+	//
+	//   &InitOptions{RequireField: RequiredField{Name: "v", Value: "2"}}
+	//
+	// will accept `{"v":"2","id":1}` and reject `{"v":"1","id":1}`
+	// as well as `{"id":1}`.
+	RequireField RequiredField
+
+	// ContainerFactories maps custom container types to the factories
+	// creating them, which allows decoding JSON arrays into containers
+	// element by element, such as sets or ordered collections.
+	// The elements are of the key type of map container types and of the
+	// element type of slice and array container types. Containers of other
+	// kinds make NewDecoder return ErrUnsupportedType.
+	//
+	// For every array the factory is called with the number of its elements
+	// and returns a pointer dst to a new container value, which is copied
+	// to the destination once all elements were added, and the function add
+	// which is called with a pointer to every decoded element in order.
+	// The element is only valid for the duration of the call to add
+	// and must be copied if retained. Null sets the container to its
+	// zero value without calling the factory.
+	//
+	// For example, the following options:
+	//
+	//   &InitOptions{ContainerFactories: map[reflect.Type]func(int) (
+	//     unsafe.Pointer, func(unsafe.Pointer),
+	//   ){
+	//     reflect.TypeOf(IntSet(nil)): func(elems int) (
+	//       unsafe.Pointer, func(unsafe.Pointer),
+	//     ) {
+	//       s := make(IntSet, elems)
+	//       return unsafe.Pointer(&s), func(e unsafe.Pointer) {
+	//         s[*(*int)(e)] = struct{}{}
+	//       }
+	//     },
+	//   }}
+	//
+	// will decode `[1,2,2,3]` into the IntSet (map[int]struct{}) {1, 2, 3}.
+	ContainerFactories map[reflect.Type]func(elems int) (
+		dst unsafe.Pointer, add func(elem unsafe.Pointer),
+	)
+
+	// EnumConstraints maps string types to the values they're allowed
+	// to take. Strings decoded into a constrained type, including map keys,
+	// that don't equal any of its values once unescaped (and transformed by
+	// DecodeOptions.StringTransform) make Decode return ErrUnexpectedValue
+	// at the index of the string. Null still leaves the zero value.
+	// Types of kinds other than string are ignored.
+	//
+	// For example, the following options:
+	//
+	//   &InitOptions{EnumConstraints: map[reflect.Type][]string{
+	//     reflect.TypeOf(Status("")): {"active", "inactive"},
+	//   }}
+	//
+	// will accept `{"status":"active"}` and reject `{"status":"frozen"}`
+	// when decoding into struct { Status Status `json:"status"` }.
+	EnumConstraints map[reflect.Type][]string
 }
 
+// DuplicateKeyStrategy defines how duplicate keys of objects decoded
+// into structs are handled.
+type DuplicateKeyStrategy uint8
+
+const (
+	// DuplicateKeyLast makes the last value of a duplicate key win,
+	// which is the default behavior of encoding/json.
+	DuplicateKeyLast DuplicateKeyStrategy = iota
+
+	// DuplicateKeyFirst makes the first value of a duplicate key win
+	// while the values of all following occurrences are skipped.
+	DuplicateKeyFirst
+
+	// DuplicateKeyError makes Decode return ErrDuplicateKey
+	// at the index of the second occurrence of a key.
+	DuplicateKeyError
+)
+
 // DecodeOptions are options for the method *Decoder[S, T].Decode.
 type DecodeOptions struct {
 	// DisallowUnknownFields will make Decode return ErrUnknownField
 	// when encountering an unknown struct field.
+	// Structs with a field tagged with the `rest` option are unaffected since
+	// their unknown fields are collected by the rest field instead.
 	DisallowUnknownFields bool
 
+	// RejectUnknownObjectValues will make Decode return ErrUnknownCompositeField
+	// when encountering an unknown struct field with an object or array value
+	// while unknown fields with scalar values are still skipped.
+	// This helps catching schema drift while tolerating scalar extras.
+	// Like DisallowUnknownFields, structs with a field tagged with the `rest`
+	// option are unaffected.
+	RejectUnknownObjectValues bool
+
 	// DisableFieldNameUnescaping disables unescaping of struct field names
 	// before matching which is enabled by default as a backward-compatibility feature
 	// of encoding/json.
@@ -697,6 +1352,450 @@ type DecodeOptions struct {
 	// DisableCaseInsensitiveMatching=true disables this behavior and will treat
 	// property "A" as an unknown field instead.
 	DisableCaseInsensitiveMatching bool
+
+	// TimeLayout defines the layout used to parse strings into
+	// time.Time values, including the elements of `[]time.Time`.
+	// It's a shorthand for TimeLayouts with a single layout and
+	// is ignored if TimeLayouts is set.
+	// If TimeLayout, TimeLayouts and TimeEpoch are unset, the elements of
+	// `[]time.Time` are parsed as time.RFC3339 and other time.Time values
+	// are decoded by time.Time.UnmarshalJSON like in encoding/json.
+	TimeLayout string
+
+	// TimeLayouts defines the layouts used to parse strings into
+	// time.Time values, including the elements of `[]time.Time`.
+	// The layouts are tried in order and the error of the first layout
+	// is returned if none of them succeeds.
+	// TimeLayouts takes precedence over TimeLayout.
+	//
+	// For example, the following options:
+	//
+	//   DecodeOptions{TimeLayouts: []string{time.RFC3339, time.DateOnly}}
+	//
+	// will accept both `"2023-01-02T03:04:05Z"` and `"2023-01-02"`.
+	TimeLayouts []string
+
+	// TimeEpoch enables decoding of integers into time.Time values
+	// as Unix time in UTC in the given unit.
+	// Integers are rejected by default (TimeEpochNone).
+	TimeEpoch TimeEpoch
+
+	// AllowHexIntegers enables decoding of hexadecimal integer literals
+	// such as `0xFF` or `-0x1f` into integer types, including struct fields
+	// with the `string` tag option (`"0xFF"`).
+	// Decoding them into any other type, such as float64, any or Number,
+	// fails with ErrUnexpectedValue.
+	// Hexadecimal literals are not valid JSON and are rejected by default.
+	AllowHexIntegers bool
+
+	// MaxAllocBytes limits the total number of bytes Decode may allocate
+	// for slices, maps, pointers and strings during a single call and makes
+	// Decode return ErrAllocBudgetExceeded once the limit would be exceeded.
+	// Strings are accounted for if they're copied, which is the case
+	// if S is []byte or if they contain escape sequences.
+	// Memory reused from the destination value isn't accounted for.
+	// Zero stands for unlimited.
+	MaxAllocBytes uintptr
+
+	// AllowLeadingZeros enables decoding of number literals with redundant
+	// leading zeros such as `007` or `-00.5`, which are not valid JSON and
+	// are rejected by default. It's the inverse of a RejectLeadingZeros
+	// option that defaults to true, since the zero value of DecodeOptions
+	// must decode standard JSON only like with the other Allow options.
+	// Like in encoding/json, struct fields with the `string` tag option
+	// accept leading zeros (`"007"`) regardless of this option.
+	// Quoted values of type Number are stripped of leading zeros
+	// if enabled and are left unchanged otherwise.
+	AllowLeadingZeros bool
+
+	// AllowUnquotedKeys enables decoding of objects with unquoted
+	// identifier keys (like in JSON5) such as `{name:"x"}`, which are
+	// matched against struct fields and map keys like quoted keys.
+	// Identifiers consist of ASCII letters, digits, '_' and '$'
+	// and must not begin with a digit.
+	// Unquoted keys are not valid JSON and are rejected by default.
+	AllowUnquotedKeys bool
+
+	// AllowUnderscoreInNumbers enables decoding of number literals with
+	// underscores separating digits such as `1_000_000` or `3_000.5`,
+	// including struct fields with the `string` tag option (`"1_000"`).
+	// The underscores are removed before parsing, also from the literals
+	// stored in values of type Number.
+	// Digit separators are not valid JSON and are rejected by default.
+	AllowUnderscoreInNumbers bool
+
+	// AllowFloatAsInt enables decoding of integral numbers written with
+	// a fraction or an exponent, such as `1.0`, `1e3` or `2.5e1`, into integer
+	// types, which are rejected by default. Decode returns ErrUnexpectedValue
+	// for numbers that aren't integral (like `1.5e0`) and ErrIntegerOverflow
+	// for numbers that overflow the integer type.
+	AllowFloatAsInt bool
+
+	// IntegersAsInt64 makes integer numbers decoded into values of type `any`
+	// be represented as int64 instead of float64. Integers that overflow int64,
+	// as well as numbers with a fraction or an exponent, remain float64.
+	IntegersAsInt64 bool
+
+	// UseNumberDepth makes numbers decoded into values of type `any` be
+	// represented as Number instead of float64 if they're at a nesting depth
+	// of at most UseNumberDepth, which preserves them losslessly.
+	// The depth is counted like for MaxDepth: a number that is the value of
+	// type `any` itself is at depth 1 and the numbers in `[1,{"a":2}]`
+	// are at depth 2 and 3 respectively. Deeper numbers remain float64
+	// (or int64 if IntegersAsInt64 is enabled). Zero disables it.
+	UseNumberDepth int
+
+	// MergeDuplicateKeysIntoSlice makes duplicate keys of objects decoded into
+	// maps with slice values, such as `map[string][]string`, append to the
+	// existing slice instead of replacing it. For example, the following input:
+	//
+	//   `{"a":["1"],"a":["2"]}`
+	//
+	// decodes to `map[string][]string{"a":{"1","2"}}`.
+	// Maps with non-slice values are unaffected.
+	MergeDuplicateKeysIntoSlice bool
+
+	// FieldOffsets, if not nil, makes Decode record the start byte index of
+	// the value of every decoded struct field in the map it points to,
+	// keyed by the JSON Pointer (RFC 6901) of the field, such as `/items/0/id`.
+	// A new map is allocated if *FieldOffsets is nil. Existing entries are
+	// kept unless overwritten.
+	FieldOffsets *map[string]int
+
+	// OnFieldDecoded, if not nil, is called for every struct field present
+	// in the input after Decode succeeded, in the order of appearance.
+	// path is the JSON Pointer (RFC 6901) of the field, such as `/items/0/id`,
+	// and kind is the expected type of its value. It's not called for unknown,
+	// ignored or skipped duplicate fields.
+	OnFieldDecoded func(path string, kind ExpectType)
+
+	// RawMessageNoCopy makes Decode assign values of type json.RawMessage
+	// a sub-slice of the input instead of a copy when S is []byte,
+	// which avoids allocating. It has no effect when S is string.
+	//
+	// WARNING: the decoded json.RawMessage values alias the input and
+	// will change if the input is mutated, don't retain them beyond
+	// the lifetime of the input buffer when it's reused.
+	RawMessageNoCopy bool
+
+	// StringTransform, if not nil, is applied to every string value decoded
+	// into a Go string after unescaping, such as struct fields, slice and array
+	// elements and map values of type string. It isn't applied to Number and
+	// json.Number values, fields with the `string` tag option or the input of
+	// unmarshalers. Map keys and strings decoded into `any` are only transformed
+	// if StringTransformMapKeys and StringTransformAny are enabled respectively.
+	StringTransform func(string) string
+
+	// StringTransformMapKeys makes StringTransform also apply to map keys
+	// of type string, including the keys of fields with the `rest` tag option.
+	StringTransformMapKeys bool
+
+	// StringTransformAny makes StringTransform also apply to strings
+	// decoded into values of type `any`.
+	StringTransformAny bool
+
+	// RuneSliceFromString enables decoding JSON strings into values of type
+	// []rune, which are filled with the code points of the string.
+	// Since rune is an alias for int32 this equally applies to []int32.
+	// Arrays of numbers are decoded into []rune as usual regardless
+	// of this option.
+	RuneSliceFromString bool
+
+	// ExtendedBoolStrings enables decoding the strings "yes", "on" and "1"
+	// as true and "no", "off" and "0" as false into values of type bool,
+	// including fields with the `string` struct tag option, which otherwise
+	// only accept "true" and "false". Spellings are case-sensitive.
+	// Booleans in slices and arrays are unaffected.
+	ExtendedBoolStrings bool
+
+	// BoolSliceFromBitmask enables decoding integers into values of type
+	// []bool as bitmasks, which sets the element at index i to true if bit i
+	// of the integer is set, least significant bit first, such as
+	// `5` as []bool{true, false, true}. Negative integers are rejected
+	// with ErrUnexpectedValue and integers exceeding 64 bits with
+	// ErrIntegerOverflow. Arrays of booleans are decoded as usual.
+	BoolSliceFromBitmask bool
+
+	// BoolSliceBitmaskWidth defines the length of the slices decoded from
+	// bitmasks if BoolSliceFromBitmask is enabled, trailing elements beyond
+	// the most significant set bit are false. Integers with set bits beyond
+	// the width are rejected with ErrIntegerOverflow. Zero makes the length
+	// the number of bits up to the most significant set bit,
+	// which makes `0` decode to an empty slice.
+	BoolSliceBitmaskWidth int
+
+	// BoolStringNumeric enables decoding the strings "1" as true and "0"
+	// as false into fields of type bool with the `string` struct tag option
+	// and Stringed[bool], which otherwise only accept "true" and "false".
+	// Unlike ExtendedBoolStrings it doesn't affect values of type bool
+	// without the option, which only accept JSON booleans.
+	BoolStringNumeric bool
+
+	// MaxDepth limits the nesting depth of arrays and objects decoded into
+	// values of type `any`, including the elements of `[]any` and the values
+	// of `map[string]any`, and makes Decode return ErrMaxDepthExceeded
+	// once the limit is exceeded. The depth is counted from the value of
+	// type `any`, which is at depth 1 if it's an array or an object.
+	// Zero stands for unlimited.
+	MaxDepth int
+
+	// MaxArrayElements limits the number of elements of arrays decoded into
+	// values of type `any` and makes Decode return ErrLimitExceeded
+	// once the limit is exceeded. Zero stands for unlimited.
+	MaxArrayElements int
+
+	// MaxDecodedArrayElements limits the number of elements decoded into
+	// slices. Only the first MaxDecodedArrayElements elements of longer arrays
+	// are decoded and the remaining elements are skipped without error,
+	// which makes the length of the slice the smaller of the two.
+	// Skipped elements aren't checked against the element type.
+	// Unlike MaxArrayElements it applies to slices only, values of type `any`
+	// are unaffected. Zero stands for unlimited.
+	MaxDecodedArrayElements int
+
+	// MaxStringLength limits the length in bytes of unescaped string values
+	// decoded into values of type string (including struct fields, map
+	// values and the elements of []string and [N]string), map keys of type
+	// string and strings decoded into values of type `any` and makes Decode
+	// return ErrLimitExceeded at the index of the offending string once
+	// the limit is exceeded. Zero stands for unlimited.
+	MaxStringLength int
+
+	// MapCapacityFactor scales the capacity newly allocated maps are created
+	// with relative to the number of elements of the decoded object.
+	// Values greater than 1 over-allocate to reduce rehashing of maps
+	// that will grow after decoding. Over-allocated capacity is accounted
+	// for by MaxAllocBytes. Values less than or equal to 1 (including zero)
+	// make maps be allocated with the exact number of elements.
+	MapCapacityFactor float64
+
+	// ValidateUTF8 makes Decode return ErrInvalidUTF8 at the index of
+	// a string value that contains invalid UTF-8 when it's decoded into
+	// a string, including strings in values of type any. Object keys and
+	// strings of skipped values aren't validated. Escape sequences always
+	// unescape to valid UTF-8 and aren't affected.
+	// By default strings are decoded as is and invalid bytes are preserved,
+	// unlike encoding/json which replaces them with utf8.RuneError (U+FFFD).
+	ValidateUTF8 bool
+
+	// DisallowEmptyString makes Decode return ErrEmptyString at the index of
+	// an empty string `""` decoded into a struct field of type string.
+	// Strings decoded into `any`, map values, slice and array elements
+	// are unaffected, and null still leaves the zero value.
+	DisallowEmptyString bool
+
+	// DisallowNullForContainers makes Decode return ErrUnexpectedValue at the
+	// index of a null decoded into a slice, map or array instead of setting
+	// slices and maps to nil and leaving arrays unchanged.
+	// Null is still accepted for pointers (including pointers to containers),
+	// interfaces and Optional.
+	DisallowNullForContainers bool
+
+	// DuplicateKeyStrategy defines how keys of an object decoded into a struct
+	// that match a field already assigned by a preceding key of the same object
+	// are handled. Keys are matched against fields the same way field names
+	// are matched, which means that `{"a":1,"A":2}` contains a duplicate
+	// unless DisableCaseInsensitiveMatching is enabled.
+	// Maps, `any` and fields with the `rest` tag option are unaffected.
+	// DuplicateKeyLast is used by default.
+	DuplicateKeyStrategy DuplicateKeyStrategy
+
+	// IgnoreFields defines the names of struct fields that can't be set by
+	// the input, which prevents clients from assigning sensitive fields
+	// such as `{"isAdmin":true}` (mass assignment).
+	// Names are the JSON field names (as defined by the json struct tag)
+	// and are compared after resolving the key to the field, which means
+	// `{"ISADMIN":true}` is ignored as well unless
+	// DisableCaseInsensitiveMatching is enabled.
+	// The values of ignored fields are skipped and never cause
+	// ErrUnknownField, nor are they collected by fields with
+	// the `rest` tag option. Ignored names apply to structs at any depth.
+	IgnoreFields map[string]bool
+
+	// RequireCanonicalNumbers makes Decode return ErrNonCanonicalNumber at the
+	// index of the first number literal in the input that isn't in canonical
+	// form, which guarantees that every number has exactly one accepted
+	// encoding, as required by content-addressed storage for example.
+	// A number literal is canonical if:
+	//
+	//   - it isn't negative zero (`-0`).
+	//   - its fraction doesn't end with zero (`1.0`, `1.50`).
+	//   - its exponent is written with a lowercase `e` without
+	//     a plus sign or leading zeros and isn't zero (`1E21`, `1e+21`,
+	//     `1e021`, `1e0`).
+	//   - with an exponent, it has exactly one non-zero integer digit
+	//     (`12e21`, `0.1e22`).
+	//   - it uses an exponent if and only if its magnitude is at least 1e21
+	//     or less than 1e-6 (`1e2` must be written as `100` and
+	//     `0.0000001` as `1e-7`), like numbers formatted by
+	//     encoding/json and ECMAScript.
+	//
+	// Integer fields therefore accept only integer literals like `1`.
+	// The check is syntactical, it doesn't check whether the digits
+	// are the shortest representation of a floating point value.
+	// All number literals in the input are checked, including those
+	// of skipped values, while numbers in strings, such as those of
+	// fields with the `string` tag option, aren't.
+	RequireCanonicalNumbers bool
+
+	// Warnings, if not nil, makes Decode append recoverable issues to it
+	// as *Warning instead of aborting and continue decoding.
+	// Recoverable issues are:
+	//
+	//   - unknown struct fields, which are reported as ErrUnknownField
+	//     (or ErrUnknownCompositeField if RejectUnknownObjectValues is
+	//     enabled) whether or not DisallowUnknownFields or
+	//     RejectUnknownObjectValues are enabled. Fields collected by
+	//     a field with the `rest` tag option and IgnoreFields aren't reported.
+	//   - integers decoded into float32, float64 or `any` that can't be
+	//     represented exactly, such as `9007199254740993`,
+	//     which are reported as ErrImpreciseNumber.
+	//
+	// All other errors still abort decoding. Warnings appended before
+	// an error are retained. Like errors, warnings are in the order
+	// of the input.
+	Warnings *[]error
+
+	// TextUnmarshalerAcceptsScalars makes Decode pass numbers and booleans
+	// decoded into types implementing encoding.TextUnmarshaler to UnmarshalText
+	// as their literal text, such as `42` as "42" and `true` as "true".
+	// By default, like encoding/json, only strings are accepted and
+	// numbers and booleans are rejected with ErrUnexpectedValue.
+	TextUnmarshalerAcceptsScalars bool
+
+	// ObjectAsSingletonSlice makes Decode decode an object targeting a slice,
+	// such as `{"a":1}` for []S, as a slice of length 1 containing
+	// the object, which accommodates APIs that return either an object or
+	// an array of objects for the same value. Arrays and null are unaffected.
+	// Slices of recursive types and []struct{} still reject objects.
+	ObjectAsSingletonSlice bool
+
+	// mergePatch is set by ApplyMergePatch.
+	mergePatch bool
+}
+
+// mapCapacity returns the capacity for a new map that will receive
+// the given number of elements, scaled by MapCapacityFactor.
+func (o *DecodeOptions) mapCapacity(elements int) int {
+	if o.MapCapacityFactor <= 1 {
+		return elements
+	}
+	return int(float64(elements) * o.MapCapacityFactor)
+}
+
+// transformValue applies StringTransform to the string value v.
+func (o *DecodeOptions) transformValue(v string) string {
+	if o.StringTransform == nil {
+		return v
+	}
+	return o.StringTransform(v)
+}
+
+// transformMapKey applies StringTransform to the map key k
+// if StringTransformMapKeys is enabled.
+func (o *DecodeOptions) transformMapKey(k string) string {
+	if o.StringTransform == nil || !o.StringTransformMapKeys {
+		return k
+	}
+	return o.StringTransform(k)
+}
+
+// transformAny applies StringTransform to the string v decoded into `any`
+// if StringTransformAny is enabled.
+func (o *DecodeOptions) transformAny(v string) string {
+	if o.StringTransform == nil || !o.StringTransformAny {
+		return v
+	}
+	return o.StringTransform(v)
+}
+
+// decodedElements returns the number of elements of an array
+// with the given number of elements to decode into a slice
+// (see MaxDecodedArrayElements).
+func (o *DecodeOptions) decodedElements(elements int) int {
+	if o.MaxDecodedArrayElements > 0 && elements > o.MaxDecodedArrayElements {
+		return o.MaxDecodedArrayElements
+	}
+	return elements
+}
+
+// skipUndecodedElements returns the index of the end token of the array
+// tokens[ti] is an element of if the slice element frame f was already
+// assigned the maximum number of elements (see MaxDecodedArrayElements),
+// otherwise returns ti.
+func skipUndecodedElements[S []byte | string](
+	tokens []jscan.Token[S], ti int, f *stackFrame[S], options *DecodeOptions,
+) int {
+	if options.MaxDecodedArrayElements < 1 ||
+		f.Offset < uintptr(options.MaxDecodedArrayElements)*f.Size {
+		return ti
+	}
+	for tokens[ti].Type != jscan.TokenTypeArrayEnd {
+		switch tokens[ti].Type {
+		case jscan.TokenTypeObject, jscan.TokenTypeArray:
+			ti = tokens[ti].End + 1
+		default:
+			ti++
+		}
+	}
+	return ti
+}
+
+// stringTooLong returns true if the contents of the string or key token tok
+// exceed options.MaxStringLength once unescaped. Escape sequences only ever
+// shrink once unescaped, so the string is only unescaped if the length
+// of its token already exceeds the limit.
+func stringTooLong[S []byte | string](
+	s S, tok jscan.Token[S], options *DecodeOptions,
+) bool {
+	if options.MaxStringLength == 0 ||
+		tok.End-tok.Index-len(`""`) <= options.MaxStringLength {
+		return false
+	}
+	return len(unescape.Valid[S, string](s[tok.Index+1:tok.End-1])) >
+		options.MaxStringLength
+}
+
+// allocString adds the size of the copy of the contents of the string or
+// key token tok to b and returns false if the budget is exceeded.
+// Strings are copied if S is []byte or if they contain escape sequences,
+// otherwise they refer to the input and aren't accounted for.
+func allocString[S []byte | string](
+	b *allocBudget, s S, tok jscan.Token[S],
+) (ok bool) {
+	if b.Max == 0 {
+		return true // Don't scan for escape sequences if unlimited.
+	}
+	str := s[tok.Index+1 : tok.End-1]
+	var z S
+	if _, ok := any(z).([]byte); !ok && strings.IndexByte(string(str), '\\') == -1 {
+		return true
+	}
+	return b.alloc(uintptr(len(str)))
+}
+
+// isIntegerKey returns true if the contents of the map key token
+// are entirely a JSON integer, which must not be negative unless signed.
+// Floats with integral values such as "1.0" and "1e0" are rejected.
+func isIntegerKey[S []byte | string](key S, signed bool) bool {
+	if len(key) < 1 || (!signed && key[0] == '-') {
+		return false
+	}
+	tail, rc := jsonnum.ReadNumber(key)
+	return rc == jsonnum.ReturnCodeInteger && len(tail) < 1
+}
+
+// isStructField returns true if the frame at index si is a struct field.
+func (d *Decoder[S, T]) isStructField(si uint32) bool {
+	pi := d.stackExp[si].ParentFrameIndex
+	if pi == noParentFrame {
+		return false
+	}
+	switch d.stackExp[pi].Type {
+	case ExpectTypeStruct, ExpectTypeStructRecur:
+		return true
+	}
+	return false
 }
 
 // Decode unmarshals the JSON contents of s into t.
@@ -704,6 +1803,11 @@ type DecodeOptions struct {
 // to the source string instead since Go strings are guaranteed to be immutable.
 // When S is []byte all strings are copied.
 //
+// Decoding into a T that contains no pointers, slices, maps, strings copied
+// from a []byte input or interfaces (such as scalars, fixed-size arrays
+// and structs thereof) doesn't allocate on the heap, provided the tokenizer
+// buffers are large enough (see Grow).
+//
 // Tip: To improve performance reducing dynamic memory allocations define
 // options as a variable and pass the pointer. Don't initialize it like this:
 //
@@ -714,21 +1818,63 @@ type DecodeOptions struct {
 //	d.Decode(input, &v, predefinedOptions)
 func (d *Decoder[S, T]) Decode(
 	s S, t *T, options *DecodeOptions,
+) (errIndex int, err error) {
+	if t == nil {
+		return 0, ErrNilDest
+	}
+	return d.decode(s, unsafe.Pointer(t), options)
+}
+
+// decode decodes s into the value at p, which must be of the type
+// the type stack of d was built for.
+func (d *Decoder[S, T]) decode(
+	s S, p unsafe.Pointer, options *DecodeOptions,
 ) (errIndex int, err error) {
 	defer func() {
 		for i := range d.stackExp {
 			d.stackExp[i].Dest = nil
+			d.stackExp[i].Pool.Used = 0
+		}
+		for i := range d.mergeStack {
+			d.mergeStack[i] = pendingMerge{}
 		}
+		d.mergeStack = d.mergeStack[:0]
+		for i := range d.decodedFields {
+			d.decodedFields[i] = decodedField{}
+		}
+		d.decodedFields = d.decodedFields[:0]
 	}()
 
-	if t == nil {
-		return 0, ErrNilDest
+	src := s
+	d.rewriteSpans = d.rewriteSpans[:0]
+	if options.AllowHexIntegers || options.AllowLeadingZeros ||
+		options.AllowUnquotedKeys || options.AllowUnderscoreInNumbers {
+		s, d.rewriteSpans = rewriteNonstandard(s, d.rewriteSpans, options)
 	}
 
+	budget := allocBudget{Max: options.MaxAllocBytes}
 	si := uint32(0)
-	d.stackExp[0].Dest = unsafe.Pointer(t)
+	d.stackExp[0].Dest = p
+
+	var warningsFrom int
+	if options.Warnings != nil {
+		warningsFrom = len(*options.Warnings)
+	}
 
+	// Only initialized if options.FieldOffsets or options.OnFieldDecoded != nil
+	var paths []string
 	errTok := d.tokenizer.Tokenize(s, func(tokens []jscan.Token[S]) (exit bool) {
+		if d.requireField.Name != "" &&
+			!hasRequiredField(s, tokens, d.requireField) {
+			errIndex, err = tokens[0].Index, ErrSchemaMismatch
+			return true
+		}
+		if options.RequireCanonicalNumbers {
+			if i := nonCanonicalNumberIndex(s, tokens); i != -1 {
+				errIndex, err = tokens[i].Index, ErrNonCanonicalNumber
+				return true
+			}
+		}
 		// ti stands for the token index and points at the current token
 		for ti := 0; ti < len(tokens); {
 			switch tokens[ti].Type {
@@ -747,8 +1893,14 @@ func (d *Decoder[S, T]) Decode(
 					*(*bool)(p) = tokens[ti].Type == jscan.TokenTypeTrue
 				case ExpectTypePtr:
 					goto ON_PTR
+				case ExpectTypeOptional:
+					goto ON_OPTIONAL
+				case ExpectTypeInterface:
+					goto ON_INTERFACE
 				case ExpectTypeJSONUnmarshaler:
 					goto ON_JSON_UNMARSHALER
+				case ExpectTypeTextUnmarshaler:
+					goto ON_TEXT_UNMARSHALER
 				default:
 					errIndex, err = tokens[ti].Index, ErrUnexpectedValue
 					return true
@@ -757,12 +1909,32 @@ func (d *Decoder[S, T]) Decode(
 				goto ON_VAL_END
 
 			case jscan.TokenTypeInteger:
+				if len(d.rewriteSpans) > 0 && d.stackExp[si].Type.isNonIntegerNumber() &&
+					hexLiteral(d.rewriteSpans, tokens[ti].Index) {
+					// Hexadecimal literals are only accepted by integer types.
+					errIndex, err = tokens[ti].Index, ErrUnexpectedValue
+					return true
+				}
 				p := unsafe.Pointer(
 					uintptr(d.stackExp[si].Dest) + d.stackExp[si].Offset,
 				)
 				switch d.stackExp[si].Type {
 				case ExpectTypeAny:
 					tv := s[tokens[ti].Index:tokens[ti].End]
+					if options.UseNumberDepth > 0 {
+						*(*any)(p) = Number(tv)
+						break
+					}
+					if options.IntegersAsInt64 {
+						if v, overflow := atoi.I64(tv); !overflow {
+							*(*any)(p) = v
+							break
+						}
+					}
+					if v, ok := boxSmallInt(tv); ok {
+						*(*any)(p) = v
+						break
+					}
 					var sz S
 					var su string
 					switch any(sz).(type) {
@@ -776,6 +1948,7 @@ func (d *Decoder[S, T]) Decode(
 						errIndex, err = tokens[ti].Index, errParse
 						return true
 					}
+					checkPrecision(options, tokens[ti].Index, tv, v)
 					*(*any)(p) = v
 
 				case ExpectTypeUint:
@@ -852,6 +2025,18 @@ func (d *Decoder[S, T]) Decode(
 					}
 					*(*uint64)(p) = v
 
+				case ExpectTypeSliceBool:
+					if !options.BoolSliceFromBitmask {
+						errIndex, err = tokens[ti].Index, ErrUnexpectedValue
+						return true
+					}
+					if errBitmask := decodeBitmask(
+						s[tokens[ti].Index:tokens[ti].End], p, options, &budget,
+					); errBitmask != nil {
+						errIndex, err = tokens[ti].Index, errBitmask
+						return true
+					}
+
 				case ExpectTypeInt8:
 					v, overflow := atoi.I8(s[tokens[ti].Index:tokens[ti].End])
 					if overflow {
@@ -898,12 +2083,19 @@ func (d *Decoder[S, T]) Decode(
 							return true
 						}
 						*(*float32)(p) = float32(i32)
+						if i32 == 0 && s[tokens[ti].Index] == '-' {
+							// Preserve the sign of -0 like strconv.ParseFloat does.
+							*(*float32)(p) = float32(math.Copysign(0, -1))
+						}
 					} else {
 						v, errParse := d.parseFloat32(s[tokens[ti].Index:tokens[ti].End])
 						if errParse != nil {
 							errIndex, err = tokens[ti].Index, errParse
 							return true
 						}
+						checkPrecision(
+							options, tokens[ti].Index, s[tokens[ti].Index:tokens[ti].End], float64(v),
+						)
 						*(*float32)(p) = v
 					}
 
@@ -917,23 +2109,43 @@ func (d *Decoder[S, T]) Decode(
 							return true
 						}
 						*(*float64)(p) = float64(i64)
+						if i64 == 0 && s[tokens[ti].Index] == '-' {
+							// Preserve the sign of -0 like strconv.ParseFloat does.
+							*(*float64)(p) = math.Copysign(0, -1)
+						}
 					} else {
 						v, errParse := d.parseFloat64(s[tokens[ti].Index:tokens[ti].End])
 						if errParse != nil {
 							errIndex, err = tokens[ti].Index, errParse
 							return true
 						}
+						checkPrecision(
+							options, tokens[ti].Index, s[tokens[ti].Index:tokens[ti].End], v,
+						)
 						*(*float64)(p) = v
 					}
 
 				case ExpectTypeNumber:
 					*(*Number)(p) = Number(s[tokens[ti].Index:tokens[ti].End])
 
+				case ExpectTypeBigRat:
+					tv := s[tokens[ti].Index:tokens[ti].End]
+					if _, ok := (*big.Rat)(p).SetString(string(tv)); !ok {
+						errIndex, err = tokens[ti].Index, ErrUnexpectedValue
+						return true
+					}
+
 				case ExpectTypePtr:
 					goto ON_PTR
+				case ExpectTypeOptional:
+					goto ON_OPTIONAL
+				case ExpectTypeInterface:
+					goto ON_INTERFACE
 
 				case ExpectTypeJSONUnmarshaler:
 					goto ON_JSON_UNMARSHALER
+				case ExpectTypeTextUnmarshaler:
+					goto ON_TEXT_UNMARSHALER
 
 				default:
 					errIndex, err = tokens[ti].Index, ErrUnexpectedValue
@@ -949,6 +2161,10 @@ func (d *Decoder[S, T]) Decode(
 				switch d.stackExp[si].Type {
 				case ExpectTypeAny:
 					tv := s[tokens[ti].Index:tokens[ti].End]
+					if options.UseNumberDepth > 0 {
+						*(*any)(p) = Number(tv)
+						break
+					}
 					var sz S
 					var su string
 					switch any(sz).(type) {
@@ -980,23 +2196,79 @@ func (d *Decoder[S, T]) Decode(
 					*(*float64)(p) = v
 				case ExpectTypeNumber:
 					*(*Number)(p) = Number(s[tokens[ti].Index:tokens[ti].End])
-				case ExpectTypePtr:
-					goto ON_PTR
-				case ExpectTypeJSONUnmarshaler:
-					goto ON_JSON_UNMARSHALER
-				default:
-					errIndex, err = tokens[ti].Index, ErrUnexpectedValue
-					return true
-				}
-				ti++
-				goto ON_VAL_END
-
-			case jscan.TokenTypeString:
-				p := unsafe.Pointer(
-					uintptr(d.stackExp[si].Dest) + d.stackExp[si].Offset,
-				)
-
-				switch d.stackExp[si].Type {
+				case ExpectTypeBigRat:
+					// Unlike SetFloat64, SetString keeps the exact value
+					// of the decimal literal (0.1 is 1/10).
+					tv := s[tokens[ti].Index:tokens[ti].End]
+					if _, ok := (*big.Rat)(p).SetString(string(tv)); !ok {
+						errIndex, err = tokens[ti].Index, ErrUnexpectedValue
+						return true
+					}
+				case ExpectTypeInt,
+					ExpectTypeInt8,
+					ExpectTypeInt16,
+					ExpectTypeInt32,
+					ExpectTypeInt64,
+					ExpectTypeUint,
+					ExpectTypeUint8,
+					ExpectTypeUint16,
+					ExpectTypeUint32,
+					ExpectTypeUint64:
+					if !options.AllowFloatAsInt {
+						errIndex, err = tokens[ti].Index, ErrUnexpectedValue
+						return true
+					}
+					tv := s[tokens[ti].Index:tokens[ti].End]
+					var errConv error
+					switch d.stackExp[si].Type {
+					case ExpectTypeInt:
+						*(*int)(p), errConv = floatAsInt[int](tv)
+					case ExpectTypeInt8:
+						*(*int8)(p), errConv = floatAsInt[int8](tv)
+					case ExpectTypeInt16:
+						*(*int16)(p), errConv = floatAsInt[int16](tv)
+					case ExpectTypeInt32:
+						*(*int32)(p), errConv = floatAsInt[int32](tv)
+					case ExpectTypeInt64:
+						*(*int64)(p), errConv = floatAsInt[int64](tv)
+					case ExpectTypeUint:
+						*(*uint)(p), errConv = floatAsInt[uint](tv)
+					case ExpectTypeUint8:
+						*(*uint8)(p), errConv = floatAsInt[uint8](tv)
+					case ExpectTypeUint16:
+						*(*uint16)(p), errConv = floatAsInt[uint16](tv)
+					case ExpectTypeUint32:
+						*(*uint32)(p), errConv = floatAsInt[uint32](tv)
+					case ExpectTypeUint64:
+						*(*uint64)(p), errConv = floatAsInt[uint64](tv)
+					}
+					if errConv != nil {
+						errIndex, err = tokens[ti].Index, errConv
+						return true
+					}
+				case ExpectTypePtr:
+					goto ON_PTR
+				case ExpectTypeOptional:
+					goto ON_OPTIONAL
+				case ExpectTypeInterface:
+					goto ON_INTERFACE
+				case ExpectTypeJSONUnmarshaler:
+					goto ON_JSON_UNMARSHALER
+				case ExpectTypeTextUnmarshaler:
+					goto ON_TEXT_UNMARSHALER
+				default:
+					errIndex, err = tokens[ti].Index, ErrUnexpectedValue
+					return true
+				}
+				ti++
+				goto ON_VAL_END
+
+			case jscan.TokenTypeString:
+				p := unsafe.Pointer(
+					uintptr(d.stackExp[si].Dest) + d.stackExp[si].Offset,
+				)
+
+				switch d.stackExp[si].Type {
 				case ExpectTypeTextUnmarshaler:
 					u := reflect.NewAt(
 						d.stackExp[si].RType, p,
@@ -1009,23 +2281,167 @@ func (d *Decoder[S, T]) Decode(
 						return true
 					}
 				case ExpectTypeAny:
-					*(*any)(p) = unescape.Valid[S, string](
+					if stringTooLong(s, tokens[ti], options) {
+						errIndex, err = tokens[ti].Index, ErrLimitExceeded
+						return true
+					}
+					if invalidUTF8(s, tokens[ti], options) {
+						errIndex, err = tokens[ti].Index, ErrInvalidUTF8
+						return true
+					}
+					if !allocString(&budget, s, tokens[ti]) {
+						errIndex, err = tokens[ti].Index, ErrAllocBudgetExceeded
+						return true
+					}
+					*(*any)(p) = options.transformAny(unescape.Valid[S, string](
+						s[tokens[ti].Index+1 : tokens[ti].End-1],
+					))
+				case ExpectTypeError:
+					if invalidUTF8(s, tokens[ti], options) {
+						errIndex, err = tokens[ti].Index, ErrInvalidUTF8
+						return true
+					}
+					if !allocString(&budget, s, tokens[ti]) {
+						errIndex, err = tokens[ti].Index, ErrAllocBudgetExceeded
+						return true
+					}
+					*(*error)(p) = errors.New(unescape.Valid[S, string](
+						s[tokens[ti].Index+1 : tokens[ti].End-1],
+					))
+				case ExpectTypeBigRat:
+					// Either a fraction like "1/3" or a decimal like "0.333".
+					tv := unescape.Valid[S, string](
 						s[tokens[ti].Index+1 : tokens[ti].End-1],
 					)
+					if _, ok := (*big.Rat)(p).SetString(tv); !ok {
+						errIndex, err = tokens[ti].Index, ErrUnexpectedValue
+						return true
+					}
 				case ExpectTypeStr:
-					*(*string)(p) = unescape.Valid[S, string](
+					if options.DisallowEmptyString &&
+						tokens[ti].End-tokens[ti].Index == len(`""`) &&
+						d.isStructField(si) {
+						errIndex, err = tokens[ti].Index, ErrEmptyString
+						return true
+					}
+					if stringTooLong(s, tokens[ti], options) {
+						errIndex, err = tokens[ti].Index, ErrLimitExceeded
+						return true
+					}
+					if invalidUTF8(s, tokens[ti], options) {
+						errIndex, err = tokens[ti].Index, ErrInvalidUTF8
+						return true
+					}
+					if !allocString(&budget, s, tokens[ti]) {
+						errIndex, err = tokens[ti].Index, ErrAllocBudgetExceeded
+						return true
+					}
+					*(*string)(p) = options.transformValue(unescape.Valid[S, string](
+						s[tokens[ti].Index+1 : tokens[ti].End-1],
+					))
+				case ExpectTypeStrEnum:
+					if options.DisallowEmptyString &&
+						tokens[ti].End-tokens[ti].Index == len(`""`) &&
+						d.isStructField(si) {
+						errIndex, err = tokens[ti].Index, ErrEmptyString
+						return true
+					}
+					if stringTooLong(s, tokens[ti], options) {
+						errIndex, err = tokens[ti].Index, ErrLimitExceeded
+						return true
+					}
+					v, ok := enumValue(d.stackExp[si].Enum, options.transformValue(
+						unescape.Valid[S, string](s[tokens[ti].Index+1:tokens[ti].End-1]),
+					))
+					if !ok {
+						errIndex, err = tokens[ti].Index, ErrUnexpectedValue
+						return true
+					}
+					*(*string)(p) = v
+				case ExpectTypeSliceUint8:
+					// Byte slices are base64-encoded strings in encoding/json.
+					src := unescape.Valid[S, []byte](
 						s[tokens[ti].Index+1 : tokens[ti].End-1],
 					)
+					l := base64.StdEncoding.DecodedLen(len(src))
+					if !budget.alloc(uintptr(l)) {
+						errIndex, err = tokens[ti].Index, ErrAllocBudgetExceeded
+						return true
+					}
+					b := make([]byte, l)
+					n, errDecode := base64.StdEncoding.Decode(b, src)
+					if errDecode != nil {
+						errIndex, err = tokens[ti].Index, errDecode
+						return true
+					}
+					*(*[]byte)(p) = b[:n]
+				case ExpectTypeBytesString:
+					// Unlike ExpectTypeSliceUint8 the string isn't base64-encoded.
+					tv := unescape.Valid[S, []byte](
+						s[tokens[ti].Index+1 : tokens[ti].End-1],
+					)
+					if !budget.alloc(uintptr(len(tv))) {
+						errIndex, err = tokens[ti].Index, ErrAllocBudgetExceeded
+						return true
+					}
+					// Copy since tv may refer to the input.
+					*(*[]byte)(p) = append([]byte{}, tv...)
+				case ExpectTypeSliceInt32:
+					if !options.RuneSliceFromString {
+						errIndex, err = tokens[ti].Index, ErrUnexpectedValue
+						return true
+					}
+					str := unescape.Valid[S, string](
+						s[tokens[ti].Index+1 : tokens[ti].End-1],
+					)
+					n := utf8.RuneCountInString(str)
+					sl := *(*[]rune)(p)
+					if sl == nil || cap(sl) < n {
+						if !budget.alloc(uintptr(n) * unsafe.Sizeof(rune(0))) {
+							errIndex, err = tokens[ti].Index, ErrAllocBudgetExceeded
+							return true
+						}
+						sl = make([]rune, n)
+					} else {
+						sl = sl[:n]
+					}
+					i := 0
+					for _, r := range str {
+						sl[i] = r
+						i++
+					}
+					*(*[]rune)(p) = sl
 				case ExpectTypeBoolString:
-					switch string(s[tokens[ti].Index+1 : tokens[ti].End-1]) {
+					switch tv := s[tokens[ti].Index+1 : tokens[ti].End-1]; string(tv) {
 					case "true":
 						*(*bool)(p) = true
 					case "false":
 						*(*bool)(p) = false
+					case "1", "0":
+						if !options.BoolStringNumeric && !options.ExtendedBoolStrings {
+							errIndex, err = tokens[ti].Index, ErrUnexpectedValue
+							return true
+						}
+						*(*bool)(p) = tv[0] == '1'
 					default:
+						v, ok := parseExtendedBool(tv)
+						if !ok || !options.ExtendedBoolStrings {
+							errIndex, err = tokens[ti].Index, ErrUnexpectedValue
+							return true
+						}
+						*(*bool)(p) = v
+					}
+				case ExpectTypeBool:
+					if !options.ExtendedBoolStrings {
 						errIndex, err = tokens[ti].Index, ErrUnexpectedValue
 						return true
 					}
+					v, ok := parseExtendedBool(s[tokens[ti].Index+1 : tokens[ti].End-1])
+					if !ok {
+						errIndex, err = tokens[ti].Index, ErrUnexpectedValue
+						return true
+					}
+					*(*bool)(p) = v
 				case ExpectTypeStrString:
 					tv := s[tokens[ti].Index+1 : tokens[ti].End-1]
 					if len(tv) < len(`\"\"`) ||
@@ -1047,10 +2463,25 @@ func (d *Decoder[S, T]) Decode(
 						errIndex, err = tokens[ti].Index, ErrUnexpectedValue
 						return true
 					}
+					if invalidUTF8(s, tokens[ti], options) {
+						errIndex, err = tokens[ti].Index, ErrInvalidUTF8
+						return true
+					}
+					if _, ok := any(val).([]byte); ok && !budget.alloc(uintptr(len(val))) {
+						// Only copied if S is []byte since val contains no escapes.
+						errIndex, err = tokens[ti].Index, ErrAllocBudgetExceeded
+						return true
+					}
 					*(*string)(p) = string(val)
 
 				case ExpectTypeNumber:
 					tv := s[tokens[ti].Index+1 : tokens[ti].End-1]
+					if options.AllowUnderscoreInNumbers {
+						tv = removeDigitSeparators(tv)
+					}
+					if options.AllowLeadingZeros {
+						tv = trimLeadingZeros(tv)
+					}
 					if _, rc := jsonnum.ReadNumber(tv); rc == jsonnum.ReturnCodeErr {
 						errIndex, err = tokens[ti].Index, ErrUnexpectedValue
 						return true
@@ -1059,8 +2490,11 @@ func (d *Decoder[S, T]) Decode(
 
 				case ExpectTypeFloat32String:
 					tv := s[tokens[ti].Index+1 : tokens[ti].End-1]
-					_, rc := jsonnum.ReadNumber(tv)
-					if rc == jsonnum.ReturnCodeErr {
+					if options.AllowUnderscoreInNumbers {
+						tv = removeDigitSeparators(tv)
+					}
+					tail, rc := jsonnum.ReadNumber(tv)
+					if rc == jsonnum.ReturnCodeErr || len(tail) > 0 {
 						errIndex, err = tokens[ti].Index, ErrUnexpectedValue
 						return true
 					}
@@ -1072,8 +2506,11 @@ func (d *Decoder[S, T]) Decode(
 					*(*float32)(p) = v
 				case ExpectTypeFloat64String:
 					tv := s[tokens[ti].Index+1 : tokens[ti].End-1]
-					_, rc := jsonnum.ReadNumber(tv)
-					if rc == jsonnum.ReturnCodeErr {
+					if options.AllowUnderscoreInNumbers {
+						tv = removeDigitSeparators(tv)
+					}
+					tail, rc := jsonnum.ReadNumber(tv)
+					if rc == jsonnum.ReturnCodeErr || len(tail) > 0 {
 						errIndex, err = tokens[ti].Index, ErrUnexpectedValue
 						return true
 					}
@@ -1085,8 +2522,16 @@ func (d *Decoder[S, T]) Decode(
 					*(*float64)(p) = v
 				case ExpectTypeIntString:
 					tv := s[tokens[ti].Index+1 : tokens[ti].End-1]
-					_, rc := jsonnum.ReadNumber(tv)
-					if rc != jsonnum.ReturnCodeInteger {
+					if options.AllowUnderscoreInNumbers {
+						tv = removeDigitSeparators(tv)
+					}
+					if options.AllowHexIntegers {
+						if dec, ok := hexToDecimal(tv); ok {
+							tv = dec
+						}
+					}
+					tail, rc := jsonnum.ReadNumber(tv)
+					if rc != jsonnum.ReturnCodeInteger || len(tail) > 0 {
 						errIndex, err = tokens[ti].Index, ErrUnexpectedValue
 						return true
 					}
@@ -1098,8 +2543,16 @@ func (d *Decoder[S, T]) Decode(
 					*(*int)(p) = v
 				case ExpectTypeInt8String:
 					tv := s[tokens[ti].Index+1 : tokens[ti].End-1]
-					_, rc := jsonnum.ReadNumber(tv)
-					if rc != jsonnum.ReturnCodeInteger {
+					if options.AllowUnderscoreInNumbers {
+						tv = removeDigitSeparators(tv)
+					}
+					if options.AllowHexIntegers {
+						if dec, ok := hexToDecimal(tv); ok {
+							tv = dec
+						}
+					}
+					tail, rc := jsonnum.ReadNumber(tv)
+					if rc != jsonnum.ReturnCodeInteger || len(tail) > 0 {
 						errIndex, err = tokens[ti].Index, ErrUnexpectedValue
 						return true
 					}
@@ -1111,8 +2564,16 @@ func (d *Decoder[S, T]) Decode(
 					*(*int8)(p) = v
 				case ExpectTypeInt16String:
 					tv := s[tokens[ti].Index+1 : tokens[ti].End-1]
-					_, rc := jsonnum.ReadNumber(tv)
-					if rc != jsonnum.ReturnCodeInteger {
+					if options.AllowUnderscoreInNumbers {
+						tv = removeDigitSeparators(tv)
+					}
+					if options.AllowHexIntegers {
+						if dec, ok := hexToDecimal(tv); ok {
+							tv = dec
+						}
+					}
+					tail, rc := jsonnum.ReadNumber(tv)
+					if rc != jsonnum.ReturnCodeInteger || len(tail) > 0 {
 						errIndex, err = tokens[ti].Index, ErrUnexpectedValue
 						return true
 					}
@@ -1124,8 +2585,16 @@ func (d *Decoder[S, T]) Decode(
 					*(*int16)(p) = v
 				case ExpectTypeInt32String:
 					tv := s[tokens[ti].Index+1 : tokens[ti].End-1]
-					_, rc := jsonnum.ReadNumber(tv)
-					if rc != jsonnum.ReturnCodeInteger {
+					if options.AllowUnderscoreInNumbers {
+						tv = removeDigitSeparators(tv)
+					}
+					if options.AllowHexIntegers {
+						if dec, ok := hexToDecimal(tv); ok {
+							tv = dec
+						}
+					}
+					tail, rc := jsonnum.ReadNumber(tv)
+					if rc != jsonnum.ReturnCodeInteger || len(tail) > 0 {
 						errIndex, err = tokens[ti].Index, ErrUnexpectedValue
 						return true
 					}
@@ -1137,8 +2606,16 @@ func (d *Decoder[S, T]) Decode(
 					*(*int32)(p) = v
 				case ExpectTypeInt64String:
 					tv := s[tokens[ti].Index+1 : tokens[ti].End-1]
-					_, rc := jsonnum.ReadNumber(tv)
-					if rc != jsonnum.ReturnCodeInteger {
+					if options.AllowUnderscoreInNumbers {
+						tv = removeDigitSeparators(tv)
+					}
+					if options.AllowHexIntegers {
+						if dec, ok := hexToDecimal(tv); ok {
+							tv = dec
+						}
+					}
+					tail, rc := jsonnum.ReadNumber(tv)
+					if rc != jsonnum.ReturnCodeInteger || len(tail) > 0 {
 						errIndex, err = tokens[ti].Index, ErrUnexpectedValue
 						return true
 					}
@@ -1150,8 +2627,16 @@ func (d *Decoder[S, T]) Decode(
 					*(*int64)(p) = v
 				case ExpectTypeUintString:
 					tv := s[tokens[ti].Index+1 : tokens[ti].End-1]
-					_, rc := jsonnum.ReadNumber(tv)
-					if rc != jsonnum.ReturnCodeInteger || tv[0] == '-' {
+					if options.AllowUnderscoreInNumbers {
+						tv = removeDigitSeparators(tv)
+					}
+					if options.AllowHexIntegers {
+						if dec, ok := hexToDecimal(tv); ok {
+							tv = dec
+						}
+					}
+					tail, rc := jsonnum.ReadNumber(tv)
+					if rc != jsonnum.ReturnCodeInteger || len(tail) > 0 || tv[0] == '-' {
 						errIndex, err = tokens[ti].Index, ErrUnexpectedValue
 						return true
 					}
@@ -1163,8 +2648,16 @@ func (d *Decoder[S, T]) Decode(
 					*(*uint)(p) = v
 				case ExpectTypeUint8String:
 					tv := s[tokens[ti].Index+1 : tokens[ti].End-1]
-					_, rc := jsonnum.ReadNumber(tv)
-					if rc != jsonnum.ReturnCodeInteger || tv[0] == '-' {
+					if options.AllowUnderscoreInNumbers {
+						tv = removeDigitSeparators(tv)
+					}
+					if options.AllowHexIntegers {
+						if dec, ok := hexToDecimal(tv); ok {
+							tv = dec
+						}
+					}
+					tail, rc := jsonnum.ReadNumber(tv)
+					if rc != jsonnum.ReturnCodeInteger || len(tail) > 0 || tv[0] == '-' {
 						errIndex, err = tokens[ti].Index, ErrUnexpectedValue
 						return true
 					}
@@ -1176,8 +2669,16 @@ func (d *Decoder[S, T]) Decode(
 					*(*uint8)(p) = v
 				case ExpectTypeUint16String:
 					tv := s[tokens[ti].Index+1 : tokens[ti].End-1]
-					_, rc := jsonnum.ReadNumber(tv)
-					if rc != jsonnum.ReturnCodeInteger || tv[0] == '-' {
+					if options.AllowUnderscoreInNumbers {
+						tv = removeDigitSeparators(tv)
+					}
+					if options.AllowHexIntegers {
+						if dec, ok := hexToDecimal(tv); ok {
+							tv = dec
+						}
+					}
+					tail, rc := jsonnum.ReadNumber(tv)
+					if rc != jsonnum.ReturnCodeInteger || len(tail) > 0 || tv[0] == '-' {
 						errIndex, err = tokens[ti].Index, ErrUnexpectedValue
 						return true
 					}
@@ -1189,8 +2690,16 @@ func (d *Decoder[S, T]) Decode(
 					*(*uint16)(p) = v
 				case ExpectTypeUint32String:
 					tv := s[tokens[ti].Index+1 : tokens[ti].End-1]
-					_, rc := jsonnum.ReadNumber(tv)
-					if rc != jsonnum.ReturnCodeInteger || tv[0] == '-' {
+					if options.AllowUnderscoreInNumbers {
+						tv = removeDigitSeparators(tv)
+					}
+					if options.AllowHexIntegers {
+						if dec, ok := hexToDecimal(tv); ok {
+							tv = dec
+						}
+					}
+					tail, rc := jsonnum.ReadNumber(tv)
+					if rc != jsonnum.ReturnCodeInteger || len(tail) > 0 || tv[0] == '-' {
 						errIndex, err = tokens[ti].Index, ErrUnexpectedValue
 						return true
 					}
@@ -1202,8 +2711,16 @@ func (d *Decoder[S, T]) Decode(
 					*(*uint32)(p) = v
 				case ExpectTypeUint64String:
 					tv := s[tokens[ti].Index+1 : tokens[ti].End-1]
-					_, rc := jsonnum.ReadNumber(tv)
-					if rc != jsonnum.ReturnCodeInteger || tv[0] == '-' {
+					if options.AllowUnderscoreInNumbers {
+						tv = removeDigitSeparators(tv)
+					}
+					if options.AllowHexIntegers {
+						if dec, ok := hexToDecimal(tv); ok {
+							tv = dec
+						}
+					}
+					tail, rc := jsonnum.ReadNumber(tv)
+					if rc != jsonnum.ReturnCodeInteger || len(tail) > 0 || tv[0] == '-' {
 						errIndex, err = tokens[ti].Index, ErrUnexpectedValue
 						return true
 					}
@@ -1214,7 +2731,17 @@ func (d *Decoder[S, T]) Decode(
 					}
 					*(*uint64)(p) = v
 				case ExpectTypePtr:
+					if d.stackExp[si+1].Type.isStringTagType() &&
+						string(s[tokens[ti].Index:tokens[ti].End]) == `"null"` {
+						// Like encoding/json, treat a quoted null as null.
+						*(*unsafe.Pointer)(p) = nil
+						break
+					}
 					goto ON_PTR
+				case ExpectTypeOptional:
+					goto ON_OPTIONAL
+				case ExpectTypeInterface:
+					goto ON_INTERFACE
 				case ExpectTypeJSONUnmarshaler:
 					goto ON_JSON_UNMARSHALER
 				default:
@@ -1229,25 +2756,51 @@ func (d *Decoder[S, T]) Decode(
 				p := unsafe.Pointer(
 					uintptr(d.stackExp[si].Dest) + d.stackExp[si].Offset,
 				)
+				if options.DisallowNullForContainers &&
+					d.stackExp[si].Type.isContainer() {
+					errIndex, err = tokens[ti].Index, ErrUnexpectedValue
+					return true
+				}
+				if options.mergePatch && d.isMergePatchMember(si) {
+					// A null member removes the target value.
+					if errNull := d.mergePatchRemove(s, tokens, ti, si, p, options); errNull != nil {
+						errIndex, err = tokens[ti-1].Index, errNull
+						return true
+					}
+					ti++
+					goto ON_VAL_END
+				}
 				switch d.stackExp[si].Type {
 				case ExpectTypeEmptyStruct:
 					// Nothing
 				case ExpectTypeAny:
 					*(*any)(p) = nil
+				case ExpectTypeError:
+					*(*error)(p) = nil
 				case ExpectTypeMapStringString:
 					*(*map[string]string)(p) = nil
-				case ExpectTypeMap, ExpectTypeMapRecur:
+				case ExpectTypeMapStringRawMessage:
+					*(*map[string]json.RawMessage)(p) = nil
+				case ExpectTypeMap, ExpectTypeMapRecur, ExpectTypeMapNested:
 					*(*unsafe.Pointer)(p) = nil
-				case ExpectTypeSlice, ExpectTypeSliceRecur:
+				case ExpectTypeContainer:
+					typedmemclr(d.stackExp[si].Typ, p)
+				case ExpectTypeSlice, ExpectTypeSliceRecur, ExpectTypeSlicePairs:
 					// Skip
 					*(*[]any)(p) = nil
 				case ExpectTypeStruct, ExpectTypeStructRecur:
 					// Skip
 				case ExpectTypePtr, ExpectTypePtrRecur:
 					*(*unsafe.Pointer)(p) = nil
+				case ExpectTypeInterface:
+					*(*ifaceHeader)(p) = ifaceHeader{}
+				case ExpectTypeOptional:
+					// The key is present but the value is null.
+					*(*optionalHeader)(p) = optionalHeader{Set: true}
+					typedmemclr(d.stackExp[si+1].Typ, unsafe.Add(p, d.stackExp[si+1].Offset))
 				case ExpectTypeBool:
 					*(*bool)(p) = zeroBool
-				case ExpectTypeStr:
+				case ExpectTypeStr, ExpectTypeStrEnum:
 					*(*string)(p) = zeroStr
 				case ExpectTypeNumber:
 					*(*Number)(p) = ""
@@ -1295,7 +2848,7 @@ func (d *Decoder[S, T]) Decode(
 					*(*[]int64)(p) = nil
 				case ExpectTypeSliceUint:
 					*(*[]uint)(p) = nil
-				case ExpectTypeSliceUint8:
+				case ExpectTypeSliceUint8, ExpectTypeBytesString:
 					*(*[]uint8)(p) = nil
 				case ExpectTypeSliceUint16:
 					*(*[]uint16)(p) = nil
@@ -1303,6 +2856,8 @@ func (d *Decoder[S, T]) Decode(
 					*(*[]uint32)(p) = nil
 				case ExpectTypeSliceUint64:
 					*(*[]uint64)(p) = nil
+				case ExpectTypeSliceTime:
+					*(*[]time.Time)(p) = nil
 				case ExpectTypeJSONUnmarshaler:
 					goto ON_JSON_UNMARSHALER
 				}
@@ -1315,43 +2870,246 @@ func (d *Decoder[S, T]) Decode(
 					p := unsafe.Pointer(
 						uintptr(d.stackExp[si].Dest) + d.stackExp[si].Offset,
 					)
-					v, tail, errDecode := decodeAny(s, tokens[ti:])
+					v, tail, errDecode := decodeAny(
+						s, tokens[ti:], options, &budget, d.rewriteSpans, 1,
+					)
 					if errDecode != nil {
-						errIndex, err = tokens[ti].Index, ErrUnexpectedValue
+						errIndex, err = tokens[ti].Index, errDecode
+						if tail != nil {
+							// The failing token, such as a number out of range.
+							errIndex = tail[0].Index
+						}
 						return true
 					}
-					*(*any)(p) = v
-					ti = len(tokens) - len(tail)
+					*(*any)(p) = v
+					ti = len(tokens) - len(tail)
+					goto ON_VAL_END
+
+				case ExpectTypeArrayLen0:
+					// Ignore this array
+					ti = tokens[ti].End + 1
+					goto ON_VAL_END
+
+				case ExpectTypeArray:
+					p := unsafe.Pointer(
+						uintptr(d.stackExp[si].Dest) + d.stackExp[si].Offset,
+					)
+					if tokens[ti].Elements < 1 {
+						ti += 2 // Skip over closing TokenArrayEnd
+						// Empty array, no need to allocate memory.
+						// Go allocates it together with its parent,
+						// only the previous contents need to be zeroed.
+						typedmemclr(d.stackExp[si].Typ, p)
+						goto ON_VAL_END
+					}
+					ti++
+					si++
+					d.stackExp[si].Dest = p
+					d.stackExp[si].Len = 0
+					d.stackExp[si].Offset = 0
+
+				case ExpectTypeArrayBool:
+					p := unsafe.Pointer(
+						uintptr(d.stackExp[si].Dest) + d.stackExp[si].Offset,
+					)
+					a := unsafe.Slice(
+						(*bool)(p), d.stackExp[si].Size/unsafe.Sizeof(false),
+					)
+					n := min(tokens[ti].Elements, len(a))
+					end := tokens[ti].End
+					tokens := tokens[ti+1 : ti+1+n]
+					for i := range tokens {
+						switch tokens[i].Type {
+						case jscan.TokenTypeNull:
+							a[i] = false
+						case jscan.TokenTypeTrue:
+							a[i] = true
+						case jscan.TokenTypeFalse:
+							a[i] = false
+						default:
+							errIndex, err = tokens[i].Index, ErrUnexpectedValue
+							return true
+						}
+					}
+					// Zero all trailing elements and ignore all extra values.
+					clear(a[n:])
+					ti = end + 1
+					goto ON_VAL_END
+
+				case ExpectTypeArrayStr:
+					p := unsafe.Pointer(
+						uintptr(d.stackExp[si].Dest) + d.stackExp[si].Offset,
+					)
+					a := unsafe.Slice(
+						(*string)(p), d.stackExp[si].Size/unsafe.Sizeof(""),
+					)
+					n := min(tokens[ti].Elements, len(a))
+					end := tokens[ti].End
+					tokens := tokens[ti+1 : ti+1+n]
+					for i := range tokens {
+						switch tokens[i].Type {
+						case jscan.TokenTypeNull:
+							a[i] = ""
+						case jscan.TokenTypeString:
+							if stringTooLong(s, tokens[i], options) {
+								errIndex, err = tokens[i].Index, ErrLimitExceeded
+								return true
+							}
+							if invalidUTF8(s, tokens[i], options) {
+								errIndex, err = tokens[i].Index, ErrInvalidUTF8
+								return true
+							}
+							if !allocString(&budget, s, tokens[i]) {
+								errIndex, err = tokens[i].Index, ErrAllocBudgetExceeded
+								return true
+							}
+							a[i] = options.transformValue(unescape.Valid[S, string](
+								s[tokens[i].Index+1 : tokens[i].End-1],
+							))
+						default:
+							errIndex, err = tokens[i].Index, ErrUnexpectedValue
+							return true
+						}
+					}
+					// Zero all trailing elements and ignore all extra values.
+					clear(a[n:])
+					ti = end + 1
+					goto ON_VAL_END
+
+				case ExpectTypeArrayFloat32:
+					p := unsafe.Pointer(
+						uintptr(d.stackExp[si].Dest) + d.stackExp[si].Offset,
+					)
+					a := unsafe.Slice(
+						(*float32)(p), d.stackExp[si].Size/unsafe.Sizeof(float32(0)),
+					)
+					n := min(tokens[ti].Elements, len(a))
+					end := tokens[ti].End
+					tokens := tokens[ti+1 : ti+1+n]
+					for i := range tokens {
+						switch tokens[i].Type {
+						case jscan.TokenTypeNull:
+							a[i] = 0
+						case jscan.TokenTypeNumber:
+							v, errParse := d.parseFloat32(
+								s[tokens[i].Index:tokens[i].End],
+							)
+							if errParse != nil {
+								errIndex, err = tokens[i].Index, errParse
+								return true
+							}
+							a[i] = v
+						case jscan.TokenTypeInteger:
+							if len(d.rewriteSpans) > 0 &&
+								hexLiteral(d.rewriteSpans, tokens[i].Index) {
+								errIndex, err = tokens[i].Index, ErrUnexpectedValue
+								return true
+							}
+							if tokens[i].End-tokens[i].Index < len("16777216") {
+								// Numbers below this length are guaranteed to be smaller
+								// 1<<24 and float32(i32) is faster than parseFloat32.
+								v, errParse := tokens[i].Int32(s)
+								if errParse != nil {
+									errIndex, err = tokens[i].Index, errParse
+									return true
+								}
+								a[i] = float32(v)
+								if v == 0 && s[tokens[i].Index] == '-' {
+									a[i] = float32(math.Copysign(0, -1))
+								}
+							} else {
+								v, errParse := d.parseFloat32(
+									s[tokens[i].Index:tokens[i].End],
+								)
+								if errParse != nil {
+									errIndex, err = tokens[i].Index, errParse
+									return true
+								}
+								checkPrecision(
+									options, tokens[i].Index, s[tokens[i].Index:tokens[i].End], float64(v),
+								)
+								a[i] = v
+							}
+						default:
+							errIndex, err = tokens[i].Index, ErrUnexpectedValue
+							return true
+						}
+					}
+					// Zero all trailing elements and ignore all extra values.
+					clear(a[n:])
+					ti = end + 1
+					goto ON_VAL_END
+
+				case ExpectTypeArrayFloat64:
+					p := unsafe.Pointer(
+						uintptr(d.stackExp[si].Dest) + d.stackExp[si].Offset,
+					)
+					a := unsafe.Slice(
+						(*float64)(p), d.stackExp[si].Size/unsafe.Sizeof(float64(0)),
+					)
+					n := min(tokens[ti].Elements, len(a))
+					end := tokens[ti].End
+					tokens := tokens[ti+1 : ti+1+n]
+					for i := range tokens {
+						switch tokens[i].Type {
+						case jscan.TokenTypeNull:
+							a[i] = 0
+						case jscan.TokenTypeNumber:
+							v, errParse := d.parseFloat64(
+								s[tokens[i].Index:tokens[i].End],
+							)
+							if errParse != nil {
+								errIndex, err = tokens[i].Index, errParse
+								return true
+							}
+							a[i] = v
+						case jscan.TokenTypeInteger:
+							if len(d.rewriteSpans) > 0 &&
+								hexLiteral(d.rewriteSpans, tokens[i].Index) {
+								errIndex, err = tokens[i].Index, ErrUnexpectedValue
+								return true
+							}
+							if tokens[i].End-tokens[i].Index < len("9007199254740992") {
+								// Numbers below this length are guaranteed to be smaller
+								// 1<<53 and float64(i64) is faster than parseFloat64.
+								v, errParse := tokens[i].Int64(s)
+								if errParse != nil {
+									errIndex, err = tokens[i].Index, errParse
+									return true
+								}
+								a[i] = float64(v)
+								if v == 0 && s[tokens[i].Index] == '-' {
+									a[i] = math.Copysign(0, -1)
+								}
+							} else {
+								v, errParse := d.parseFloat64(
+									s[tokens[i].Index:tokens[i].End],
+								)
+								if errParse != nil {
+									errIndex, err = tokens[i].Index, errParse
+									return true
+								}
+								checkPrecision(
+									options, tokens[i].Index, s[tokens[i].Index:tokens[i].End], v,
+								)
+								a[i] = v
+							}
+						default:
+							errIndex, err = tokens[i].Index, ErrUnexpectedValue
+							return true
+						}
+					}
+					// Zero all trailing elements and ignore all extra values.
+					clear(a[n:])
+					ti = end + 1
 					goto ON_VAL_END
 
-				case ExpectTypeArrayLen0:
-					// Ignore this array
-					ti = tokens[ti].End + 1
-					goto ON_VAL_END
-
-				case ExpectTypeArray:
-					p := unsafe.Pointer(
-						uintptr(d.stackExp[si].Dest) + d.stackExp[si].Offset,
-					)
-					if tokens[ti].Elements < 1 {
-						ti += 2 // Skip over closing TokenArrayEnd
-						// Empty array, no need to allocate memory.
-						// Go will automatically allocate it together with its parent
-						// and zero it.
-						goto ON_VAL_END
-					}
-					ti++
-					si++
-					d.stackExp[si].Dest = p
-					d.stackExp[si].Len = 0
-					d.stackExp[si].Offset = 0
-
 				case ExpectTypeSlice:
 					p := unsafe.Pointer(
 						uintptr(d.stackExp[si].Dest) + d.stackExp[si].Offset,
 					)
 					elementSize := d.stackExp[si+1].Size
-					elems := uintptr(tokens[ti].Elements)
+					elems := uintptr(options.decodedElements(tokens[ti].Elements))
 
 					if elems == 0 {
 						// Allocate empty slice
@@ -1365,10 +3123,20 @@ func (d *Decoder[S, T]) Decode(
 					}
 
 					var dp unsafe.Pointer
-					if h := *(*sliceHeader)(p); h.Cap < uintptr(tokens[ti].Elements) {
+					if h := *(*sliceHeader)(p); h.Cap < elems {
+						if !budget.alloc(elems * elementSize) {
+							errIndex, err = tokens[ti].Index, ErrAllocBudgetExceeded
+							return true
+						}
 						sh := sliceHeader{Len: elems, Cap: elems}
 						if elementSize > 0 {
-							sh.Data = newarray(d.stackExp[si+1].Typ, elems)
+							if d.slicePool {
+								sh.Data = d.stackExp[si].Pool.get(
+									d.stackExp[si+1].Typ, elementSize, elems,
+								)
+							} else {
+								sh.Data = newarray(d.stackExp[si+1].Typ, elems)
+							}
 							if h.Len != 0 && d.stackExp[si+1].Type.isElemComposite() {
 								// Must copy existing data bzecause it's not guarenteed
 								// that the existing data will be fully overwritten.
@@ -1385,9 +3153,38 @@ func (d *Decoder[S, T]) Decode(
 						*(*sliceHeader)(p) = sh
 						dp = sh.Data
 					} else {
-						(*sliceHeader)(p).Len = uintptr(tokens[ti].Elements)
+						(*sliceHeader)(p).Len = elems
 						dp = (*sliceHeader)(p).Data
 					}
+					if d.stackExp[si].FlatStruct && options.FieldOffsets == nil &&
+						options.OnFieldDecoded == nil {
+						ti, errIndex, err = d.decodeFlatStructSlice(
+							s, tokens, ti, si, dp, elems, options, &budget,
+						)
+						if err != nil {
+							return true
+						}
+						goto ON_VAL_END
+					}
+					if d.stackExp[si+1].Type == ExpectTypeTextUnmarshaler {
+						ti, errIndex, err = d.decodeTextUnmarshalerSlice(
+							s, tokens, ti, si, dp, elems, options,
+						)
+						if err != nil {
+							return true
+						}
+						goto ON_VAL_END
+					}
+					if d.stackExp[si+1].Type == ExpectTypeJSONUnmarshaler {
+						ti, errIndex, err = d.decodeJSONUnmarshalerSlice(
+							s, tokens, ti, si, dp, elems, options,
+						)
+						if err != nil {
+							return true
+						}
+						goto ON_VAL_END
+					}
+					d.stackExp[si].Singleton = false
 					ti++
 					si++
 					d.stackExp[si].Dest = dp
@@ -1400,7 +3197,7 @@ func (d *Decoder[S, T]) Decode(
 
 					recursiveFrame := d.stackExp[si].RecurFrame
 					elementSize := d.stackExp[recursiveFrame].Size
-					elems := uintptr(tokens[ti].Elements)
+					elems := uintptr(options.decodedElements(tokens[ti].Elements))
 
 					if elems == 0 {
 						// Allocate empty slice
@@ -1420,7 +3217,11 @@ func (d *Decoder[S, T]) Decode(
 					}
 
 					var dp unsafe.Pointer
-					if h := *(*sliceHeader)(p); h.Cap < uintptr(tokens[ti].Elements) {
+					if h := *(*sliceHeader)(p); h.Cap < elems {
+						if !budget.alloc(elems * elementSize) {
+							errIndex, err = tokens[ti].Index, ErrAllocBudgetExceeded
+							return true
+						}
 						sh := sliceHeader{
 							Data: newarray(d.stackExp[recursiveFrame].Typ, elems),
 							Len:  elems,
@@ -1438,7 +3239,7 @@ func (d *Decoder[S, T]) Decode(
 						*(*sliceHeader)(p) = sh
 						dp = sh.Data
 					} else {
-						(*sliceHeader)(p).Len = uintptr(tokens[ti].Elements)
+						(*sliceHeader)(p).Len = elems
 						dp = (*sliceHeader)(p).Data
 					}
 					ti++
@@ -1456,14 +3257,37 @@ func (d *Decoder[S, T]) Decode(
 					d.stackExp[si].Dest = dp
 					d.stackExp[si].Offset = 0
 
+				case ExpectTypeContainer:
+					p := unsafe.Pointer(
+						uintptr(d.stackExp[si].Dest) + d.stackExp[si].Offset,
+					)
+					f := &d.stackExp[si]
+					f.ContainerDst, f.ContainerAdd = f.ContainerFactory(tokens[ti].Elements)
+					if tokens[ti].Elements == 0 {
+						d.completeContainer(si, p)
+						ti += 2
+						goto ON_VAL_END
+					}
+					// Elements are decoded into a temporary value
+					// which is zeroed after it's added.
+					si++
+					if !budget.alloc(d.stackExp[si].Size) {
+						errIndex, err = tokens[ti].Index, ErrAllocBudgetExceeded
+						return true
+					}
+					d.stackExp[si].Dest = mallocgc(d.stackExp[si].Size, d.stackExp[si].Typ, true)
+					d.stackExp[si].Offset = 0
+					ti++
+
 				case ExpectTypeSliceEmptyStruct:
 					p := unsafe.Pointer(
 						uintptr(d.stackExp[si].Dest) + d.stackExp[si].Offset,
 					)
+					elems := uintptr(options.decodedElements(tokens[ti].Elements))
 					*(*sliceHeader)(p) = sliceHeader{
 						Data: emptyStructAddr,
-						Len:  uintptr(tokens[ti].Elements),
-						Cap:  uintptr(tokens[ti].Elements),
+						Len:  elems,
+						Cap:  elems,
 					}
 					ti = tokens[ti].End + 1
 					goto ON_VAL_END
@@ -1473,7 +3297,7 @@ func (d *Decoder[S, T]) Decode(
 						uintptr(d.stackExp[si].Dest) + d.stackExp[si].Offset,
 					)
 
-					elems := tokens[ti].Elements
+					elems := options.decodedElements(tokens[ti].Elements)
 					if elems < 1 {
 						*(*[]bool)(p) = []bool{}
 						ti += 2
@@ -1482,12 +3306,17 @@ func (d *Decoder[S, T]) Decode(
 
 					sl := *(*[]bool)(p)
 					if cap(sl) < elems {
+						if !budget.alloc(uintptr(elems) * unsafe.Sizeof(sl[0])) {
+							errIndex, err = tokens[ti].Index, ErrAllocBudgetExceeded
+							return true
+						}
 						sl = make([]bool, elems)
 					} else {
 						sl = sl[:elems]
 					}
 
-					tokens := tokens[ti+1 : tokens[ti].End]
+					end := tokens[ti].End
+					tokens := tokens[ti+1 : ti+1+elems]
 					for i := range tokens {
 						switch tokens[i].Type {
 						case jscan.TokenTypeNull:
@@ -1501,7 +3330,7 @@ func (d *Decoder[S, T]) Decode(
 							return true
 						}
 					}
-					ti += len(tokens) + 2
+					ti = end + 1
 					*(*[]bool)(p) = sl
 					goto ON_VAL_END
 
@@ -1510,7 +3339,7 @@ func (d *Decoder[S, T]) Decode(
 						uintptr(d.stackExp[si].Dest) + d.stackExp[si].Offset,
 					)
 
-					elems := tokens[ti].Elements
+					elems := options.decodedElements(tokens[ti].Elements)
 					if elems < 1 {
 						*(*[]string)(p) = []string{}
 						ti += 2
@@ -1519,26 +3348,43 @@ func (d *Decoder[S, T]) Decode(
 
 					sl := *(*[]string)(p)
 					if cap(sl) < elems {
+						if !budget.alloc(uintptr(elems) * unsafe.Sizeof(sl[0])) {
+							errIndex, err = tokens[ti].Index, ErrAllocBudgetExceeded
+							return true
+						}
 						sl = make([]string, elems)
 					} else {
 						sl = sl[:elems]
 					}
 
-					tokens := tokens[ti+1 : tokens[ti].End]
+					end := tokens[ti].End
+					tokens := tokens[ti+1 : ti+1+elems]
 					for i := range tokens {
 						switch tokens[i].Type {
 						case jscan.TokenTypeNull:
 							sl[i] = ""
 						case jscan.TokenTypeString:
-							sl[i] = unescape.Valid[S, string](
+							if stringTooLong(s, tokens[i], options) {
+								errIndex, err = tokens[i].Index, ErrLimitExceeded
+								return true
+							}
+							if invalidUTF8(s, tokens[i], options) {
+								errIndex, err = tokens[i].Index, ErrInvalidUTF8
+								return true
+							}
+							if !allocString(&budget, s, tokens[i]) {
+								errIndex, err = tokens[i].Index, ErrAllocBudgetExceeded
+								return true
+							}
+							sl[i] = options.transformValue(unescape.Valid[S, string](
 								s[tokens[i].Index+1 : tokens[i].End-1],
-							)
+							))
 						default:
 							errIndex, err = tokens[i].Index, ErrUnexpectedValue
 							return true
 						}
 					}
-					ti += len(tokens) + 2
+					ti = end + 1
 					*(*[]string)(p) = sl
 					goto ON_VAL_END
 
@@ -1547,7 +3393,7 @@ func (d *Decoder[S, T]) Decode(
 						uintptr(d.stackExp[si].Dest) + d.stackExp[si].Offset,
 					)
 
-					elems := tokens[ti].Elements
+					elems := options.decodedElements(tokens[ti].Elements)
 					if elems < 1 {
 						*(*[]int)(p) = []int{}
 						ti += 2
@@ -1556,12 +3402,17 @@ func (d *Decoder[S, T]) Decode(
 
 					sl := *(*[]int)(p)
 					if cap(sl) < elems {
+						if !budget.alloc(uintptr(elems) * unsafe.Sizeof(sl[0])) {
+							errIndex, err = tokens[ti].Index, ErrAllocBudgetExceeded
+							return true
+						}
 						sl = make([]int, elems)
 					} else {
 						sl = sl[:elems]
 					}
 
-					tokens := tokens[ti+1 : tokens[ti].End]
+					end := tokens[ti].End
+					tokens := tokens[ti+1 : ti+1+elems]
 					for i := range tokens {
 						switch tokens[i].Type {
 						case jscan.TokenTypeNull:
@@ -1573,12 +3424,25 @@ func (d *Decoder[S, T]) Decode(
 								return true
 							}
 							sl[i] = v
+						case jscan.TokenTypeNumber:
+							if !options.AllowFloatAsInt {
+								errIndex, err = tokens[i].Index, ErrUnexpectedValue
+								return true
+							}
+							v, errConv := floatAsInt[int](
+								s[tokens[i].Index:tokens[i].End],
+							)
+							if errConv != nil {
+								errIndex, err = tokens[i].Index, errConv
+								return true
+							}
+							sl[i] = v
 						default:
 							errIndex, err = tokens[i].Index, ErrUnexpectedValue
 							return true
 						}
 					}
-					ti += len(tokens) + 2
+					ti = end + 1
 					*(*[]int)(p) = sl
 					goto ON_VAL_END
 
@@ -1587,7 +3451,7 @@ func (d *Decoder[S, T]) Decode(
 						uintptr(d.stackExp[si].Dest) + d.stackExp[si].Offset,
 					)
 
-					elems := tokens[ti].Elements
+					elems := options.decodedElements(tokens[ti].Elements)
 					if elems < 1 {
 						*(*[]int8)(p) = []int8{}
 						ti += 2
@@ -1596,12 +3460,17 @@ func (d *Decoder[S, T]) Decode(
 
 					sl := *(*[]int8)(p)
 					if cap(sl) < elems {
+						if !budget.alloc(uintptr(elems) * unsafe.Sizeof(sl[0])) {
+							errIndex, err = tokens[ti].Index, ErrAllocBudgetExceeded
+							return true
+						}
 						sl = make([]int8, elems)
 					} else {
 						sl = sl[:elems]
 					}
 
-					tokens := tokens[ti+1 : tokens[ti].End]
+					end := tokens[ti].End
+					tokens := tokens[ti+1 : ti+1+elems]
 					for i := range tokens {
 						switch tokens[i].Type {
 						case jscan.TokenTypeNull:
@@ -1613,12 +3482,25 @@ func (d *Decoder[S, T]) Decode(
 								return true
 							}
 							sl[i] = v
+						case jscan.TokenTypeNumber:
+							if !options.AllowFloatAsInt {
+								errIndex, err = tokens[i].Index, ErrUnexpectedValue
+								return true
+							}
+							v, errConv := floatAsInt[int8](
+								s[tokens[i].Index:tokens[i].End],
+							)
+							if errConv != nil {
+								errIndex, err = tokens[i].Index, errConv
+								return true
+							}
+							sl[i] = v
 						default:
 							errIndex, err = tokens[i].Index, ErrUnexpectedValue
 							return true
 						}
 					}
-					ti += len(tokens) + 2
+					ti = end + 1
 					*(*[]int8)(p) = sl
 					goto ON_VAL_END
 
@@ -1627,7 +3509,7 @@ func (d *Decoder[S, T]) Decode(
 						uintptr(d.stackExp[si].Dest) + d.stackExp[si].Offset,
 					)
 
-					elems := tokens[ti].Elements
+					elems := options.decodedElements(tokens[ti].Elements)
 					if elems < 1 {
 						*(*[]int16)(p) = []int16{}
 						ti += 2
@@ -1636,12 +3518,17 @@ func (d *Decoder[S, T]) Decode(
 
 					sl := *(*[]int16)(p)
 					if cap(sl) < elems {
+						if !budget.alloc(uintptr(elems) * unsafe.Sizeof(sl[0])) {
+							errIndex, err = tokens[ti].Index, ErrAllocBudgetExceeded
+							return true
+						}
 						sl = make([]int16, elems)
 					} else {
 						sl = sl[:elems]
 					}
 
-					tokens := tokens[ti+1 : tokens[ti].End]
+					end := tokens[ti].End
+					tokens := tokens[ti+1 : ti+1+elems]
 					for i := range tokens {
 						switch tokens[i].Type {
 						case jscan.TokenTypeNull:
@@ -1653,12 +3540,25 @@ func (d *Decoder[S, T]) Decode(
 								return true
 							}
 							sl[i] = v
+						case jscan.TokenTypeNumber:
+							if !options.AllowFloatAsInt {
+								errIndex, err = tokens[i].Index, ErrUnexpectedValue
+								return true
+							}
+							v, errConv := floatAsInt[int16](
+								s[tokens[i].Index:tokens[i].End],
+							)
+							if errConv != nil {
+								errIndex, err = tokens[i].Index, errConv
+								return true
+							}
+							sl[i] = v
 						default:
 							errIndex, err = tokens[i].Index, ErrUnexpectedValue
 							return true
 						}
 					}
-					ti += len(tokens) + 2
+					ti = end + 1
 					*(*[]int16)(p) = sl
 					goto ON_VAL_END
 
@@ -1667,7 +3567,7 @@ func (d *Decoder[S, T]) Decode(
 						uintptr(d.stackExp[si].Dest) + d.stackExp[si].Offset,
 					)
 
-					elems := tokens[ti].Elements
+					elems := options.decodedElements(tokens[ti].Elements)
 					if elems < 1 {
 						*(*[]int32)(p) = []int32{}
 						ti += 2
@@ -1676,12 +3576,17 @@ func (d *Decoder[S, T]) Decode(
 
 					sl := *(*[]int32)(p)
 					if cap(sl) < elems {
+						if !budget.alloc(uintptr(elems) * unsafe.Sizeof(sl[0])) {
+							errIndex, err = tokens[ti].Index, ErrAllocBudgetExceeded
+							return true
+						}
 						sl = make([]int32, elems)
 					} else {
 						sl = sl[:elems]
 					}
 
-					tokens := tokens[ti+1 : tokens[ti].End]
+					end := tokens[ti].End
+					tokens := tokens[ti+1 : ti+1+elems]
 					for i := range tokens {
 						switch tokens[i].Type {
 						case jscan.TokenTypeNull:
@@ -1693,12 +3598,25 @@ func (d *Decoder[S, T]) Decode(
 								return true
 							}
 							sl[i] = v
+						case jscan.TokenTypeNumber:
+							if !options.AllowFloatAsInt {
+								errIndex, err = tokens[i].Index, ErrUnexpectedValue
+								return true
+							}
+							v, errConv := floatAsInt[int32](
+								s[tokens[i].Index:tokens[i].End],
+							)
+							if errConv != nil {
+								errIndex, err = tokens[i].Index, errConv
+								return true
+							}
+							sl[i] = v
 						default:
 							errIndex, err = tokens[i].Index, ErrUnexpectedValue
 							return true
 						}
 					}
-					ti += len(tokens) + 2
+					ti = end + 1
 					*(*[]int32)(p) = sl
 					goto ON_VAL_END
 
@@ -1707,7 +3625,7 @@ func (d *Decoder[S, T]) Decode(
 						uintptr(d.stackExp[si].Dest) + d.stackExp[si].Offset,
 					)
 
-					elems := tokens[ti].Elements
+					elems := options.decodedElements(tokens[ti].Elements)
 					if elems < 1 {
 						*(*[]int64)(p) = []int64{}
 						ti += 2
@@ -1716,12 +3634,17 @@ func (d *Decoder[S, T]) Decode(
 
 					sl := *(*[]int64)(p)
 					if cap(sl) < elems {
+						if !budget.alloc(uintptr(elems) * unsafe.Sizeof(sl[0])) {
+							errIndex, err = tokens[ti].Index, ErrAllocBudgetExceeded
+							return true
+						}
 						sl = make([]int64, elems)
 					} else {
 						sl = sl[:elems]
 					}
 
-					tokens := tokens[ti+1 : tokens[ti].End]
+					end := tokens[ti].End
+					tokens := tokens[ti+1 : ti+1+elems]
 					for i := range tokens {
 						switch tokens[i].Type {
 						case jscan.TokenTypeNull:
@@ -1733,12 +3656,25 @@ func (d *Decoder[S, T]) Decode(
 								return true
 							}
 							sl[i] = v
+						case jscan.TokenTypeNumber:
+							if !options.AllowFloatAsInt {
+								errIndex, err = tokens[i].Index, ErrUnexpectedValue
+								return true
+							}
+							v, errConv := floatAsInt[int64](
+								s[tokens[i].Index:tokens[i].End],
+							)
+							if errConv != nil {
+								errIndex, err = tokens[i].Index, errConv
+								return true
+							}
+							sl[i] = v
 						default:
 							errIndex, err = tokens[i].Index, ErrUnexpectedValue
 							return true
 						}
 					}
-					ti += len(tokens) + 2
+					ti = end + 1
 					*(*[]int64)(p) = sl
 					goto ON_VAL_END
 
@@ -1747,7 +3683,7 @@ func (d *Decoder[S, T]) Decode(
 						uintptr(d.stackExp[si].Dest) + d.stackExp[si].Offset,
 					)
 
-					elems := tokens[ti].Elements
+					elems := options.decodedElements(tokens[ti].Elements)
 					if elems < 1 {
 						*(*[]uint)(p) = []uint{}
 						ti += 2
@@ -1756,12 +3692,17 @@ func (d *Decoder[S, T]) Decode(
 
 					sl := *(*[]uint)(p)
 					if cap(sl) < elems {
+						if !budget.alloc(uintptr(elems) * unsafe.Sizeof(sl[0])) {
+							errIndex, err = tokens[ti].Index, ErrAllocBudgetExceeded
+							return true
+						}
 						sl = make([]uint, elems)
 					} else {
 						sl = sl[:elems]
 					}
 
-					tokens := tokens[ti+1 : tokens[ti].End]
+					end := tokens[ti].End
+					tokens := tokens[ti+1 : ti+1+elems]
 					for i := range tokens {
 						switch tokens[i].Type {
 						case jscan.TokenTypeNull:
@@ -1777,12 +3718,25 @@ func (d *Decoder[S, T]) Decode(
 								return true
 							}
 							sl[i] = v
+						case jscan.TokenTypeNumber:
+							if !options.AllowFloatAsInt {
+								errIndex, err = tokens[i].Index, ErrUnexpectedValue
+								return true
+							}
+							v, errConv := floatAsInt[uint](
+								s[tokens[i].Index:tokens[i].End],
+							)
+							if errConv != nil {
+								errIndex, err = tokens[i].Index, errConv
+								return true
+							}
+							sl[i] = v
 						default:
 							errIndex, err = tokens[i].Index, ErrUnexpectedValue
 							return true
 						}
 					}
-					ti += len(tokens) + 2
+					ti = end + 1
 					*(*[]uint)(p) = sl
 					goto ON_VAL_END
 
@@ -1791,7 +3745,7 @@ func (d *Decoder[S, T]) Decode(
 						uintptr(d.stackExp[si].Dest) + d.stackExp[si].Offset,
 					)
 
-					elems := tokens[ti].Elements
+					elems := options.decodedElements(tokens[ti].Elements)
 					if elems < 1 {
 						*(*[]uint8)(p) = []uint8{}
 						ti += 2
@@ -1800,12 +3754,17 @@ func (d *Decoder[S, T]) Decode(
 
 					sl := *(*[]uint8)(p)
 					if cap(sl) < elems {
+						if !budget.alloc(uintptr(elems) * unsafe.Sizeof(sl[0])) {
+							errIndex, err = tokens[ti].Index, ErrAllocBudgetExceeded
+							return true
+						}
 						sl = make([]uint8, elems)
 					} else {
 						sl = sl[:elems]
 					}
 
-					tokens := tokens[ti+1 : tokens[ti].End]
+					end := tokens[ti].End
+					tokens := tokens[ti+1 : ti+1+elems]
 					for i := range tokens {
 						switch tokens[i].Type {
 						case jscan.TokenTypeNull:
@@ -1821,12 +3780,25 @@ func (d *Decoder[S, T]) Decode(
 								return true
 							}
 							sl[i] = v
+						case jscan.TokenTypeNumber:
+							if !options.AllowFloatAsInt {
+								errIndex, err = tokens[i].Index, ErrUnexpectedValue
+								return true
+							}
+							v, errConv := floatAsInt[uint8](
+								s[tokens[i].Index:tokens[i].End],
+							)
+							if errConv != nil {
+								errIndex, err = tokens[i].Index, errConv
+								return true
+							}
+							sl[i] = v
 						default:
 							errIndex, err = tokens[i].Index, ErrUnexpectedValue
 							return true
 						}
 					}
-					ti += len(tokens) + 2
+					ti = end + 1
 					*(*[]uint8)(p) = sl
 					goto ON_VAL_END
 
@@ -1835,7 +3807,7 @@ func (d *Decoder[S, T]) Decode(
 						uintptr(d.stackExp[si].Dest) + d.stackExp[si].Offset,
 					)
 
-					elems := tokens[ti].Elements
+					elems := options.decodedElements(tokens[ti].Elements)
 					if elems < 1 {
 						*(*[]uint16)(p) = []uint16{}
 						ti += 2
@@ -1844,12 +3816,17 @@ func (d *Decoder[S, T]) Decode(
 
 					sl := *(*[]uint16)(p)
 					if cap(sl) < elems {
+						if !budget.alloc(uintptr(elems) * unsafe.Sizeof(sl[0])) {
+							errIndex, err = tokens[ti].Index, ErrAllocBudgetExceeded
+							return true
+						}
 						sl = make([]uint16, elems)
 					} else {
 						sl = sl[:elems]
 					}
 
-					tokens := tokens[ti+1 : tokens[ti].End]
+					end := tokens[ti].End
+					tokens := tokens[ti+1 : ti+1+elems]
 					for i := range tokens {
 						switch tokens[i].Type {
 						case jscan.TokenTypeNull:
@@ -1865,12 +3842,25 @@ func (d *Decoder[S, T]) Decode(
 								return true
 							}
 							sl[i] = v
+						case jscan.TokenTypeNumber:
+							if !options.AllowFloatAsInt {
+								errIndex, err = tokens[i].Index, ErrUnexpectedValue
+								return true
+							}
+							v, errConv := floatAsInt[uint16](
+								s[tokens[i].Index:tokens[i].End],
+							)
+							if errConv != nil {
+								errIndex, err = tokens[i].Index, errConv
+								return true
+							}
+							sl[i] = v
 						default:
 							errIndex, err = tokens[i].Index, ErrUnexpectedValue
 							return true
 						}
 					}
-					ti += len(tokens) + 2
+					ti = end + 1
 					*(*[]uint16)(p) = sl
 					goto ON_VAL_END
 
@@ -1879,7 +3869,7 @@ func (d *Decoder[S, T]) Decode(
 						uintptr(d.stackExp[si].Dest) + d.stackExp[si].Offset,
 					)
 
-					elems := tokens[ti].Elements
+					elems := options.decodedElements(tokens[ti].Elements)
 					if elems < 1 {
 						*(*[]uint32)(p) = []uint32{}
 						ti += 2
@@ -1888,12 +3878,17 @@ func (d *Decoder[S, T]) Decode(
 
 					sl := *(*[]uint32)(p)
 					if cap(sl) < elems {
+						if !budget.alloc(uintptr(elems) * unsafe.Sizeof(sl[0])) {
+							errIndex, err = tokens[ti].Index, ErrAllocBudgetExceeded
+							return true
+						}
 						sl = make([]uint32, elems)
 					} else {
 						sl = sl[:elems]
 					}
 
-					tokens := tokens[ti+1 : tokens[ti].End]
+					end := tokens[ti].End
+					tokens := tokens[ti+1 : ti+1+elems]
 					for i := range tokens {
 						switch tokens[i].Type {
 						case jscan.TokenTypeNull:
@@ -1909,12 +3904,25 @@ func (d *Decoder[S, T]) Decode(
 								return true
 							}
 							sl[i] = v
+						case jscan.TokenTypeNumber:
+							if !options.AllowFloatAsInt {
+								errIndex, err = tokens[i].Index, ErrUnexpectedValue
+								return true
+							}
+							v, errConv := floatAsInt[uint32](
+								s[tokens[i].Index:tokens[i].End],
+							)
+							if errConv != nil {
+								errIndex, err = tokens[i].Index, errConv
+								return true
+							}
+							sl[i] = v
 						default:
 							errIndex, err = tokens[i].Index, ErrUnexpectedValue
 							return true
 						}
 					}
-					ti += len(tokens) + 2
+					ti = end + 1
 					*(*[]uint32)(p) = sl
 					goto ON_VAL_END
 
@@ -1923,7 +3931,7 @@ func (d *Decoder[S, T]) Decode(
 						uintptr(d.stackExp[si].Dest) + d.stackExp[si].Offset,
 					)
 
-					elems := tokens[ti].Elements
+					elems := options.decodedElements(tokens[ti].Elements)
 					if elems < 1 {
 						*(*[]uint64)(p) = []uint64{}
 						ti += 2
@@ -1932,12 +3940,17 @@ func (d *Decoder[S, T]) Decode(
 
 					sl := *(*[]uint64)(p)
 					if cap(sl) < elems {
+						if !budget.alloc(uintptr(elems) * unsafe.Sizeof(sl[0])) {
+							errIndex, err = tokens[ti].Index, ErrAllocBudgetExceeded
+							return true
+						}
 						sl = make([]uint64, elems)
 					} else {
 						sl = sl[:elems]
 					}
 
-					tokens := tokens[ti+1 : tokens[ti].End]
+					end := tokens[ti].End
+					tokens := tokens[ti+1 : ti+1+elems]
 					for i := range tokens {
 						switch tokens[i].Type {
 						case jscan.TokenTypeNull:
@@ -1953,12 +3966,25 @@ func (d *Decoder[S, T]) Decode(
 								return true
 							}
 							sl[i] = v
+						case jscan.TokenTypeNumber:
+							if !options.AllowFloatAsInt {
+								errIndex, err = tokens[i].Index, ErrUnexpectedValue
+								return true
+							}
+							v, errConv := floatAsInt[uint64](
+								s[tokens[i].Index:tokens[i].End],
+							)
+							if errConv != nil {
+								errIndex, err = tokens[i].Index, errConv
+								return true
+							}
+							sl[i] = v
 						default:
 							errIndex, err = tokens[i].Index, ErrUnexpectedValue
 							return true
 						}
 					}
-					ti += len(tokens) + 2
+					ti = end + 1
 					*(*[]uint64)(p) = sl
 					goto ON_VAL_END
 
@@ -1967,7 +3993,7 @@ func (d *Decoder[S, T]) Decode(
 						uintptr(d.stackExp[si].Dest) + d.stackExp[si].Offset,
 					)
 
-					elems := tokens[ti].Elements
+					elems := options.decodedElements(tokens[ti].Elements)
 					if elems < 1 {
 						*(*[]float32)(p) = []float32{}
 						ti += 2
@@ -1976,12 +4002,17 @@ func (d *Decoder[S, T]) Decode(
 
 					sl := *(*[]float32)(p)
 					if cap(sl) < elems {
+						if !budget.alloc(uintptr(elems) * unsafe.Sizeof(sl[0])) {
+							errIndex, err = tokens[ti].Index, ErrAllocBudgetExceeded
+							return true
+						}
 						sl = make([]float32, elems)
 					} else {
 						sl = sl[:elems]
 					}
 
-					tokens := tokens[ti+1 : tokens[ti].End]
+					end := tokens[ti].End
+					tokens := tokens[ti+1 : ti+1+elems]
 					for i := range tokens {
 						switch tokens[i].Type {
 						case jscan.TokenTypeNull:
@@ -1996,6 +4027,11 @@ func (d *Decoder[S, T]) Decode(
 							}
 							sl[i] = v
 						case jscan.TokenTypeInteger:
+							if len(d.rewriteSpans) > 0 &&
+								hexLiteral(d.rewriteSpans, tokens[i].Index) {
+								errIndex, err = tokens[i].Index, ErrUnexpectedValue
+								return true
+							}
 							if tokens[i].End-tokens[i].Index < len("16777216") {
 								// Numbers below this length are guaranteed to be smaller
 								// 1<<24 and float32(i32) is faster than parseFloat32.
@@ -2005,6 +4041,9 @@ func (d *Decoder[S, T]) Decode(
 									return true
 								}
 								sl[i] = float32(i32)
+								if i32 == 0 && s[tokens[i].Index] == '-' {
+									sl[i] = float32(math.Copysign(0, -1))
+								}
 							} else {
 								v, errParse := d.parseFloat32(
 									s[tokens[i].Index:tokens[i].End],
@@ -2013,6 +4052,9 @@ func (d *Decoder[S, T]) Decode(
 									errIndex, err = tokens[i].Index, errParse
 									return true
 								}
+								checkPrecision(
+									options, tokens[i].Index, s[tokens[i].Index:tokens[i].End], float64(v),
+								)
 								sl[i] = v
 							}
 						default:
@@ -2020,7 +4062,7 @@ func (d *Decoder[S, T]) Decode(
 							return true
 						}
 					}
-					ti += len(tokens) + 2
+					ti = end + 1
 					*(*[]float32)(p) = sl
 					goto ON_VAL_END
 
@@ -2029,7 +4071,7 @@ func (d *Decoder[S, T]) Decode(
 						uintptr(d.stackExp[si].Dest) + d.stackExp[si].Offset,
 					)
 
-					elems := tokens[ti].Elements
+					elems := options.decodedElements(tokens[ti].Elements)
 					if elems < 1 {
 						*(*[]float64)(p) = []float64{}
 						ti += 2
@@ -2038,56 +4080,104 @@ func (d *Decoder[S, T]) Decode(
 
 					sl := *(*[]float64)(p)
 					if cap(sl) < elems {
+						if !budget.alloc(uintptr(elems) * unsafe.Sizeof(sl[0])) {
+							errIndex, err = tokens[ti].Index, ErrAllocBudgetExceeded
+							return true
+						}
 						sl = make([]float64, elems)
 					} else {
 						sl = sl[:elems]
 					}
 
-					tokens := tokens[ti+1 : tokens[ti].End]
+					end := tokens[ti].End
+					tokens := tokens[ti+1 : ti+1+elems]
+					sl = sl[:len(tokens)] // Eliminate bounds checks in the loop.
 					for i := range tokens {
 						switch tokens[i].Type {
 						case jscan.TokenTypeNull:
 							sl[i] = 0
-						case jscan.TokenTypeNumber:
-							v, errParse := d.parseFloat64(
-								s[tokens[i].Index:tokens[i].End],
-							)
+						case jscan.TokenTypeNumber, jscan.TokenTypeInteger:
+							if len(d.rewriteSpans) > 0 &&
+								hexLiteral(d.rewriteSpans, tokens[i].Index) {
+								errIndex, err = tokens[i].Index, ErrUnexpectedValue
+								return true
+							}
+							// Most numbers can be parsed exactly without calling
+							// parseFloat64, which dominates on large arrays.
+							tv := s[tokens[i].Index:tokens[i].End]
+							if v, ok := parseFloat64Exact(tv); ok {
+								sl[i] = v
+								continue
+							}
+							v, errParse := d.parseFloat64(tv)
 							if errParse != nil {
 								errIndex, err = tokens[i].Index, errParse
 								return true
 							}
-							sl[i] = v
-						case jscan.TokenTypeInteger:
-							if tokens[i].End-tokens[i].Index < len("9007199254740992") {
-								// Numbers below this length are guaranteed to be smaller
-								// 1<<53a and float64(i64) is faster than parseFloat64.
-								v, errParse := tokens[i].Int64(s)
-								if errParse != nil {
-									errIndex, err = tokens[i].Index, errParse
-									return true
-								}
-								sl[i] = float64(v)
-							} else {
-								v, errParse := d.parseFloat64(
-									s[tokens[i].Index:tokens[i].End],
-								)
-								if errParse != nil {
-									errIndex, err = tokens[i].Index, errParse
-									return true
-								}
-								sl[i] = v
+							if tokens[i].Type == jscan.TokenTypeInteger {
+								checkPrecision(options, tokens[i].Index, tv, v)
 							}
+							sl[i] = v
 						default:
 							errIndex, err = tokens[i].Index, ErrUnexpectedValue
 							return true
 						}
 					}
-					ti += len(tokens) + 2
+					ti = end + 1
 					*(*[]float64)(p) = sl
 					goto ON_VAL_END
 
+				case ExpectTypeSliceTime:
+					p := unsafe.Pointer(
+						uintptr(d.stackExp[si].Dest) + d.stackExp[si].Offset,
+					)
+
+					elems := options.decodedElements(tokens[ti].Elements)
+					if elems < 1 {
+						*(*[]time.Time)(p) = []time.Time{}
+						ti += 2
+						goto ON_VAL_END
+					}
+
+					sl := *(*[]time.Time)(p)
+					if cap(sl) < elems {
+						if !budget.alloc(uintptr(elems) * unsafe.Sizeof(sl[0])) {
+							errIndex, err = tokens[ti].Index, ErrAllocBudgetExceeded
+							return true
+						}
+						sl = make([]time.Time, elems)
+					} else {
+						sl = sl[:elems]
+					}
+
+					layouts := options.timeLayouts()
+
+					end := tokens[ti].End
+					tokens := tokens[ti+1 : ti+1+elems]
+					for i := range tokens {
+						if tokens[i].Type == jscan.TokenTypeNull {
+							sl[i] = time.Time{}
+							continue
+						}
+						v, errParse := decodeTime(
+							s, tokens[i], layouts, options.TimeEpoch,
+						)
+						if errParse != nil {
+							errIndex, err = tokens[i].Index, errParse
+							return true
+						}
+						sl[i] = v
+					}
+					ti = end + 1
+					*(*[]time.Time)(p) = sl
+					goto ON_VAL_END
+
 				case ExpectTypePtr:
 					goto ON_PTR
+				case ExpectTypeOptional:
+					goto ON_OPTIONAL
+				case ExpectTypeInterface:
+					goto ON_INTERFACE
 
 				case ExpectTypeJSONUnmarshaler:
 					goto ON_JSON_UNMARSHALER
@@ -2103,14 +4193,23 @@ func (d *Decoder[S, T]) Decode(
 					ti = tokens[ti].End // Skip object value
 					goto ON_VAL_END
 				case ExpectTypeAny:
-					v, tail, errDecode := decodeAny(s, tokens[ti:])
+					v, tail, errDecode := decodeAny(
+						s, tokens[ti:], options, &budget, d.rewriteSpans, 1,
+					)
 					if errDecode != nil {
-						errIndex, err = tokens[ti].Index, ErrUnexpectedValue
+						errIndex, err = tokens[ti].Index, errDecode
+						if tail != nil {
+							// The failing token, such as a number out of range.
+							errIndex = tail[0].Index
+						}
 						return true
 					}
 					p := unsafe.Pointer(
 						uintptr(d.stackExp[si].Dest) + d.stackExp[si].Offset,
 					)
+					if options.mergePatch {
+						v = mergePatchAny(*(*any)(p), v)
+					}
 					*(*any)(p) = v
 					ti = len(tokens) - len(tail)
 					goto ON_VAL_END
@@ -2125,9 +4224,11 @@ func (d *Decoder[S, T]) Decode(
 					// Push recursion stack
 					d.stackExp[si].RecursionStack = append(
 						d.stackExp[si].RecursionStack, recursionStackFrame{
-							Dest:           d.stackExp[si].Dest,
-							Offset:         d.stackExp[si].Offset,
-							ContainerFrame: siPointer,
+							Dest:            d.stackExp[si].Dest,
+							Offset:          d.stackExp[si].Offset,
+							ContainerFrame:  siPointer,
+							ContainerDest:   d.stackExp[siPointer].Dest,
+							ContainerOffset: d.stackExp[siPointer].Offset,
 						},
 					)
 
@@ -2135,10 +4236,32 @@ func (d *Decoder[S, T]) Decode(
 					if *(*unsafe.Pointer)(p) != nil {
 						dp = *(*unsafe.Pointer)(p)
 					} else {
+						if !budget.alloc(d.stackExp[si].Size) {
+							errIndex, err = tokens[ti].Index, ErrAllocBudgetExceeded
+							return true
+						}
 						dp = mallocgc(d.stackExp[si].Size, d.stackExp[si].Typ, true)
 						*(*unsafe.Pointer)(p) = dp
 					}
 					d.stackExp[si].Dest = dp
+					d.stackExp[si].Offset = 0
+					if d.stackExp[si].HasRaw {
+						raw, ok := d.rawObject(src, tokens, ti, &budget)
+						if !ok {
+							errIndex, err = tokens[ti].Index, ErrAllocBudgetExceeded
+							return true
+						}
+						*(*[]byte)(unsafe.Add(dp, d.stackExp[si].RawOffset)) = raw
+					}
+					if len(d.stackExp[si].Defaults) > 0 {
+						d.applyDefaults(s, tokens, ti, si, dp, options)
+					}
+					if len(d.stackExp[si].Required) > 0 {
+						if e := d.checkRequired(s, tokens, ti, si, options); e != nil {
+							errIndex, err = tokens[ti].Index, e
+							return true
+						}
+					}
 
 					if tokens[ti].Elements == 0 {
 						ti += 2
@@ -2166,9 +4289,13 @@ func (d *Decoder[S, T]) Decode(
 					)
 					if *(*unsafe.Pointer)(p) == nil {
 						// Map not yet initialized, initialize map.
-						*(*unsafe.Pointer)(p) = makemap(
-							d.stackExp[si].Typ, tokens[ti].Elements,
-						)
+						capacity := options.mapCapacity(tokens[ti].Elements)
+						if !budget.alloc(uintptr(capacity) * (d.stackExp[si+1].Size +
+							d.stackExp[d.stackExp[si].RecurFrame].Size)) {
+							errIndex, err = tokens[ti].Index, ErrAllocBudgetExceeded
+							return true
+						}
+						*(*unsafe.Pointer)(p) = makemap(d.stackExp[si].Typ, capacity)
 					}
 					if tokens[ti].Elements == 0 {
 						ti += 2
@@ -2182,9 +4309,13 @@ func (d *Decoder[S, T]) Decode(
 					)
 					if *(*unsafe.Pointer)(p) == nil {
 						// Map not yet initialized, initialize map.
-						*(*unsafe.Pointer)(p) = makemap(
-							d.stackExp[si].Typ, tokens[ti].Elements,
-						)
+						capacity := options.mapCapacity(tokens[ti].Elements)
+						if !budget.alloc(uintptr(capacity) *
+							(d.stackExp[si+1].Size + d.stackExp[si+2].Size)) {
+							errIndex, err = tokens[ti].Index, ErrAllocBudgetExceeded
+							return true
+						}
+						*(*unsafe.Pointer)(p) = makemap(d.stackExp[si].Typ, capacity)
 					}
 					if tokens[ti].Elements == 0 {
 						ti += 2
@@ -2192,43 +4323,143 @@ func (d *Decoder[S, T]) Decode(
 					}
 					ti++
 
-				case ExpectTypeMapStringString:
+				case ExpectTypeSlicePairs:
 					p := unsafe.Pointer(
 						uintptr(d.stackExp[si].Dest) + d.stackExp[si].Offset,
 					)
-					if tokens[ti].Elements == 0 {
+					elems := uintptr(tokens[ti].Elements)
+					if !budget.alloc(elems * d.stackExp[si].PairSize) {
+						errIndex, err = tokens[ti].Index, ErrAllocBudgetExceeded
+						return true
+					}
+					*(*sliceHeader)(p) = sliceHeader{
+						Data: newarray(d.stackExp[si].PairTyp, elems),
+						Len:  elems,
+						Cap:  elems,
+					}
+					if elems == 0 {
 						ti += 2
-						*(*map[string]string)(p) = make(map[string]string, 0)
 						goto ON_VAL_END
 					}
-					m := make(map[string]string, tokens[ti].Elements)
+					d.stackExp[si].Len = 0
+					ti++
+
+				case ExpectTypeMapStringString:
+					p := unsafe.Pointer(
+						uintptr(d.stackExp[si].Dest) + d.stackExp[si].Offset,
+					)
+					m := *(*map[string]string)(p)
+					if options.mergePatch && m != nil {
+						// Merge into the existing map.
+					} else if tokens[ti].Elements == 0 {
+						m = make(map[string]string, 0)
+					} else {
+						capacity := options.mapCapacity(tokens[ti].Elements)
+						if !budget.alloc(uintptr(capacity) * unsafe.Sizeof("") * 2) {
+							errIndex, err = tokens[ti].Index, ErrAllocBudgetExceeded
+							return true
+						}
+						m = make(map[string]string, capacity)
+					}
 					tiEnd := tokens[ti].End
 
 					for ti++; ti < tiEnd; ti += 2 {
 						tokVal := tokens[ti+1]
+						if stringTooLong(s, tokens[ti], options) {
+							errIndex, err = tokens[ti].Index, ErrLimitExceeded
+							return true
+						}
+						if !allocString(&budget, s, tokens[ti]) {
+							errIndex, err = tokens[ti].Index, ErrAllocBudgetExceeded
+							return true
+						}
 						if tokVal.Type != jscan.TokenTypeString {
 							if tokVal.Type == jscan.TokenTypeNull {
 								key := s[tokens[ti].Index+1 : tokens[ti].End-1]
 								keyUnescaped := unescape.Valid[S, string](key)
-								m[keyUnescaped] = ""
+								if options.mergePatch {
+									delete(m, options.transformMapKey(keyUnescaped))
+									continue
+								}
+								m[options.transformMapKey(keyUnescaped)] = ""
 								continue
 							}
 							errIndex, err = tokVal.Index, ErrUnexpectedValue
 							return true
 						}
+						if stringTooLong(s, tokVal, options) {
+							errIndex, err = tokVal.Index, ErrLimitExceeded
+							return true
+						}
+						if invalidUTF8(s, tokVal, options) {
+							errIndex, err = tokVal.Index, ErrInvalidUTF8
+							return true
+						}
+						if !allocString(&budget, s, tokVal) {
+							errIndex, err = tokVal.Index, ErrAllocBudgetExceeded
+							return true
+						}
 						key := s[tokens[ti].Index+1 : tokens[ti].End-1]
 						keyUnescaped := unescape.Valid[S, string](key)
 						value := s[tokVal.Index+1 : tokVal.End-1]
-						m[keyUnescaped] = unescape.Valid[S, string](value)
+						m[options.transformMapKey(keyUnescaped)] = options.transformValue(
+							unescape.Valid[S, string](value),
+						)
 					}
 					ti++
 					*(*map[string]string)(p) = m
 					goto ON_VAL_END
 
+				case ExpectTypeMapStringRawMessage:
+					p := unsafe.Pointer(
+						uintptr(d.stackExp[si].Dest) + d.stackExp[si].Offset,
+					)
+					next, errIndexMap, errMap := decodeMapStringRawMessage(
+						s, tokens, ti, p, options, &budget,
+					)
+					if errMap != nil {
+						errIndex, err = errIndexMap, errMap
+						return true
+					}
+					ti = next
+					goto ON_VAL_END
+
+				case ExpectTypeMapNested:
+					p := unsafe.Pointer(
+						uintptr(d.stackExp[si].Dest) + d.stackExp[si].Offset,
+					)
+					next, errIndexNested, errNested := d.decodeNestedMap(
+						s, tokens, ti, d.stackExp[si].MapLevels,
+						d.stackExp[si].MapLeaf, p, options, &budget,
+					)
+					if errNested != nil {
+						errIndex, err = errIndexNested, errNested
+						return true
+					}
+					ti = next
+					goto ON_VAL_END
+
 				case ExpectTypeStruct:
 					p := unsafe.Pointer(
 						uintptr(d.stackExp[si].Dest) + d.stackExp[si].Offset,
 					)
+					if d.stackExp[si].HasRaw {
+						raw, ok := d.rawObject(src, tokens, ti, &budget)
+						if !ok {
+							errIndex, err = tokens[ti].Index, ErrAllocBudgetExceeded
+							return true
+						}
+						*(*[]byte)(unsafe.Add(p, d.stackExp[si].RawOffset)) = raw
+					}
+					if len(d.stackExp[si].Defaults) > 0 {
+						d.applyDefaults(s, tokens, ti, si, p, options)
+					}
+					if len(d.stackExp[si].Required) > 0 {
+						if e := d.checkRequired(s, tokens, ti, si, options); e != nil {
+							errIndex, err = tokens[ti].Index, e
+							return true
+						}
+					}
 
 					if tokens[ti].Elements == 0 {
 						ti += 2
@@ -2245,6 +4476,23 @@ func (d *Decoder[S, T]) Decode(
 					p := unsafe.Pointer(
 						uintptr(d.stackExp[si].Dest) + d.stackExp[si].Offset,
 					)
+					if d.stackExp[si].HasRaw {
+						raw, ok := d.rawObject(src, tokens, ti, &budget)
+						if !ok {
+							errIndex, err = tokens[ti].Index, ErrAllocBudgetExceeded
+							return true
+						}
+						*(*[]byte)(unsafe.Add(p, d.stackExp[si].RawOffset)) = raw
+					}
+					if len(d.stackExp[si].Defaults) > 0 {
+						d.applyDefaults(s, tokens, ti, si, p, options)
+					}
+					if len(d.stackExp[si].Required) > 0 {
+						if e := d.checkRequired(s, tokens, ti, si, options); e != nil {
+							errIndex, err = tokens[ti].Index, e
+							return true
+						}
+					}
 					if tokens[ti].Elements == 0 {
 						ti += 2
 						goto ON_RECUR_OBJ_END
@@ -2258,10 +4506,46 @@ func (d *Decoder[S, T]) Decode(
 
 				case ExpectTypePtr:
 					goto ON_PTR
+				case ExpectTypeOptional:
+					goto ON_OPTIONAL
+				case ExpectTypeInterface:
+					goto ON_INTERFACE
 
 				case ExpectTypeJSONUnmarshaler:
 					goto ON_JSON_UNMARSHALER
 
+				case ExpectTypeSlice:
+					if !options.ObjectAsSingletonSlice {
+						errIndex, err = tokens[ti].Index, ErrUnexpectedValue
+						return true
+					}
+					p := unsafe.Pointer(
+						uintptr(d.stackExp[si].Dest) + d.stackExp[si].Offset,
+					)
+					elementSize := d.stackExp[si+1].Size
+					var dp unsafe.Pointer
+					if h := *(*sliceHeader)(p); h.Cap < 1 {
+						if !budget.alloc(elementSize) {
+							errIndex, err = tokens[ti].Index, ErrAllocBudgetExceeded
+							return true
+						}
+						dp = emptyStructAddr
+						if elementSize > 0 {
+							dp = newarray(d.stackExp[si+1].Typ, 1)
+						}
+						*(*sliceHeader)(p) = sliceHeader{Data: dp, Len: 1, Cap: 1}
+					} else {
+						(*sliceHeader)(p).Len = 1
+						dp = h.Data
+					}
+					// Decode the object as the only element of the slice
+					// without advancing to the next token.
+					d.stackExp[si].Singleton = true
+					si++
+					d.stackExp[si].Dest = dp
+					d.stackExp[si].Offset = 0
+					continue
+
 				default:
 					errIndex, err = tokens[ti].Index, ErrUnexpectedValue
 					return true
@@ -2277,7 +4561,7 @@ func (d *Decoder[S, T]) Decode(
 							key = unescape.Valid[S, S](key)
 						}
 						frameIndex := uint32(noParentFrame)
-						if options.DisableCaseInsensitiveMatching {
+						if options.DisableCaseInsensitiveMatching || d.exactOnly {
 							fields := d.stackExp[si].Fields
 							{ // Check for exact matches first
 								// Try 4 at a time if enough are left
@@ -2310,11 +4594,87 @@ func (d *Decoder[S, T]) Decode(
 							frameIndex = fieldFrameIndexByName(d.stackExp[si].Fields, key)
 						}
 					PROCEED:
-						if frameIndex == noParentFrame {
-							if options.DisallowUnknownFields {
-								errIndex, err = tokens[ti].Index, ErrUnknownField
+						if frameIndex == noParentFrame && d.stackExp[si].HasRest {
+							// Decode unknown field into the rest field.
+							pStruct := unsafe.Pointer(
+								uintptr(d.stackExp[si].Dest) + d.stackExp[si].Offset,
+							)
+							if d.stackExp[si].Type == ExpectTypeStructRecur {
+								// Recursive struct frames keep track of
+								// the current struct through their fields.
+								pStruct = d.stackExp[d.stackExp[si].Fields[0].FrameIndex].Dest
+							}
+							m := (*map[string]any)(unsafe.Pointer(
+								uintptr(pStruct) + d.stackExp[si].RestOffset,
+							))
+							if *m == nil {
+								if !budget.alloc(unsafe.Sizeof("") + unsafe.Sizeof(any(nil))) {
+									errIndex, err = tokens[ti].Index, ErrAllocBudgetExceeded
+									return true
+								}
+								*m = make(map[string]any, 1)
+							}
+							if !allocString(&budget, s, tokens[ti]) {
+								errIndex, err = tokens[ti].Index, ErrAllocBudgetExceeded
+								return true
+							}
+							keyRest := options.transformMapKey(unescape.Valid[S, string](
+								s[tokens[ti].Index+1 : tokens[ti].End-1],
+							))
+							v, tail, errDecode := decodeAny(
+								s, tokens[ti+1:], options, &budget, d.rewriteSpans, 1,
+							)
+							if errDecode != nil {
+								errIndex, err = tokens[ti+1].Index, errDecode
+								if tail != nil {
+									// The failing token, such as a number out of range.
+									errIndex = tail[0].Index
+								}
 								return true
 							}
+							switch {
+							case !options.mergePatch:
+								(*m)[keyRest] = v
+							case v == nil:
+								delete(*m, keyRest)
+							default:
+								(*m)[keyRest] = mergePatchAny((*m)[keyRest], v)
+							}
+							if ti = len(tokens) - len(tail); tokens[ti].Type ==
+								jscan.TokenTypeKey {
+								continue SCAN_KEYVALS
+							}
+							ti--
+							break SCAN_KEYVALS
+						}
+						skip := false
+						if frameIndex != noParentFrame {
+							if options.IgnoreFields != nil && options.IgnoreFields[fieldName(
+								d.stackExp[si].Fields, frameIndex,
+							)] {
+								skip = true
+							} else if options.DuplicateKeyStrategy != DuplicateKeyLast &&
+								d.isDuplicateField(
+									s, tokens, ti, d.stackExp[si].Fields, frameIndex, options,
+								) {
+								if options.DuplicateKeyStrategy == DuplicateKeyError {
+									errIndex, err = tokens[ti].Index, ErrDuplicateKey
+									return true
+								}
+								skip = true
+							}
+						}
+						if frameIndex == noParentFrame || skip {
+							if !skip {
+								if errUnknown := options.unknownField(
+									tokens[ti].Index,
+									tokens[ti+1].Type == jscan.TokenTypeObject ||
+										tokens[ti+1].Type == jscan.TokenTypeArray,
+								); errUnknown != nil {
+									errIndex, err = tokens[ti].Index, errUnknown
+									return true
+								}
+							}
 							// Skip value, go to the next key
 							ti++
 							for l := 0; ; ti++ {
@@ -2334,6 +4694,27 @@ func (d *Decoder[S, T]) Decode(
 								}
 							}
 						}
+						if options.FieldOffsets != nil {
+							if paths == nil {
+								paths = keyPaths(s, tokens)
+							}
+							if *options.FieldOffsets == nil {
+								*options.FieldOffsets = make(map[string]int)
+							}
+							offset := tokens[ti+1].Index
+							if len(d.rewriteSpans) > 0 {
+								offset = originalIndex(d.rewriteSpans, offset)
+							}
+							(*options.FieldOffsets)[paths[ti]] = offset
+						}
+						if options.OnFieldDecoded != nil {
+							if paths == nil {
+								paths = keyPaths(s, tokens)
+							}
+							d.decodedFields = append(d.decodedFields, decodedField{
+								Path: paths[ti], Type: d.stackExp[frameIndex].Type,
+							})
+						}
 						si = frameIndex
 						if si == noParentFrame {
 							errIndex, err = tokens[ti].Index, ErrUnexpectedValue
@@ -2360,6 +4741,19 @@ func (d *Decoder[S, T]) Decode(
 					// and at an offset of 1 relative to the map frame index.
 					switch d.stackExp[si+1].Type {
 					case ExpectTypeTextUnmarshaler:
+						if d.stackExp[si+1].RType == tpTime {
+							// Parse RFC 3339 time keys directly
+							// without going through reflection.
+							v, errParse := time.Parse(
+								time.RFC3339, unescape.Valid[S, string](key),
+							)
+							if errParse != nil {
+								errIndex, err = tokens[ti].Index, ErrUnexpectedValue
+								return true
+							}
+							pNewData = mapassign(typMap, pMap, noescape(unsafe.Pointer(&v)))
+							break
+						}
 						v := reflect.New(d.stackExp[si+1].RType)
 						iface := v.Interface().(encoding.TextUnmarshaler)
 						keyUnescaped := unescape.Valid[S, []byte](key)
@@ -2371,7 +4765,33 @@ func (d *Decoder[S, T]) Decode(
 						pNewData = mapassign(typMap, pMap, noescape(pKey))
 
 					case ExpectTypeStr:
-						keyStr := unescape.Valid[S, string](key)
+						if stringTooLong(s, tokens[ti], options) {
+							errIndex, err = tokens[ti].Index, ErrLimitExceeded
+							return true
+						}
+						if !allocString(&budget, s, tokens[ti]) {
+							errIndex, err = tokens[ti].Index, ErrAllocBudgetExceeded
+							return true
+						}
+						keyStr := options.transformMapKey(unescape.Valid[S, string](key))
+						if d.stackExp[si].MapCanUseAssignFaststr {
+							pNewData = mapassign_faststr(typMap, pMap, keyStr)
+						} else {
+							pNewData = mapassign(typMap, pMap, unsafe.Pointer(&keyStr))
+						}
+
+					case ExpectTypeStrEnum:
+						if stringTooLong(s, tokens[ti], options) {
+							errIndex, err = tokens[ti].Index, ErrLimitExceeded
+							return true
+						}
+						keyStr, ok := enumValue(d.stackExp[si+1].Enum, options.transformMapKey(
+							unescape.Valid[S, string](key),
+						))
+						if !ok {
+							errIndex, err = tokens[ti].Index, ErrUnexpectedValue
+							return true
+						}
 						if d.stackExp[si].MapCanUseAssignFaststr {
 							pNewData = mapassign_faststr(typMap, pMap, keyStr)
 						} else {
@@ -2379,8 +4799,7 @@ func (d *Decoder[S, T]) Decode(
 						}
 
 					case ExpectTypeInt:
-						_, rc := jsonnum.ReadNumber(key)
-						if rc != jsonnum.ReturnCodeInteger {
+						if !isIntegerKey(key, true) {
 							errIndex, err = tokens[ti].Index, ErrUnexpectedValue
 							return true
 						}
@@ -2392,8 +4811,7 @@ func (d *Decoder[S, T]) Decode(
 						pNewData = mapassign(typMap, pMap, noescape(unsafe.Pointer(&v)))
 
 					case ExpectTypeInt8:
-						_, rc := jsonnum.ReadNumber(key)
-						if rc != jsonnum.ReturnCodeInteger {
+						if !isIntegerKey(key, true) {
 							errIndex, err = tokens[ti].Index, ErrUnexpectedValue
 							return true
 						}
@@ -2405,8 +4823,7 @@ func (d *Decoder[S, T]) Decode(
 						pNewData = mapassign(typMap, pMap, noescape(unsafe.Pointer(&v)))
 
 					case ExpectTypeInt16:
-						_, rc := jsonnum.ReadNumber(key)
-						if rc != jsonnum.ReturnCodeInteger {
+						if !isIntegerKey(key, true) {
 							errIndex, err = tokens[ti].Index, ErrUnexpectedValue
 							return true
 						}
@@ -2418,8 +4835,7 @@ func (d *Decoder[S, T]) Decode(
 						pNewData = mapassign(typMap, pMap, noescape(unsafe.Pointer(&v)))
 
 					case ExpectTypeInt32:
-						_, rc := jsonnum.ReadNumber(key)
-						if rc != jsonnum.ReturnCodeInteger {
+						if !isIntegerKey(key, true) {
 							errIndex, err = tokens[ti].Index, ErrUnexpectedValue
 							return true
 						}
@@ -2431,8 +4847,7 @@ func (d *Decoder[S, T]) Decode(
 						pNewData = mapassign(typMap, pMap, noescape(unsafe.Pointer(&v)))
 
 					case ExpectTypeInt64:
-						_, rc := jsonnum.ReadNumber(key)
-						if rc != jsonnum.ReturnCodeInteger {
+						if !isIntegerKey(key, true) {
 							errIndex, err = tokens[ti].Index, ErrUnexpectedValue
 							return true
 						}
@@ -2444,8 +4859,7 @@ func (d *Decoder[S, T]) Decode(
 						pNewData = mapassign(typMap, pMap, noescape(unsafe.Pointer(&v)))
 
 					case ExpectTypeUint:
-						_, rc := jsonnum.ReadNumber(key)
-						if rc != jsonnum.ReturnCodeInteger || key[0] == '-' {
+						if !isIntegerKey(key, false) {
 							errIndex, err = tokens[ti].Index, ErrUnexpectedValue
 							return true
 						}
@@ -2457,8 +4871,7 @@ func (d *Decoder[S, T]) Decode(
 						pNewData = mapassign(typMap, pMap, noescape(unsafe.Pointer(&v)))
 
 					case ExpectTypeUint8:
-						_, rc := jsonnum.ReadNumber(key)
-						if rc != jsonnum.ReturnCodeInteger || key[0] == '-' {
+						if !isIntegerKey(key, false) {
 							errIndex, err = tokens[ti].Index, ErrUnexpectedValue
 							return true
 						}
@@ -2470,8 +4883,7 @@ func (d *Decoder[S, T]) Decode(
 						pNewData = mapassign(typMap, pMap, noescape(unsafe.Pointer(&v)))
 
 					case ExpectTypeUint16:
-						_, rc := jsonnum.ReadNumber(key)
-						if rc != jsonnum.ReturnCodeInteger || key[0] == '-' {
+						if !isIntegerKey(key, false) {
 							errIndex, err = tokens[ti].Index, ErrUnexpectedValue
 							return true
 						}
@@ -2483,8 +4895,7 @@ func (d *Decoder[S, T]) Decode(
 						pNewData = mapassign(typMap, pMap, noescape(unsafe.Pointer(&v)))
 
 					case ExpectTypeUint32:
-						_, rc := jsonnum.ReadNumber(key)
-						if rc != jsonnum.ReturnCodeInteger || key[0] == '-' {
+						if !isIntegerKey(key, false) {
 							errIndex, err = tokens[ti].Index, ErrUnexpectedValue
 							return true
 						}
@@ -2496,8 +4907,7 @@ func (d *Decoder[S, T]) Decode(
 						pNewData = mapassign(typMap, pMap, noescape(unsafe.Pointer(&v)))
 
 					case ExpectTypeUint64:
-						_, rc := jsonnum.ReadNumber(key)
-						if rc != jsonnum.ReturnCodeInteger || key[0] == '-' {
+						if !isIntegerKey(key, false) {
 							errIndex, err = tokens[ti].Index, ErrUnexpectedValue
 							return true
 						}
@@ -2509,6 +4919,17 @@ func (d *Decoder[S, T]) Decode(
 						pNewData = mapassign(typMap, pMap, noescape(unsafe.Pointer(&v)))
 					}
 
+					if options.MergeDuplicateKeysIntoSlice &&
+						d.stackExp[si].Type == ExpectTypeMap &&
+						d.stackExp[si].RType.Elem().Kind() == reflect.Slice {
+						// Keep the previous value to merge it once the new one
+						// is decoded, see ON_VAL_END.
+						d.mergeStack = append(d.mergeStack, pendingMerge{
+							Dest: pNewData,
+							Prev: *(*sliceHeader)(pNewData),
+						})
+					}
+
 					if d.stackExp[si].Type == ExpectTypeMapRecur {
 						recursiveFrame := d.stackExp[si].RecurFrame
 						si = uint32(recursiveFrame)
@@ -2520,12 +4941,33 @@ func (d *Decoder[S, T]) Decode(
 
 					d.stackExp[si].Dest = pNewData
 
-					// Zero struct values
-					if d.stackExp[si].Type == ExpectTypeStruct ||
-						d.stackExp[si].Type == ExpectTypeStructRecur {
+					// Zero the value like encoding/json does to avoid reusing
+					// the previous value of an existing key.
+					// Merge patch objects are merged into the existing value instead.
+					if !options.mergePatch ||
+						tokens[ti+1].Type != jscan.TokenTypeObject {
 						typedmemclr(typVal, pNewData)
 					}
 
+				case ExpectTypeSlicePairs:
+					// Fill the key of the next pair in document order and
+					// point the value frame to it.
+					f := &d.stackExp[si]
+					h := (*sliceHeader)(unsafe.Add(f.Dest, f.Offset))
+					pair := unsafe.Add(h.Data, uintptr(f.Len)*f.PairSize)
+					f.Len++
+					if !allocString(&budget, s, tokens[ti]) {
+						errIndex, err = tokens[ti].Index, ErrAllocBudgetExceeded
+						return true
+					}
+					key := s[tokens[ti].Index+1 : tokens[ti].End-1]
+					*(*string)(unsafe.Add(pair, f.PairKeyOffset)) =
+						unescape.Valid[S, string](key)
+					// The value frame is guaranteed to be at an offset of 1
+					// relative to the pairs frame index.
+					si++
+					d.stackExp[si].Dest = pair
+
 				default:
 					errIndex, err = tokens[ti].Index, ErrUnexpectedValue
 					return true
@@ -2589,6 +5031,22 @@ func (d *Decoder[S, T]) Decode(
 					si = d.stackExp[resetTo.ContainerFrame].ParentFrameIndex
 					continue
 				}
+				if pi := d.stackExp[si].ParentFrameIndex; pi != noParentFrame {
+					switch d.stackExp[pi].Type {
+					case ExpectTypeArray:
+						// Zero all trailing elements the input didn't provide.
+						for f := &d.stackExp[si]; f.Len < f.Cap; f.Len++ {
+							typedmemclr(f.Typ, unsafe.Add(f.Dest, f.Offset))
+							f.Offset += f.Size
+						}
+					case ExpectTypeContainer:
+						// Release the temporary element.
+						d.stackExp[si].Dest = nil
+						d.completeContainer(pi, unsafe.Pointer(
+							uintptr(d.stackExp[pi].Dest)+d.stackExp[pi].Offset,
+						))
+					}
+				}
 				si--
 				goto ON_VAL_END
 			}
@@ -2600,6 +5058,7 @@ func (d *Decoder[S, T]) Decode(
 				switch d.stackExp[top.ContainerFrame].Type {
 				case ExpectTypeSliceRecur:
 					d.stackExp[si].Offset += d.stackExp[si].Size
+					ti = skipUndecodedElements(tokens, ti, &d.stackExp[si], options)
 				case ExpectTypePtrRecur:
 					recurStack := d.stackExp[si].RecursionStack
 					if len(recurStack) < 1 {
@@ -2618,9 +5077,14 @@ func (d *Decoder[S, T]) Decode(
 
 					// Pop recursion stack
 					recurStack[topIndex].Dest = nil
+					recurStack[topIndex].ContainerDest = nil
 					d.stackExp[si].RecursionStack = recurStack[:topIndex]
-					si = d.stackExp[top.ContainerFrame].ParentFrameIndex
-					continue
+					// The pointer may be a slice, array or map element
+					// and must be completed in the context of its container.
+					si = top.ContainerFrame
+					d.stackExp[si].Dest = top.ContainerDest
+					d.stackExp[si].Offset = top.ContainerOffset
+					goto ON_VAL_END
 
 				case ExpectTypeMapRecur:
 					si = top.ContainerFrame
@@ -2636,8 +5100,20 @@ func (d *Decoder[S, T]) Decode(
 		ON_VAL_END:
 			if siCon := d.stackExp[si].ParentFrameIndex; siCon != noParentFrame {
 				switch d.stackExp[siCon].Type {
-				case ExpectTypePtr:
-					si--
+				case ExpectTypePtr, ExpectTypeOptional:
+					// The pointer's value is complete too,
+					// handle it in the context of its container.
+					si = siCon
+					goto ON_VAL_END
+				case ExpectTypeInterface:
+					// The concrete value is complete,
+					// make the interface refer to it.
+					p := unsafe.Pointer(
+						uintptr(d.stackExp[siCon].Dest) + d.stackExp[siCon].Offset,
+					)
+					(*ifaceHeader)(p).Tab = d.stackExp[siCon].Itab
+					si = siCon
+					goto ON_VAL_END
 				case ExpectTypeArray:
 					d.stackExp[si].Len++
 					if d.stackExp[si].Len >= d.stackExp[si].Cap {
@@ -2658,9 +5134,36 @@ func (d *Decoder[S, T]) Decode(
 						d.stackExp[si].Offset += d.stackExp[si].Size
 					}
 				case ExpectTypeSlice:
+					if d.stackExp[siCon].Singleton {
+						// The object decoded as the only element is complete,
+						// there's no closing TokenArrayEnd.
+						d.stackExp[siCon].Singleton = false
+						si = siCon
+						goto ON_VAL_END
+					}
 					d.stackExp[si].Offset += d.stackExp[si].Size
-
-				case ExpectTypeMap, ExpectTypeStruct, ExpectTypeStructRecur:
+					ti = skipUndecodedElements(tokens, ti, &d.stackExp[si], options)
+				case ExpectTypeContainer:
+					e := &d.stackExp[si]
+					d.stackExp[siCon].ContainerAdd(e.Dest)
+					typedmemclr(e.Typ, e.Dest)
+
+				case ExpectTypeMap, ExpectTypeStruct, ExpectTypeStructRecur,
+					ExpectTypeSlicePairs:
+					if options.MergeDuplicateKeysIntoSlice &&
+						d.stackExp[siCon].Type == ExpectTypeMap &&
+						d.stackExp[siCon].RType.Elem().Kind() == reflect.Slice {
+						top := len(d.mergeStack) - 1
+						m := d.mergeStack[top]
+						d.mergeStack[top] = pendingMerge{}
+						d.mergeStack = d.mergeStack[:top]
+						if !mergeSlices(
+							d.stackExp[siCon].RType.Elem(), m.Dest, m.Prev, &budget,
+						) {
+							errIndex, err = tokens[ti-1].Index, ErrAllocBudgetExceeded
+							return true
+						}
+					}
 					si = siCon
 					if si == noParentFrame {
 						errIndex, err = tokens[ti].Index, ErrUnexpectedValue
@@ -2678,7 +5181,15 @@ func (d *Decoder[S, T]) Decode(
 				si++
 				if d.stackExp[si].Size == 0 {
 					*(*unsafe.Pointer)(p) = emptyStructAddr
+				} else if dp := *(*unsafe.Pointer)(p); options.mergePatch && dp != nil &&
+					tokens[ti].Type == jscan.TokenTypeObject {
+					// Merge the object into the existing value.
+					d.stackExp[si].Dest = dp
 				} else {
+					if !budget.alloc(d.stackExp[si].Size) {
+						errIndex, err = tokens[ti].Index, ErrAllocBudgetExceeded
+						return true
+					}
 					dp := mallocgc(d.stackExp[si].Size, d.stackExp[si].Typ, true)
 					d.stackExp[si].Dest = dp
 					*(*unsafe.Pointer)(p) = dp
@@ -2686,13 +5197,38 @@ func (d *Decoder[S, T]) Decode(
 			}
 			continue
 
+		ON_OPTIONAL:
+			{
+				p := unsafe.Pointer(
+					uintptr(d.stackExp[si].Dest) + d.stackExp[si].Offset,
+				)
+				*(*optionalHeader)(p) = optionalHeader{Set: true, Valid: true}
+				si++
+				d.stackExp[si].Dest = p
+			}
+			continue
+
+		ON_INTERFACE:
+			{
+				p := unsafe.Pointer(
+					uintptr(d.stackExp[si].Dest) + d.stackExp[si].Offset,
+				)
+				// The interface is only assigned the itab once the concrete
+				// value is complete, the data word is set by the pointer frame.
+				*(*ifaceHeader)(p) = ifaceHeader{}
+				si++
+				d.stackExp[si].Dest = p
+			}
+			continue
+
 		ON_JSON_UNMARSHALER:
 			{
 				p := unsafe.Pointer(
 					uintptr(d.stackExp[si].Dest) + d.stackExp[si].Offset,
 				)
 				var raw S
-				tkIndex := tokens[ti].Index
+				tok := tokens[ti]
+				tkIndex := tok.Index
 				switch tokens[ti].Type {
 				case jscan.TokenTypeObject, jscan.TokenTypeArray:
 					// Composite value
@@ -2703,6 +5239,27 @@ func (d *Decoder[S, T]) Decode(
 					raw = s[tokens[ti].Index:tokens[ti].End]
 					ti++
 				}
+				if options.RawMessageNoCopy && d.stackExp[si].RType == tpRawMessage {
+					var z S
+					if _, ok := any(z).([]byte); ok {
+						// Alias the input, limit the capacity to prevent appends
+						// to the message from overwriting the input.
+						b := *(*[]byte)(unsafe.Pointer(&raw))
+						*(*json.RawMessage)(p) = b[:len(b):len(b)]
+						goto ON_VAL_END
+					}
+				}
+				if d.stackExp[si].RType == tpTime && options.decodesTime() {
+					v, errTime := decodeTime(
+						s, tok, options.timeLayouts(), options.TimeEpoch,
+					)
+					if errTime != nil {
+						errIndex, err = tkIndex, errTime
+						return true
+					}
+					*(*time.Time)(p) = v
+					goto ON_VAL_END
+				}
 				u := reflect.NewAt(d.stackExp[si].RType, p).Interface().(json.Unmarshaler)
 				if errUnmarshal := u.UnmarshalJSON([]byte(raw)); errUnmarshal != nil {
 					errIndex, err = tkIndex, errUnmarshal
@@ -2710,24 +5267,123 @@ func (d *Decoder[S, T]) Decode(
 				}
 			}
 			goto ON_VAL_END
+
+		ON_TEXT_UNMARSHALER:
+			// The number or boolean token is passed to UnmarshalText as is.
+			if !options.TextUnmarshalerAcceptsScalars {
+				errIndex, err = tokens[ti].Index, ErrUnexpectedValue
+				return true
+			}
+			{
+				p := unsafe.Pointer(
+					uintptr(d.stackExp[si].Dest) + d.stackExp[si].Offset,
+				)
+				u := reflect.NewAt(
+					d.stackExp[si].RType, p,
+				).Interface().(encoding.TextUnmarshaler)
+				tb := []byte(s[tokens[ti].Index:tokens[ti].End])
+				if errUnmarshal := u.UnmarshalText(tb); errUnmarshal != nil {
+					errIndex, err = tokens[ti].Index, errUnmarshal
+					return true
+				}
+			}
+			ti++
+			goto ON_VAL_END
 		}
 		return false
 	})
+	if options.Warnings != nil && len(d.rewriteSpans) > 0 {
+		translateWarnings(options, warningsFrom, d.rewriteSpans)
+	}
 	if errTok.IsErr() {
+		if len(d.rewriteSpans) > 0 {
+			// Translate the error index back to the original input.
+			errIndex = originalIndex(d.rewriteSpans, errIndex)
+			errTok.Index = originalIndex(d.rewriteSpans, errTok.Index)
+			errTok.Src = src
+		}
 		if errTok.Code == jscan.ErrorCodeCallback {
 			return errIndex, err
 		}
 		return errTok.Index, errTok
 	}
-	*t = *(*T)(d.stackExp[0].Dest)
+	for _, f := range d.decodedFields {
+		options.OnFieldDecoded(f.Path, f.Type)
+	}
 	return -1, nil
 }
 
+// decodedField is a struct field reported to DecodeOptions.OnFieldDecoded.
+type decodedField struct {
+	Path string
+	Type ExpectType
+}
+
+// rawObject returns a copy of the verbatim bytes of the object at tokens[ti]
+// in the original input src, which differs from the tokenized input
+// if it was rewritten. Returns false if the allocation budget is exceeded.
+func (d *Decoder[S, T]) rawObject(
+	src S, tokens []jscan.Token[S], ti int, budget *allocBudget,
+) (raw []byte, ok bool) {
+	start, end := tokens[ti].Index, tokens[tokens[ti].End].Index+1
+	if len(d.rewriteSpans) > 0 {
+		start = originalIndex(d.rewriteSpans, start)
+		end = originalIndex(d.rewriteSpans, end-1) + 1
+	}
+	if !budget.alloc(uintptr(end - start)) {
+		return nil, false
+	}
+	return append([]byte(nil), src[start:end]...), true
+}
+
+// pendingMerge is a map slice value that needs to be merged with the new slice
+// value of a duplicate key once it's decoded.
+type pendingMerge struct {
+	Dest unsafe.Pointer // Map value
+	Prev sliceHeader    // Previous value
+}
+
+// mergeSlices replaces the slice of type t at dest with a new slice
+// containing the elements of prev followed by the elements of the slice at dest.
+// Returns false if the allocation budget is exceeded.
+func mergeSlices(
+	t reflect.Type, dest unsafe.Pointer, prev sliceHeader, budget *allocBudget,
+) (ok bool) {
+	if prev.Len == 0 {
+		return true
+	}
+	v := reflect.NewAt(t, dest).Elem()
+	p := reflect.NewAt(t, unsafe.Pointer(&prev)).Elem()
+	l := p.Len() + v.Len()
+	if !budget.alloc(uintptr(l) * t.Elem().Size()) {
+		return false
+	}
+	m := reflect.MakeSlice(t, 0, l)
+	v.Set(reflect.AppendSlice(reflect.AppendSlice(m, p), v))
+	return true
+}
+
+// allocBudget keeps track of the number of bytes allocated during Decode.
+type allocBudget struct {
+	Allocated uintptr
+	Max       uintptr // Zero stands for unlimited
+}
+
+// alloc adds size to the number of allocated bytes and
+// returns false if the budget is exceeded.
+func (b *allocBudget) alloc(size uintptr) (ok bool) {
+	b.Allocated += size
+	return b.Max == 0 || b.Allocated <= b.Max
+}
+
 type sliceHeader struct {
 	Data     unsafe.Pointer
 	Len, Cap uintptr
 }
 
+// ifaceHeader is the layout of a non-empty interface value.
+type ifaceHeader struct{ Tab, Data unsafe.Pointer }
+
 var (
 	zeroBool    bool
 	zeroStr     string
@@ -2745,6 +5401,16 @@ var (
 	zeroFloat64 float64
 )
 
+// fieldName returns the name of the field with the given frame index.
+func fieldName(fields []fieldStackFrame, frameIndex uint32) string {
+	for i := range fields {
+		if fields[i].FrameIndex == frameIndex {
+			return fields[i].Name
+		}
+	}
+	return ""
+}
+
 // fieldFrameIndexByName returns the frame index of the field identified by name
 // or -1 if no field is found. Exact matches are prioritized over
 // case-insensitive matches.
@@ -2796,16 +5462,64 @@ func fieldFrameIndexByName[S []byte | string](fields []fieldStackFrame, name S)
 	return noParentFrame
 }
 
+// parseExtendedBool returns the boolean value of the string contents s
+// and true if s is one of the spellings accepted by
+// DecodeOptions.ExtendedBoolStrings, otherwise returns false, false.
+func parseExtendedBool[S []byte | string](s S) (v, ok bool) {
+	switch string(s) {
+	case "true", "yes", "on", "1":
+		return true, true
+	case "false", "no", "off", "0":
+		return false, true
+	}
+	return false, false
+}
+
+// decodeAny decodes the value starting at tokens[0] into an `any`
+// and returns the remaining tokens. depth is the nesting depth the value
+// would have if it's an array or an object, which is checked against
+// options.MaxDepth. spans are the sections rewritten by rewriteNonstandard.
 func decodeAny[S []byte | string](
-	str S, tokens []jscan.Token[S],
+	str S, tokens []jscan.Token[S], options *DecodeOptions, budget *allocBudget,
+	spans []rewriteSpan, depth int,
 ) (any, []jscan.Token[S], error) {
 	switch tokens[0].Type {
 	case jscan.TokenTypeNull:
 		return nil, tokens[1:], nil
-	case jscan.TokenTypeNumber, jscan.TokenTypeInteger:
+	case jscan.TokenTypeInteger:
+		if len(spans) > 0 && hexLiteral(spans, tokens[0].Index) {
+			// Return the failing token as tail to let the caller report its index.
+			return nil, tokens, ErrUnexpectedValue
+		}
+		if depth <= options.UseNumberDepth {
+			return Number(str[tokens[0].Index:tokens[0].End]), tokens[1:], nil
+		}
+		if options.IntegersAsInt64 {
+			v, overflow := atoi.I64(str[tokens[0].Index:tokens[0].End])
+			if !overflow {
+				return v, tokens[1:], nil
+			}
+		}
+		if v, ok := boxSmallInt(str[tokens[0].Index:tokens[0].End]); ok {
+			return v, tokens[1:], nil
+		}
+		f64, err := tokens[0].Float64(str)
+		if err != nil {
+			// Return the failing token as tail to let the caller report its index.
+			return nil, tokens, err
+		}
+		checkPrecision(
+			options, tokens[0].Index, str[tokens[0].Index:tokens[0].End], f64,
+		)
+		return f64, tokens[1:], nil
+	case jscan.TokenTypeNumber:
+		if depth <= options.UseNumberDepth {
+			return Number(str[tokens[0].Index:tokens[0].End]), tokens[1:], nil
+		}
 		f64, err := tokens[0].Float64(str)
 		if err != nil {
-			return nil, nil, err
+			// Return the failing token as tail to let the caller report its index.
+			return nil, tokens, err
 		}
 		return f64, tokens[1:], nil
 	case jscan.TokenTypeTrue:
@@ -2813,33 +5527,68 @@ func decodeAny[S []byte | string](
 	case jscan.TokenTypeFalse:
 		return false, tokens[1:], nil
 	case jscan.TokenTypeString:
-		return unescape.Valid[S, string](
+		if stringTooLong(str, tokens[0], options) {
+			// Return the failing token as tail to let the caller report its index.
+			return nil, tokens, ErrLimitExceeded
+		}
+		if invalidUTF8(str, tokens[0], options) {
+			return nil, tokens, ErrInvalidUTF8
+		}
+		if !allocString(budget, str, tokens[0]) {
+			return nil, tokens, ErrAllocBudgetExceeded
+		}
+		return options.transformAny(unescape.Valid[S, string](
 			str[tokens[0].Index+1 : tokens[0].End-1],
-		), tokens[1:], nil
+		)), tokens[1:], nil
 	case jscan.TokenTypeArray:
+		if options.MaxDepth != 0 && depth > options.MaxDepth {
+			return nil, nil, ErrMaxDepthExceeded
+		}
+		if options.MaxArrayElements != 0 && tokens[0].Elements > options.MaxArrayElements {
+			return nil, nil, ErrLimitExceeded
+		}
+		if !budget.alloc(uintptr(tokens[0].Elements) * unsafe.Sizeof(any(nil))) {
+			return nil, nil, ErrAllocBudgetExceeded
+		}
 		l := make([]any, 0, tokens[0].Elements)
 		for tokens = tokens[1:]; tokens[0].Type != jscan.TokenTypeArrayEnd; {
 			var v any
 			var err error
-			if v, tokens, err = decodeAny(str, tokens); err != nil {
-				return nil, nil, err
+			v, tokens, err = decodeAny(str, tokens, options, budget, spans, depth+1)
+			if err != nil {
+				return nil, tokens, err
 			}
 			l = append(l, v)
 		}
 		return l, tokens[1:], nil
 	case jscan.TokenTypeObject:
+		if options.MaxDepth != 0 && depth > options.MaxDepth {
+			return nil, nil, ErrMaxDepthExceeded
+		}
 		if tokens[0].Elements == 0 {
 			return map[string]any{}, tokens[2:], nil
 		}
-		m := make(map[string]any, tokens[0].Elements)
+		capacity := options.mapCapacity(tokens[0].Elements)
+		if !budget.alloc(uintptr(capacity) *
+			(unsafe.Sizeof("") + unsafe.Sizeof(any(nil)))) {
+			return nil, nil, ErrAllocBudgetExceeded
+		}
+		m := make(map[string]any, capacity)
 		for tokens = tokens[1:]; tokens[0].Type != jscan.TokenTypeObjectEnd; {
+			if stringTooLong(str, tokens[0], options) {
+				return nil, tokens, ErrLimitExceeded
+			}
+			if !allocString(budget, str, tokens[0]) {
+				return nil, tokens, ErrAllocBudgetExceeded
+			}
 			key := str[tokens[0].Index+1 : tokens[0].End-1]
 			var v any
 			var err error
-			if v, tokens, err = decodeAny(str, tokens[1:]); err != nil {
-				return nil, nil, err
+			v, tokens, err = decodeAny(str, tokens[1:], options, budget, spans, depth+1)
+			if err != nil {
+				return nil, tokens, err
 			}
-			m[unescape.Valid[S, string](key)] = v
+			m[options.transformMapKey(unescape.Valid[S, string](key))] = v
 		}
 		return m, tokens[1:], nil
 	}
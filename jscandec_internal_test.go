@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 )
@@ -36,6 +37,10 @@ func TestAppendTypeToStack(t *testing.T) {
 		Unmarshaler *testImplJSONUnmarshaler `json:"unmar"`
 		Tail        []int                    `json:"tail"`
 	}
+	type S6 struct {
+		A *testImplJSONUnmarshaler `json:"a"`
+		B *testImplJSONUnmarshaler `json:"b"`
+	}
 	type SStringString struct {
 		String string `json:",string"`
 	}
@@ -94,6 +99,7 @@ func TestAppendTypeToStack(t *testing.T) {
 	tpS3 := reflect.TypeOf(S3{})
 	tpS4 := reflect.TypeOf(S4{})
 	tpS5 := reflect.TypeOf(S5{})
+	tpS6 := reflect.TypeOf(S6{})
 	tpEmptyIface := reflect.TypeOf(struct{ typ, data uintptr }{})
 
 	for _, td := range []struct {
@@ -171,35 +177,26 @@ func TestAppendTypeToStack(t *testing.T) {
 					RType: reflect.TypeOf(S2{}),
 					Size:  reflect.TypeOf(S2{}).Size(),
 					Fields: []fieldStackFrame{
-						{FrameIndex: 1, Name: "S1"},
-						{FrameIndex: 4, Name: "Bar"},
-						{FrameIndex: 5, Name: "bazz"},
+						{FrameIndex: 1, Name: "foo"},
+						{FrameIndex: 2, Name: "bar"},
+						{FrameIndex: 3, Name: "Bar"},
+						{FrameIndex: 4, Name: "bazz"},
 					},
 					ParentFrameIndex: noParentFrame,
 				},
-				{ // S2.S1
-					Type:  ExpectTypeStruct,
-					Typ:   getTyp(reflect.TypeOf(S1{})),
-					RType: reflect.TypeOf(S1{}),
-					Size:  reflect.TypeOf(S1{}).Size(),
-					Fields: []fieldStackFrame{
-						{FrameIndex: 2, Name: "foo"},
-						{FrameIndex: 3, Name: "bar"},
-					},
-					ParentFrameIndex: 0,
-				},
-				{ // S2.S1.Foo
+				{ // S2.S1.Foo (promoted)
 					Type:             ExpectTypeInt,
 					Typ:              getTyp(reflect.TypeOf(int(0))),
 					Size:             reflect.TypeOf(int(0)).Size(),
-					ParentFrameIndex: 1,
+					ParentFrameIndex: 0,
 				},
-				{ // S2.S1.Bar
+				{ // S2.S1.Bar (promoted)
 					Type:             ExpectTypeStr,
 					Typ:              getTyp(reflect.TypeOf(string(""))),
 					Size:             reflect.TypeOf(string("")).Size(),
-					ParentFrameIndex: 1,
-					Offset:           reflect.TypeOf(S1{}).Field(1).Offset,
+					ParentFrameIndex: 0,
+					Offset: reflect.TypeOf(S2{}).Field(0).Offset +
+						reflect.TypeOf(S1{}).Field(1).Offset,
 				},
 				{ // S2.Bar
 					Type:             ExpectTypeSliceString,
@@ -239,6 +236,17 @@ func TestAppendTypeToStack(t *testing.T) {
 				},
 			},
 		},
+		{
+			Input: []time.Time{},
+			ExpectStack: []stackFrame[string]{
+				{
+					Type:             ExpectTypeSliceTime,
+					Typ:              getTyp(reflect.TypeOf([]time.Time(nil))),
+					Size:             reflect.TypeOf([]time.Time(nil)).Size(),
+					ParentFrameIndex: noParentFrame,
+				},
+			},
+		},
 		{
 			Input: []int{},
 			ExpectStack: []stackFrame[string]{
@@ -391,6 +399,7 @@ func TestAppendTypeToStack(t *testing.T) {
 					Typ:              getTyp(reflect.TypeOf([]S1(nil))),
 					Size:             reflect.TypeOf([]S1(nil)).Size(),
 					ParentFrameIndex: noParentFrame,
+					FlatStruct:       true,
 				},
 				{ // []S1
 					Type:  ExpectTypeStruct,
@@ -473,6 +482,35 @@ func TestAppendTypeToStack(t *testing.T) {
 				},
 			},
 		},
+		{
+			Input: [4]float64{},
+			ExpectStack: []stackFrame[string]{
+				{
+					Type:             ExpectTypeArrayFloat64,
+					Typ:              getTyp(reflect.TypeOf([4]float64{})),
+					Size:             reflect.TypeOf([4]float64{}).Size(),
+					ParentFrameIndex: noParentFrame,
+				},
+			},
+		},
+		{
+			Input: [2][3]string{},
+			ExpectStack: []stackFrame[string]{
+				{
+					Type:             ExpectTypeArray,
+					Typ:              getTyp(reflect.TypeOf([2][3]string{})),
+					Size:             reflect.TypeOf([2][3]string{}).Size(),
+					ParentFrameIndex: noParentFrame,
+				},
+				{
+					Type:             ExpectTypeArrayStr,
+					Typ:              getTyp(reflect.TypeOf([3]string{})),
+					Size:             reflect.TypeOf([3]string{}).Size(),
+					ParentFrameIndex: 0,
+					Cap:              2,
+				},
+			},
+		},
 		{
 			Input: map[string]string{},
 			ExpectStack: []stackFrame[string]{
@@ -1001,6 +1039,24 @@ func TestAppendTypeToStack(t *testing.T) {
 				},
 			},
 		},
+		{
+			Input: Optional[int]{},
+			ExpectStack: []stackFrame[string]{
+				{ // Optional[int]
+					Type:             ExpectTypeOptional,
+					Typ:              getTyp(reflect.TypeOf(Optional[int]{})),
+					Size:             reflect.TypeOf(Optional[int]{}).Size(),
+					ParentFrameIndex: noParentFrame,
+				},
+				{ // Optional[int].Value
+					Type:             ExpectTypeInt,
+					Typ:              getTyp(reflect.TypeOf(int(0))),
+					Size:             reflect.TypeOf(int(0)).Size(),
+					Offset:           reflect.TypeOf(Optional[int]{}).Field(2).Offset,
+					ParentFrameIndex: 0,
+				},
+			},
+		},
 		{
 			Input: testImplJSONUnmarshaler{},
 			ExpectStack: []stackFrame[string]{
@@ -1017,12 +1073,18 @@ func TestAppendTypeToStack(t *testing.T) {
 			Input: &testImplJSONUnmarshaler{},
 			ExpectStack: []stackFrame[string]{
 				{
-					Type:             ExpectTypeJSONUnmarshaler,
-					Typ:              getTyp(reflect.TypeOf(&testImplTextUnmarshaler{})),
-					RType:            reflect.TypeOf(&testImplJSONUnmarshaler{}),
+					Type:             ExpectTypePtr,
+					Typ:              getTyp(reflect.TypeOf(&testImplJSONUnmarshaler{})),
 					Size:             reflect.TypeOf(&testImplJSONUnmarshaler{}).Size(),
 					ParentFrameIndex: noParentFrame,
 				},
+				{
+					Type:             ExpectTypeJSONUnmarshaler,
+					Typ:              getTyp(reflect.TypeOf(testImplJSONUnmarshaler{})),
+					RType:            reflect.TypeOf(testImplJSONUnmarshaler{}),
+					Size:             reflect.TypeOf(testImplJSONUnmarshaler{}).Size(),
+					ParentFrameIndex: 0,
+				},
 			},
 		},
 		{
@@ -1041,12 +1103,18 @@ func TestAppendTypeToStack(t *testing.T) {
 			Input: &testImplTextUnmarshaler{},
 			ExpectStack: []stackFrame[string]{
 				{
-					Type:             ExpectTypeTextUnmarshaler,
-					Typ:              getTyp(reflect.TypeOf(testImplTextUnmarshaler{})),
-					RType:            reflect.TypeOf(&testImplTextUnmarshaler{}),
+					Type:             ExpectTypePtr,
+					Typ:              getTyp(reflect.TypeOf(&testImplTextUnmarshaler{})),
 					Size:             reflect.TypeOf(&testImplTextUnmarshaler{}).Size(),
 					ParentFrameIndex: noParentFrame,
 				},
+				{
+					Type:             ExpectTypeTextUnmarshaler,
+					Typ:              getTyp(reflect.TypeOf(testImplTextUnmarshaler{})),
+					RType:            reflect.TypeOf(testImplTextUnmarshaler{}),
+					Size:             reflect.TypeOf(testImplTextUnmarshaler{}).Size(),
+					ParentFrameIndex: 0,
+				},
 			},
 		},
 		{
@@ -1155,7 +1223,7 @@ func TestAppendTypeToStack(t *testing.T) {
 					Fields: []fieldStackFrame{
 						{Name: "name", FrameIndex: 1},
 						{Name: "unmar", FrameIndex: 2},
-						{Name: "tail", FrameIndex: 3},
+						{Name: "tail", FrameIndex: 4},
 					},
 					Type:             ExpectTypeStruct,
 					Typ:              getTyp(reflect.TypeOf(S5{})),
@@ -1171,13 +1239,19 @@ func TestAppendTypeToStack(t *testing.T) {
 					Offset:           tpS5.Field(0).Offset,
 				},
 				{ // S5.Unmarshaler
-					Type:             ExpectTypeJSONUnmarshaler,
+					Type:             ExpectTypePtr,
 					Typ:              getTyp(reflect.TypeOf(&testImplJSONUnmarshaler{})),
 					Size:             reflect.TypeOf(&testImplJSONUnmarshaler{}).Size(),
-					RType:            reflect.TypeOf(&testImplJSONUnmarshaler{}),
 					ParentFrameIndex: 0,
 					Offset:           tpS5.Field(1).Offset,
 				},
+				{ // *S5.Unmarshaler
+					Type:             ExpectTypeJSONUnmarshaler,
+					Typ:              getTyp(reflect.TypeOf(testImplJSONUnmarshaler{})),
+					Size:             reflect.TypeOf(testImplJSONUnmarshaler{}).Size(),
+					RType:            reflect.TypeOf(testImplJSONUnmarshaler{}),
+					ParentFrameIndex: 2,
+				},
 				{ // S5.Tail
 					Type:             ExpectTypeSliceInt,
 					Typ:              getTyp(reflect.TypeOf([]int(nil))),
@@ -1187,6 +1261,52 @@ func TestAppendTypeToStack(t *testing.T) {
 				},
 			},
 		},
+		{
+			// Multiple pointers to the same unmarshaler type
+			// must not be mistaken for a recursive struct type.
+			Input: S6{},
+			ExpectStack: []stackFrame[string]{
+				{ // S6
+					Fields: []fieldStackFrame{
+						{Name: "a", FrameIndex: 1},
+						{Name: "b", FrameIndex: 3},
+					},
+					Type:             ExpectTypeStruct,
+					Typ:              getTyp(reflect.TypeOf(S6{})),
+					RType:            reflect.TypeOf(S6{}),
+					Size:             reflect.TypeOf(S6{}).Size(),
+					ParentFrameIndex: noParentFrame,
+				},
+				{ // S6.A
+					Type:             ExpectTypePtr,
+					Typ:              getTyp(reflect.TypeOf(&testImplJSONUnmarshaler{})),
+					Size:             reflect.TypeOf(&testImplJSONUnmarshaler{}).Size(),
+					ParentFrameIndex: 0,
+					Offset:           tpS6.Field(0).Offset,
+				},
+				{ // *S6.A
+					Type:             ExpectTypeJSONUnmarshaler,
+					Typ:              getTyp(reflect.TypeOf(testImplJSONUnmarshaler{})),
+					Size:             reflect.TypeOf(testImplJSONUnmarshaler{}).Size(),
+					RType:            reflect.TypeOf(testImplJSONUnmarshaler{}),
+					ParentFrameIndex: 1,
+				},
+				{ // S6.B
+					Type:             ExpectTypePtr,
+					Typ:              getTyp(reflect.TypeOf(&testImplJSONUnmarshaler{})),
+					Size:             reflect.TypeOf(&testImplJSONUnmarshaler{}).Size(),
+					ParentFrameIndex: 0,
+					Offset:           tpS6.Field(1).Offset,
+				},
+				{ // *S6.B
+					Type:             ExpectTypeJSONUnmarshaler,
+					Typ:              getTyp(reflect.TypeOf(testImplJSONUnmarshaler{})),
+					Size:             reflect.TypeOf(testImplJSONUnmarshaler{}).Size(),
+					RType:            reflect.TypeOf(testImplJSONUnmarshaler{}),
+					ParentFrameIndex: 3,
+				},
+			},
+		},
 		{
 			Input: SStringString{},
 			ExpectStack: []stackFrame[string]{
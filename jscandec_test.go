@@ -2,15 +2,21 @@ package jscandec_test
 
 import (
 	_ "embed"
+	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"math"
+	"math/big"
+	"net"
 	"reflect"
 	"runtime"
 	"strconv"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 	"unsafe"
 
 	jscandec "github.com/romshark/jscan-experimental-decoder"
@@ -41,16 +47,19 @@ type testSetup[T any] struct {
 
 func newTestSetup[T any](
 	t *testing.T, decodeOptions jscandec.DecodeOptions,
+) testSetup[T] {
+	return newTestSetupInit[T](t, jscandec.DefaultInitOptions, decodeOptions)
+}
+
+func newTestSetupInit[T any](
+	t *testing.T, initOptions *jscandec.InitOptions,
+	decodeOptions jscandec.DecodeOptions,
 ) testSetup[T] {
 	tokenizerString := jscan.NewTokenizer[string](16, 1024)
-	dStr, err := jscandec.NewDecoder[string, T](
-		tokenizerString, jscandec.DefaultInitOptions,
-	)
+	dStr, err := jscandec.NewDecoder[string, T](tokenizerString, initOptions)
 	require.NoError(t, err)
 	tokenizerBytes := jscan.NewTokenizer[[]byte](16, 1024)
-	dBytes, err := jscandec.NewDecoder[[]byte, T](
-		tokenizerBytes, jscandec.DefaultInitOptions,
-	)
+	dBytes, err := jscandec.NewDecoder[[]byte, T](tokenizerBytes, initOptions)
 	require.NoError(t, err)
 	return testSetup[T]{
 		decodeOptions: &decodeOptions,
@@ -219,6 +228,58 @@ func (s testSetup[T]) testErrCheck(
 	})
 }
 
+// testErrNonstandard makes sure that input fails to parse and that the returned
+// error equals expect. Unlike testErr, it doesn't require encoding/json to fail
+// and is meant for errors caused by options encoding/json doesn't support.
+func (s testSetup[T]) testErrNonstandard(
+	t *testing.T, name, input string, expectIndex int, expect error,
+) {
+	t.Helper()
+	t.Run(name+"/bytes", func(t *testing.T) {
+		t.Helper()
+		var v T
+		errIndex, err := s.decoderBytes.Decode([]byte(input), &v, s.decodeOptions)
+		require.Equal(t, expect, err)
+		require.Equal(t, expectIndex, errIndex)
+		runtime.GC() // Make sure GC is happy
+	})
+	t.Run(name+"/string", func(t *testing.T) {
+		t.Helper()
+		var v T
+		errIndex, err := s.decoderString.Decode(input, &v, s.decodeOptions)
+		require.Equal(t, expect, err)
+		require.Equal(t, expectIndex, errIndex)
+		runtime.GC() // Make sure GC is happy
+	})
+}
+
+// testOKNonstandard makes sure that input can be decoded to T successfully
+// and equals expect. Unlike TestOK, it doesn't compare the result against
+// encoding/json and is meant for inputs encoding/json doesn't accept.
+func (s testSetup[T]) testOKNonstandard(t *testing.T, name, input string, expect T) {
+	t.Helper()
+	t.Run(name+"/bytes", func(t *testing.T) {
+		t.Helper()
+		var v T
+		errIndex, err := s.decoderBytes.Decode([]byte(input), &v, s.decodeOptions)
+		if err != nil {
+			t.Fatal(fmt.Errorf("ERR at %d: %s", errIndex, err.Error()))
+		}
+		runtime.GC() // Make sure GC is happy
+		require.Equal(t, expect, v)
+	})
+	t.Run(name+"/string", func(t *testing.T) {
+		t.Helper()
+		var v T
+		errIndex, err := s.decoderString.Decode(input, &v, s.decodeOptions)
+		if err != nil {
+			t.Fatal(fmt.Errorf("ERR at %d: %s", errIndex, err.Error()))
+		}
+		runtime.GC() // Make sure GC is happy
+		require.Equal(t, expect, v)
+	})
+}
+
 func TestDecodeNil(t *testing.T) {
 	tokenizer := jscan.NewTokenizer[string](64, 1024)
 	d, err := jscandec.NewDecoder[string, [][]bool](
@@ -331,6 +392,46 @@ func TestDecodeAny(t *testing.T) {
 		})
 }
 
+func TestDecodeAnySmallIntegers(t *testing.T) {
+	// Small integers are decoded into preboxed float64 values,
+	// the results must be bit-exact to those of encoding/json.
+	var in strings.Builder
+	in.WriteString(`[-0,-0.0,`)
+	for i := -130; i <= 260; i++ {
+		in.WriteString(strconv.Itoa(i))
+		in.WriteByte(',')
+	}
+	in.WriteString(`1000,-1000,0.5]`)
+
+	checkBits := func(t *testing.T, vJscan, vEncodingJson []any) {
+		require.Len(t, vJscan, len(vEncodingJson))
+		for i := range vEncodingJson {
+			require.Equal(t,
+				math.Float64bits(vEncodingJson[i].(float64)),
+				math.Float64bits(vJscan[i].(float64)),
+				"element %d", i)
+		}
+	}
+
+	t.Run("slice", func(t *testing.T) {
+		s := newTestSetup[[]any](t, *jscandec.DefaultOptions)
+		s.TestOKPrepare(t, "bits", in.String(), Test[[]any]{
+			Check: func(t *testing.T, vJscan []any, vEncodingJson any) {
+				checkBits(t, vJscan, *vEncodingJson.(*[]any))
+			},
+		})
+	})
+
+	t.Run("any", func(t *testing.T) {
+		s := newTestSetup[any](t, *jscandec.DefaultOptions)
+		s.TestOKPrepare(t, "bits", in.String(), Test[any]{
+			Check: func(t *testing.T, vJscan any, vEncodingJson any) {
+				checkBits(t, vJscan.([]any), (*vEncodingJson.(*any)).([]any))
+			},
+		})
+	})
+}
+
 func TestDecodeUint(t *testing.T) {
 	skipIfNot64bitSystem(t)
 	s := newTestSetup[uint](t, *jscandec.DefaultOptions)
@@ -405,6 +506,129 @@ func TestDecodeFloat32(t *testing.T) {
 	s.testErr(t, "array", `[]`, 0, jscandec.ErrUnexpectedValue)
 }
 
+// TestDecodeFloat32BitExact makes sure float32 values are rounded
+// exactly once and are bit-identical to what encoding/json produces.
+func TestDecodeFloat32BitExact(t *testing.T) {
+	for _, input := range []string{
+		// Zeros.
+		`0`, `-0`, `0.0`, `-0.0`, `0e0`, `-0e0`, `0E-7`,
+		// Short decimals that aren't exactly representable.
+		`0.1`, `0.2`, `0.3`, `-0.1`, `1.1`, `0.33333334`, `2.7182817`,
+		`3.1415927`, `3.14159265358979`, `1.0000001`, `1.00000005`,
+		// Halfway cases between 1 and its successor 1.00000011920928955078125
+		// and between 1.0000002384185791015625 and its predecessor.
+		`1.00000011920928955078125`,
+		`1.000000059604644775390625`,
+		`1.0000000596046447753906249`,
+		`1.0000000596046447753906251`,
+		`1.00000017881393432617187499`,
+		`1.000000178813934326171875`,
+		`1.00000017881393432617187501`,
+		// Integers around 1<<24.
+		`9999999`, `-9999999`, `16777215`, `16777216`, `16777217`,
+		`16777218`, `16777219`, `-16777217`, `33554431`, `33554433`,
+		`123456789`, `2147483647`, `-2147483648`, `4294967295`,
+		`9007199254740993`,
+		// Subnormals.
+		`1.4e-45`, `1e-45`, `7e-46`, `7.1e-46`, `2.8e-45`,
+		`5.877471754111438e-39`, `9.999999e-39`,
+		// Around the smallest normal.
+		`1.1754942e-38`, `1.17549435e-38`, `1.1754943508222875e-38`,
+		`0.000000000000000000000000000000000000011754943`,
+		// Around the largest finite value.
+		`3.4028234663852886e38`, `3.4028235e38`, `-3.4028235e38`,
+		`3.4028235677973366e38`, `1e38`,
+		`100000000000000000000000000000000000000`,
+		// Exponents.
+		`1e10`, `1e-10`, `2.5e-5`, `1E-7`, `6.022e23`,
+		`8.589973e9`, `8.589974e9`,
+	} {
+		var expect float32
+		require.NoError(t, json.Unmarshal([]byte(input), &expect))
+
+		t.Run(input+"/bytes", func(t *testing.T) {
+			d, err := jscandec.NewDecoder[[]byte, []float32](
+				jscan.NewTokenizer[[]byte](16, 64), jscandec.DefaultInitOptions,
+			)
+			require.NoError(t, err)
+			var v []float32
+			_, err = d.Decode([]byte("["+input+"]"), &v, jscandec.DefaultOptions)
+			require.NoError(t, err)
+			require.Len(t, v, 1)
+			require.Equal(t, math.Float32bits(expect), math.Float32bits(v[0]))
+		})
+
+		t.Run(input+"/string", func(t *testing.T) {
+			d, err := jscandec.NewDecoder[string, float32](
+				jscan.NewTokenizer[string](16, 64), jscandec.DefaultInitOptions,
+			)
+			require.NoError(t, err)
+			var v float32
+			_, err = d.Decode(input, &v, jscandec.DefaultOptions)
+			require.NoError(t, err)
+			require.Equal(t, math.Float32bits(expect), math.Float32bits(v))
+		})
+	}
+}
+
+// TestDecodeSliceFloat32IntegerFastPath makes sure the integer fast path
+// of []float32 and [N]float32, which converts integers shorter than
+// len("16777216") directly, is bit-identical to encoding/json for all
+// elements of an array straddling 1<<24.
+func TestDecodeSliceFloat32IntegerFastPath(t *testing.T) {
+	const input = `[9999999, -9999999, 1677721, -1677721, ` +
+		`16777215, 16777216, 16777217, 16777218, ` +
+		`-16777215, -16777216, -16777217, -16777218, 0, -0]`
+	var expect []float32
+	require.NoError(t, json.Unmarshal([]byte(input), &expect))
+
+	for _, td := range []struct {
+		name   string
+		decode func(t *testing.T) []float32
+	}{
+		{"slice/bytes", func(t *testing.T) []float32 {
+			d, err := jscandec.NewDecoder[[]byte, []float32](
+				jscan.NewTokenizer[[]byte](16, 64), jscandec.DefaultInitOptions,
+			)
+			require.NoError(t, err)
+			var v []float32
+			_, err = d.Decode([]byte(input), &v, jscandec.DefaultOptions)
+			require.NoError(t, err)
+			return v
+		}},
+		{"slice/string", func(t *testing.T) []float32 {
+			d, err := jscandec.NewDecoder[string, []float32](
+				jscan.NewTokenizer[string](16, 64), jscandec.DefaultInitOptions,
+			)
+			require.NoError(t, err)
+			var v []float32
+			_, err = d.Decode(input, &v, jscandec.DefaultOptions)
+			require.NoError(t, err)
+			return v
+		}},
+		{"array/string", func(t *testing.T) []float32 {
+			d, err := jscandec.NewDecoder[string, [14]float32](
+				jscan.NewTokenizer[string](16, 64), jscandec.DefaultInitOptions,
+			)
+			require.NoError(t, err)
+			var v [14]float32
+			_, err = d.Decode(input, &v, jscandec.DefaultOptions)
+			require.NoError(t, err)
+			return v[:]
+		}},
+	} {
+		t.Run(td.name, func(t *testing.T) {
+			v := td.decode(t)
+			require.Len(t, v, len(expect))
+			for i := range expect {
+				require.Equal(t,
+					math.Float32bits(expect[i]), math.Float32bits(v[i]),
+					"element %d", i)
+			}
+		})
+	}
+}
+
 func TestDecodeFloat64(t *testing.T) {
 	s := newTestSetup[float64](t, *jscandec.DefaultOptions)
 	s.TestOK(t, "null", `null`, 0)
@@ -442,6 +666,52 @@ func TestDecodeFloat64(t *testing.T) {
 	s.testErr(t, "array", `[]`, 0, jscandec.ErrUnexpectedValue)
 }
 
+func TestDecodeFloat64Overflow(t *testing.T) {
+	checkRange := func(expectIndex int) func(*testing.T, int, error) {
+		return func(t *testing.T, errIndex int, err error) {
+			require.ErrorIs(t, err, strconv.ErrRange)
+			require.Equal(t, expectIndex, errIndex)
+		}
+	}
+
+	t.Run("float64", func(t *testing.T) {
+		s := newTestSetup[float64](t, *jscandec.DefaultOptions)
+		s.testErrCheck(t, "positive", `1e309`, checkRange(0))
+		s.testErrCheck(t, "negative", `-1e309`, checkRange(0))
+	})
+
+	t.Run("slice", func(t *testing.T) {
+		s := newTestSetup[[]float64](t, *jscandec.DefaultOptions)
+		s.testErrCheck(t, "first", `[1e309]`, checkRange(1))
+		s.testErrCheck(t, "second", `[1, 1e309]`, checkRange(4))
+	})
+
+	t.Run("any", func(t *testing.T) {
+		s := newTestSetup[any](t, *jscandec.DefaultOptions)
+		s.testErrCheck(t, "scalar", `1e309`, checkRange(0))
+		s.testErrCheck(t, "array", `[1, 1e309]`, checkRange(4))
+		s.testErrCheck(t, "object", `{"x":1e309}`, checkRange(5))
+		s.testErrCheck(t, "nested", `{"x":[0,{"y":-1e309}]}`, checkRange(13))
+	})
+
+	t.Run("struct_any", func(t *testing.T) {
+		type S struct {
+			Any any `json:"any"`
+		}
+		s := newTestSetup[S](t, *jscandec.DefaultOptions)
+		s.testErrCheck(t, "scalar", `{"any":1e309}`, checkRange(7))
+		s.testErrCheck(t, "array", `{"any":[1e309]}`, checkRange(8))
+	})
+
+	t.Run("string_tag", func(t *testing.T) {
+		type S struct {
+			Float64 float64 `json:"float64,string"`
+		}
+		s := newTestSetup[S](t, *jscandec.DefaultOptions)
+		s.testErrCheck(t, "string", `{"float64":"1e309"}`, checkRange(11))
+	})
+}
+
 func TestDecodeUint64(t *testing.T) {
 	s := newTestSetup[uint64](t, *jscandec.DefaultOptions)
 	s.TestOK(t, "null", `null`, 0)
@@ -958,6 +1228,48 @@ func TestDecodeSliceUint8(t *testing.T) {
 		3, jscandec.ErrUnexpectedValue)
 }
 
+func TestDecodeSliceUint8Base64(t *testing.T) {
+	type T = []byte
+	s := newTestSetup[T](t, *jscandec.DefaultOptions)
+	s.TestOK(t, "empty", `""`, T{})
+	s.TestOK(t, "hi", `"aGk="`, T("hi"))
+	s.TestOK(t, "no_padding_needed", `"Zm9vYmFy"`, T("foobar"))
+	s.TestOK(t, "escaped", `"aGk\u003d"`, T("hi"))
+	s.TestOK(t, "newlines_ignored", `"Zm9v\r\nYmFy"`, T("foobar"))
+	s.TestOKPrepare(t, "var_overwrite", `"aGk="`, Test[T]{
+		PrepareJscan: func() T { return T{1, 2, 3, 4} },
+		Expect:       T("hi"),
+	})
+
+	s.testErrCheck(t, "illegal_character", `"a*k="`,
+		func(t *testing.T, errIndex int, err error) {
+			require.Equal(t, base64.CorruptInputError(1), err)
+			require.Equal(t, 0, errIndex)
+		})
+	s.testErrCheck(t, "missing_padding", `"aGk"`,
+		func(t *testing.T, errIndex int, err error) {
+			require.Equal(t, base64.CorruptInputError(0), err)
+			require.Equal(t, 0, errIndex)
+		})
+}
+
+func TestDecodeMapStringBytes(t *testing.T) {
+	type T = map[string][]byte
+	s := newTestSetup[T](t, *jscandec.DefaultOptions)
+	s.TestOK(t, "null", `null`, T(nil))
+	s.TestOK(t, "empty", `{}`, T{})
+	s.TestOK(t, "base64", `{"k":"aGk="}`, T{"k": []byte("hi")})
+	s.TestOK(t, "multiple", `{"a":"aGk=","b":"","c":null,"d":[1,2]}`,
+		T{"a": []byte("hi"), "b": {}, "c": nil, "d": {1, 2}})
+
+	s.testErrCheck(t, "illegal_base64", `{"k":"a*k="}`,
+		func(t *testing.T, errIndex int, err error) {
+			require.Equal(t, base64.CorruptInputError(1), err)
+			require.Equal(t, 5, errIndex)
+		})
+	s.testErr(t, "wrong_type_int", `{"k":1}`, 5, jscandec.ErrUnexpectedValue)
+}
+
 func TestDecodeSliceUint16(t *testing.T) {
 	skipIfNot64bitSystem(t)
 
@@ -1169,6 +1481,52 @@ func TestDecodeSliceBool(t *testing.T) {
 		6, jscandec.ErrUnexpectedValue)
 }
 
+func TestDecodeSliceBoolFromBitmask(t *testing.T) {
+	type T = []bool
+	s := newTestSetup[T](t, jscandec.DecodeOptions{BoolSliceFromBitmask: true})
+	s.testOKNonstandard(t, "5", `5`, T{true, false, true})
+	s.testOKNonstandard(t, "1", `1`, T{true})
+	s.testOKNonstandard(t, "zero", `0`, T{})
+	s.testOKNonstandard(t, "max_uint64", `18446744073709551615`, func() T {
+		v := make(T, 64)
+		for i := range v {
+			v[i] = true
+		}
+		return v
+	}())
+	s.TestOK(t, "array", `[true,false]`, T{true, false})
+	s.TestOK(t, "null", `null`, T(nil))
+	s.testErrNonstandard(t, "negative", `-1`, 0, jscandec.ErrUnexpectedValue)
+	s.testErrNonstandard(t, "overflow", `18446744073709551616`,
+		0, jscandec.ErrIntegerOverflow)
+	s.testErr(t, "float", `5.0`, 0, jscandec.ErrUnexpectedValue)
+
+	t.Run("width", func(t *testing.T) {
+		s := newTestSetup[T](t, jscandec.DecodeOptions{
+			BoolSliceFromBitmask:  true,
+			BoolSliceBitmaskWidth: 4,
+		})
+		s.testOKNonstandard(t, "5", `5`, T{true, false, true, false})
+		s.testOKNonstandard(t, "zero", `0`, T{false, false, false, false})
+		s.testOKNonstandard(t, "15", `15`, T{true, true, true, true})
+		s.testErrNonstandard(t, "exceeds_width", `16`, 0, jscandec.ErrIntegerOverflow)
+	})
+
+	t.Run("field", func(t *testing.T) {
+		type S struct {
+			Flags T `json:"flags"`
+		}
+		s := newTestSetup[S](t, jscandec.DecodeOptions{BoolSliceFromBitmask: true})
+		s.testOKNonstandard(t, "bitmask", `{"flags":6}`, S{Flags: T{false, true, true}})
+		s.TestOK(t, "array", `{"flags":[true]}`, S{Flags: T{true}})
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		s := newTestSetup[T](t, *jscandec.DefaultOptions)
+		s.testErr(t, "int", `5`, 0, jscandec.ErrUnexpectedValue)
+	})
+}
+
 func TestDecodeSliceFloat32(t *testing.T) {
 	type T = []float32
 	s := newTestSetup[T](t, *jscandec.DefaultOptions)
@@ -1253,6 +1611,9 @@ func TestDecodeSliceFloat64(t *testing.T) {
 	s.TestOK(t, "-3.4e38", `[-3.4e38]`, T{-3.4e38})
 	s.TestOK(t, "avogadros_num", `[6.022e23]`, T{6.022e23})
 	s.TestOK(t, "array_float_1024", arrayFloat1024)
+	s.TestOK(t, "exact_fast_path_bounds", `[1e22,1e23,1E-22,1e-23,`+
+		`123456789012345,1234567890123456,0.1,-0.0,0.00000000000000000000012e1,`+
+		`9007199254740993,1.5e+300,-12.5E-3]`)
 
 	s.TestOKPrepare(t, "var_overwrite", `[1.1, 2.2, 3.3]`, Test[T]{
 		PrepareJscan: func() []float64 { return []float64{10.1, 20.2, 30.3} },
@@ -1291,6 +1652,280 @@ func TestDecodeSliceFloat64(t *testing.T) {
 		3, jscandec.ErrUnexpectedValue)
 }
 
+func TestFastFloatParsing(t *testing.T) {
+	literals := []string{
+		"0", "-0.0", "1", "0.1", "-12.5E-3", "6.022e23", "1e22", "1e23",
+		"1.7976931348623157e308", "-1.7976931348623157e308",
+		"2.2250738585072014e-308", "4.9e-324", "1e-320",
+		"3.141592653589793238462643383279502884197",
+		"9007199254740993", "123456789012345678901234567890",
+		"0.000000000000000000000000000012345678901234567890",
+		"2.718281828459045235360287471352662497757e-150",
+		"1.2345678901234567e300", "9.999999999999999999e-301",
+		"3.4028235e38", "1.4e-45",
+	}
+	for _, f := range strings.Split(strings.Trim(arrayFloat1024, "[]\n"), ",") {
+		literals = append(literals, strings.TrimSpace(f))
+	}
+	in := "[" + strings.Join(literals, ",") + "]"
+	initOpts := &jscandec.InitOptions{FastFloatParsing: true}
+
+	t.Run("float64", func(t *testing.T) {
+		tok := jscan.NewTokenizer[string](16, 1024)
+		d, err := jscandec.NewDecoder[string, []float64](tok, initOpts)
+		require.NoError(t, err)
+		var v []float64
+		_, err = d.Decode(in, &v, jscandec.DefaultOptions)
+		require.NoError(t, err)
+		require.Len(t, v, len(literals))
+		for i, l := range literals {
+			expect, err := strconv.ParseFloat(l, 64)
+			require.NoError(t, err)
+			requireRelErr(t, l, expect, v[i], 1e-15)
+		}
+	})
+
+	t.Run("float32", func(t *testing.T) {
+		var literals32 []string
+		for _, l := range literals {
+			if _, err := strconv.ParseFloat(l, 32); err == nil {
+				literals32 = append(literals32, l)
+			}
+		}
+		tok := jscan.NewTokenizer[string](16, 1024)
+		d, err := jscandec.NewDecoder[string, []float32](tok, initOpts)
+		require.NoError(t, err)
+		var v []float32
+		_, err = d.Decode("["+strings.Join(literals32, ",")+"]", &v,
+			jscandec.DefaultOptions)
+		require.NoError(t, err)
+		require.Len(t, v, len(literals32))
+		for i, l := range literals32 {
+			expect, err := strconv.ParseFloat(l, 32)
+			require.NoError(t, err)
+			requireRelErr(t, l, expect, float64(v[i]), 1.2e-7)
+		}
+
+		_, err = d.Decode(`[3.5e38]`, &v, jscandec.DefaultOptions)
+		require.ErrorIs(t, err, strconv.ErrRange)
+	})
+
+	t.Run("exact", func(t *testing.T) {
+		// Short literals are parsed exactly.
+		s := newTestSetupInit[[]float64](t, initOpts, *jscandec.DefaultOptions)
+		s.TestOK(t, "short", `[0.1,-12.5E-3,6.022e23,1e22,123456789012345]`,
+			[]float64{0.1, -12.5e-3, 6.022e23, 1e22, 123456789012345})
+	})
+}
+
+// requireRelErr requires the relative error of actual to expect
+// to not exceed max.
+func requireRelErr(t *testing.T, literal string, expect, actual, max float64) {
+	t.Helper()
+	if expect == actual {
+		return
+	}
+	relErr := math.Abs((actual - expect) / expect)
+	require.LessOrEqual(t, relErr, max,
+		"literal %s: expected %v, got %v", literal, expect, actual)
+}
+
+func TestDecodeSliceTime(t *testing.T) {
+	type T = []time.Time
+	s := newTestSetup[T](t, *jscandec.DefaultOptions)
+	s.TestOK(t, "two_items", `["2023-01-01T00:00:00Z","2023-02-01T00:00:00Z"]`, T{
+		time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC),
+		time.Date(2023, 2, 1, 0, 0, 0, 0, time.UTC),
+	})
+	s.TestOK(t, "fraction", `["2023-01-01T00:00:00.123456789Z"]`, T{
+		time.Date(2023, 1, 1, 0, 0, 0, 123456789, time.UTC),
+	})
+	s.TestOK(t, "empty", `[]`, T{})
+	s.TestOK(t, "null", `null`, T(nil))
+	s.TestOK(t, "null_element", `[null,"2023-01-01T00:00:00Z"]`, T{
+		{}, time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC),
+	})
+
+	s.TestOKPrepare(t, "var_overwrite", `["2023-01-01T00:00:00Z"]`, Test[T]{
+		PrepareJscan: func() T {
+			return T{time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC), {}}
+		},
+		Expect: T{time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)},
+	})
+	s.TestOKPrepare(t, "var_realloc", `["2023-01-01T00:00:00Z","2023-02-01T00:00:00Z"]`,
+		Test[T]{
+			PrepareJscan: func() T { return T{time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)} },
+			Expect: T{
+				time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC),
+				time.Date(2023, 2, 1, 0, 0, 0, 0, time.UTC),
+			},
+		})
+
+	s.testErr(t, "wrong_type_string", `"2023-01-01T00:00:00Z"`,
+		0, jscandec.ErrUnexpectedValue)
+	s.testErr(t, "wrong_type_object", `{}`,
+		0, jscandec.ErrUnexpectedValue)
+	s.testErr(t, "wrong_type_element_int", `["2023-01-01T00:00:00Z",42]`,
+		24, jscandec.ErrUnexpectedValue)
+	s.testErr(t, "wrong_type_element_array", `["2023-01-01T00:00:00Z",[]]`,
+		24, jscandec.ErrUnexpectedValue)
+	s.testErrCheck(t, "malformed", `["2023-01-01T00:00:00Z","2023-13-01"]`,
+		func(t *testing.T, errIndex int, err error) {
+			var errParse *time.ParseError
+			require.True(t, errors.As(err, &errParse))
+			require.Equal(t, 24, errIndex)
+		})
+
+	t.Run("time_layout", func(t *testing.T) {
+		tok := jscan.NewTokenizer[string](8, 64)
+		d, err := jscandec.NewDecoder[string, T](tok, jscandec.DefaultInitOptions)
+		require.NoError(t, err)
+		var v T
+		_, err = d.Decode(`["2023-01-02",null]`, &v, &jscandec.DecodeOptions{
+			TimeLayout: time.DateOnly,
+		})
+		require.NoError(t, err)
+		require.Equal(t, T{time.Date(2023, 1, 2, 0, 0, 0, 0, time.UTC), {}}, v)
+	})
+}
+
+func TestDecodeTimeLayouts(t *testing.T) {
+	type T struct {
+		Time    time.Time   `json:"time"`
+		TimePtr *time.Time  `json:"ptr"`
+		Times   []time.Time `json:"times"`
+	}
+	decode := func(t *testing.T, input string, o *jscandec.DecodeOptions) (T, error) {
+		t.Helper()
+		tok := jscan.NewTokenizer[string](8, 64)
+		d, err := jscandec.NewDecoder[string, T](tok, jscandec.DefaultInitOptions)
+		require.NoError(t, err)
+		var v T
+		_, err = d.Decode(input, &v, o)
+		return v, err
+	}
+	options := &jscandec.DecodeOptions{
+		TimeLayouts: []string{time.RFC3339, time.DateOnly},
+		TimeEpoch:   jscandec.TimeEpochSeconds,
+	}
+	dateTime := time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	for _, td := range []struct {
+		name, input string
+		expect      time.Time
+	}{
+		{"date_only", `{"time":"2023-01-02"}`, time.Date(2023, 1, 2, 0, 0, 0, 0, time.UTC)},
+		{"rfc3339", `{"time":"2023-01-02T03:04:05Z"}`, dateTime},
+		{"epoch_seconds", `{"time":1672628645}`, dateTime},
+		{"epoch_negative", `{"time":-1}`, time.Unix(-1, 0).UTC()},
+	} {
+		t.Run(td.name, func(t *testing.T) {
+			v, err := decode(t, td.input, options)
+			require.NoError(t, err)
+			require.Equal(t, T{Time: td.expect}, v)
+		})
+	}
+
+	t.Run("pointer_and_slice", func(t *testing.T) {
+		v, err := decode(t,
+			`{"ptr":1672628645,"times":["2023-01-02",1672628645,null]}`, options)
+		require.NoError(t, err)
+		require.Equal(t, T{
+			TimePtr: &dateTime,
+			Times: []time.Time{
+				time.Date(2023, 1, 2, 0, 0, 0, 0, time.UTC), dateTime, {},
+			},
+		}, v)
+	})
+
+	t.Run("epoch_millis", func(t *testing.T) {
+		v, err := decode(t, `{"time":1672628645000}`, &jscandec.DecodeOptions{
+			TimeEpoch: jscandec.TimeEpochMillis,
+		})
+		require.NoError(t, err)
+		require.Equal(t, T{Time: dateTime}, v)
+	})
+
+	t.Run("epoch_only_rfc3339", func(t *testing.T) {
+		v, err := decode(t, `{"time":"2023-01-02T03:04:05Z"}`, &jscandec.DecodeOptions{
+			TimeEpoch: jscandec.TimeEpochSeconds,
+		})
+		require.NoError(t, err)
+		require.Equal(t, T{Time: dateTime}, v)
+	})
+
+	t.Run("time_layout", func(t *testing.T) {
+		// TimeLayout applies to all time.Time values, not only slices.
+		v, err := decode(t, `{"time":"2023-01-02","ptr":"2023-01-03","times":["2023-01-04"]}`,
+			&jscandec.DecodeOptions{TimeLayout: time.DateOnly})
+		require.NoError(t, err)
+		require.Equal(t, T{
+			Time:    time.Date(2023, 1, 2, 0, 0, 0, 0, time.UTC),
+			TimePtr: Ptr(time.Date(2023, 1, 3, 0, 0, 0, 0, time.UTC)),
+			Times:   []time.Time{time.Date(2023, 1, 4, 0, 0, 0, 0, time.UTC)},
+		}, v)
+
+		type C struct {
+			Array [1]time.Time         `json:"array"`
+			Map   map[string]time.Time `json:"map"`
+		}
+		tok := jscan.NewTokenizer[string](8, 64)
+		d, err := jscandec.NewDecoder[string, C](tok, jscandec.DefaultInitOptions)
+		require.NoError(t, err)
+		var c C
+		_, err = d.Decode(`{"array":["2023-01-02"],"map":{"a":"2023-01-03"}}`, &c,
+			&jscandec.DecodeOptions{TimeLayout: time.DateOnly})
+		require.NoError(t, err)
+		require.Equal(t, [1]time.Time{time.Date(2023, 1, 2, 0, 0, 0, 0, time.UTC)}, c.Array)
+		require.Equal(t, map[string]time.Time{
+			"a": time.Date(2023, 1, 3, 0, 0, 0, 0, time.UTC),
+		}, c.Map)
+	})
+
+	t.Run("time_layout_and_layouts", func(t *testing.T) {
+		// TimeLayouts takes precedence over TimeLayout
+		// for both scalar and slice values.
+		o := &jscandec.DecodeOptions{
+			TimeLayout:  time.DateOnly,
+			TimeLayouts: []string{time.RFC3339},
+		}
+		v, err := decode(t,
+			`{"time":"2023-01-02T03:04:05Z","times":["2023-01-02T03:04:05Z"]}`, o)
+		require.NoError(t, err)
+		require.Equal(t, T{Time: dateTime, Times: []time.Time{dateTime}}, v)
+
+		_, err = decode(t, `{"time":"2023-01-02"}`, o)
+		var errParse *time.ParseError
+		require.True(t, errors.As(err, &errParse))
+		_, err = decode(t, `{"times":["2023-01-02"]}`, o)
+		require.True(t, errors.As(err, &errParse))
+	})
+
+	t.Run("err_all_layouts_fail", func(t *testing.T) {
+		_, err := decode(t, `{"time":"02.01.2023"}`, options)
+		var errParse *time.ParseError
+		require.True(t, errors.As(err, &errParse))
+		require.Equal(t, time.RFC3339, errParse.Layout)
+	})
+
+	t.Run("err_epoch_disabled", func(t *testing.T) {
+		_, err := decode(t, `{"time":1672628645}`, &jscandec.DecodeOptions{
+			TimeLayouts: []string{time.DateOnly},
+		})
+		require.ErrorIs(t, err, jscandec.ErrUnexpectedValue)
+	})
+
+	t.Run("err_epoch_overflow", func(t *testing.T) {
+		_, err := decode(t, `{"time":99999999999999999999}`, options)
+		require.ErrorIs(t, err, jscandec.ErrIntegerOverflow)
+	})
+
+	t.Run("err_float", func(t *testing.T) {
+		_, err := decode(t, `{"time":1.5}`, options)
+		require.ErrorIs(t, err, jscandec.ErrUnexpectedValue)
+	})
+}
+
 func TestDecode2DSliceInt(t *testing.T) {
 	type T = [][]int
 	s := newTestSetup[T](t, *jscandec.DefaultOptions)
@@ -1372,6 +2007,142 @@ func TestDecodeMatrix4Int(t *testing.T) {
 		T{{1, 2, 3, 4}, {5, 6, 7, 8}, {9, 10, 11, 12}, {13, 14, 15, 16}})
 }
 
+func TestDecodeArrayBool(t *testing.T) {
+	type T = [3]bool
+	s := newTestSetup[T](t, *jscandec.DefaultOptions)
+	s.TestOK(t, "null", `null`, T{})
+	s.TestOK(t, "empty", `[]`, T{})
+	s.TestOK(t, "incomplete", `[true]`, T{true, false, false})
+	s.TestOK(t, "complete", `[true,false,true]`, T{true, false, true})
+	s.TestOK(t, "null_element", `[true,null,true]`, T{true, false, true})
+	s.TestOK(t, "overflow_ignore",
+		`[true,true,true, false,{},{"x":"y"},[],null,42,3.14]`, T{true, true, true})
+	s.TestOKPrepare(t, "reuse_zero_trailing", `[false]`, Test[T]{
+		PrepareJscan: func() T { return T{true, true, true} },
+		Expect:       T{false, false, false},
+	})
+
+	s.testErr(t, "string", `"text"`, 0, jscandec.ErrUnexpectedValue)
+	s.testErr(t, "element_int", `[true,1]`, 6, jscandec.ErrUnexpectedValue)
+	s.testErr(t, "element_string", `[true,"x"]`, 6, jscandec.ErrUnexpectedValue)
+	s.testErr(t, "element_array", `[true,[]]`, 6, jscandec.ErrUnexpectedValue)
+	s.testErr(t, "element_object", `[true,{}]`, 6, jscandec.ErrUnexpectedValue)
+}
+
+func TestDecodeArrayString(t *testing.T) {
+	type T = [3]string
+	s := newTestSetup[T](t, *jscandec.DefaultOptions)
+	s.TestOK(t, "null", `null`, T{})
+	s.TestOK(t, "empty", `[]`, T{})
+	s.TestOK(t, "incomplete", `["a"]`, T{"a", "", ""})
+	s.TestOK(t, "complete", `["a","b","c"]`, T{"a", "b", "c"})
+	s.TestOK(t, "escaped", `["\"ä\"","",""]`, T{`"ä"`, "", ""})
+	s.TestOK(t, "null_element", `["a",null,"c"]`, T{"a", "", "c"})
+	s.TestOK(t, "overflow_ignore",
+		`["a","b","c", false,{},{"x":"y"},[],null,42,3.14]`, T{"a", "b", "c"})
+	s.TestOKPrepare(t, "reuse_zero_trailing", `["x"]`, Test[T]{
+		PrepareJscan: func() T { return T{"a", "b", "c"} },
+		Expect:       T{"x", "", ""},
+	})
+
+	s.testErr(t, "string", `"text"`, 0, jscandec.ErrUnexpectedValue)
+	s.testErr(t, "element_int", `["a",1]`, 5, jscandec.ErrUnexpectedValue)
+	s.testErr(t, "element_bool", `["a",true]`, 5, jscandec.ErrUnexpectedValue)
+	s.testErr(t, "element_array", `["a",[]]`, 5, jscandec.ErrUnexpectedValue)
+	s.testErr(t, "element_object", `["a",{}]`, 5, jscandec.ErrUnexpectedValue)
+}
+
+func TestDecodeArrayFloat32(t *testing.T) {
+	type T = [3]float32
+	s := newTestSetup[T](t, *jscandec.DefaultOptions)
+	s.TestOK(t, "null", `null`, T{})
+	s.TestOK(t, "empty", `[]`, T{})
+	s.TestOK(t, "incomplete", `[1.5]`, T{1.5, 0, 0})
+	s.TestOK(t, "complete", `[1,-2.5,16777217]`, T{1, -2.5, 16777217})
+	s.TestOK(t, "exponent", `[1e2,-1E-2,0]`, T{1e2, -1e-2, 0})
+	s.TestOK(t, "null_element", `[1,null,3]`, T{1, 0, 3})
+	s.TestOK(t, "overflow_ignore",
+		`[1,2,3, false,{},{"x":"y"},[],null,"x"]`, T{1, 2, 3})
+	s.TestOKPrepare(t, "reuse_zero_trailing", `[4]`, Test[T]{
+		PrepareJscan: func() T { return T{1, 2, 3} },
+		Expect:       T{4, 0, 0},
+	})
+
+	s.testErr(t, "string", `"text"`, 0, jscandec.ErrUnexpectedValue)
+	s.testErr(t, "element_string", `[1,"2"]`, 3, jscandec.ErrUnexpectedValue)
+	s.testErr(t, "element_bool", `[1,true]`, 3, jscandec.ErrUnexpectedValue)
+	s.testErr(t, "element_array", `[1,[]]`, 3, jscandec.ErrUnexpectedValue)
+	s.testErr(t, "element_object", `[1,{}]`, 3, jscandec.ErrUnexpectedValue)
+}
+
+func TestDecodeArrayFloat64(t *testing.T) {
+	type T = [3]float64
+	s := newTestSetup[T](t, *jscandec.DefaultOptions)
+	s.TestOK(t, "null", `null`, T{})
+	s.TestOK(t, "empty", `[]`, T{})
+	s.TestOK(t, "incomplete", `[1.5]`, T{1.5, 0, 0})
+	s.TestOK(t, "complete", `[1,-2.5,9007199254740993]`, T{1, -2.5, 9007199254740993})
+	s.TestOK(t, "exponent", `[1e2,-1E-2,0]`, T{1e2, -1e-2, 0})
+	s.TestOK(t, "null_element", `[1,null,3]`, T{1, 0, 3})
+	s.TestOK(t, "overflow_ignore",
+		`[1,2,3, false,{},{"x":"y"},[],null,"x"]`, T{1, 2, 3})
+	s.TestOKPrepare(t, "reuse_zero_trailing", `[4]`, Test[T]{
+		PrepareJscan: func() T { return T{1, 2, 3} },
+		Expect:       T{4, 0, 0},
+	})
+
+	s.testErr(t, "string", `"text"`, 0, jscandec.ErrUnexpectedValue)
+	s.testErr(t, "element_string", `[1,"2"]`, 3, jscandec.ErrUnexpectedValue)
+	s.testErr(t, "element_bool", `[1,true]`, 3, jscandec.ErrUnexpectedValue)
+	s.testErr(t, "element_array", `[1,[]]`, 3, jscandec.ErrUnexpectedValue)
+	s.testErr(t, "element_object", `[1,{}]`, 3, jscandec.ErrUnexpectedValue)
+}
+
+func TestDecodeMatrix16Float64(t *testing.T) {
+	type T = [4][16]float64
+	s := newTestSetup[T](t, *jscandec.DefaultOptions)
+	s.TestOK(t, "empty", `[]`, T{})
+	s.TestOK(t, "sub_arrays_empty", `[[],[],[],[]]`, T{})
+	s.TestOK(t, "incomplete",
+		`[[1,2],[3],[],[4,5,6]]`,
+		T{{1, 2}, {3}, {}, {4, 5, 6}})
+	s.TestOK(t, "complete",
+		`[`+
+			`[0,1,2,3,4,5,6,7,8,9,10,11,12,13,14,15],`+
+			`[0.5,1.5,2.5,3.5,4.5,5.5,6.5,7.5,8.5,9.5,10.5,11.5,12.5,13.5,14.5,15.5],`+
+			`[-1,-2,-3,-4,-5,-6,-7,-8,-9,-10,-11,-12,-13,-14,-15,-16],`+
+			`[1e1,1e2,1e3,1e4,1e5,1e6,1e7,1e8,1e9,1e10,1e11,1e12,1e13,1e14,1e15,1e16]`+
+			`]`,
+		T{
+			{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15},
+			{
+				0.5, 1.5, 2.5, 3.5, 4.5, 5.5, 6.5, 7.5,
+				8.5, 9.5, 10.5, 11.5, 12.5, 13.5, 14.5, 15.5,
+			},
+			{-1, -2, -3, -4, -5, -6, -7, -8, -9, -10, -11, -12, -13, -14, -15, -16},
+			{
+				1e1, 1e2, 1e3, 1e4, 1e5, 1e6, 1e7, 1e8,
+				1e9, 1e10, 1e11, 1e12, 1e13, 1e14, 1e15, 1e16,
+			},
+		})
+	s.TestOK(t, "overflow_ignore",
+		`[[1,2,3,4,5,6,7,8,9,10,11,12,13,14,15,16,  17,[],{}],[],[],[],  [1]]`,
+		T{{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16}})
+	s.TestOKPrepare(t, "reuse_zero_trailing", `[[1],[2,3],[],[4]]`, Test[T]{
+		PrepareJscan: func() (v T) {
+			for i := range v {
+				for j := range v[i] {
+					v[i][j] = 42
+				}
+			}
+			return v
+		},
+		Expect: T{{1}, {2, 3}, {}, {4}},
+	})
+
+	s.testErr(t, "element_string", `[[1,2],[3,"4"]]`, 10, jscandec.ErrUnexpectedValue)
+}
+
 func TestDecodeEmptyStruct(t *testing.T) {
 	type S struct{}
 	s := newTestSetup[S](t, *jscandec.DefaultOptions)
@@ -1654,10 +2425,83 @@ func TestDecodeArrayEmptyStruct(t *testing.T) {
 		0, jscandec.ErrUnexpectedValue)
 }
 
-func TestDecodeStruct(t *testing.T) {
-	type S struct {
-		Foo int    `json:"foo"`
-		Bar string `json:"bar"`
+func TestDecodeArrayStructReuse(t *testing.T) {
+	type Record struct {
+		A    int    `json:"a"`
+		B    int    `json:"b"`
+		Tags []int  `json:"tags"`
+		Name string `json:"name"`
+	}
+	type A = [4]Record
+	preset := func() A {
+		return A{
+			{A: 10, B: 11, Tags: []int{1}, Name: "r0"},
+			{A: 20, B: 21, Tags: []int{2}, Name: "r1"},
+			{A: 30, B: 31, Tags: []int{3}, Name: "r2"},
+			{A: 40, B: 41, Tags: []int{4}, Name: "r3"},
+		}
+	}
+	s := newTestSetup[A](t, *jscandec.DefaultOptions)
+	s.TestOKPrepare(t, "partial", `[{"a":1},{"b":2}]`, Test[A]{
+		PrepareJscan: preset,
+		Expect: A{
+			{A: 1, B: 11, Tags: []int{1}, Name: "r0"},
+			{A: 20, B: 2, Tags: []int{2}, Name: "r1"},
+		},
+	})
+	s.TestOKPrepare(t, "empty_array", `[]`, Test[A]{
+		PrepareJscan: preset,
+		Expect:       A{},
+	})
+	s.TestOKPrepare(t, "empty_objects", `[{},{}]`, Test[A]{
+		PrepareJscan: preset,
+		Expect: A{
+			{A: 10, B: 11, Tags: []int{1}, Name: "r0"},
+			{A: 20, B: 21, Tags: []int{2}, Name: "r1"},
+		},
+	})
+	s.TestOKPrepare(t, "null_element", `[null,{"tags":[5,6]}]`, Test[A]{
+		PrepareJscan: preset,
+		Expect: A{
+			{A: 10, B: 11, Tags: []int{1}, Name: "r0"},
+			{A: 20, B: 21, Tags: []int{5, 6}, Name: "r1"},
+		},
+	})
+	s.TestOKPrepare(t, "overflow",
+		`[{"a":1},{"a":2},{"a":3},{"a":4},{"a":5}]`, Test[A]{
+			PrepareJscan: preset,
+			Expect: A{
+				{A: 1, B: 11, Tags: []int{1}, Name: "r0"},
+				{A: 2, B: 21, Tags: []int{2}, Name: "r1"},
+				{A: 3, B: 31, Tags: []int{3}, Name: "r2"},
+				{A: 4, B: 41, Tags: []int{4}, Name: "r3"},
+			},
+		})
+	s.TestOKPrepare(t, "null", `null`, Test[A]{
+		PrepareJscan: preset,
+		Expect:       preset(),
+	})
+
+	t.Run("field", func(t *testing.T) {
+		type S struct {
+			Records A   `json:"records"`
+			After   int `json:"after"`
+		}
+		s := newTestSetup[S](t, *jscandec.DefaultOptions)
+		s.TestOKPrepare(t, "partial", `{"records":[{"name":"x"}],"after":1}`, Test[S]{
+			PrepareJscan: func() S { return S{Records: preset(), After: 9} },
+			Expect: S{
+				Records: A{{A: 10, B: 11, Tags: []int{1}, Name: "x"}},
+				After:   1,
+			},
+		})
+	})
+}
+
+func TestDecodeStruct(t *testing.T) {
+	type S struct {
+		Foo int    `json:"foo"`
+		Bar string `json:"bar"`
 	}
 	type T = S
 	s := newTestSetup[T](t, *jscandec.DefaultOptions)
@@ -1975,6 +2819,75 @@ func TestDecodeStructRecursiveSlice(t *testing.T) {
 		0, jscandec.ErrUnexpectedValue)
 }
 
+func TestDecodeStructRecursiveSlicePtr(t *testing.T) {
+	type S struct {
+		ID       string
+		Children []*S
+	}
+	s := newTestSetup[S](t, *jscandec.DefaultOptions)
+	s.TestOK(t, "null", `null`, S{})
+	s.TestOK(t, "empty", `{}`, S{})
+	s.TestOK(t, "2_level",
+		`{"id":"root","children":[{"id":"level2"}]}`,
+		S{ID: "root", Children: []*S{{ID: "level2"}}})
+	s.TestOK(t, "3_level",
+		`{
+			"id": "root",
+			"children": [
+				{"id": "a", "children": [{"id": "a1"}, {"id": "a2"}]},
+				{"id": "b", "children": [{"id": "b1", "children": []}]}
+			]
+		}`,
+		S{ID: "root", Children: []*S{
+			{ID: "a", Children: []*S{{ID: "a1"}, {ID: "a2"}}},
+			{ID: "b", Children: []*S{{ID: "b1", Children: []*S{}}}},
+		}})
+	s.TestOK(t, "3_level_reversed_field_order",
+		`{
+			"children": [{
+				"children": [{"id": "level3"}],
+				"id": "level2"
+			}],
+			"id": "root"
+		}`,
+		S{ID: "root", Children: []*S{
+			{ID: "level2", Children: []*S{{ID: "level3"}}},
+		}})
+	s.TestOK(t, "null_children",
+		`{"id":"root","children":null}`,
+		S{ID: "root", Children: nil})
+	s.TestOK(t, "empty_children",
+		`{"id":"root","children":[]}`,
+		S{ID: "root", Children: []*S{}})
+	s.TestOK(t, "null_child_elements",
+		`{"id":"root","children":[null,{"id":"level2","children":[null]},null]}`,
+		S{ID: "root", Children: []*S{
+			nil, {ID: "level2", Children: []*S{nil}}, nil,
+		}})
+	s.TestOK(t, "3_level_unknown_field",
+		`{"children":[{"children":[{"unknown":["okay"]}]}]}`,
+		S{Children: []*S{{Children: []*S{{}}}}})
+
+	s.TestOKPrepare(t, "overwrite",
+		`{"children":[{"id":"new"}]}`,
+		Test[S]{
+			PrepareJscan: func() S {
+				return S{ID: "root", Children: []*S{{ID: "old"}, {ID: "old2"}}}
+			},
+			Expect: S{ID: "root", Children: []*S{{ID: "new"}}},
+		})
+
+	s.testErr(t, "wrong_type_level2", `{"id":"root","children":{}}`,
+		24, jscandec.ErrUnexpectedValue)
+	s.testErr(t, "wrong_type_level3",
+		`{"id":"root","children":[{"id":"2","children":"x"}]}`,
+		46, jscandec.ErrUnexpectedValue)
+	s.testErr(t, "wrong_type_element", `{"id":"root","children":[1]}`,
+		25, jscandec.ErrUnexpectedValue)
+	s.testErr(t, "array", `[]`,
+		0, jscandec.ErrUnexpectedValue)
+}
+
 func TestDecodeStructRecursiveMap(t *testing.T) {
 	type S struct {
 		ID      string
@@ -2175,6 +3088,67 @@ func TestDecodeStructRecursiveMap(t *testing.T) {
 		0, jscandec.ErrUnexpectedValue)
 }
 
+func TestDecodeStructRecursiveMapPtr(t *testing.T) {
+	type S struct {
+		ID      string
+		Recurse map[string]*S
+		Name    string
+	}
+	s := newTestSetup[S](t, *jscandec.DefaultOptions)
+	s.TestOK(t, "null", `null`, S{})
+	s.TestOK(t, "empty", `{}`, S{})
+	s.TestOK(t, "empty_map", `{"recurse":{}}`, S{Recurse: map[string]*S{}})
+	s.TestOK(t, "null_node", `{"recurse":{"a":null},"id":"root"}`,
+		S{ID: "root", Recurse: map[string]*S{"a": nil}})
+	s.TestOK(t, "empty_node", `{"recurse":{"a":{}},"id":"root"}`,
+		S{ID: "root", Recurse: map[string]*S{"a": {}}})
+	s.TestOK(t, "3_level",
+		`{
+			"id": "root",
+			"recurse": {
+				"a": {
+					"id": "a",
+					"recurse": {
+						"b": {"id": "b", "recurse": {"c": {"id": "c"}}},
+						"null": null,
+						"empty": {}
+					},
+					"name": "A"
+				},
+				"x": {"recurse": null, "id": "x"}
+			},
+			"name": "Root"
+		}`,
+		S{
+			ID: "root", Name: "Root", Recurse: map[string]*S{
+				"a": {
+					ID: "a", Name: "A", Recurse: map[string]*S{
+						"b": {ID: "b", Recurse: map[string]*S{
+							"c": {ID: "c"},
+						}},
+						"null":  nil,
+						"empty": {},
+					},
+				},
+				"x": {ID: "x"},
+			},
+		})
+	s.TestOK(t, "nested_empty", `{"recurse":{"a":{"recurse":{"b":{}}}}}`)
+	s.TestOKPrepare(t, "reuse", `{"recurse":{"a":{"id":"new"},"b":null}}`, Test[S]{
+		PrepareJscan: func() S {
+			return S{ID: "root", Recurse: map[string]*S{
+				"a": {ID: "old", Name: "old"}, "b": {ID: "b"}, "c": {ID: "c"},
+			}}
+		},
+	})
+
+	sl := newTestSetup[[]S](t, *jscandec.DefaultOptions)
+	sl.TestOK(t, "slice",
+		`[{"id":"1","recurse":{"a":{"id":"1a"}}},{"id":"2","recurse":{"b":null}}]`)
+
+	s.testErr(t, "non_object_node", `{"recurse":{"a":42}}`, 16, jscandec.ErrUnexpectedValue)
+}
+
 func TestDecodeStructErrUknownField(t *testing.T) {
 	type S struct {
 		Foo int    `json:"foo"`
@@ -2215,6 +3189,409 @@ func TestDecodeStructErrUknownField(t *testing.T) {
 		0, jscandec.ErrUnexpectedValue)
 }
 
+func TestDecodeRejectUnknownObjectValues(t *testing.T) {
+	type S struct {
+		Foo int `json:"foo"`
+	}
+	s := newTestSetup[S](t, jscandec.DecodeOptions{RejectUnknownObjectValues: true})
+	s.TestOK(t, "scalar_unknowns_tolerated",
+		`{"foo":1,"a":42,"b":"x","c":true,"d":null}`, S{Foo: 1})
+	s.TestOK(t, "composite_known", `{"foo":1}`, S{Foo: 1})
+	s.testErrNonstandard(t, "object_unknown",
+		`{"foo":1,"bar":{"x":1}}`, 9, jscandec.ErrUnknownCompositeField)
+	s.testErrNonstandard(t, "array_unknown",
+		`{"bar":[],"foo":1}`, 1, jscandec.ErrUnknownCompositeField)
+
+	t.Run("flat_struct_slice", func(t *testing.T) {
+		s := newTestSetup[[]S](t, jscandec.DecodeOptions{RejectUnknownObjectValues: true})
+		s.TestOK(t, "scalar_unknown", `[{"foo":1,"a":2}]`, []S{{Foo: 1}})
+		s.testErrNonstandard(t, "object_unknown",
+			`[{"foo":1},{"a":{}}]`, 12, jscandec.ErrUnknownCompositeField)
+	})
+
+	t.Run("rest", func(t *testing.T) {
+		type S struct {
+			Foo  int            `json:"foo"`
+			Rest map[string]any `json:",rest"`
+		}
+		s := newTestSetup[S](t, jscandec.DecodeOptions{RejectUnknownObjectValues: true})
+		s.testOKNonstandard(t, "collected", `{"foo":1,"a":{"b":1}}`,
+			S{Foo: 1, Rest: map[string]any{"a": map[string]any{"b": float64(1)}}})
+	})
+}
+
+func TestDecodeWarnings(t *testing.T) {
+	type S struct {
+		ID    int     `json:"id"`
+		Value float64 `json:"value"`
+	}
+	decode := func(
+		t *testing.T, input string, o jscandec.DecodeOptions,
+	) (S, []error, int, error) {
+		t.Helper()
+		tok := jscan.NewTokenizer[string](8, 64)
+		d, err := jscandec.NewDecoder[string, S](tok, jscandec.DefaultInitOptions)
+		require.NoError(t, err)
+		var warnings []error
+		o.Warnings = &warnings
+		var v S
+		errIndex, err := d.Decode(input, &v, &o)
+		return v, warnings, errIndex, err
+	}
+
+	t.Run("unknown_field_and_imprecise_integer", func(t *testing.T) {
+		v, warnings, _, err := decode(t,
+			`{"id":1,"extra":"x","value":9007199254740993}`,
+			jscandec.DecodeOptions{DisallowUnknownFields: true})
+		require.NoError(t, err)
+		require.Equal(t, S{ID: 1, Value: 9007199254740992}, v)
+		require.Equal(t, []error{
+			&jscandec.Warning{Index: 8, Err: jscandec.ErrUnknownField},
+			&jscandec.Warning{Index: 28, Err: jscandec.ErrImpreciseNumber},
+		}, warnings)
+		require.ErrorIs(t, warnings[1], jscandec.ErrImpreciseNumber)
+	})
+
+	t.Run("unknown_composite", func(t *testing.T) {
+		v, warnings, _, err := decode(t, `{"extra":{"x":1},"id":1}`,
+			jscandec.DecodeOptions{RejectUnknownObjectValues: true})
+		require.NoError(t, err)
+		require.Equal(t, S{ID: 1}, v)
+		require.Equal(t, []error{
+			&jscandec.Warning{Index: 1, Err: jscandec.ErrUnknownCompositeField},
+		}, warnings)
+	})
+
+	t.Run("exact", func(t *testing.T) {
+		v, warnings, _, err := decode(t,
+			`{"id":1,"value":9007199254740992}`, jscandec.DecodeOptions{})
+		require.NoError(t, err)
+		require.Equal(t, S{ID: 1, Value: 9007199254740992}, v)
+		require.Empty(t, warnings)
+	})
+
+	t.Run("ignored_field", func(t *testing.T) {
+		v, warnings, _, err := decode(t, `{"id":1,"value":2}`,
+			jscandec.DecodeOptions{IgnoreFields: map[string]bool{"id": true}})
+		require.NoError(t, err)
+		require.Equal(t, S{Value: 2}, v)
+		require.Empty(t, warnings)
+	})
+
+	t.Run("retained_on_error", func(t *testing.T) {
+		_, warnings, errIndex, err := decode(t, `{"extra":1,"id":"x"}`,
+			jscandec.DecodeOptions{})
+		require.ErrorIs(t, err, jscandec.ErrUnexpectedValue)
+		require.Equal(t, 16, errIndex)
+		require.Equal(t, []error{
+			&jscandec.Warning{Index: 1, Err: jscandec.ErrUnknownField},
+		}, warnings)
+	})
+
+	t.Run("unquoted_keys", func(t *testing.T) {
+		_, warnings, _, err := decode(t, `{extra:1,"id":1}`,
+			jscandec.DecodeOptions{AllowUnquotedKeys: true})
+		require.NoError(t, err)
+		require.Equal(t, []error{
+			&jscandec.Warning{Index: 1, Err: jscandec.ErrUnknownField},
+		}, warnings)
+	})
+
+	t.Run("flat_struct_slice", func(t *testing.T) {
+		type T struct {
+			V float32 `json:"v"`
+		}
+		tok := jscan.NewTokenizer[string](8, 64)
+		d, err := jscandec.NewDecoder[string, []T](tok, jscandec.DefaultInitOptions)
+		require.NoError(t, err)
+		var warnings []error
+		var v []T
+		_, err = d.Decode(`[{"a":1,"v":16777217}]`, &v, &jscandec.DecodeOptions{
+			Warnings: &warnings,
+		})
+		require.NoError(t, err)
+		require.Equal(t, []T{{V: 16777216}}, v)
+		require.Equal(t, []error{
+			&jscandec.Warning{Index: 2, Err: jscandec.ErrUnknownField},
+			&jscandec.Warning{Index: 12, Err: jscandec.ErrImpreciseNumber},
+		}, warnings)
+	})
+
+	t.Run("any", func(t *testing.T) {
+		tok := jscan.NewTokenizer[string](8, 64)
+		d, err := jscandec.NewDecoder[string, any](tok, jscandec.DefaultInitOptions)
+		require.NoError(t, err)
+		var warnings []error
+		var v any
+		_, err = d.Decode(`[9007199254740992,9007199254740993]`, &v,
+			&jscandec.DecodeOptions{Warnings: &warnings})
+		require.NoError(t, err)
+		require.Equal(t, []error{
+			&jscandec.Warning{Index: 18, Err: jscandec.ErrImpreciseNumber},
+		}, warnings)
+	})
+}
+
+func TestDecodeFieldAliases(t *testing.T) {
+	type S struct {
+		Value int    `json:"newName" jsonalias:"oldName,legacy"`
+		Other string `json:"other"`
+	}
+	s := newTestSetup[S](t, *jscandec.DefaultOptions)
+	s.TestOK(t, "name", `{"newName":5}`, S{Value: 5})
+	s.testOKNonstandard(t, "alias", `{"oldName":5}`, S{Value: 5})
+	s.testOKNonstandard(t, "alias_second", `{"legacy":5,"other":"x"}`,
+		S{Value: 5, Other: "x"})
+	s.testOKNonstandard(t, "alias_case_insensitive", `{"OLDNAME":5}`, S{Value: 5})
+	s.testOKNonstandard(t, "last_wins", `{"oldName":1,"newName":2}`, S{Value: 2})
+	s.testOKNonstandard(t, "last_wins_alias", `{"newName":1,"legacy":2}`, S{Value: 2})
+	s.testErrNonstandard(t, "alias_type", `{"oldName":"5"}`,
+		11, jscandec.ErrUnexpectedValue)
+
+	t.Run("disallow_unknown_fields", func(t *testing.T) {
+		s := newTestSetup[S](t, jscandec.DecodeOptions{DisallowUnknownFields: true})
+		s.testOKNonstandard(t, "alias", `{"oldName":5}`, S{Value: 5})
+	})
+
+	t.Run("duplicate_key_error", func(t *testing.T) {
+		s := newTestSetup[S](t, jscandec.DecodeOptions{
+			DuplicateKeyStrategy: jscandec.DuplicateKeyError,
+		})
+		s.testErrNonstandard(t, "name_and_alias", `{"oldName":1,"newName":2}`,
+			13, jscandec.ErrDuplicateKey)
+	})
+
+	t.Run("name_takes_precedence", func(t *testing.T) {
+		type S struct {
+			A int `json:"a" jsonalias:"b"`
+			B int `json:"b"`
+		}
+		s := newTestSetup[S](t, *jscandec.DefaultOptions)
+		s.TestOK(t, "b", `{"b":1}`, S{B: 1})
+	})
+
+	t.Run("flat_struct_slice", func(t *testing.T) {
+		s := newTestSetup[[]S](t, *jscandec.DefaultOptions)
+		s.testOKNonstandard(t, "alias", `[{"oldName":1},{"newName":2,"legacy":3}]`,
+			[]S{{Value: 1}, {Value: 3}})
+	})
+}
+
+func TestDecodeFieldDefaults(t *testing.T) {
+	type S struct {
+		Port  int     `json:"port" default:"8080"`
+		Host  string  `json:"host" default:"localhost"`
+		Debug bool    `json:"debug" default:"true"`
+		Ratio float64 `json:"ratio" default:"0.5"`
+		Name  string  `json:"name"`
+	}
+	defaults := S{Port: 8080, Host: "localhost", Debug: true, Ratio: 0.5}
+	s := newTestSetup[S](t, *jscandec.DefaultOptions)
+	s.testOKNonstandard(t, "empty", `{}`, defaults)
+	s.testOKNonstandard(t, "present", `{"port":9090}`,
+		S{Port: 9090, Host: "localhost", Debug: true, Ratio: 0.5})
+	s.testOKNonstandard(t, "case_insensitive", `{"PORT":9090,"debug":false}`,
+		S{Port: 9090, Host: "localhost", Ratio: 0.5})
+	s.testOKNonstandard(t, "null_is_present", `{"port":null}`,
+		S{Host: "localhost", Debug: true, Ratio: 0.5})
+	s.testOKNonstandard(t, "unknown_and_other", `{"x":{"port":1},"name":"n"}`,
+		S{Port: 8080, Host: "localhost", Debug: true, Ratio: 0.5, Name: "n"})
+	s.TestOK(t, "all_present",
+		`{"port":1,"host":"h","debug":false,"ratio":2,"name":"n"}`,
+		S{Port: 1, Host: "h", Ratio: 2, Name: "n"})
+	s.TestOK(t, "null", `null`, S{})
+
+	t.Run("nested", func(t *testing.T) {
+		type T struct {
+			Inner S  `json:"inner"`
+			Ptr   *S `json:"ptr"`
+		}
+		s := newTestSetup[T](t, *jscandec.DefaultOptions)
+		s.testOKNonstandard(t, "inner", `{"inner":{},"ptr":{"port":1}}`, T{
+			Inner: defaults,
+			Ptr:   &S{Port: 1, Host: "localhost", Debug: true, Ratio: 0.5},
+		})
+		s.TestOK(t, "absent", `{}`, T{})
+	})
+
+	t.Run("slice", func(t *testing.T) {
+		s := newTestSetup[[]S](t, *jscandec.DefaultOptions)
+		s.testOKNonstandard(t, "elements", `[{},{"host":"h"}]`, []S{
+			defaults,
+			{Port: 8080, Host: "h", Debug: true, Ratio: 0.5},
+		})
+	})
+
+	t.Run("unsupported_type", func(t *testing.T) {
+		type S struct {
+			Tags []string `json:"tags" default:"[]"`
+		}
+		tok := jscan.NewTokenizer[string](1, 1)
+		_, err := jscandec.NewDecoder[string, S](tok, jscandec.DefaultInitOptions)
+		require.ErrorIs(t, err, jscandec.ErrDefaultTagOnUnsupportedType)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		type S struct {
+			Port int `json:"port" default:"http"`
+		}
+		tok := jscan.NewTokenizer[string](1, 1)
+		_, err := jscandec.NewDecoder[string, S](tok, jscandec.DefaultInitOptions)
+		require.ErrorIs(t, err, jscandec.ErrInvalidDefault)
+	})
+}
+
+func TestDecodeRequiredFields(t *testing.T) {
+	type S struct {
+		A int `json:"a"`
+		B int `json:"b" required:"true"`
+		C int `json:"c" required:"false"`
+	}
+	missing := func(name string) error {
+		return fmt.Errorf("%w: %s", jscandec.ErrMissingRequiredField, name)
+	}
+	s := newTestSetup[S](t, *jscandec.DefaultOptions)
+	s.TestOK(t, "present", `{"a":1,"b":2}`, S{A: 1, B: 2})
+	s.TestOK(t, "case_insensitive", `{"B":2}`, S{B: 2})
+	s.TestOK(t, "null_is_present", `{"b":null}`, S{})
+	s.TestOK(t, "null", `null`, S{})
+	s.testErrNonstandard(t, "missing", `{"a":1}`, 0, missing("b"))
+	s.testErrNonstandard(t, "empty", `{}`, 0, missing("b"))
+	s.testErrNonstandard(t, "nested_key", `{"x":{"b":2}}`, 0, missing("b"))
+
+	t.Run("error_is", func(t *testing.T) {
+		var v S
+		_, err := s.decoderString.Decode(`{"c":3}`, &v, s.decodeOptions)
+		require.ErrorIs(t, err, jscandec.ErrMissingRequiredField)
+		require.ErrorContains(t, err, "b")
+	})
+
+	t.Run("nested", func(t *testing.T) {
+		type T struct {
+			Inner S  `json:"inner" required:"true"`
+			Ptr   *S `json:"ptr"`
+		}
+		s := newTestSetup[T](t, *jscandec.DefaultOptions)
+		s.TestOK(t, "present", `{"inner":{"b":1},"ptr":{"b":2}}`,
+			T{Inner: S{B: 1}, Ptr: &S{B: 2}})
+		s.TestOK(t, "ptr_null", `{"inner":{"b":1},"ptr":null}`, T{Inner: S{B: 1}})
+		s.testErrNonstandard(t, "missing_inner", `{"ptr":{"b":2}}`, 0, missing("inner"))
+		s.testErrNonstandard(t, "missing_in_inner", `{"inner":{"a":1}}`, 9, missing("b"))
+		s.testErrNonstandard(t, "missing_in_ptr",
+			`{"inner":{"b":1},"ptr":{}}`, 23, missing("b"))
+	})
+
+	t.Run("embedded", func(t *testing.T) {
+		type E struct {
+			ID string `json:"id" required:"true"`
+		}
+		type T struct {
+			E
+			Name string `json:"name"`
+		}
+		s := newTestSetup[T](t, *jscandec.DefaultOptions)
+		s.TestOK(t, "present", `{"id":"x","name":"n"}`, T{E: E{ID: "x"}, Name: "n"})
+		s.testErrNonstandard(t, "missing", `{"name":"n"}`, 0, missing("id"))
+	})
+
+	t.Run("slice", func(t *testing.T) {
+		s := newTestSetup[[]S](t, *jscandec.DefaultOptions)
+		s.TestOK(t, "present", `[{"b":1},{"b":2}]`, []S{{B: 1}, {B: 2}})
+		s.testErrNonstandard(t, "missing", `[{"b":1},{"a":2}]`, 9, missing("b"))
+	})
+}
+
+func TestDecodeStructEmbedded(t *testing.T) {
+	type C struct {
+		Name   string `json:"name"`
+		Deep   int    `json:"deep"`
+		Shared int
+	}
+	type B struct {
+		C
+		Name string `json:"name"` // Shadows C.Name
+		Mid  int
+	}
+	type A struct {
+		B
+		Top int `json:"top"`
+	}
+
+	t.Run("two_levels", func(t *testing.T) {
+		s := newTestSetup[A](t, *jscandec.DefaultOptions)
+		s.TestOK(t, "promoted", `{"name":"outer","deep":1,"Shared":2,"Mid":3,"top":4}`,
+			A{B: B{C: C{Deep: 1, Shared: 2}, Name: "outer", Mid: 3}, Top: 4})
+		s.TestOK(t, "case_insensitive", `{"NAME":"x","DEEP":1}`,
+			A{B: B{C: C{Deep: 1}, Name: "x"}})
+		s.TestOK(t, "embedded_name_unknown", `{"B":{"Mid":1},"C":{"deep":1}}`, A{})
+	})
+
+	t.Run("conflict_same_depth", func(t *testing.T) {
+		type X struct {
+			Dup   int
+			OnlyX int
+		}
+		type Y struct {
+			Dup   int
+			OnlyY int
+		}
+		type S struct {
+			X
+			Y
+		}
+		s := newTestSetup[S](t, *jscandec.DefaultOptions)
+		// Neither X.Dup nor Y.Dup wins.
+		s.TestOK(t, "dropped", `{"Dup":1,"OnlyX":2,"OnlyY":3}`,
+			S{X: X{OnlyX: 2}, Y: Y{OnlyY: 3}})
+	})
+
+	t.Run("conflict_tagged_wins", func(t *testing.T) {
+		type X struct {
+			Dup int `json:"Dup"`
+		}
+		type Y struct {
+			Dup int
+		}
+		type S struct {
+			X
+			Y
+		}
+		s := newTestSetup[S](t, *jscandec.DefaultOptions)
+		s.TestOK(t, "tagged", `{"Dup":1}`, S{X: X{Dup: 1}})
+	})
+
+	t.Run("tagged_embedded", func(t *testing.T) {
+		type S struct {
+			C `json:"c"`
+		}
+		s := newTestSetup[S](t, *jscandec.DefaultOptions)
+		s.TestOK(t, "not_promoted", `{"c":{"name":"x"},"deep":1}`,
+			S{C: C{Name: "x"}})
+	})
+
+	t.Run("unexported", func(t *testing.T) {
+		type embedded struct{ E int }
+		type S struct {
+			embedded
+			unexported int
+			Exported   int
+		}
+		s := newTestSetup[S](t, *jscandec.DefaultOptions)
+		s.TestOK(t, "ignored", `{"E":1,"unexported":2,"Exported":3}`,
+			S{embedded: embedded{E: 1}, Exported: 3})
+	})
+
+	t.Run("err_embedded_pointer", func(t *testing.T) {
+		type S struct {
+			*C
+		}
+		tok := jscan.NewTokenizer[string](1, 1)
+		dec, err := jscandec.NewDecoder[string, S](tok, jscandec.DefaultInitOptions)
+		require.ErrorIs(t, err, jscandec.ErrUnsupportedType)
+		require.Nil(t, dec)
+	})
+}
+
 func TestDecodeStructFields(t *testing.T) {
 	type S struct {
 		Any   any
@@ -2297,6 +3674,42 @@ func TestDecodeStringTagBool(t *testing.T) {
 	s.testErr(t, "string", `"text"`, 0, jscandec.ErrUnexpectedValue)
 }
 
+func TestDecodeStringTagBoolNumeric(t *testing.T) {
+	type S struct {
+		Bool  bool  `json:"bool,string"`
+		Ptr   *bool `json:"ptr,string"`
+		Plain bool  `json:"plain"`
+	}
+	options := jscandec.DecodeOptions{BoolStringNumeric: true}
+	s := newTestSetup[S](t, options)
+	s.testOKNonstandard(t, "one", `{"bool":"1"}`, S{Bool: true})
+	s.testOKNonstandard(t, "zero", `{"bool":"0","plain":true}`,
+		S{Bool: false, Plain: true})
+	s.testOKNonstandard(t, "ptr", `{"ptr":"1"}`, S{Ptr: func() *bool {
+		v := true
+		return &v
+	}()})
+	s.TestOK(t, "true", `{"bool":"true"}`, S{Bool: true})
+	s.TestOK(t, "false", `{"bool":"false"}`, S{Bool: false})
+
+	s.testErr(t, "two", `{"bool":"2"}`, 8, jscandec.ErrUnexpectedValue)
+	s.testErr(t, "leading_zero", `{"bool":"01"}`, 8, jscandec.ErrUnexpectedValue)
+	s.testErr(t, "yes", `{"bool":"yes"}`, 8, jscandec.ErrUnexpectedValue)
+	s.testErr(t, "number", `{"bool":1}`, 8, jscandec.ErrUnexpectedValue)
+	s.testErr(t, "plain_string", `{"plain":"1"}`, 9, jscandec.ErrUnexpectedValue)
+
+	t.Run("stringed", func(t *testing.T) {
+		s := newTestSetup[jscandec.Stringed[bool]](t, options)
+		s.testOKNonstandard(t, "one", `"1"`, jscandec.Stringed[bool]{Value: true})
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		s := newTestSetup[S](t, *jscandec.DefaultOptions)
+		s.testErr(t, "one", `{"bool":"1"}`, 8, jscandec.ErrUnexpectedValue)
+		s.testErr(t, "zero", `{"bool":"0"}`, 8, jscandec.ErrUnexpectedValue)
+	})
+}
+
 func TestDecodeStringTagString(t *testing.T) {
 	type S struct {
 		String string `json:",string"`
@@ -2584,30 +3997,157 @@ func TestDecodeStringTagFloat64(t *testing.T) {
 		})
 }
 
-func TestDecodePointerInt(t *testing.T) {
-	s := newTestSetup[*int](t, *jscandec.DefaultOptions)
-	s.TestOK(t, "valid", `42`, Ptr(int(42)))
-	s.TestOK(t, "null", `null`, (*int)(nil))
-
-	s.testErr(t, "float", `1.1`, 0, jscandec.ErrUnexpectedValue)
-	s.testErr(t, "array", `[]`, 0, jscandec.ErrUnexpectedValue)
-	s.testErr(t, "object", `{}`, 0, jscandec.ErrUnexpectedValue)
-	s.testErr(t, "string", `"text"`, 0, jscandec.ErrUnexpectedValue)
-	s.testErr(t, "false", `false`, 0, jscandec.ErrUnexpectedValue)
-	s.testErr(t, "true", `true`, 0, jscandec.ErrUnexpectedValue)
+func TestDecodeStringTagPointer(t *testing.T) {
+	type S struct {
+		Int    *int     `json:",string"`
+		Bool   *bool    `json:",string"`
+		Float  *float64 `json:",string"`
+		String *string  `json:",string"`
+	}
+	s := newTestSetup[S](t, *jscandec.DefaultOptions)
+	s.TestOK(t, "empty", `{}`, S{})
+	s.TestOK(t, "null", `{"int":null,"bool":null,"float":null,"string":null}`, S{})
+	s.TestOK(t, "quoted",
+		`{"int":"42","bool":"true","float":"3.14","string":"\"text\""}`,
+		S{Int: Ptr(42), Bool: Ptr(true), Float: Ptr(3.14), String: Ptr("text")})
+	s.TestOKPrepare(t, "quoted_null", `{"int":"null"}`, Test[S]{
+		PrepareJscan: func() S { return S{Int: Ptr(1)} },
+		Expect:       S{},
+	})
+
+	s.testErr(t, "int_unquoted", `{"int":42}`, 7, jscandec.ErrUnexpectedValue)
+	s.testErr(t, "int_float", `{"int":"3.14"}`, 7, jscandec.ErrUnexpectedValue)
+	s.testErr(t, "bool_unquoted", `{"bool":true}`, 8, jscandec.ErrUnexpectedValue)
 }
 
-func TestDecodePointerBool(t *testing.T) {
-	s := newTestSetup[*bool](t, *jscandec.DefaultOptions)
-	s.TestOK(t, "valid_true", `true`, Ptr(bool(true)))
-	s.TestOK(t, "valid_false", `false`, Ptr(bool(false)))
-	s.TestOK(t, "null", `null`, (*bool)(nil))
-
-	s.testErr(t, "float", `1.1`, 0, jscandec.ErrUnexpectedValue)
-	s.testErr(t, "array", `[]`, 0, jscandec.ErrUnexpectedValue)
-	s.testErr(t, "object", `{}`, 0, jscandec.ErrUnexpectedValue)
-	s.testErr(t, "string", `"text"`, 0, jscandec.ErrUnexpectedValue)
-	s.testErr(t, "int", `42`, 0, jscandec.ErrUnexpectedValue)
+func TestDecodeStringTagNamedTypes(t *testing.T) {
+	type Flag bool
+	type Name string
+	type Count uint16
+	type S struct {
+		Flag  Flag  `json:"flag,string"`
+		Name  Name  `json:"name,string"`
+		Count Count `json:"count,string"`
+		Ptr   *Flag `json:"ptr,string"`
+	}
+	s := newTestSetup[S](t, *jscandec.DefaultOptions)
+	s.TestOK(t, "flag", `{"flag":"true"}`, S{Flag: true})
+	s.TestOK(t, "name", `{"name":"\"x\""}`, S{Name: "x"})
+	s.TestOK(t, "count", `{"count":"42"}`, S{Count: 42})
+	s.TestOK(t, "pointer", `{"ptr":"false"}`, S{Ptr: Ptr(Flag(false))})
+	s.TestOK(t, "null", `{"flag":null,"name":null,"count":null,"ptr":null}`, S{})
+
+	s.testErr(t, "flag_unquoted", `{"flag":true}`, 8, jscandec.ErrUnexpectedValue)
+	s.testErr(t, "name_unquoted", `{"name":"x"}`, 8, jscandec.ErrUnexpectedValue)
+}
+
+func TestDecodeStringTagPointerSliceNotDescended(t *testing.T) {
+	type S struct {
+		//lint:ignore SA5008 the JSON string option is used intentionally
+		Slice []int `json:",string"` //nolint:staticcheck
+	}
+	// encoding/json only descends through pointers but never
+	// into slices, arrays or maps, so the option is ignored here.
+	s := newTestSetup[S](t, *jscandec.DefaultOptions)
+	s.TestOK(t, "unquoted", `{"slice":[1,2]}`, S{Slice: []int{1, 2}})
+	s.testErr(t, "quoted", `{"slice":["1","2"]}`, 10, jscandec.ErrUnexpectedValue)
+}
+
+func TestDecodeStringTagUnsupportedContainers(t *testing.T) {
+	f := func(t *testing.T, newDecoder func(*jscandec.InitOptions) error) {
+		t.Helper()
+		require.NoError(t, newDecoder(jscandec.DefaultInitOptions))
+		err := newDecoder(&jscandec.InitOptions{
+			DisallowStringTagOptOnUnsupportedTypes: true,
+		})
+		require.Equal(t, jscandec.ErrStringTagOptionOnUnsupportedType, err)
+	}
+	tok := jscan.NewTokenizer[string](1, 1)
+	t.Run("pointer_allowed", func(t *testing.T) {
+		type S struct {
+			F *int `json:",string"`
+		}
+		_, err := jscandec.NewDecoder[string, S](tok, &jscandec.InitOptions{
+			DisallowStringTagOptOnUnsupportedTypes: true,
+		})
+		require.NoError(t, err)
+	})
+	t.Run("pointer_to_pointer", func(t *testing.T) {
+		f(t, func(o *jscandec.InitOptions) error {
+			type S struct {
+				//lint:ignore SA5008 the JSON string option is used intentionally
+				F **int `json:",string"` //nolint:staticcheck
+			}
+			_, err := jscandec.NewDecoder[string, S](tok, o)
+			return err
+		})
+	})
+	t.Run("slice", func(t *testing.T) {
+		f(t, func(o *jscandec.InitOptions) error {
+			type S struct {
+				//lint:ignore SA5008 the JSON string option is used intentionally
+				F []int `json:",string"` //nolint:staticcheck
+			}
+			_, err := jscandec.NewDecoder[string, S](tok, o)
+			return err
+		})
+	})
+	t.Run("array", func(t *testing.T) {
+		f(t, func(o *jscandec.InitOptions) error {
+			type S struct {
+				//lint:ignore SA5008 the JSON string option is used intentionally
+				F [2]float64 `json:",string"` //nolint:staticcheck
+			}
+			_, err := jscandec.NewDecoder[string, S](tok, o)
+			return err
+		})
+	})
+	t.Run("map", func(t *testing.T) {
+		f(t, func(o *jscandec.InitOptions) error {
+			type S struct {
+				//lint:ignore SA5008 the JSON string option is used intentionally
+				F map[string]int `json:",string"` //nolint:staticcheck
+			}
+			_, err := jscandec.NewDecoder[string, S](tok, o)
+			return err
+		})
+	})
+	t.Run("slice_of_pointers", func(t *testing.T) {
+		f(t, func(o *jscandec.InitOptions) error {
+			type S struct {
+				//lint:ignore SA5008 the JSON string option is used intentionally
+				F []*int `json:",string"` //nolint:staticcheck
+			}
+			_, err := jscandec.NewDecoder[string, S](tok, o)
+			return err
+		})
+	})
+}
+
+func TestDecodePointerInt(t *testing.T) {
+	s := newTestSetup[*int](t, *jscandec.DefaultOptions)
+	s.TestOK(t, "valid", `42`, Ptr(int(42)))
+	s.TestOK(t, "null", `null`, (*int)(nil))
+
+	s.testErr(t, "float", `1.1`, 0, jscandec.ErrUnexpectedValue)
+	s.testErr(t, "array", `[]`, 0, jscandec.ErrUnexpectedValue)
+	s.testErr(t, "object", `{}`, 0, jscandec.ErrUnexpectedValue)
+	s.testErr(t, "string", `"text"`, 0, jscandec.ErrUnexpectedValue)
+	s.testErr(t, "false", `false`, 0, jscandec.ErrUnexpectedValue)
+	s.testErr(t, "true", `true`, 0, jscandec.ErrUnexpectedValue)
+}
+
+func TestDecodePointerBool(t *testing.T) {
+	s := newTestSetup[*bool](t, *jscandec.DefaultOptions)
+	s.TestOK(t, "valid_true", `true`, Ptr(bool(true)))
+	s.TestOK(t, "valid_false", `false`, Ptr(bool(false)))
+	s.TestOK(t, "null", `null`, (*bool)(nil))
+
+	s.testErr(t, "float", `1.1`, 0, jscandec.ErrUnexpectedValue)
+	s.testErr(t, "array", `[]`, 0, jscandec.ErrUnexpectedValue)
+	s.testErr(t, "object", `{}`, 0, jscandec.ErrUnexpectedValue)
+	s.testErr(t, "string", `"text"`, 0, jscandec.ErrUnexpectedValue)
+	s.testErr(t, "int", `42`, 0, jscandec.ErrUnexpectedValue)
 }
 
 func TestDecodePointerFloat64(t *testing.T) {
@@ -2651,6 +4191,61 @@ func TestDecodePointerSlice(t *testing.T) {
 	s.testErr(t, "true", `true`, 0, jscandec.ErrUnexpectedValue)
 }
 
+func TestDecodePointerMap(t *testing.T) {
+	t.Run("map_string_int", func(t *testing.T) {
+		type M = map[string]int
+		s := newTestSetup[*M](t, *jscandec.DefaultOptions)
+		s.TestOK(t, "valid", `{"a":1}`, Ptr(M{"a": 1}))
+		s.TestOK(t, "empty", `{}`, Ptr(M{}))
+		s.TestOK(t, "null", `null`, (*M)(nil))
+
+		s.testErr(t, "array", `[]`, 0, jscandec.ErrUnexpectedValue)
+		s.testErr(t, "int", `42`, 0, jscandec.ErrUnexpectedValue)
+		s.testErr(t, "string", `"text"`, 0, jscandec.ErrUnexpectedValue)
+		s.testErr(t, "value", `{"a":"1"}`, 5, jscandec.ErrUnexpectedValue)
+	})
+
+	t.Run("map_string_string", func(t *testing.T) {
+		type M = map[string]string
+		s := newTestSetup[*M](t, *jscandec.DefaultOptions)
+		s.TestOK(t, "valid", `{"a":"b"}`, Ptr(M{"a": "b"}))
+		s.TestOK(t, "empty", `{}`, Ptr(M{}))
+		s.TestOK(t, "null", `null`, (*M)(nil))
+
+		s.testErr(t, "array", `[]`, 0, jscandec.ErrUnexpectedValue)
+		s.testErr(t, "value", `{"a":1}`, 5, jscandec.ErrUnexpectedValue)
+	})
+
+	t.Run("struct_field", func(t *testing.T) {
+		type S struct {
+			Ints    *map[string]int    `json:"ints"`
+			Strings *map[string]string `json:"strings"`
+		}
+		s := newTestSetup[S](t, *jscandec.DefaultOptions)
+		s.TestOK(t, "valid", `{"ints":{"a":1},"strings":{}}`, S{
+			Ints: Ptr(map[string]int{"a": 1}), Strings: Ptr(map[string]string{}),
+		})
+		s.TestOK(t, "null", `{"ints":null,"strings":null}`, S{})
+		s.TestOK(t, "absent", `{}`, S{})
+	})
+
+	t.Run("null_not_allocated", func(t *testing.T) {
+		d, err := jscandec.NewDecoder[string, *map[string]int](
+			jscan.NewTokenizer[string](16, 64), jscandec.DefaultInitOptions,
+		)
+		require.NoError(t, err)
+		v := Ptr(map[string]int{"x": 1})
+		_, err = d.Decode(`null`, &v, jscandec.DefaultOptions)
+		require.NoError(t, err)
+		require.Nil(t, v)
+
+		_, err = d.Decode(`{"a":1}`, &v, jscandec.DefaultOptions)
+		require.NoError(t, err)
+		require.NotNil(t, v)
+		require.Equal(t, map[string]int{"a": 1}, *v)
+	})
+}
+
 func TestDecodePointerStruct(t *testing.T) {
 	type S struct {
 		Foo string `json:"foo"`
@@ -2673,6 +4268,58 @@ func TestDecodePointerStruct(t *testing.T) {
 	s.testErr(t, "true", `true`, 0, jscandec.ErrUnexpectedValue)
 }
 
+func TestDecodePointerFieldSibling(t *testing.T) {
+	type S struct {
+		X int `json:"x"`
+	}
+	type T struct {
+		P *S   `json:"p"`
+		I *int `json:"i"`
+		Y int  `json:"y"`
+	}
+	s := newTestSetup[T](t, *jscandec.DefaultOptions)
+	s.TestOK(t, "struct_then_int", `{"p":{"x":1},"y":2}`, T{P: &S{X: 1}, Y: 2})
+	s.TestOK(t, "int_then_int", `{"i":1,"y":2}`, T{I: func() *int { i := 1; return &i }(), Y: 2})
+	s.TestOK(t, "null_then_int", `{"p":null,"i":null,"y":2}`, T{Y: 2})
+}
+
+func TestDecodeSlicePointer(t *testing.T) {
+	type S struct {
+		X int `json:"x"`
+	}
+	s := newTestSetup[[]*S](t, *jscandec.DefaultOptions)
+	s.TestOK(t, "struct", `[{"x":1},null,{"x":2}]`, []*S{{X: 1}, nil, {X: 2}})
+
+	si := newTestSetup[[]*int](t, *jscandec.DefaultOptions)
+	si.TestOK(t, "int", `[1,null,2]`)
+	si.TestOK(t, "int_expect", `[1,null,3]`, []*int{Ptr(1), nil, Ptr(3)})
+	si.TestOK(t, "all_null", `[null,null]`, []*int{nil, nil})
+	si.TestOKPrepare(t, "overwrite", `[1,null,3]`, Test[[]*int]{
+		PrepareJscan: func() []*int { return []*int{Ptr(10), Ptr(20), Ptr(30), Ptr(40)} },
+		Expect:       []*int{Ptr(1), nil, Ptr(3)},
+	})
+
+	ss := newTestSetup[[]*string](t, *jscandec.DefaultOptions)
+	ss.TestOK(t, "string", `["a",null,""]`, []*string{Ptr("a"), nil, Ptr("")})
+
+	t.Run("reuse", func(t *testing.T) {
+		// Elements decoded by a previous call must not be written through.
+		tok := jscan.NewTokenizer[string](16, 1024)
+		d, err := jscandec.NewDecoder[string, []*int](tok, jscandec.DefaultInitOptions)
+		require.NoError(t, err)
+		var v []*int
+		_, err = d.Decode(`[1,2,3]`, &v, jscandec.DefaultOptions)
+		require.NoError(t, err)
+		p1, p2 := v[0], v[1]
+		_, err = d.Decode(`[4,null]`, &v, jscandec.DefaultOptions)
+		require.NoError(t, err)
+		require.Equal(t, []*int{Ptr(4), nil}, v)
+		require.NotSame(t, p1, v[0])
+		require.Equal(t, 1, *p1)
+		require.Equal(t, 2, *p2)
+	})
+}
+
 func TestDecodePointerAny(t *testing.T) {
 	s := newTestSetup[*any](t, *jscandec.DefaultOptions)
 	s.TestOK(t, "int", `[1]`, Ptr(any([]any{float64(1)})))
@@ -3117,6 +4764,95 @@ func TestDecodeMapUint64ToString(t *testing.T) {
 		0, jscandec.ErrUnexpectedValue)
 }
 
+func TestDecodeMapNamedIntToString(t *testing.T) {
+	type UserID int64
+	type M map[UserID]string
+	s := newTestSetup[M](t, *jscandec.DefaultOptions)
+	s.TestOK(t, "empty", `{}`, M{})
+	s.TestOK(t, "null", `null`, M(nil))
+	s.TestOK(t, "key", `{"100":"a"}`, M{UserID(100): "a"})
+	s.TestOK(t, "min_and_max", `{
+		"-9223372036854775808":"min",
+		"9223372036854775807":"max"}`,
+		M{-9223372036854775808: "min", 9223372036854775807: "max"})
+
+	s.testErr(t, "overflow", `{"9223372036854775808":"a"}`,
+		1, jscandec.ErrUnexpectedValue)
+	s.testErr(t, "float", `{"3.14":"a"}`,
+		1, jscandec.ErrUnexpectedValue)
+	s.testErr(t, "non_integer", `{"abc":"a"}`,
+		1, jscandec.ErrUnexpectedValue)
+
+	t.Run("uint", func(t *testing.T) {
+		type Port uint16
+		type M map[Port]string
+		s := newTestSetup[M](t, *jscandec.DefaultOptions)
+		s.TestOK(t, "key", `{"8080":"http"}`, M{Port(8080): "http"})
+		s.testErr(t, "overflow", `{"65536":"a"}`,
+			1, jscandec.ErrUnexpectedValue)
+		s.testErr(t, "negative", `{"-1":"a"}`,
+			1, jscandec.ErrUnexpectedValue)
+	})
+}
+
+func TestDecodeMapIntKeyStrict(t *testing.T) {
+	testMapIntKeyStrict[int](t)
+	testMapIntKeyStrict[int8](t)
+	testMapIntKeyStrict[int16](t)
+	testMapIntKeyStrict[int32](t)
+	testMapIntKeyStrict[int64](t)
+	testMapIntKeyStrict[uint](t)
+	testMapIntKeyStrict[uint8](t)
+	testMapIntKeyStrict[uint16](t)
+	testMapIntKeyStrict[uint32](t)
+	testMapIntKeyStrict[uint64](t)
+}
+
+func testMapIntKeyStrict[K int | int8 | int16 | int32 | int64 |
+	uint | uint8 | uint16 | uint32 | uint64,
+](t *testing.T) {
+	t.Helper()
+	t.Run(reflect.TypeOf(K(0)).String(), func(t *testing.T) {
+		type M map[K]int
+		s := newTestSetup[M](t, *jscandec.DefaultOptions)
+		s.TestOK(t, "integer", `{"1":5}`, M{1: 5})
+		for _, td := range []struct{ name, input string }{
+			{"float_integral", `{"1.0":5}`},
+			{"exponent_integral", `{"1e0":5}`},
+			{"leading_space", `{" 1":5}`},
+			{"trailing_space", `{"1 ":5}`},
+			{"trailing_garbage", `{"1x":5}`},
+			{"leading_plus", `{"+1":5}`},
+			{"empty", `{"":5}`},
+		} {
+			s.testErr(t, td.name, td.input, 1, jscandec.ErrUnexpectedValue)
+		}
+		s.testErr(t, "second_key", `{"1":5,"2.0":6}`,
+			7, jscandec.ErrUnexpectedValue)
+	})
+}
+
+func TestDecodeMapTimeToInt(t *testing.T) {
+	type M map[time.Time]int
+	s := newTestSetup[M](t, *jscandec.DefaultOptions)
+	s.TestOK(t, "empty", `{}`, M{})
+	s.TestOK(t, "null", `null`, M(nil))
+	s.TestOK(t, "rfc3339", `{"2023-01-01T00:00:00Z":5}`,
+		M{time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC): 5})
+	s.TestOK(t, "multiple", `{
+		"2023-01-01T00:00:00Z": 1,
+		"2023-01-02T12:30:00.123456789Z": 2
+	}`, M{
+		time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC):           1,
+		time.Date(2023, 1, 2, 12, 30, 0, 123456789, time.UTC): 2,
+	})
+
+	s.testErr(t, "invalid_key", `{"2023-13-01T00:00:00Z":5}`,
+		1, jscandec.ErrUnexpectedValue)
+	s.testErr(t, "not_a_time", `{"2023-01-01T00:00:00Z":1,"x":5}`,
+		26, jscandec.ErrUnexpectedValue)
+}
+
 func TestDecodeMapStringToMapStringToString(t *testing.T) {
 	type M2 map[string]string
 	type M map[string]M2
@@ -3189,6 +4925,73 @@ func TestDecodeMapStringToMapStringToString(t *testing.T) {
 		14, jscandec.ErrUnexpectedValue)
 }
 
+func TestDecodeNestedMap(t *testing.T) {
+	type M map[string]map[string]map[string]map[string]int
+	s := newTestSetup[M](t, *jscandec.DefaultOptions)
+	s.TestOK(t, "empty", `{}`, M{})
+	s.TestOK(t, "4_levels",
+		`{"a":{"b":{"c":{"d":1,"e":-2}},"f":{}},"g":{"h":null}}`, M{
+			"a": {"b": {"c": {"d": 1, "e": -2}}, "f": {}},
+			"g": {"h": nil},
+		})
+	s.TestOK(t, "null_leaf", `{"a":{"b":{"c":{"d":null}}}}`,
+		M{"a": {"b": {"c": {"d": 0}}}})
+	s.TestOK(t, "null_levels", `{"a":null,"b":{"c":null,"d":{"e":null}}}`,
+		M{"a": nil, "b": {"c": nil, "d": {"e": nil}}})
+	s.TestOK(t, "duplicate_keys",
+		`{"a":{"b":{"c":{"d":1}}},"a":{"b":{"c":{"e":2}}}}`,
+		M{"a": {"b": {"c": {"e": 2}}}}) // Take last
+	s.TestOK(t, "escaped_keys", `{"\u0061":{"\"":{"\\":{"\t":1}}}}`,
+		M{"a": {`"`: {`\`: {"\t": 1}}}})
+
+	s.TestOKPrepare(t, "overwrite", `{"a":{"b":{"c":{"e":2}}}}`, Test[M]{
+		PrepareJscan: func() M {
+			return M{"a": {"b": {"c": {"d": 1}}, "x": {}}, "y": {}}
+		},
+		Expect: M{"a": {"b": {"c": {"e": 2}}}, "y": {}},
+	})
+
+	s.testErr(t, "int", `1`, 0, jscandec.ErrUnexpectedValue)
+	s.testErr(t, "array_level", `{"a":{"b":[]}}`, 10, jscandec.ErrUnexpectedValue)
+	s.testErr(t, "string_leaf", `{"a":{"b":{"c":{"d":"x"}}}}`,
+		20, jscandec.ErrUnexpectedValue)
+	s.testErr(t, "float_leaf", `{"a":{"b":{"c":{"d":1.5}}}}`,
+		20, jscandec.ErrUnexpectedValue)
+	s.testErr(t, "object_leaf", `{"a":{"b":{"c":{"d":{}}}}}`,
+		20, jscandec.ErrUnexpectedValue)
+
+	t.Run("leaf_types", func(t *testing.T) {
+		type K string
+		type V int8
+		sb := newTestSetup[map[string]map[string]bool](t, *jscandec.DefaultOptions)
+		sb.TestOK(t, "bool", `{"a":{"t":true,"f":false,"n":null}}`)
+		sf := newTestSetup[map[string]map[string]float64](t, *jscandec.DefaultOptions)
+		sf.TestOK(t, "float64", `{"a":{"x":1.5,"y":-2,"z":1e3}}`)
+		sn := newTestSetup[map[K]map[K]V](t, *jscandec.DefaultOptions)
+		sn.TestOK(t, "named", `{"a":{"b":127,"c":-128}}`)
+		sn.testErr(t, "named_overflow", `{"a":{"b":128}}`,
+			10, jscandec.ErrIntegerOverflow)
+	})
+
+	t.Run("disallow_empty_string", func(t *testing.T) {
+		// Doesn't apply to map values.
+		s := newTestSetup[map[string]map[string]string](t, jscandec.DecodeOptions{
+			DisallowEmptyString: true,
+		})
+		s.TestOK(t, "empty_string", `{"a":{"b":""}}`)
+	})
+
+	t.Run("disallow_null_for_containers", func(t *testing.T) {
+		s := newTestSetup[M](t, jscandec.DecodeOptions{
+			DisallowNullForContainers: true,
+		})
+		s.testErrNonstandard(t, "null_level", `{"a":{"b":null}}`,
+			10, jscandec.ErrUnexpectedValue)
+		s.testOKNonstandard(t, "null_leaf", `{"a":{"b":{"c":{"d":null}}}}`,
+			M{"a": {"b": {"c": {"d": 0}}}})
+	})
+}
+
 func TestDecodeMapStringToStruct(t *testing.T) {
 	type S struct {
 		Name string `json:"name"`
@@ -3264,102 +5067,578 @@ func TestDecodeMapStringToStruct(t *testing.T) {
 	s.testErr(t, "non_object_element", `{"x":42}`, 5, jscandec.ErrUnexpectedValue)
 }
 
-func TestDecodeMapStringStruct512(t *testing.T) {
-	type D [512]byte
-	type S struct{ Data D }
-	type M map[string]S
+func TestDecodeMapStringToPtrInt(t *testing.T) {
+	type T = map[string]*int
+	s := newTestSetup[T](t, *jscandec.DefaultOptions)
+	s.TestOK(t, "null", `null`, T(nil))
+	s.TestOK(t, "empty", `{}`, T{})
+	s.TestOK(t, "values", `{"a":1,"b":null}`, T{"a": Ptr(1), "b": nil})
+	s.TestOK(t, "many", `{"a":1,"b":2,"c":3,"d":-4}`,
+		T{"a": Ptr(1), "b": Ptr(2), "c": Ptr(3), "d": Ptr(-4)})
+	s.TestOKPrepare(t, "overwrite", `{"a":1,"b":null}`, Test[T]{
+		PrepareJscan: func() T { return T{"a": Ptr(10), "b": Ptr(20), "c": Ptr(30)} },
+		Expect:       T{"a": Ptr(1), "b": nil, "c": Ptr(30)},
+	})
+
+	s.testErr(t, "quoted", `{"a":"1"}`, 5, jscandec.ErrUnexpectedValue)
+	s.testErr(t, "float", `{"a":1.5}`, 5, jscandec.ErrUnexpectedValue)
+
+	t.Run("pointers_distinct", func(t *testing.T) {
+		var v T
+		err := jscandec.Unmarshal(`{"a":1,"b":1}`, &v)
+		require.NoError(t, err)
+		require.NotSame(t, v["a"], v["b"])
+		*v["a"] = 2
+		require.Equal(t, 1, *v["b"])
+	})
+}
+
+// TestDecodeStringTagMapNotDescended makes sure that, like in encoding/json,
+// the `string` tag option isn't applied to map values.
+func TestDecodeStringTagMapNotDescended(t *testing.T) {
+	type S struct {
+		//lint:ignore SA5008 the JSON string option is used intentionally
+		M map[string]*int `json:",string"` //nolint:staticcheck
+	}
+	s := newTestSetup[S](t, *jscandec.DefaultOptions)
+	s.TestOK(t, "unquoted", `{"m":{"a":1,"b":null}}`,
+		S{M: map[string]*int{"a": Ptr(1), "b": nil}})
+	s.testErr(t, "quoted", `{"m":{"a":"1"}}`, 10, jscandec.ErrUnexpectedValue)
+
+	tok := jscan.NewTokenizer[string](1, 1)
+	_, err := jscandec.NewDecoder[string, S](tok, &jscandec.InitOptions{
+		DisallowStringTagOptOnUnsupportedTypes: true,
+	})
+	require.Equal(t, jscandec.ErrStringTagOptionOnUnsupportedType, err)
+}
+
+func TestDecodeMapStringToPtrStruct(t *testing.T) {
+	type S struct {
+		X int `json:"x"`
+	}
+	type M map[string]*S
 	s := newTestSetup[M](t, *jscandec.DefaultOptions)
 	s.TestOK(t, "empty", `{}`, M{})
 	s.TestOK(t, "null", `null`, M(nil))
-	s.TestOK(t, "one",
-		`{"x":{"data":[1,2,3]}}`, M{"x": S{Data: D{0: 1, 1: 2, 2: 3}}})
+	s.TestOK(t, "value_and_null",
+		`{"a":{"x":1},"b":null}`, M{"a": &S{X: 1}, "b": nil})
 	s.TestOK(t, "empty_struct",
-		`{"x":{}}`, M{"x": {}})
-	s.TestOK(t, "null_value",
-		`{"":null}`, M{"": {}})
-	s.TestOK(t, "escaped_key",
-		`{"\u30c4":{}}`, M{`ツ`: {}})
+		`{"a":{}}`, M{"a": &S{}})
 	s.TestOK(t, "multiple",
-		`{
-			"x":{"data":[0,1,2]},
-			"y":{"data":[3,4]}
-		}`, M{
-			"x": S{Data: D{0: 0, 1: 1, 2: 2}},
-			"y": S{Data: D{0: 3, 1: 4}},
-		})
+		`{"a":{"x":1},"b":{"x":2},"c":{"x":3}}`,
+		M{"a": &S{X: 1}, "b": &S{X: 2}, "c": &S{X: 3}})
 
-	s.TestOKPrepare(t, "overwrite_null", `null`, Test[M]{
-		PrepareJscan: func() M { return M{"a": S{Data: D{0}}, "b": {Data: D{0}}} },
-		Expect:       M(nil),
+	s.TestOKPrepare(t, "overwrite", `{"a":{"x":2},"b":null}`, Test[M]{
+		PrepareJscan: func() M {
+			return M{"a": {X: 1}, "b": {X: 1}, "c": {X: 1}}
+		},
+		Expect: M{"a": {X: 2}, "b": nil, "c": {X: 1}},
+	})
+	s.TestOKPrepare(t, "overwrite_fresh_pointer", `{"a":{}}`, Test[M]{
+		PrepareJscan: func() M { return M{"a": {X: 1}} },
+		Check: func(t *testing.T, vJscan M, vEncodingJson any) {
+			// Preexisting values must be replaced, not updated in place.
+			require.Equal(t, M{"a": {}}, vJscan)
+			require.Equal(t, *vEncodingJson.(*M), vJscan)
+		},
 	})
-	s.TestOKPrepare(t, "no_overwrite_empty", `{}`, Test[M]{
-		PrepareJscan: func() M { return M{"a": {Data: D{0}}, "b": {Data: D{1}}} },
-		Expect:       M{"a": {Data: D{0}}, "b": {Data: D{1}}},
+
+	s.testErr(t, "int", `1`, 0, jscandec.ErrUnexpectedValue)
+	s.testErr(t, "non_object_element", `{"x":42}`, 5, jscandec.ErrUnexpectedValue)
+}
+
+func TestDecodeSliceMapStringToInt(t *testing.T) {
+	type T = []map[string]int
+	s := newTestSetup[T](t, *jscandec.DefaultOptions)
+	s.TestOK(t, "empty", `[]`, T{})
+	s.TestOK(t, "null", `null`, T(nil))
+	s.TestOK(t, "empty_maps", `[{},{}]`, T{{}, {}})
+	s.TestOK(t, "null_element", `[{"a":1},null]`, T{{"a": 1}, nil})
+	s.TestOKPrepare(t, "separate_maps", `[{"a":1},{"b":2}]`, Test[T]{
+		Check: func(t *testing.T, vJscan T, vEncodingJson any) {
+			require.Equal(t, T{{"a": 1}, {"b": 2}}, vJscan)
+			require.NotContains(t, vJscan[1], "a")
+			require.Equal(t, *vEncodingJson.(*T), vJscan)
+		},
 	})
-	s.TestOKPrepare(t, "no_overwrite_new", `{"c":{"data":[1]}}`, Test[M]{
-		PrepareJscan: func() M { return M{"a": {Data: D{0}}, "b": {Data: D{0}}} },
-		Expect:       M{"a": {Data: D{0}}, "b": {Data: D{0}}, "c": {Data: D{1}}},
+	s.TestOKPrepare(t, "reuse", `[{"b":2},{"c":3},{"d":4}]`, Test[T]{
+		PrepareJscan: func() T { return T{{"a": 1}, nil} },
+		Check: func(t *testing.T, vJscan T, vEncodingJson any) {
+			// Existing maps are reused like in encoding/json.
+			require.Equal(t, T{{"a": 1, "b": 2}, {"c": 3}, {"d": 4}}, vJscan)
+			require.Equal(t, *vEncodingJson.(*T), vJscan)
+		},
 	})
-	s.TestOKPrepare(t, "overwrite_a_empty", `{"a": {}}`, Test[M]{
-		PrepareJscan: func() M { return M{"a": {Data: D{0}}, "b": {Data: D{1}}} },
-		Expect:       M{"a": {}, "b": {Data: D{1}}},
+	s.TestOKPrepare(t, "reuse_capacity", `[{"b":2},{"c":3}]`, Test[T]{
+		PrepareJscan: func() T {
+			v := make(T, 1, 4)
+			v[0] = map[string]int{"a": 1}
+			return v
+		},
+		Check: func(t *testing.T, vJscan T, vEncodingJson any) {
+			require.Equal(t, T{{"a": 1, "b": 2}, {"c": 3}}, vJscan)
+			require.Equal(t, *vEncodingJson.(*T), vJscan)
+		},
 	})
-	s.TestOKPrepare(t, "overwrite_a", `{"a": {"data":[9,9]}}`, Test[M]{
-		PrepareJscan: func() M {
-			return M{"a": {Data: D{0, 1, 2, 3, 4, 5}}, "b": {Data: D{1}}}
+
+	s.TestOKPrepare(t, "reuse_capacity_stale", `[{"b":2},{"c":3}]`, Test[T]{
+		PrepareJscan: func() T {
+			v := make(T, 2, 4)
+			v[0], v[1] = map[string]int{"a": 1}, map[string]int{"x": 9}
+			return v[:1]
 		},
-		Expect: M{"a": {Data: D{9, 9}}, "b": {Data: D{1}}},
 	})
 
-	s.testErr(t, "int", `1`, 0, jscandec.ErrUnexpectedValue)
-	s.testErr(t, "string", `"text"`, 0, jscandec.ErrUnexpectedValue)
-	s.testErr(t, "array", `[]`, 0, jscandec.ErrUnexpectedValue)
-	s.testErr(t, "non_array_element", `{"x":42}`, 5, jscandec.ErrUnexpectedValue)
+	s.testErr(t, "non_object_element", `[{},1]`, 4, jscandec.ErrUnexpectedValue)
+	s.testErr(t, "non_int_value", `[{"a":"1"}]`, 6, jscandec.ErrUnexpectedValue)
 }
 
-func TestDecodeJSONUnmarshaler(t *testing.T) {
-	s := newTestSetup[jsonUnmarshalerImpl](t, *jscandec.DefaultOptions)
-	s.TestOK(t, "integer", `123`, jsonUnmarshalerImpl{Value: `123`})
-	s.TestOK(t, "float", `3.14`, jsonUnmarshalerImpl{Value: `3.14`})
-	s.TestOK(t, "string", `"okay"`, jsonUnmarshalerImpl{Value: `"okay"`})
-	s.TestOK(t, "true", `true`, jsonUnmarshalerImpl{Value: `true`})
-	s.TestOK(t, "false", `false`, jsonUnmarshalerImpl{Value: `false`})
-	s.TestOK(t, "null", `null`, jsonUnmarshalerImpl{Value: `null`})
-	s.TestOK(t, "array_empty", `[]`, jsonUnmarshalerImpl{Value: `[]`})
-	s.TestOK(t, "array", `[1,"okay",true,{ }]`,
-		jsonUnmarshalerImpl{Value: `[1,"okay",true,{ }]`})
-	s.TestOK(t, "object_empty", `{}`, jsonUnmarshalerImpl{Value: `{}`})
-	s.TestOK(t, "object_empty", `{"foo":{"bar":"baz"}}`,
-		jsonUnmarshalerImpl{Value: `{"foo":{"bar":"baz"}}`})
+func TestDecodeRawMessage(t *testing.T) {
+	type S struct {
+		Raw json.RawMessage `json:"raw"`
+	}
+	s := newTestSetup[S](t, *jscandec.DefaultOptions)
+	s.TestOK(t, "null", `null`, S{})
+	s.TestOK(t, "object", `{"raw":{"x":[1, 2]}}`,
+		S{Raw: json.RawMessage(`{"x":[1, 2]}`)})
+	s.TestOK(t, "null_value", `{"raw":null}`, S{Raw: json.RawMessage(`null`)})
+	s.TestOK(t, "number", `{"raw":-1.5e3}`, S{Raw: json.RawMessage(`-1.5e3`)})
 }
 
-func TestDecodeTextUnmarshaler(t *testing.T) {
-	s := newTestSetup[textUnmarshalerImpl](t, *jscandec.DefaultOptions)
-	s.TestOK(t, "string", `"text"`, textUnmarshalerImpl{Value: `text`})
-	s.TestOK(t, "string_escaped", `"\"text\""`, textUnmarshalerImpl{Value: `"text"`})
-	s.TestOK(t, "null", `null`, textUnmarshalerImpl{Value: ``})
-	s.TestOK(t, "string_empty", `""`, textUnmarshalerImpl{Value: ``})
+func TestDecodeRawMessageNoCopy(t *testing.T) {
+	type S struct {
+		Raw json.RawMessage `json:"raw"`
+	}
+	const input = `{"raw": {"x":[1,2]} }`
+	start := strings.Index(input, `{"x"`)
+	optsNoCopy := &jscandec.DecodeOptions{RawMessageNoCopy: true}
+
+	newDecoder := func(t *testing.T) *jscandec.Decoder[[]byte, S] {
+		d, err := jscandec.NewDecoder[[]byte, S](
+			jscan.NewTokenizer[[]byte](16, 64), jscandec.DefaultInitOptions,
+		)
+		require.NoError(t, err)
+		return d
+	}
 
-	s.testErr(t, "int", `123`, 0, jscandec.ErrUnexpectedValue)
-	s.testErr(t, "float", `3.14`, 0, jscandec.ErrUnexpectedValue)
-	s.testErr(t, "true", `true`, 0, jscandec.ErrUnexpectedValue)
-	s.testErr(t, "false", `false`, 0, jscandec.ErrUnexpectedValue)
-	s.testErr(t, "true", `true`, 0, jscandec.ErrUnexpectedValue)
-	s.testErr(t, "array_empty", `[]`, 0, jscandec.ErrUnexpectedValue)
-	s.testErr(t, "array", `["foo"]`, 0, jscandec.ErrUnexpectedValue)
-	s.testErr(t, "object_empty", `{}`, 0, jscandec.ErrUnexpectedValue)
-	s.testErr(t, "object", `{"foo":"bar"}`, 0, jscandec.ErrUnexpectedValue)
-}
+	t.Run("bytes_alias", func(t *testing.T) {
+		in := []byte(input)
+		var v S
+		_, err := newDecoder(t).Decode(in, &v, optsNoCopy)
+		require.NoError(t, err)
+		require.Equal(t, `{"x":[1,2]}`, string(v.Raw))
+		require.Same(t, &in[start], &v.Raw[0])
+		require.Equal(t, len(v.Raw), cap(v.Raw), "capacity must be limited")
+	})
 
-func TestDecodeTextUnmarshalerMapKey(t *testing.T) {
-	type U = textUnmarshalerImpl
-	s := newTestSetup[map[U]int](t, *jscandec.DefaultOptions)
-	s.TestOK(t, "empty", `{}`, map[U]int{})
-	s.TestOK(t, "null", `null`, map[U]int(nil))
-	s.TestOK(t, "text", `{"text":1}`, map[U]int{{Value: "text"}: 1})
-	s.TestOK(t, "empty_key", `{"":2}`, map[U]int{{Value: ""}: 2})
-	s.TestOK(t, "escaped", `{"\"escaped\tkey\"":3}`,
-		map[U]int{{Value: "\"escaped\tkey\""}: 3})
+	t.Run("bytes_copy_by_default", func(t *testing.T) {
+		in := []byte(input)
+		var v S
+		_, err := newDecoder(t).Decode(in, &v, jscandec.DefaultOptions)
+		require.NoError(t, err)
+		require.Equal(t, `{"x":[1,2]}`, string(v.Raw))
+		require.NotSame(t, &in[start], &v.Raw[0])
+	})
+
+	t.Run("string_copy", func(t *testing.T) {
+		d, err := jscandec.NewDecoder[string, S](
+			jscan.NewTokenizer[string](16, 64), jscandec.DefaultInitOptions,
+		)
+		require.NoError(t, err)
+		var v S
+		_, err = d.Decode(input, &v, optsNoCopy)
+		require.NoError(t, err)
+		require.Equal(t, `{"x":[1,2]}`, string(v.Raw))
+		require.NotEqual(t,
+			unsafe.Pointer(unsafe.StringData(input[start:])),
+			unsafe.Pointer(&v.Raw[0]))
+	})
+
+	t.Run("no_alloc", func(t *testing.T) {
+		in := []byte(input)
+		d := newDecoder(t)
+		var v S
+		allocs := testing.AllocsPerRun(16, func() {
+			if _, err := d.Decode(in, &v, optsNoCopy); err != nil {
+				t.Fatal(err)
+			}
+		})
+		require.Zero(t, allocs)
+	})
+}
+
+func TestDecodeMapStringRawMessage(t *testing.T) {
+	type M = map[string]json.RawMessage
+	s := newTestSetup[M](t, *jscandec.DefaultOptions)
+	s.TestOK(t, "null", `null`, M(nil))
+	s.TestOK(t, "empty", `{}`, M{})
+	s.TestOK(t, "composite", `{"a":{"x":1},"b":[1,2]}`, M{
+		"a": json.RawMessage(`{"x":1}`),
+		"b": json.RawMessage(`[1,2]`),
+	})
+	s.TestOK(t, "scalars", `{"s":"\u0041","n":-1.5e3,"t":true,"z":null}`, M{
+		"s": json.RawMessage(`"\u0041"`),
+		"n": json.RawMessage(`-1.5e3`),
+		"t": json.RawMessage(`true`),
+		"z": json.RawMessage(`null`),
+	})
+	s.TestOK(t, "whitespace", `{ "a" : { "x" : [ 1 ] } , "\u0062" : 2 }`, M{
+		"a": json.RawMessage(`{ "x" : [ 1 ] }`),
+		"b": json.RawMessage(`2`),
+	})
+	s.testErr(t, "wrong_type", `[]`, 0, jscandec.ErrUnexpectedValue)
+
+	t.Run("struct_field", func(t *testing.T) {
+		type S struct {
+			Fields M `json:"fields"`
+		}
+		s := newTestSetup[S](t, *jscandec.DefaultOptions)
+		s.TestOK(t, "object", `{"fields":{"a":{"b":[{}]}}}`,
+			S{Fields: M{"a": json.RawMessage(`{"b":[{}]}`)}})
+	})
+
+	t.Run("no_copy", func(t *testing.T) {
+		d, err := jscandec.NewDecoder[[]byte, M](
+			jscan.NewTokenizer[[]byte](16, 64), jscandec.DefaultInitOptions,
+		)
+		require.NoError(t, err)
+		in := []byte(`{"a":{"x":1},"b":[1,2]}`)
+		var v M
+		_, err = d.Decode(in, &v, &jscandec.DecodeOptions{RawMessageNoCopy: true})
+		require.NoError(t, err)
+		require.Same(t, &in[5], &v["a"][0])
+		require.Same(t, &in[17], &v["b"][0])
+		require.Equal(t, len(v["b"]), cap(v["b"]), "capacity must be limited")
+
+		_, err = d.Decode(in, &v, jscandec.DefaultOptions)
+		require.NoError(t, err)
+		require.Equal(t, `{"x":1}`, string(v["a"]))
+		require.NotSame(t, &in[5], &v["a"][0])
+	})
+
+	t.Run("merge_patch", func(t *testing.T) {
+		d, err := jscandec.NewDecoder[string, M](
+			jscan.NewTokenizer[string](16, 64), jscandec.DefaultInitOptions,
+		)
+		require.NoError(t, err)
+		v := M{"a": json.RawMessage(`1`), "b": json.RawMessage(`2`)}
+		_, err = d.ApplyMergePatch(`{"a":null,"c":[3]}`, &v, jscandec.DefaultOptions)
+		require.NoError(t, err)
+		require.Equal(t, M{
+			"b": json.RawMessage(`2`), "c": json.RawMessage(`[3]`),
+		}, v)
+	})
+}
+
+func TestDecodeFieldOffsets(t *testing.T) {
+	type Item struct {
+		ID   int    `json:"id"`
+		Name string `json:"name"`
+	}
+	type Entry struct {
+		ID int `json:"id"`
+	}
+	type Named struct {
+		Name string `json:"name"`
+	}
+	type S struct {
+		Title   string           `json:"title"`
+		Items   []Item           `json:"items"`
+		ByKey   map[string]Entry `json:"by/key"`
+		Ptr     *Named           `json:"ptr"`
+		Tilde   int              `json:"a~b"`
+		Ignored int              `json:"-"`
+	}
+	const input = `{
+		"title": "T",
+		"unknown": {"id": 404},
+		"items": [
+			{"id": 1, "name": "first"},
+			{"ID": 2}
+		],
+		"by/key": {"k": {"id": 3}},
+		"ptr": {"name": "ptr"},
+		"a~b": 4
+	}`
+	index := func(substr string) int {
+		i := strings.Index(input, substr)
+		require.NotEqual(t, -1, i, substr)
+		return i
+	}
+	expect := map[string]int{
+		"/title":        index(`"T"`),
+		"/items":        index(`[`),
+		"/items/0/id":   index(`1,`),
+		"/items/0/name": index(`"first"`),
+		"/items/1/ID":   index(`2}`),
+		"/by~1key":      index(`{"k"`),
+		"/by~1key/k/id": index(`3}`),
+		"/ptr":          index(`{"name": "ptr"}`),
+		"/ptr/name":     index(`"ptr"}`),
+		"/a~0b":         index(": 4\n") + 2,
+	}
+
+	for _, inputType := range []string{"string", "bytes"} {
+		t.Run(inputType, func(t *testing.T) {
+			var offsets map[string]int
+			opts := &jscandec.DecodeOptions{FieldOffsets: &offsets}
+			var v S
+			var err error
+			if inputType == "string" {
+				d, errInit := jscandec.NewDecoder[string, S](
+					jscan.NewTokenizer[string](16, 64), jscandec.DefaultInitOptions,
+				)
+				require.NoError(t, errInit)
+				_, err = d.Decode(input, &v, opts)
+			} else {
+				d, errInit := jscandec.NewDecoder[[]byte, S](
+					jscan.NewTokenizer[[]byte](16, 64), jscandec.DefaultInitOptions,
+				)
+				require.NoError(t, errInit)
+				_, err = d.Decode([]byte(input), &v, opts)
+			}
+			require.NoError(t, err)
+			require.Equal(t, expect, offsets)
+		})
+	}
+
+	t.Run("rewritten_input", func(t *testing.T) {
+		type S struct{ A, B int }
+		const input = `{"A":007,"B":8}`
+		d, err := jscandec.NewDecoder[string, S](
+			jscan.NewTokenizer[string](16, 64), jscandec.DefaultInitOptions,
+		)
+		require.NoError(t, err)
+		offsets := map[string]int{"/existing": 1}
+		var v S
+		_, err = d.Decode(input, &v, &jscandec.DecodeOptions{
+			AllowLeadingZeros: true,
+			FieldOffsets:      &offsets,
+		})
+		require.NoError(t, err)
+		require.Equal(t, S{A: 7, B: 8}, v)
+		require.Equal(t, map[string]int{"/existing": 1, "/A": 5, "/B": 13}, offsets)
+	})
+}
+
+func TestDecodeOnFieldDecoded(t *testing.T) {
+	type Item struct {
+		ID   int    `json:"id"`
+		Name string `json:"name"`
+	}
+	type Named struct {
+		Name string `json:"name"`
+	}
+	type S struct {
+		Title   string  `json:"title"`
+		Items   []Item  `json:"items"`
+		Ptr     *Named  `json:"ptr"`
+		Absent  float64 `json:"absent"`
+		Ignored int     `json:"ignored"`
+	}
+	type Call struct {
+		Path string
+		Kind jscandec.ExpectType
+	}
+	decode := func(t *testing.T, input string, opts jscandec.DecodeOptions) ([]Call, error) {
+		d, err := jscandec.NewDecoder[string, S](
+			jscan.NewTokenizer[string](16, 64), jscandec.DefaultInitOptions,
+		)
+		require.NoError(t, err)
+		var calls []Call
+		opts.OnFieldDecoded = func(path string, kind jscandec.ExpectType) {
+			calls = append(calls, Call{Path: path, Kind: kind})
+		}
+		var v S
+		_, err = d.Decode(input, &v, &opts)
+		return calls, err
+	}
+
+	t.Run("present", func(t *testing.T) {
+		calls, err := decode(t, `{
+			"title": "T",
+			"unknown": {"id": 404},
+			"items": [{"id": 1, "name": "first"}, {"ID": 2}],
+			"ptr": {"name": "ptr"},
+			"ignored": 5
+		}`, jscandec.DecodeOptions{IgnoreFields: map[string]bool{"ignored": true}})
+		require.NoError(t, err)
+		require.Equal(t, []Call{
+			{Path: "/title", Kind: jscandec.ExpectTypeStr},
+			{Path: "/items", Kind: jscandec.ExpectTypeSlice},
+			{Path: "/items/0/id", Kind: jscandec.ExpectTypeInt},
+			{Path: "/items/0/name", Kind: jscandec.ExpectTypeStr},
+			{Path: "/items/1/ID", Kind: jscandec.ExpectTypeInt},
+			{Path: "/ptr", Kind: jscandec.ExpectTypePtr},
+			{Path: "/ptr/name", Kind: jscandec.ExpectTypeStr},
+		}, calls)
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		calls, err := decode(t, `{}`, jscandec.DecodeOptions{})
+		require.NoError(t, err)
+		require.Nil(t, calls)
+	})
+
+	t.Run("duplicate_first", func(t *testing.T) {
+		calls, err := decode(t, `{"title":"a","title":"b"}`, jscandec.DecodeOptions{
+			DuplicateKeyStrategy: jscandec.DuplicateKeyFirst,
+		})
+		require.NoError(t, err)
+		require.Equal(t, []Call{{Path: "/title", Kind: jscandec.ExpectTypeStr}}, calls)
+	})
+
+	t.Run("error", func(t *testing.T) {
+		calls, err := decode(t, `{"title":"T","absent":"x"}`, jscandec.DecodeOptions{})
+		require.ErrorIs(t, err, jscandec.ErrUnexpectedValue)
+		require.Nil(t, calls)
+	})
+}
+
+func TestDecodeMergeDuplicateKeysIntoSlice(t *testing.T) {
+	type M = map[string][]string
+	t.Run("disabled", func(t *testing.T) {
+		s := newTestSetup[M](t, *jscandec.DefaultOptions)
+		s.TestOK(t, "last_wins", `{"a":["1"],"a":["2"]}`, M{"a": {"2"}})
+	})
+
+	opts := jscandec.DecodeOptions{MergeDuplicateKeysIntoSlice: true}
+	s := newTestSetup[M](t, opts)
+	s.testOKNonstandard(t, "merge", `{"a":["1"],"a":["2"]}`, M{"a": {"1", "2"}})
+	s.testOKNonstandard(t, "merge_multiple", `{"a":["1"],"b":["x"],"a":[],"a":["2","3"]}`,
+		M{"a": {"1", "2", "3"}, "b": {"x"}})
+	s.testOKNonstandard(t, "merge_null", `{"a":["1"],"a":null}`, M{"a": {"1"}})
+	s.testOKNonstandard(t, "no_duplicates", `{"a":["1"],"b":["2"]}`, M{"a": {"1"}, "b": {"2"}})
+	s.TestOKPrepare(t, "existing_value", `{"a":["2"]}`, Test[M]{
+		PrepareJscan: func() M { return M{"a": {"1"}} },
+		Check: func(t *testing.T, vJscan M, vEncodingJson any) {
+			require.Equal(t, M{"a": {"1", "2"}}, vJscan)
+		},
+	})
+
+	t.Run("generic_slice", func(t *testing.T) {
+		type S struct{ X int }
+		type M = map[string][]S
+		s := newTestSetup[M](t, opts)
+		s.testOKNonstandard(t, "merge", `{"a":[{"X":1}],"a":[{"X":2},{"X":3}]}`,
+			M{"a": {{X: 1}, {X: 2}, {X: 3}}})
+	})
+
+	t.Run("nested", func(t *testing.T) {
+		type M = map[string][]map[string][]int
+		s := newTestSetup[M](t, opts)
+		s.testOKNonstandard(t, "merge",
+			`{"a":[{"x":[1],"x":[2]}],"a":[{"y":[3]}]}`,
+			M{"a": {{"x": {1, 2}}, {"y": {3}}}})
+	})
+
+	t.Run("non_slice_values", func(t *testing.T) {
+		s := newTestSetup[map[string]int](t, opts)
+		s.TestOK(t, "last_wins", `{"a":1,"a":2}`, map[string]int{"a": 2})
+	})
+}
+
+func TestDecodeMapStringStruct512(t *testing.T) {
+	type D [512]byte
+	type S struct{ Data D }
+	type M map[string]S
+	s := newTestSetup[M](t, *jscandec.DefaultOptions)
+	s.TestOK(t, "empty", `{}`, M{})
+	s.TestOK(t, "null", `null`, M(nil))
+	s.TestOK(t, "one",
+		`{"x":{"data":[1,2,3]}}`, M{"x": S{Data: D{0: 1, 1: 2, 2: 3}}})
+	s.TestOK(t, "empty_struct",
+		`{"x":{}}`, M{"x": {}})
+	s.TestOK(t, "null_value",
+		`{"":null}`, M{"": {}})
+	s.TestOK(t, "escaped_key",
+		`{"\u30c4":{}}`, M{`ツ`: {}})
+	s.TestOK(t, "multiple",
+		`{
+			"x":{"data":[0,1,2]},
+			"y":{"data":[3,4]}
+		}`, M{
+			"x": S{Data: D{0: 0, 1: 1, 2: 2}},
+			"y": S{Data: D{0: 3, 1: 4}},
+		})
+
+	s.TestOKPrepare(t, "overwrite_null", `null`, Test[M]{
+		PrepareJscan: func() M { return M{"a": S{Data: D{0}}, "b": {Data: D{0}}} },
+		Expect:       M(nil),
+	})
+	s.TestOKPrepare(t, "no_overwrite_empty", `{}`, Test[M]{
+		PrepareJscan: func() M { return M{"a": {Data: D{0}}, "b": {Data: D{1}}} },
+		Expect:       M{"a": {Data: D{0}}, "b": {Data: D{1}}},
+	})
+	s.TestOKPrepare(t, "no_overwrite_new", `{"c":{"data":[1]}}`, Test[M]{
+		PrepareJscan: func() M { return M{"a": {Data: D{0}}, "b": {Data: D{0}}} },
+		Expect:       M{"a": {Data: D{0}}, "b": {Data: D{0}}, "c": {Data: D{1}}},
+	})
+	s.TestOKPrepare(t, "overwrite_a_empty", `{"a": {}}`, Test[M]{
+		PrepareJscan: func() M { return M{"a": {Data: D{0}}, "b": {Data: D{1}}} },
+		Expect:       M{"a": {}, "b": {Data: D{1}}},
+	})
+	s.TestOKPrepare(t, "overwrite_a", `{"a": {"data":[9,9]}}`, Test[M]{
+		PrepareJscan: func() M {
+			return M{"a": {Data: D{0, 1, 2, 3, 4, 5}}, "b": {Data: D{1}}}
+		},
+		Expect: M{"a": {Data: D{9, 9}}, "b": {Data: D{1}}},
+	})
+
+	s.testErr(t, "int", `1`, 0, jscandec.ErrUnexpectedValue)
+	s.testErr(t, "string", `"text"`, 0, jscandec.ErrUnexpectedValue)
+	s.testErr(t, "array", `[]`, 0, jscandec.ErrUnexpectedValue)
+	s.testErr(t, "non_array_element", `{"x":42}`, 5, jscandec.ErrUnexpectedValue)
+}
+
+func TestDecodeJSONUnmarshaler(t *testing.T) {
+	s := newTestSetup[jsonUnmarshalerImpl](t, *jscandec.DefaultOptions)
+	s.TestOK(t, "integer", `123`, jsonUnmarshalerImpl{Value: `123`})
+	s.TestOK(t, "float", `3.14`, jsonUnmarshalerImpl{Value: `3.14`})
+	s.TestOK(t, "string", `"okay"`, jsonUnmarshalerImpl{Value: `"okay"`})
+	s.TestOK(t, "true", `true`, jsonUnmarshalerImpl{Value: `true`})
+	s.TestOK(t, "false", `false`, jsonUnmarshalerImpl{Value: `false`})
+	s.TestOK(t, "null", `null`, jsonUnmarshalerImpl{Value: `null`})
+	s.TestOK(t, "array_empty", `[]`, jsonUnmarshalerImpl{Value: `[]`})
+	s.TestOK(t, "array", `[1,"okay",true,{ }]`,
+		jsonUnmarshalerImpl{Value: `[1,"okay",true,{ }]`})
+	s.TestOK(t, "object_empty", `{}`, jsonUnmarshalerImpl{Value: `{}`})
+	s.TestOK(t, "object_empty", `{"foo":{"bar":"baz"}}`,
+		jsonUnmarshalerImpl{Value: `{"foo":{"bar":"baz"}}`})
+}
+
+func TestDecodeArrayJSONUnmarshaler(t *testing.T) {
+	type A = [3]jsonUnmarshalerImpl
+	prepare := func() A {
+		return A{{Value: "x"}, {Value: "y"}, {Value: "z"}}
+	}
+	s := newTestSetup[A](t, *jscandec.DefaultOptions)
+	s.TestOKPrepare(t, "trailing_zeroed", `[1,2]`, Test[A]{
+		PrepareJscan: prepare,
+		Expect:       A{{Value: `1`}, {Value: `2`}, {}},
+	})
+	s.TestOKPrepare(t, "overflow_ignored", `[1,2,3,4]`, Test[A]{
+		PrepareJscan: prepare,
+		Expect:       A{{Value: `1`}, {Value: `2`}, {Value: `3`}},
+	})
+	s.TestOKPrepare(t, "empty", `[]`, Test[A]{
+		PrepareJscan: prepare,
+		Expect:       A{},
+	})
+	s.TestOKPrepare(t, "null", `null`, Test[A]{
+		PrepareJscan: prepare,
+		Expect:       prepare(),
+	})
+	s.TestOK(t, "composite", `[[1, 2],{"a":1},"x"]`,
+		A{{Value: `[1, 2]`}, {Value: `{"a":1}`}, {Value: `"x"`}})
+}
+
+func TestDecodeTextUnmarshaler(t *testing.T) {
+	s := newTestSetup[textUnmarshalerImpl](t, *jscandec.DefaultOptions)
+	s.TestOK(t, "string", `"text"`, textUnmarshalerImpl{Value: `text`})
+	s.TestOK(t, "string_escaped", `"\"text\""`, textUnmarshalerImpl{Value: `"text"`})
+	s.TestOK(t, "null", `null`, textUnmarshalerImpl{Value: ``})
+	s.TestOK(t, "string_empty", `""`, textUnmarshalerImpl{Value: ``})
 
 	s.testErr(t, "int", `123`, 0, jscandec.ErrUnexpectedValue)
 	s.testErr(t, "float", `3.14`, 0, jscandec.ErrUnexpectedValue)
@@ -3368,202 +5647,4053 @@ func TestDecodeTextUnmarshalerMapKey(t *testing.T) {
 	s.testErr(t, "true", `true`, 0, jscandec.ErrUnexpectedValue)
 	s.testErr(t, "array_empty", `[]`, 0, jscandec.ErrUnexpectedValue)
 	s.testErr(t, "array", `["foo"]`, 0, jscandec.ErrUnexpectedValue)
-	s.testErr(t, "object", `{"foo":"bar"}`, 7, jscandec.ErrUnexpectedValue)
+	s.testErr(t, "object_empty", `{}`, 0, jscandec.ErrUnexpectedValue)
+	s.testErr(t, "object", `{"foo":"bar"}`, 0, jscandec.ErrUnexpectedValue)
 }
 
-func TestDecodeUnmarshalerFields(t *testing.T) {
+func TestDecodeTextUnmarshalerAcceptsScalars(t *testing.T) {
+	type U = textUnmarshalerImpl
+	options := jscandec.DecodeOptions{TextUnmarshalerAcceptsScalars: true}
+	s := newTestSetup[U](t, options)
+	s.testOKNonstandard(t, "int", `42`, U{Value: "42"})
+	s.testOKNonstandard(t, "negative", `-42`, U{Value: "-42"})
+	s.testOKNonstandard(t, "float", `3.14e2`, U{Value: "3.14e2"})
+	s.testOKNonstandard(t, "true", `true`, U{Value: "true"})
+	s.testOKNonstandard(t, "false", `false`, U{Value: "false"})
+	s.TestOK(t, "string", `"42"`, U{Value: "42"})
+	s.TestOK(t, "null", `null`, U{})
+	s.testErr(t, "array", `[42]`, 0, jscandec.ErrUnexpectedValue)
+	s.testErr(t, "object", `{}`, 0, jscandec.ErrUnexpectedValue)
+
+	t.Run("struct", func(t *testing.T) {
+		type S struct {
+			ID  U  `json:"id"`
+			Ptr *U `json:"ptr"`
+		}
+		s := newTestSetup[S](t, options)
+		s.testOKNonstandard(t, "scalars", `{"id":42,"ptr":true}`,
+			S{ID: U{Value: "42"}, Ptr: &U{Value: "true"}})
+	})
+
+	t.Run("slice", func(t *testing.T) {
+		s := newTestSetup[[]U](t, options)
+		s.testOKNonstandard(t, "mixed", `["a",1,false,null]`,
+			[]U{{Value: "a"}, {Value: "1"}, {Value: "false"}, {}})
+	})
+
+	t.Run("err", func(t *testing.T) {
+		s := newTestSetup[textUnmarshalerImplErr](t, options)
+		s.testErrNonstandard(t, "int", `42`, 0, errTextUnmarshalerImpl)
+		ss := newTestSetup[[]textUnmarshalerImplErr](t, options)
+		ss.testErrNonstandard(t, "slice", `[1]`, 1, errTextUnmarshalerImpl)
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		s := newTestSetup[[]U](t, *jscandec.DefaultOptions)
+		s.testErr(t, "int", `[42]`, 1, jscandec.ErrUnexpectedValue)
+		s.testErr(t, "true", `[true]`, 1, jscandec.ErrUnexpectedValue)
+	})
+}
+
+func TestDecodeSliceTextUnmarshaler(t *testing.T) {
+	t.Run("pointer_receiver", func(t *testing.T) {
+		s := newTestSetup[[]textUnmarshalerImpl](t, *jscandec.DefaultOptions)
+		s.TestOK(t, "empty", `[]`, []textUnmarshalerImpl{})
+		s.TestOK(t, "null", `null`, []textUnmarshalerImpl(nil))
+		s.TestOK(t, "values", `["a","\"b\"",null,""]`, []textUnmarshalerImpl{
+			{Value: "a"}, {Value: `"b"`}, {}, {},
+		})
+		s.TestOKPrepare(t, "reuse", `["x","y"]`, Test[[]textUnmarshalerImpl]{
+			PrepareJscan: func() []textUnmarshalerImpl {
+				return []textUnmarshalerImpl{{"1"}, {"2"}, {"3"}}
+			},
+			Expect: []textUnmarshalerImpl{{"x"}, {"y"}},
+		})
+
+		s.testErr(t, "int", `["a",1]`, 5, jscandec.ErrUnexpectedValue)
+		s.testErr(t, "array", `["a",[]]`, 5, jscandec.ErrUnexpectedValue)
+		s.testErr(t, "object", `[{}]`, 1, jscandec.ErrUnexpectedValue)
+	})
+
+	t.Run("value_receiver", func(t *testing.T) {
+		s := newTestSetup[[]textUnmarshalerValueReceiver](t, *jscandec.DefaultOptions)
+		s.TestOK(t, "values", `["a","b"]`, []textUnmarshalerValueReceiver{{}, {}})
+		s.testErr(t, "err", `["a","!b"]`, 5, errTextUnmarshalerImpl)
+	})
+
+	t.Run("array", func(t *testing.T) {
+		s := newTestSetup[[]textUnmarshalerArray](t, *jscandec.DefaultOptions)
+		s.TestOK(t, "values", `["1.2.3.4","255.0.0.1"]`, []textUnmarshalerArray{
+			{1, 2, 3, 4}, {255, 0, 0, 1},
+		})
+		s.testErr(t, "err", `["1.2.3.4","1.2.3"]`, 11, errTextUnmarshalerImpl)
+	})
+
+	t.Run("net.IP", func(t *testing.T) {
+		type S struct {
+			IPs []net.IP `json:"ips"`
+		}
+		s := newTestSetup[S](t, *jscandec.DefaultOptions)
+		s.TestOK(t, "values", `{"ips":["127.0.0.1","::1",null]}`, S{
+			IPs: []net.IP{net.ParseIP("127.0.0.1"), net.ParseIP("::1"), nil},
+		})
+		s.testErrCheck(t, "invalid", `{"ips":["127.0.0.1","x"]}`,
+			func(t *testing.T, errIndex int, err error) {
+				require.Error(t, err)
+				require.Equal(t, 20, errIndex)
+			})
+	})
+}
+
+func TestDecodeSliceJSONUnmarshaler(t *testing.T) {
+	s := newTestSetup[[]jsonUnmarshalerImpl](t, *jscandec.DefaultOptions)
+	s.TestOK(t, "empty", `[]`, []jsonUnmarshalerImpl{})
+	s.TestOK(t, "null", `null`, []jsonUnmarshalerImpl(nil))
+	s.TestOK(t, "objects", `[{"a": [1,2]}, {}, { "b" : {"c":null} }]`,
+		[]jsonUnmarshalerImpl{
+			{Value: `{"a": [1,2]}`}, {Value: `{}`}, {Value: `{ "b" : {"c":null} }`},
+		})
+	s.TestOK(t, "arrays", `[[1, [2]], [], [{"x":"y"}]]`,
+		[]jsonUnmarshalerImpl{
+			{Value: `[1, [2]]`}, {Value: `[]`}, {Value: `[{"x":"y"}]`},
+		})
+	s.TestOK(t, "scalars", `[42, -1.5e3, "str", true, false, null]`,
+		[]jsonUnmarshalerImpl{
+			{Value: `42`}, {Value: `-1.5e3`}, {Value: `"str"`},
+			{Value: `true`}, {Value: `false`}, {Value: `null`},
+		})
+	s.TestOKPrepare(t, "reuse", `["x"]`, Test[[]jsonUnmarshalerImpl]{
+		PrepareJscan: func() []jsonUnmarshalerImpl {
+			return []jsonUnmarshalerImpl{{"1"}, {"2"}}
+		},
+		Expect: []jsonUnmarshalerImpl{{`"x"`}},
+	})
+
+	t.Run("err", func(t *testing.T) {
+		s := newTestSetup[[]unmarshalerImplErr](t, *jscandec.DefaultOptions)
+		s.testErr(t, "first", `[{"x":1}]`, 1, errUnmarshalerImpl)
+	})
+
+	t.Run("raw_message_no_copy", func(t *testing.T) {
+		d, err := jscandec.NewDecoder[[]byte, []json.RawMessage](
+			jscan.NewTokenizer[[]byte](16, 64), jscandec.DefaultInitOptions,
+		)
+		require.NoError(t, err)
+		in := []byte(`[1, {"a" : 2}]`)
+		var v []json.RawMessage
+		_, err = d.Decode(in, &v, &jscandec.DecodeOptions{RawMessageNoCopy: true})
+		require.NoError(t, err)
+		require.Equal(t, []json.RawMessage{
+			json.RawMessage(`1`), json.RawMessage(`{"a" : 2}`),
+		}, v)
+		require.Same(t, &in[1], &v[0][0])
+		require.Same(t, &in[4], &v[1][0])
+		require.Equal(t, len(v[1]), cap(v[1]), "capacity must be limited")
+	})
+}
+
+func TestDecodePointerUnmarshaler(t *testing.T) {
 	type S struct {
-		String string              `json:"string"`
-		JSON   jsonUnmarshalerImpl `json:"json"`
-		Text   textUnmarshalerImpl `json:"text"`
-		Tail   []int               `json:"tail"`
+		JSON *jsonUnmarshalerImpl `json:"json"`
+		Text *textUnmarshalerImpl `json:"text"`
+		Raw  *json.RawMessage     `json:"raw"`
 	}
 	s := newTestSetup[S](t, *jscandec.DefaultOptions)
-	s.TestOK(t, "integer",
-		`{"string":"a","json":42,"text":"foo","tail":[1,2]}`,
-		S{
-			String: "a",
-			JSON:   jsonUnmarshalerImpl{Value: `42`},
-			Text:   textUnmarshalerImpl{Value: "foo"},
-			Tail:   []int{1, 2},
+	s.TestOK(t, "empty", `{}`, S{})
+	s.TestOK(t, "values", `{"json":[1, 2],"text":"foo","raw":"text"}`, S{
+		JSON: &jsonUnmarshalerImpl{Value: `[1, 2]`},
+		Text: &textUnmarshalerImpl{Value: `foo`},
+		Raw:  Ptr(json.RawMessage(`"text"`)),
+	})
+	s.TestOKPrepare(t, "null", `{"json":null,"text":null,"raw":null}`, Test[S]{
+		PrepareJscan: func() S {
+			return S{JSON: &jsonUnmarshalerImpl{}, Text: &textUnmarshalerImpl{}}
+		},
+		Expect: S{},
+	})
+}
+
+func TestDecodePointerToJSONUnmarshaler(t *testing.T) {
+	type U = jsonUnmarshalerImpl
+
+	t.Run("pointer", func(t *testing.T) {
+		s := newTestSetup[*U](t, *jscandec.DefaultOptions)
+		s.TestOK(t, "object", `{"a":1}`, &U{Value: `{"a":1}`})
+		s.TestOK(t, "string", `"x"`, &U{Value: `"x"`})
+		s.TestOK(t, "null", `null`, (*U)(nil))
+		// UnmarshalJSON must not be called on null.
+		s.TestOKPrepare(t, "null_reset", `null`, Test[*U]{
+			PrepareJscan: func() *U { return &U{Value: "x"} },
+			Expect:       (*U)(nil),
 		})
-	s.TestOK(t, "string", `{
-		"string":"b",
-		"json":"\"text\"",
-		"text":"\"text\"",
-		"tail":[1,2]}`,
-		S{
-			String: "b",
-			JSON:   jsonUnmarshalerImpl{Value: `"\"text\""`},
-			Text:   textUnmarshalerImpl{Value: `"text"`},
-			Tail:   []int{1, 2},
+	})
+
+	t.Run("fields", func(t *testing.T) {
+		// Multiple fields of the same unmarshaler type must not be
+		// mistaken for a recursive struct type.
+		type S struct {
+			P *U   `json:"p"`
+			Q *U   `json:"q"`
+			L []*U `json:"l"`
+		}
+		s := newTestSetup[S](t, *jscandec.DefaultOptions)
+		s.TestOK(t, "object", `{"p":{"a":1}}`, S{P: &U{Value: `{"a":1}`}})
+		s.TestOK(t, "string", `{"q":"x"}`, S{Q: &U{Value: `"x"`}})
+		s.TestOK(t, "all", `{"p":[1],"q":{},"l":[null,"y"]}`, S{
+			P: &U{Value: `[1]`},
+			Q: &U{Value: `{}`},
+			L: []*U{nil, {Value: `"y"`}},
+		})
+		s.TestOKPrepare(t, "null", `{"p":null,"q":null,"l":[null]}`, Test[S]{
+			PrepareJscan: func() S { return S{P: &U{Value: "x"}, Q: &U{Value: "y"}} },
+			Expect:       S{L: []*U{nil}},
+		})
+	})
+}
+
+func TestDecodeTextUnmarshalerMapKey(t *testing.T) {
+	type U = textUnmarshalerImpl
+	s := newTestSetup[map[U]int](t, *jscandec.DefaultOptions)
+	s.TestOK(t, "empty", `{}`, map[U]int{})
+	s.TestOK(t, "null", `null`, map[U]int(nil))
+	s.TestOK(t, "text", `{"text":1}`, map[U]int{{Value: "text"}: 1})
+	s.TestOK(t, "empty_key", `{"":2}`, map[U]int{{Value: ""}: 2})
+	s.TestOK(t, "escaped", `{"\"escaped\tkey\"":3}`,
+		map[U]int{{Value: "\"escaped\tkey\""}: 3})
+
+	s.testErr(t, "int", `123`, 0, jscandec.ErrUnexpectedValue)
+	s.testErr(t, "float", `3.14`, 0, jscandec.ErrUnexpectedValue)
+	s.testErr(t, "true", `true`, 0, jscandec.ErrUnexpectedValue)
+	s.testErr(t, "false", `false`, 0, jscandec.ErrUnexpectedValue)
+	s.testErr(t, "true", `true`, 0, jscandec.ErrUnexpectedValue)
+	s.testErr(t, "array_empty", `[]`, 0, jscandec.ErrUnexpectedValue)
+	s.testErr(t, "array", `["foo"]`, 0, jscandec.ErrUnexpectedValue)
+	s.testErr(t, "object", `{"foo":"bar"}`, 7, jscandec.ErrUnexpectedValue)
+}
+
+func TestDecodeTextUnmarshalerMapKeyReceivers(t *testing.T) {
+	const input = `{"a":1,"b":2}`
+
+	t.Run("pointer_receiver", func(t *testing.T) {
+		type K = textUnmarshalerImpl
+		s := newTestSetup[map[K]int](t, *jscandec.DefaultOptions)
+		s.TestOK(t, "text", input, map[K]int{{Value: "a"}: 1, {Value: "b"}: 2})
+	})
+
+	t.Run("value_receiver", func(t *testing.T) {
+		// Like in encoding/json, the key is unmarshaled through a pointer
+		// but a value receiver can't modify it, which leaves all keys zero.
+		type K = textUnmarshalerValueReceiver
+		s := newTestSetup[map[K]int](t, *jscandec.DefaultOptions)
+		s.TestOK(t, "text", input, map[K]int{{}: 2})
+		s.testErr(t, "err", `{"a":1,"!b":2}`, 7, errTextUnmarshalerImpl)
+	})
+
+	t.Run("array", func(t *testing.T) {
+		type K = textUnmarshalerArray
+		s := newTestSetup[map[K]int](t, *jscandec.DefaultOptions)
+		s.TestOK(t, "text", `{"1.2.3.4":1,"255.0.0.1":2}`,
+			map[K]int{{1, 2, 3, 4}: 1, {255, 0, 0, 1}: 2})
+		s.testErr(t, "err", `{"1.2.3":1}`, 1, errTextUnmarshalerImpl)
+	})
+
+}
+
+func TestDecodeUnmarshalerFields(t *testing.T) {
+	type S struct {
+		String string              `json:"string"`
+		JSON   jsonUnmarshalerImpl `json:"json"`
+		Text   textUnmarshalerImpl `json:"text"`
+		Tail   []int               `json:"tail"`
+	}
+	s := newTestSetup[S](t, *jscandec.DefaultOptions)
+	s.TestOK(t, "integer",
+		`{"string":"a","json":42,"text":"foo","tail":[1,2]}`,
+		S{
+			String: "a",
+			JSON:   jsonUnmarshalerImpl{Value: `42`},
+			Text:   textUnmarshalerImpl{Value: "foo"},
+			Tail:   []int{1, 2},
+		})
+	s.TestOK(t, "string", `{
+		"string":"b",
+		"json":"\"text\"",
+		"text":"\"text\"",
+		"tail":[1,2]}`,
+		S{
+			String: "b",
+			JSON:   jsonUnmarshalerImpl{Value: `"\"text\""`},
+			Text:   textUnmarshalerImpl{Value: `"text"`},
+			Tail:   []int{1, 2},
+		})
+	s.TestOK(t, "array", `{"string":"c","json":[1,2, 3],"text":"","tail":[1,2]}`,
+		S{
+			String: "c",
+			JSON:   jsonUnmarshalerImpl{Value: `[1,2, 3]`},
+			Text:   textUnmarshalerImpl{Value: ""},
+			Tail:   []int{1, 2},
+		})
+	s.TestOK(t, "object", `{"string":"d","json":{"foo":["bar", null]},"tail":[1,2]}`,
+		S{
+			String: "d",
+			JSON:   jsonUnmarshalerImpl{Value: `{"foo":["bar", null]}`},
+			Text:   textUnmarshalerImpl{Value: ""},
+			Tail:   []int{1, 2},
+		})
+}
+
+func TestDecodeJSONUnmarshalerErr(t *testing.T) {
+	s := newTestSetup[unmarshalerImplErr](t, *jscandec.DefaultOptions)
+	s.testErrCheck(t, "integer", `123`, func(t *testing.T, errIndex int, err error) {
+		require.Equal(t, errUnmarshalerImpl, err)
+	})
+
+	s2 := newTestSetup[map[textUnmarshalerImplErr]struct{}](t, *jscandec.DefaultOptions)
+	s2.testErrCheck(t, "map", `{"x":"y"}`, func(t *testing.T, errIndex int, err error) {
+		require.Equal(t, errTextUnmarshalerImpl, err)
+	})
+
+	type S struct{ Unmarshaler textUnmarshalerImplErr }
+	s3 := newTestSetup[S](t, *jscandec.DefaultOptions)
+	s3.testErrCheck(t, "struct_field", `{"Unmarshaler":"abc"}`,
+		func(t *testing.T, errIndex int, err error) {
+			require.Equal(t, errTextUnmarshalerImpl, err)
+		})
+}
+
+func TestDecodeSyntaxErrorUnexpectedEOF(t *testing.T) {
+	tokenizerString := jscan.NewTokenizer[string](16, 1024)
+	d, err := jscandec.NewDecoder[string, []int](
+		tokenizerString, jscandec.DefaultInitOptions,
+	)
+	require.NoError(t, err)
+	var v []int
+	_, err = d.Decode(`[1,2,3`, &v, jscandec.DefaultOptions)
+	var jscanErr jscan.Error[string]
+	require.True(t, errors.As(err, &jscanErr))
+	require.Equal(t, jscan.ErrorCodeUnexpectedEOF, jscanErr.Code)
+}
+
+func TestSyntaxErrorCode(t *testing.T) {
+	t.Run("string", func(t *testing.T) {
+		tok := jscan.NewTokenizer[string](16, 1024)
+		d, err := jscandec.NewDecoder[string, []int](tok, jscandec.DefaultInitOptions)
+		require.NoError(t, err)
+		var v []int
+		_, err = d.Decode(`[1,2,3`, &v, jscandec.DefaultOptions)
+		code, ok := jscandec.SyntaxErrorCode(err)
+		require.True(t, ok)
+		require.Equal(t, jscan.ErrorCodeUnexpectedEOF, code)
+	})
+
+	t.Run("bytes", func(t *testing.T) {
+		tok := jscan.NewTokenizer[[]byte](16, 1024)
+		d, err := jscandec.NewDecoder[[]byte, []int](tok, jscandec.DefaultInitOptions)
+		require.NoError(t, err)
+		var v []int
+		_, err = d.Decode([]byte(`[1,2,3`), &v, jscandec.DefaultOptions)
+		code, ok := jscandec.SyntaxErrorCode(err)
+		require.True(t, ok)
+		require.Equal(t, jscan.ErrorCodeUnexpectedEOF, code)
+	})
+
+	t.Run("semantic", func(t *testing.T) {
+		tok := jscan.NewTokenizer[string](16, 1024)
+		d, err := jscandec.NewDecoder[string, []int](tok, jscandec.DefaultInitOptions)
+		require.NoError(t, err)
+		var v []int
+		_, err = d.Decode(`[1,"2",3]`, &v, jscandec.DefaultOptions)
+		require.Equal(t, jscandec.ErrUnexpectedValue, err)
+		_, ok := jscandec.SyntaxErrorCode(err)
+		require.False(t, ok)
+	})
+
+	t.Run("nil", func(t *testing.T) {
+		_, ok := jscandec.SyntaxErrorCode(nil)
+		require.False(t, ok)
+	})
+}
+
+func TestDecodeErrorsIs(t *testing.T) {
+	type T struct {
+		Count int8 `json:"count"`
+	}
+	tok := jscan.NewTokenizer[string](16, 1024)
+	d, err := jscandec.NewDecoder[string, T](tok, jscandec.DefaultInitOptions)
+	require.NoError(t, err)
+
+	t.Run("integer_overflow", func(t *testing.T) {
+		var v T
+		_, err := d.Decode(`{"count":128}`, &v, jscandec.DefaultOptions)
+		require.True(t, errors.Is(err, jscandec.ErrIntegerOverflow))
+	})
+
+	t.Run("unknown_field", func(t *testing.T) {
+		var v T
+		_, err := d.Decode(`{"foo":1}`, &v, &jscandec.DecodeOptions{
+			DisallowUnknownFields: true,
+		})
+		require.True(t, errors.Is(err, jscandec.ErrUnknownField))
+	})
+
+	t.Run("syntax", func(t *testing.T) {
+		var v T
+		_, err := d.Decode(`{"count":1`, &v, jscandec.DefaultOptions)
+		var errTok jscan.Error[string]
+		require.True(t, errors.As(err, &errTok))
+		require.Equal(t, jscan.ErrorCodeUnexpectedEOF, errTok.Code)
+		require.False(t, errors.Is(err, jscandec.ErrUnexpectedValue))
+	})
+}
+
+func BenchmarkSmall(b *testing.B) {
+	in := []byte(`[[true],[false,false,false,false],[],[],[true]]`) // 18 tokens
+
+	b.Run("jscan", func(b *testing.B) {
+		tok := jscan.NewTokenizer[[]byte](8, 64)
+		d, err := jscandec.NewDecoder[[]byte, [][]bool](tok, jscandec.DefaultInitOptions)
+		if err != nil {
+			b.Fatalf("initializing decoder: %v", err)
+		}
+		b.ResetTimer()
+		for n := 0; n < b.N; n++ {
+			var v [][]bool
+			if _, err := d.Decode(in, &v, jscandec.DefaultOptions); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("jsoniter", func(b *testing.B) {
+		for n := 0; n < b.N; n++ {
+			var v [][]bool
+			if err := jsoniter.Unmarshal(in, &v); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkDecodeMatrix16Float64(b *testing.B) {
+	var in []byte
+	{
+		var m [16][16]float64
+		for i := range m {
+			for j := range m[i] {
+				m[i][j] = float64(i*16+j) * 1.25
+			}
+		}
+		var err error
+		if in, err = json.Marshal(m); err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	b.Run("jscan/array", func(b *testing.B) {
+		tok := jscan.NewTokenizer[[]byte](8, 512)
+		d, err := jscandec.NewDecoder[[]byte, [16][16]float64](
+			tok, jscandec.DefaultInitOptions,
+		)
+		if err != nil {
+			b.Fatalf("initializing decoder: %v", err)
+		}
+		b.ResetTimer()
+		for n := 0; n < b.N; n++ {
+			var v [16][16]float64
+			if _, err := d.Decode(in, &v, jscandec.DefaultOptions); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("jscan/slice", func(b *testing.B) {
+		tok := jscan.NewTokenizer[[]byte](8, 512)
+		d, err := jscandec.NewDecoder[[]byte, [][]float64](
+			tok, jscandec.DefaultInitOptions,
+		)
+		if err != nil {
+			b.Fatalf("initializing decoder: %v", err)
+		}
+		b.ResetTimer()
+		for n := 0; n < b.N; n++ {
+			var v [][]float64
+			if _, err := d.Decode(in, &v, jscandec.DefaultOptions); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("encoding_json", func(b *testing.B) {
+		for n := 0; n < b.N; n++ {
+			var v [16][16]float64
+			if err := json.Unmarshal(in, &v); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkDecodeSliceFloat64(b *testing.B) {
+	for _, bd := range []struct {
+		name  string
+		input func() []byte
+	}{
+		{"array_dec_1024_10k", func() []byte { return []byte(arrayFloat1024) }},
+		{"10m", func() []byte {
+			if testing.Short() {
+				b.Skip("skipping 10M element array in short mode")
+			}
+			const elements = 10_000_000
+			in := make([]byte, 0, elements*16)
+			in = append(in, '[')
+			for i := 0; i < elements; i++ {
+				if i > 0 {
+					in = append(in, ',')
+				}
+				in = strconv.AppendFloat(in, float64(i)*0.001-5000, 'f', -1, 64)
+			}
+			return append(in, ']')
+		}},
+	} {
+		b.Run(bd.name, func(b *testing.B) {
+			in := bd.input()
+			b.Run("jscan", func(b *testing.B) {
+				tok := jscan.NewTokenizer[[]byte](8, 1024)
+				d, err := jscandec.NewDecoder[[]byte, []float64](
+					tok, jscandec.DefaultInitOptions,
+				)
+				if err != nil {
+					b.Fatalf("initializing decoder: %v", err)
+				}
+				b.SetBytes(int64(len(in)))
+				b.ResetTimer()
+				for n := 0; n < b.N; n++ {
+					var v []float64
+					if _, err := d.Decode(in, &v, jscandec.DefaultOptions); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+			b.Run("encoding_json", func(b *testing.B) {
+				b.SetBytes(int64(len(in)))
+				for n := 0; n < b.N; n++ {
+					var v []float64
+					if err := json.Unmarshal(in, &v); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		})
+	}
+}
+
+func BenchmarkFastFloatParsing(b *testing.B) {
+	in := []byte(arrayFloat1024)
+	for _, bd := range []struct {
+		name string
+		opts *jscandec.InitOptions
+	}{
+		{"default", jscandec.DefaultInitOptions},
+		{"fast", &jscandec.InitOptions{FastFloatParsing: true}},
+	} {
+		b.Run(bd.name, func(b *testing.B) {
+			tok := jscan.NewTokenizer[[]byte](8, 1024)
+			d, err := jscandec.NewDecoder[[]byte, []float64](tok, bd.opts)
+			if err != nil {
+				b.Fatalf("initializing decoder: %v", err)
+			}
+			b.SetBytes(int64(len(in)))
+			b.ResetTimer()
+			for n := 0; n < b.N; n++ {
+				var v []float64
+				if _, err := d.Decode(in, &v, jscandec.DefaultOptions); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkPrecomputeExactOnly(b *testing.B) {
+	type S struct {
+		ID, Name, Email, Phone, Street, City, Zip, Country string
+	}
+	// Unknown fields require a second pass over the fields
+	// unless the case-insensitive fallback is disabled.
+	in := []byte(`{"ID":"1","Name":"n","Email":"e","Phone":"p",` +
+		`"unknown1":1,"unknown2":2,"unknown3":3,"unknown4":4,` +
+		`"Street":"s","City":"c","Zip":"z","Country":"c"}`)
+
+	for _, bd := range []struct {
+		name string
+		opts *jscandec.InitOptions
+	}{
+		{"default", jscandec.DefaultInitOptions},
+		{"exact_only", &jscandec.InitOptions{PrecomputeExactOnly: true}},
+	} {
+		b.Run(bd.name, func(b *testing.B) {
+			tok := jscan.NewTokenizer[[]byte](8, 64)
+			d, err := jscandec.NewDecoder[[]byte, S](tok, bd.opts)
+			if err != nil {
+				b.Fatalf("initializing decoder: %v", err)
+			}
+			b.ResetTimer()
+			for n := 0; n < b.N; n++ {
+				var v S
+				if _, err := d.Decode(in, &v, jscandec.DefaultOptions); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkMapCapacityFactor(b *testing.B) {
+	const entries = 50_000
+	in := []byte{'{'}
+	for i := 0; i < entries; i++ {
+		if i > 0 {
+			in = append(in, ',')
+		}
+		in = append(in, `"k`...)
+		in = strconv.AppendInt(in, int64(i), 10)
+		in = append(in, `":`...)
+		in = strconv.AppendInt(in, int64(i), 10)
+	}
+	in = append(in, '}')
+
+	for _, factor := range []float64{1, 1.5} {
+		b.Run(fmt.Sprintf("factor_%g", factor), func(b *testing.B) {
+			tok := jscan.NewTokenizer[[]byte](8, entries*2+2)
+			d, err := jscandec.NewDecoder[[]byte, map[string]int](
+				tok, jscandec.DefaultInitOptions,
+			)
+			if err != nil {
+				b.Fatalf("initializing decoder: %v", err)
+			}
+			opts := &jscandec.DecodeOptions{MapCapacityFactor: factor}
+			b.ReportAllocs()
+			b.ResetTimer()
+			for n := 0; n < b.N; n++ {
+				var v map[string]int
+				if _, err := d.Decode(in, &v, opts); err != nil {
+					b.Fatal(err)
+				}
+				// Grow the map by half of its size after decoding,
+				// which requires rehashing unless the map was over-allocated.
+				for i := 0; i < entries/2; i++ {
+					v[strconv.Itoa(-i)] = i
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkDecodeNestedMap(b *testing.B) {
+	// 8 keys per level, 4096 values in total.
+	type M map[string]map[string]map[string]map[string]int
+	v := M{}
+	for i := 0; i < 8; i++ {
+		l1 := map[string]map[string]map[string]int{}
+		for j := 0; j < 8; j++ {
+			l2 := map[string]map[string]int{}
+			for k := 0; k < 8; k++ {
+				l3 := map[string]int{}
+				for l := 0; l < 8; l++ {
+					l3[fmt.Sprintf("key_%d", l)] = i*512 + j*64 + k*8 + l
+				}
+				l2[fmt.Sprintf("key_%d", k)] = l3
+			}
+			l1[fmt.Sprintf("key_%d", j)] = l2
+		}
+		v[fmt.Sprintf("key_%d", i)] = l1
+	}
+	in, err := json.Marshal(v)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.Run("jscan", func(b *testing.B) {
+		tok := jscan.NewTokenizer[[]byte](8, 16*1024)
+		d, err := jscandec.NewDecoder[[]byte, M](tok, jscandec.DefaultInitOptions)
+		if err != nil {
+			b.Fatalf("initializing decoder: %v", err)
+		}
+		b.SetBytes(int64(len(in)))
+		b.ReportAllocs()
+		b.ResetTimer()
+		for n := 0; n < b.N; n++ {
+			var v M
+			if _, err := d.Decode(in, &v, jscandec.DefaultOptions); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("encoding_json", func(b *testing.B) {
+		b.SetBytes(int64(len(in)))
+		b.ReportAllocs()
+		for n := 0; n < b.N; n++ {
+			var v M
+			if err := json.Unmarshal(in, &v); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkDecodeMapStringRawMessage(b *testing.B) {
+	type M map[string]json.RawMessage
+	v := map[string]any{}
+	for i := 0; i < 256; i++ {
+		v[fmt.Sprintf("key_%d", i)] = map[string]any{
+			"id": i, "tags": []string{"a", "b"}, "name": fmt.Sprintf("name_%d", i),
+		}
+	}
+	in, err := json.Marshal(v)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.Run("jscan", func(b *testing.B) {
+		tok := jscan.NewTokenizer[[]byte](8, 16*1024)
+		d, err := jscandec.NewDecoder[[]byte, M](tok, jscandec.DefaultInitOptions)
+		if err != nil {
+			b.Fatalf("initializing decoder: %v", err)
+		}
+		b.SetBytes(int64(len(in)))
+		b.ReportAllocs()
+		b.ResetTimer()
+		for n := 0; n < b.N; n++ {
+			var v M
+			if _, err := d.Decode(in, &v, jscandec.DefaultOptions); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("jscan_generic", func(b *testing.B) {
+		// A pointer value type bypasses the fast path.
+		type M map[string]*json.RawMessage
+		tok := jscan.NewTokenizer[[]byte](8, 16*1024)
+		d, err := jscandec.NewDecoder[[]byte, M](tok, jscandec.DefaultInitOptions)
+		if err != nil {
+			b.Fatalf("initializing decoder: %v", err)
+		}
+		b.SetBytes(int64(len(in)))
+		b.ReportAllocs()
+		b.ResetTimer()
+		for n := 0; n < b.N; n++ {
+			var v M
+			if _, err := d.Decode(in, &v, jscandec.DefaultOptions); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("encoding_json", func(b *testing.B) {
+		b.SetBytes(int64(len(in)))
+		b.ReportAllocs()
+		for n := 0; n < b.N; n++ {
+			var v M
+			if err := json.Unmarshal(in, &v); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func skipIfNot64bitSystem(t *testing.T) {
+	if uintptr(8) != unsafe.Sizeof(int(0)) {
+		t.Skip("this test must run on a 64-bit system")
+	}
+}
+
+// jsonUnmarshalerImpl implements encoding/json.Unmarshaler.
+type jsonUnmarshalerImpl struct{ Value string }
+
+func (impl *jsonUnmarshalerImpl) UnmarshalJSON(data []byte) error {
+	impl.Value = string(data)
+	return nil
+}
+
+// unmarshalerImplErr implements encoding/json.Unmarshaler and always returns an error.
+type unmarshalerImplErr struct{ Value string }
+
+func (impl *unmarshalerImplErr) UnmarshalJSON(data []byte) error {
+	return errUnmarshalerImpl
+}
+
+var errUnmarshalerImpl = errors.New("unmarshalerImplErr test error")
+
+// textUnmarshalerImpl implements encoding/json.Unmarshaler.
+type textUnmarshalerImpl struct{ Value string }
+
+func (impl *textUnmarshalerImpl) UnmarshalText(text []byte) error {
+	impl.Value = string(text)
+	return nil
+}
+
+// textUnmarshalerValueReceiver implements encoding.TextUnmarshaler
+// with a value receiver, which can validate but not modify the value.
+type textUnmarshalerValueReceiver struct{ Value string }
+
+func (impl textUnmarshalerValueReceiver) UnmarshalText(text []byte) error {
+	if len(text) > 0 && text[0] == '!' {
+		return errTextUnmarshalerImpl
+	}
+	return nil
+}
+
+// textUnmarshalerArray implements encoding.TextUnmarshaler
+// parsing dot-separated decimal bytes like "1.2.3.4".
+type textUnmarshalerArray [4]byte
+
+func (impl *textUnmarshalerArray) UnmarshalText(text []byte) error {
+	parts := strings.Split(string(text), ".")
+	if len(parts) != len(impl) {
+		return errTextUnmarshalerImpl
+	}
+	for i, p := range parts {
+		v, err := strconv.ParseUint(p, 10, 8)
+		if err != nil {
+			return errTextUnmarshalerImpl
+		}
+		impl[i] = byte(v)
+	}
+	return nil
+}
+
+// textUnmarshalerImplErr implements encoding/json.Unmarshaler.
+type textUnmarshalerImplErr struct{ Value string }
+
+func (impl *textUnmarshalerImplErr) UnmarshalText(text []byte) error {
+	return errTextUnmarshalerImpl
+}
+
+var errTextUnmarshalerImpl = errors.New("textUnmarshalerImplErr test error")
+
+func TestErrStringTagOptionOnUnsupportedType(t *testing.T) {
+	type S struct {
+		//lint:ignore SA5008 the JSON string option is used intentionally
+		Unsupported map[string]string `json:",string"` //nolint:staticcheck
+	}
+
+	testIn := `{"unsupported":{"not":"okay"}}`
+	var v S
+	require.NoError(t, json.Unmarshal([]byte(testIn), &v))
+
+	t.Run("check_disabled", func(t *testing.T) {
+		tok := jscan.NewTokenizer[string](1, 1)
+		dec, err := jscandec.NewDecoder[string, S](tok, jscandec.DefaultInitOptions)
+		require.NoError(t, err)
+		require.NotNil(t, dec)
+		var v S
+		_, err = dec.Decode(testIn, &v, jscandec.DefaultOptions)
+		require.NoError(t, err)
+	})
+
+	t.Run("err", func(t *testing.T) {
+		tok := jscan.NewTokenizer[string](1, 1)
+		opt := *jscandec.DefaultInitOptions
+		opt.DisallowStringTagOptOnUnsupportedTypes = true
+		dec, err := jscandec.NewDecoder[string, S](tok, &opt)
+		require.Equal(t, err, jscandec.ErrStringTagOptionOnUnsupportedType)
+		require.Nil(t, dec)
+	})
+}
+
+func TestErrUnsupportedType(t *testing.T) {
+	type StructChan struct{ C chan int }
+	type StructUintptr struct {
+		P uintptr `json:"p"`
+	}
+	type Addr uintptr
+	testErrUnsupportedType[chan int](t, "chan int")
+	testErrUnsupportedType[func()](t, "func()")
+	testErrUnsupportedType[uintptr](t, "uintptr")
+	testErrUnsupportedType[StructUintptr](t, "uintptr")
+	testErrUnsupportedType[Addr](t, "Addr of kind uintptr")
+	testErrUnsupportedType[[]Addr](t, "Addr of kind uintptr")
+	testErrUnsupportedType[map[string]*uintptr](t, "uintptr")
+	testErrUnsupportedType[unsafe.Pointer](t, "unsafe.Pointer")
+	testErrUnsupportedType[complex128](t, "complex128")
+	testErrUnsupportedType[io.Reader](t, "io.Reader")
+	testErrUnsupportedType[[]func()](t, "func()")
+	testErrUnsupportedType[[2]chan int](t, "chan int")
+	testErrUnsupportedType[map[string]chan int](t, "chan int")
+	testErrUnsupportedType[*chan int](t, "chan int")
+	testErrUnsupportedType[StructChan](t, "chan int")
+	testErrUnsupportedType[map[float64]int](t, "map key float64")
+	testErrUnsupportedType[map[bool]int](t, "map key bool")
+	testErrUnsupportedType[map[*string]int](t, "map key *string")
+}
+
+func TestErrUnsupportedInterface(t *testing.T) {
+	type S struct {
+		Name string    `json:"name"`
+		X    io.Reader `json:"x"`
+	}
+	type Nested struct {
+		Items []map[string]S `json:"items"`
+	}
+	for _, td := range []struct {
+		name string
+		init func() error
+	}{
+		{"io.Reader", func() error {
+			_, err := jscandec.NewDecoder[string, io.Reader](
+				jscan.NewTokenizer[string](1, 1), jscandec.DefaultInitOptions,
+			)
+			return err
+		}},
+		{"struct_field", func() error {
+			_, err := jscandec.NewDecoder[string, S](
+				jscan.NewTokenizer[string](1, 1), jscandec.DefaultInitOptions,
+			)
+			return err
+		}},
+		{"nested", func() error {
+			_, err := jscandec.NewDecoder[[]byte, Nested](
+				jscan.NewTokenizer[[]byte](1, 1), jscandec.DefaultInitOptions,
+			)
+			return err
+		}},
+		{"unmarshal", func() error {
+			var v S
+			return jscandec.Unmarshal(`{"name":"n","x":null}`, &v)
+		}},
+	} {
+		t.Run(td.name, func(t *testing.T) {
+			err := td.init()
+			require.ErrorIs(t, err, jscandec.ErrUnsupportedInterface)
+			require.ErrorIs(t, err, jscandec.ErrUnsupportedType)
+			require.Contains(t, err.Error(), "io.Reader")
+		})
+	}
+}
+
+type testShape interface{ Area() float64 }
+
+type testCircle struct {
+	R float64 `json:"r"`
+}
+
+func (c testCircle) Area() float64 { return math.Pi * c.R * c.R }
+
+// testNode implements testShape through its pointer type only.
+type testNode struct {
+	Name  string    `json:"name"`
+	Inner testShape `json:"inner"`
+}
+
+func (n *testNode) Area() float64 { return 0 }
+
+func TestDecodeConcreteTypes(t *testing.T) {
+	tpShape := reflect.TypeOf((*testShape)(nil)).Elem()
+	initOptions := func(concrete reflect.Type) *jscandec.InitOptions {
+		return &jscandec.InitOptions{
+			ConcreteTypes: map[reflect.Type]reflect.Type{tpShape: concrete},
+		}
+	}
+	// encoding/json doesn't support decoding into non-empty interfaces.
+
+	t.Run("map_value", func(t *testing.T) {
+		type M = map[string]testShape
+		s := newTestSetupInit[M](
+			t, initOptions(reflect.TypeOf(testCircle{})), *jscandec.DefaultOptions,
+		)
+		s.testOKNonstandard(t, "two", `{"a":{"r":1},"b":{"r":2}}`,
+			M{"a": testCircle{R: 1}, "b": testCircle{R: 2}})
+		s.testOKNonstandard(t, "null", `{"a":null,"b":{"r":2}}`,
+			M{"a": nil, "b": testCircle{R: 2}})
+		s.testOKNonstandard(t, "empty_object", `{"a":{}}`, M{"a": testCircle{}})
+		s.testErrNonstandard(t, "wrong_type", `{"a":{"r":"1"}}`,
+			10, jscandec.ErrUnexpectedValue)
+		s.testErrNonstandard(t, "string", `{"a":"circle"}`,
+			5, jscandec.ErrUnexpectedValue)
+	})
+
+	t.Run("map_value_ptr", func(t *testing.T) {
+		type M = map[string]testShape
+		s := newTestSetupInit[M](
+			t, initOptions(reflect.TypeOf(&testCircle{})), *jscandec.DefaultOptions,
+		)
+		s.testOKNonstandard(t, "two", `{"a":{"r":1},"b":{"r":2}}`,
+			M{"a": &testCircle{R: 1}, "b": &testCircle{R: 2}})
+		s.testOKNonstandard(t, "null", `{"a":{"r":1},"b":null}`,
+			M{"a": &testCircle{R: 1}, "b": nil})
+	})
+
+	t.Run("overwrite_map_value", func(t *testing.T) {
+		type M = map[string]testShape
+		d, err := jscandec.NewDecoder[string, M](
+			jscan.NewTokenizer[string](16, 1024),
+			initOptions(reflect.TypeOf(&testCircle{})),
+		)
+		require.NoError(t, err)
+		v := M{"a": testCircle{R: 9}, "b": &testCircle{R: 8}}
+		_, err = d.Decode(`{"a":{"r":1},"c":null}`, &v, jscandec.DefaultOptions)
+		require.NoError(t, err)
+		runtime.GC() // Make sure GC is happy
+		require.Equal(t, M{"a": &testCircle{R: 1}, "b": &testCircle{R: 8}, "c": nil}, v)
+	})
+
+	t.Run("slice", func(t *testing.T) {
+		s := newTestSetupInit[[]testShape](
+			t, initOptions(reflect.TypeOf(testCircle{})), *jscandec.DefaultOptions,
+		)
+		s.testOKNonstandard(t, "mixed", `[{"r":1},null,{"r":3}]`,
+			[]testShape{testCircle{R: 1}, nil, testCircle{R: 3}})
+	})
+
+	t.Run("recursive", func(t *testing.T) {
+		s := newTestSetupInit[testShape](
+			t, initOptions(reflect.TypeOf(&testNode{})), *jscandec.DefaultOptions,
+		)
+		s.testOKNonstandard(t, "3_level",
+			`{"name":"a","inner":{"name":"b","inner":{"name":"c","inner":null}}}`,
+			&testNode{Name: "a", Inner: &testNode{
+				Name: "b", Inner: &testNode{Name: "c"},
+			}})
+	})
+
+	t.Run("embedded", func(t *testing.T) {
+		// Like encoding/json, an embedded interface is a regular field
+		// named after its type, its fields and methods aren't promoted.
+		type Shape = testShape
+		type S struct {
+			Shape
+			Name string `json:"name"`
+		}
+		s := newTestSetupInit[S](
+			t, initOptions(reflect.TypeOf(testCircle{})), *jscandec.DefaultOptions,
+		)
+		s.testOKNonstandard(t, "field", `{"Shape":{"r":3},"name":"x"}`,
+			S{Shape: testCircle{R: 3}, Name: "x"})
+		s.testOKNonstandard(t, "case_insensitive", `{"shape":{"r":3}}`,
+			S{Shape: testCircle{R: 3}})
+		s.testOKNonstandard(t, "not_promoted", `{"r":3}`, S{})
+		s.testOKNonstandard(t, "null", `{"Shape":null}`, S{})
+
+		d, err := jscandec.NewDecoder[string, S](
+			jscan.NewTokenizer[string](16, 1024),
+			initOptions(reflect.TypeOf(&testCircle{})),
+		)
+		require.NoError(t, err)
+		var v S
+		_, err = d.Decode(`{"Shape":{"r":3}}`, &v, jscandec.DefaultOptions)
+		require.NoError(t, err)
+		require.Equal(t, 3.0, v.Shape.(*testCircle).R)
+		require.Equal(t, math.Pi*9, v.Area()) // Promoted method.
+	})
+
+	t.Run("embedded_tagged", func(t *testing.T) {
+		type Shape = testShape
+		type S struct {
+			Shape `json:"shape"`
+		}
+		s := newTestSetupInit[S](
+			t, initOptions(reflect.TypeOf(testCircle{})), *jscandec.DefaultOptions,
+		)
+		s.testOKNonstandard(t, "field", `{"shape":{"r":3}}`, S{Shape: testCircle{R: 3}})
+	})
+
+	t.Run("embedded_unexported", func(t *testing.T) {
+		// Unexported embedded interfaces are ignored and need
+		// no registered concrete type.
+		type S struct {
+			testShape
+			R float64 `json:"r"`
+		}
+		s := newTestSetup[S](t, *jscandec.DefaultOptions)
+		s.TestOK(t, "field", `{"r":3,"testShape":{"r":1}}`, S{R: 3})
+	})
+
+	for _, td := range []struct {
+		name     string
+		concrete reflect.Type
+	}{
+		{"not_implemented", reflect.TypeOf(0)},
+		{"value_of_ptr_receiver", reflect.TypeOf(testNode{})},
+		{"pointer_shaped", reflect.TypeOf(map[string]int{})},
+	} {
+		t.Run("err_"+td.name, func(t *testing.T) {
+			d, err := jscandec.NewDecoder[string, testShape](
+				jscan.NewTokenizer[string](1, 1), initOptions(td.concrete),
+			)
+			require.ErrorIs(t, err, jscandec.ErrUnsupportedType)
+			require.Nil(t, d)
+		})
+	}
+}
+
+func TestDecodeFallbackTagKeys(t *testing.T) {
+	type T struct {
+		F       int    `db:"f_id"`
+		JSON    int    `json:"json" db:"json_db"`
+		Options string `json:",omitempty" db:"opts,pk"`
+		Second  int    `db:"-" yaml:"second"`
+		Ignored int    `json:"-" db:"ignored"`
+		Plain   int
+	}
+	initOptions := &jscandec.InitOptions{FallbackTagKeys: []string{"db", "yaml"}}
+	// encoding/json only considers the json struct tag.
+	s := newTestSetupInit[T](t, initOptions, *jscandec.DefaultOptions)
+	s.testOKNonstandard(t, "fallback", `{"f_id":5}`, T{F: 5})
+	s.testOKNonstandard(t, "json_priority", `{"json":1,"json_db":2}`, T{JSON: 1})
+	s.testOKNonstandard(t, "json_without_name", `{"opts":"x"}`, T{Options: "x"})
+	s.testOKNonstandard(t, "second_fallback", `{"second":3}`, T{Second: 3})
+	s.testOKNonstandard(t, "json_ignored", `{"ignored":4,"Ignored":4}`, T{})
+	s.testOKNonstandard(t, "field_name", `{"Plain":6,"F":7}`, T{Plain: 6})
+
+	t.Run("disabled", func(t *testing.T) {
+		s := newTestSetup[T](t, *jscandec.DefaultOptions)
+		s.TestOK(t, "field_name", `{"f_id":5,"F":7}`, T{F: 7})
+	})
+}
+
+func TestDecodeRequireField(t *testing.T) {
+	type T struct {
+		V    string `json:"v"`
+		ID   int    `json:"id"`
+		Tags []int  `json:"tags"`
+	}
+	initOptions := &jscandec.InitOptions{
+		RequireField: jscandec.RequiredField{Name: "v", Value: "2"},
+	}
+	s := newTestSetupInit[T](t, initOptions, *jscandec.DefaultOptions)
+	s.TestOK(t, "match", `{"v":"2","id":1}`, T{V: "2", ID: 1})
+	s.TestOK(t, "match_last", `{"tags":[1,2],"x":{"v":"1"},"id":1,"v":"2"}`,
+		T{V: "2", ID: 1, Tags: []int{1, 2}})
+	s.TestOK(t, "match_escaped", `{"\u0076":"\u0032"}`, T{V: "2"})
+	s.testErrNonstandard(t, "nested", `{"x":{"v":"2"}}`, 0, jscandec.ErrSchemaMismatch)
+	s.testErrNonstandard(t, "other_value", `{"v":"1","id":1}`,
+		0, jscandec.ErrSchemaMismatch)
+	s.testErrNonstandard(t, "missing", ` {"id":1}`, 1, jscandec.ErrSchemaMismatch)
+	s.testErrNonstandard(t, "non_string", `{"v":2}`, 0, jscandec.ErrSchemaMismatch)
+	s.testErrNonstandard(t, "null", `null`, 0, jscandec.ErrSchemaMismatch)
+
+	t.Run("disabled", func(t *testing.T) {
+		s := newTestSetup[T](t, *jscandec.DefaultOptions)
+		s.TestOK(t, "missing", `{"id":1}`, T{ID: 1})
+	})
+}
+
+type testIntSet map[int]struct{}
+
+func TestDecodeContainerFactories(t *testing.T) {
+	type factory = func(elems int) (unsafe.Pointer, func(unsafe.Pointer))
+	newIntSet := func(elems int) (unsafe.Pointer, func(unsafe.Pointer)) {
+		s := make(testIntSet, elems)
+		return unsafe.Pointer(&s), func(e unsafe.Pointer) {
+			s[*(*int)(e)] = struct{}{}
+		}
+	}
+	initOptions := &jscandec.InitOptions{
+		ContainerFactories: map[reflect.Type]factory{
+			reflect.TypeOf(testIntSet(nil)): newIntSet,
+		},
+	}
+	// encoding/json can't decode arrays into maps.
+
+	t.Run("set", func(t *testing.T) {
+		s := newTestSetupInit[testIntSet](t, initOptions, *jscandec.DefaultOptions)
+		s.testOKNonstandard(t, "deduplicated", `[1,2,2,3]`,
+			testIntSet{1: {}, 2: {}, 3: {}})
+		s.testOKNonstandard(t, "empty", `[]`, testIntSet{})
+		s.testOKNonstandard(t, "null", `null`, nil)
+		s.testOKNonstandard(t, "null_element", `[1,null]`, testIntSet{0: {}, 1: {}})
+		s.testErrNonstandard(t, "wrong_element", `[1,"2"]`,
+			3, jscandec.ErrUnexpectedValue)
+		s.testErrNonstandard(t, "object", `{}`, 0, jscandec.ErrUnexpectedValue)
+	})
+
+	t.Run("nested", func(t *testing.T) {
+		type S struct {
+			Name string       `json:"name"`
+			IDs  testIntSet   `json:"ids"`
+			More []testIntSet `json:"more"`
+		}
+		s := newTestSetupInit[S](t, initOptions, *jscandec.DefaultOptions)
+		s.testOKNonstandard(t, "fields",
+			`{"ids":[3,1,3],"more":[[],[7],null],"name":"x"}`, S{
+				Name: "x",
+				IDs:  testIntSet{1: {}, 3: {}},
+				More: []testIntSet{{}, {7: {}}, nil},
+			})
+	})
+
+	t.Run("composite_elements", func(t *testing.T) {
+		type testStruct struct{ Values []string }
+		type E struct {
+			ID   int      `json:"id"`
+			Tags []string `json:"tags"`
+		}
+		var added []E
+		initOptions := &jscandec.InitOptions{
+			ContainerFactories: map[reflect.Type]factory{
+				reflect.TypeOf(testStruct{}): func(elems int) (
+					unsafe.Pointer, func(unsafe.Pointer),
+				) {
+					return nil, nil
+				},
+				reflect.TypeOf([]E(nil)): func(elems int) (
+					unsafe.Pointer, func(unsafe.Pointer),
+				) {
+					s := make([]E, 0, elems)
+					return unsafe.Pointer(&s), func(e unsafe.Pointer) {
+						// Reverse the order of the elements.
+						s = append([]E{*(*E)(e)}, s...)
+						added = append(added, *(*E)(e))
+					}
+				},
+			},
+		}
+		// Only map, slice and array types can be containers.
+		_, err := jscandec.NewDecoder[string, testStruct](
+			jscan.NewTokenizer[string](16, 1024), initOptions,
+		)
+		require.ErrorIs(t, err, jscandec.ErrUnsupportedType)
+
+		d, err := jscandec.NewDecoder[string, []E](
+			jscan.NewTokenizer[string](16, 1024), initOptions,
+		)
+		require.NoError(t, err)
+		var v []E
+		_, err = d.Decode(
+			`[{"id":1,"tags":["a"]},{"id":2},null]`, &v, jscandec.DefaultOptions,
+		)
+		require.NoError(t, err)
+		runtime.GC() // Make sure GC is happy
+		// Elements are zeroed before they're decoded.
+		require.Equal(t, []E{{}, {ID: 2}, {ID: 1, Tags: []string{"a"}}}, v)
+		require.Equal(t, []E{{ID: 1, Tags: []string{"a"}}, {ID: 2}, {}}, added)
+	})
+
+	t.Run("recursive", func(t *testing.T) {
+		type N struct {
+			Children []N `json:"children"`
+		}
+		_, err := jscandec.NewDecoder[string, N](
+			jscan.NewTokenizer[string](16, 1024), &jscandec.InitOptions{
+				ContainerFactories: map[reflect.Type]factory{
+					reflect.TypeOf([]N(nil)): func(int) (unsafe.Pointer, func(unsafe.Pointer)) {
+						return nil, nil
+					},
+				},
+			},
+		)
+		require.ErrorIs(t, err, jscandec.ErrUnsupportedType)
+	})
+}
+
+type testStatus string
+
+func TestDecodeEnumConstraints(t *testing.T) {
+	initOptions := &jscandec.InitOptions{
+		EnumConstraints: map[reflect.Type][]string{
+			reflect.TypeOf(testStatus("")): {"active", "inactive"},
+		},
+	}
+
+	t.Run("field", func(t *testing.T) {
+		type T struct {
+			Status testStatus `json:"status"`
+			Name   string     `json:"name"`
+		}
+		s := newTestSetupInit[T](t, initOptions, *jscandec.DefaultOptions)
+		s.TestOK(t, "active", `{"status":"active"}`, T{Status: "active"})
+		s.TestOK(t, "inactive", `{"status":"inactive"}`, T{Status: "inactive"})
+		s.TestOK(t, "escaped", `{"status":"\u0061ctive"}`, T{Status: "active"})
+		s.TestOK(t, "null", `{"status":null}`, T{})
+		s.TestOK(t, "unconstrained", `{"name":"frozen"}`, T{Name: "frozen"})
+		s.testErrNonstandard(t, "frozen", `{"status":"frozen"}`,
+			10, jscandec.ErrUnexpectedValue)
+		s.testErrNonstandard(t, "empty", `{"status":""}`,
+			10, jscandec.ErrUnexpectedValue)
+		s.testErrNonstandard(t, "case", `{"status":"Active"}`,
+			10, jscandec.ErrUnexpectedValue)
+		s.testErr(t, "number", `{"status":1}`, 10, jscandec.ErrUnexpectedValue)
+	})
+
+	t.Run("containers", func(t *testing.T) {
+		ss := newTestSetupInit[[]testStatus](t, initOptions, *jscandec.DefaultOptions)
+		ss.TestOK(t, "slice", `["active","inactive"]`)
+		ss.testErrNonstandard(t, "slice_frozen", `["active","frozen"]`,
+			10, jscandec.ErrUnexpectedValue)
+
+		sa := newTestSetupInit[[2]testStatus](t, initOptions, *jscandec.DefaultOptions)
+		sa.testErrNonstandard(t, "array_frozen", `["frozen"]`,
+			1, jscandec.ErrUnexpectedValue)
+
+		sv := newTestSetupInit[map[string]testStatus](
+			t, initOptions, *jscandec.DefaultOptions,
+		)
+		sv.TestOK(t, "map_value", `{"a":"active"}`)
+		sv.testErrNonstandard(t, "map_value_frozen", `{"a":"frozen"}`,
+			5, jscandec.ErrUnexpectedValue)
+
+		sk := newTestSetupInit[map[testStatus]int](
+			t, initOptions, *jscandec.DefaultOptions,
+		)
+		sk.TestOK(t, "map_key", `{"active":1}`)
+		sk.testErrNonstandard(t, "map_key_frozen", `{"active":1,"frozen":2}`,
+			12, jscandec.ErrUnexpectedValue)
+	})
+
+	t.Run("string_transform", func(t *testing.T) {
+		type T struct {
+			Status testStatus `json:"status"`
+		}
+		s := newTestSetupInit[T](t, initOptions, jscandec.DecodeOptions{
+			StringTransform: strings.ToLower,
+		})
+		s.testOKNonstandard(t, "transformed", `{"status":"ACTIVE"}`,
+			T{Status: "active"})
+	})
+
+	t.Run("unconstrained", func(t *testing.T) {
+		s := newTestSetup[testStatus](t, *jscandec.DefaultOptions)
+		s.TestOK(t, "frozen", `"frozen"`)
+	})
+}
+
+func TestDecodeBigRat(t *testing.T) {
+	type S struct {
+		R big.Rat   `json:"r"`
+		P *big.Rat  `json:"p"`
+		L []big.Rat `json:"l"`
+	}
+	s := newTestSetup[S](t, *jscandec.DefaultOptions)
+	s.TestOK(t, "fraction", `{"r":"1/3","p":"-2/4"}`)
+	s.TestOK(t, "decimal_string", `{"r":"0.333","l":["1","0.5"]}`)
+	s.TestOK(t, "null", `{"r":null,"p":null,"l":null}`)
+	s.testErr(t, "division_by_zero", `{"r":"1/0"}`, 5, jscandec.ErrUnexpectedValue)
+	s.testErr(t, "malformed", `{"r":"1/x"}`, 5, jscandec.ErrUnexpectedValue)
+	s.testErr(t, "bool", `{"p":true}`, 5, jscandec.ErrUnexpectedValue)
+
+	// encoding/json only accepts strings since big.Rat implements
+	// encoding.TextUnmarshaler, numbers are non-standard.
+	for _, td := range []struct {
+		name, input string
+		expectR     string
+		expectP     string
+		expectL     []string
+	}{
+		{"decimal", `{"r":0.25,"p":0.1}`, "1/4", "1/10", nil},
+		{"integer", `{"r":-7,"p":0}`, "-7", "0", nil},
+		{"exponent", `{"r":1e-3,"l":[2.5E2,1,"1/3"]}`, "1/1000", "", []string{
+			"250", "1", "1/3",
+		}},
+	} {
+		check := func(t *testing.T, v S) {
+			t.Helper()
+			runtime.GC() // Make sure GC is happy
+			require.Equal(t, td.expectR, v.R.RatString())
+			if td.expectP == "" {
+				require.Nil(t, v.P)
+			} else {
+				require.NotNil(t, v.P)
+				require.Equal(t, td.expectP, v.P.RatString())
+			}
+			var l []string
+			for i := range v.L {
+				l = append(l, v.L[i].RatString())
+			}
+			require.Equal(t, td.expectL, l)
+		}
+		t.Run(td.name+"/bytes", func(t *testing.T) {
+			var v S
+			_, err := s.decoderBytes.Decode([]byte(td.input), &v, s.decodeOptions)
+			require.NoError(t, err)
+			check(t, v)
+		})
+		t.Run(td.name+"/string", func(t *testing.T) {
+			var v S
+			_, err := s.decoderString.Decode(td.input, &v, s.decodeOptions)
+			require.NoError(t, err)
+			check(t, v)
+		})
+	}
+}
+
+func TestDecodeError(t *testing.T) {
+	type T struct {
+		Err error `json:"err"`
+	}
+	// encoding/json doesn't support decoding into error.
+	s := newTestSetup[T](t, *jscandec.DefaultOptions)
+	s.testOKNonstandard(t, "string", `{"err":"boom"}`, T{Err: errors.New("boom")})
+	s.testOKNonstandard(t, "escaped", `{"err":"\u0062oom\n"}`, T{Err: errors.New("boom\n")})
+	s.testOKNonstandard(t, "null", `{"err":null}`, T{})
+	s.testOKNonstandard(t, "absent", `{}`, T{})
+	s.testErrNonstandard(t, "number", `{"err":42}`, 7, jscandec.ErrUnexpectedValue)
+	s.testErrNonstandard(t, "object", `{"err":{}}`, 7, jscandec.ErrUnexpectedValue)
+
+	t.Run("null_resets", func(t *testing.T) {
+		tok := jscan.NewTokenizer[string](16, 1024)
+		d, err := jscandec.NewDecoder[string, T](tok, jscandec.DefaultInitOptions)
+		require.NoError(t, err)
+		v := T{Err: errors.New("previous")}
+		_, err = d.Decode(`{"err":null}`, &v, jscandec.DefaultOptions)
+		require.NoError(t, err)
+		require.Nil(t, v.Err)
+	})
+
+	t.Run("slice", func(t *testing.T) {
+		s := newTestSetup[[]error](t, *jscandec.DefaultOptions)
+		s.testOKNonstandard(t, "mixed", `["a",null,"b"]`,
+			[]error{errors.New("a"), nil, errors.New("b")})
+	})
+}
+
+func testErrUnsupportedType[T any](t *testing.T, typeName string) {
+	t.Helper()
+	t.Run(reflect.TypeOf((*T)(nil)).Elem().String(), func(t *testing.T) {
+		tok := jscan.NewTokenizer[string](1, 1)
+		dec, err := jscandec.NewDecoder[string, T](tok, jscandec.DefaultInitOptions)
+		require.ErrorIs(t, err, jscandec.ErrUnsupportedType)
+		require.Contains(t, err.Error(), typeName)
+		require.Nil(t, dec)
+	})
+}
+
+func TestDecodeStructRestField(t *testing.T) {
+	type S struct {
+		A    int            `json:"a"`
+		Rest map[string]any `json:",rest"`
+	}
+	s := newTestSetup[S](t, *jscandec.DefaultOptions)
+	s.testOKNonstandard(t, "basic", `{"a":1,"x":2,"y":3}`,
+		S{A: 1, Rest: map[string]any{"x": 2.0, "y": 3.0}})
+	s.testOKNonstandard(t, "no_unknown", `{"a":1}`, S{A: 1})
+	s.testOKNonstandard(t, "empty", `{}`, S{})
+	s.testOKNonstandard(t, "unknown_first", `{"x":{"k":[1,"s",null]},"a":1}`,
+		S{A: 1, Rest: map[string]any{"x": map[string]any{"k": []any{1.0, "s", nil}}}})
+	s.testOKNonstandard(t, "name_of_rest_field", `{"Rest":true}`,
+		S{Rest: map[string]any{"Rest": true}})
+	s.testOKNonstandard(t, "escaped_key", `{"\u0078":"\u0079"}`,
+		S{Rest: map[string]any{"x": "y"}})
+	s.testErrNonstandard(t, "invalid_unknown_value", `{"a":1,"x":1e999}`,
+		11, jscandec.ErrUnexpectedValue)
+
+	t.Run("disallow_unknown_fields", func(t *testing.T) {
+		opts := jscandec.DecodeOptions{DisallowUnknownFields: true}
+		s := newTestSetup[S](t, opts)
+		s.testOKNonstandard(t, "basic", `{"a":1,"x":2}`,
+			S{A: 1, Rest: map[string]any{"x": 2.0}})
+		s.testOKNonstandard(t, "unknown_first", `{"x":2,"a":1}`,
+			S{A: 1, Rest: map[string]any{"x": 2.0}})
+
+		type NoRest struct {
+			A int `json:"a"`
+		}
+		sn := newTestSetup[NoRest](t, opts)
+		sn.testErr(t, "without_rest", `{"a":1,"x":2}`, 7, jscandec.ErrUnknownField)
+
+		// The rest field only affects the struct it belongs to.
+		type Outer struct {
+			Inner NoRest         `json:"inner"`
+			Rest  map[string]any `json:",rest"`
+		}
+		so := newTestSetup[Outer](t, opts)
+		so.testOKNonstandard(t, "outer_unknown", `{"inner":{"a":1},"x":2}`,
+			Outer{Inner: NoRest{A: 1}, Rest: map[string]any{"x": 2.0}})
+		so.testErrNonstandard(t, "inner_unknown", `{"inner":{"a":1,"x":2}}`,
+			16, jscandec.ErrUnknownField)
+	})
+
+	t.Run("slice", func(t *testing.T) {
+		s := newTestSetup[[]S](t, *jscandec.DefaultOptions)
+		s.testOKNonstandard(t, "separate_maps", `[{"a":1,"x":2},{"y":3},{}]`, []S{
+			{A: 1, Rest: map[string]any{"x": 2.0}},
+			{Rest: map[string]any{"y": 3.0}},
+			{},
+		})
+	})
+
+	t.Run("recursive", func(t *testing.T) {
+		type R struct {
+			Name     string         `json:"name"`
+			Children []R            `json:"children"`
+			Next     *R             `json:"next"`
+			Rest     map[string]any `json:",rest"`
+		}
+		s := newTestSetup[R](t, *jscandec.DefaultOptions)
+		s.testOKNonstandard(t, "tree",
+			`{"name":"a","children":[{"name":"b","z":1}],"next":{"w":3,"name":"c"},"q":2}`,
+			R{
+				Name: "a",
+				Children: []R{
+					{Name: "b", Rest: map[string]any{"z": 1.0}},
+				},
+				Next: &R{Name: "c", Rest: map[string]any{"w": 3.0}},
+				Rest: map[string]any{"q": 2.0},
+			})
+	})
+
+	t.Run("err_unsupported_type", func(t *testing.T) {
+		type S struct {
+			Rest map[string]string `json:",rest"`
+		}
+		tok := jscan.NewTokenizer[string](1, 1)
+		dec, err := jscandec.NewDecoder[string, S](tok, jscandec.DefaultInitOptions)
+		require.Equal(t, jscandec.ErrRestTagOptionOnUnsupportedType, err)
+		require.Nil(t, dec)
+	})
+
+	t.Run("err_multiple", func(t *testing.T) {
+		type S struct {
+			Rest1 map[string]any `json:",rest"`
+			Rest2 map[string]any `json:",rest"`
+		}
+		tok := jscan.NewTokenizer[string](1, 1)
+		dec, err := jscandec.NewDecoder[string, S](tok, jscandec.DefaultInitOptions)
+		require.Equal(t, jscandec.ErrMultipleRestFields, err)
+		require.Nil(t, dec)
+	})
+}
+
+func TestDecodeStructRawField(t *testing.T) {
+	type S struct {
+		A   int             `json:"a"`
+		Raw json.RawMessage `json:",raw"`
+	}
+	type RawMsg = json.RawMessage
+	s := newTestSetup[S](t, *jscandec.DefaultOptions)
+	s.testOKNonstandard(t, "basic", `{"a":1,"sig":"x"}`,
+		S{A: 1, Raw: RawMsg(`{"a":1,"sig":"x"}`)})
+	s.testOKNonstandard(t, "empty", `{}`, S{Raw: RawMsg(`{}`)})
+	s.testOKNonstandard(t, "whitespace", " { \"a\" : 1 } ",
+		S{A: 1, Raw: RawMsg(`{ "a" : 1 }`)})
+	s.testOKNonstandard(t, "name_of_raw_field", `{"Raw":"x"}`,
+		S{Raw: RawMsg(`{"Raw":"x"}`)})
+	s.TestOK(t, "null", `null`, S{})
+
+	t.Run("bytes", func(t *testing.T) {
+		type B struct {
+			A   int    `json:"a"`
+			Raw []byte `json:"raw,raw"`
+		}
+		s := newTestSetup[B](t, *jscandec.DefaultOptions)
+		s.testOKNonstandard(t, "basic", `{"a":1}`, B{A: 1, Raw: []byte(`{"a":1}`)})
+	})
+
+	t.Run("nested", func(t *testing.T) {
+		type Outer struct {
+			Inner *S     `json:"inner"`
+			List  []S    `json:"list"`
+			Raw   []byte `json:",raw"`
+		}
+		const input = `{"inner":{"a":1},"list":[{"a":2},{}]}`
+		s := newTestSetup[Outer](t, *jscandec.DefaultOptions)
+		s.testOKNonstandard(t, "outer_and_inner", input, Outer{
+			Inner: &S{A: 1, Raw: RawMsg(`{"a":1}`)},
+			List:  []S{{A: 2, Raw: RawMsg(`{"a":2}`)}, {Raw: RawMsg(`{}`)}},
+			Raw:   []byte(input),
+		})
+	})
+
+	t.Run("recursive", func(t *testing.T) {
+		type R struct {
+			Name string          `json:"name"`
+			Next *R              `json:"next"`
+			Kids []R             `json:"kids"`
+			Raw  json.RawMessage `json:",raw"`
+		}
+		const input = `{"name":"a","next":{"name":"b"},"kids":[{"name":"c"}]}`
+		s := newTestSetup[R](t, *jscandec.DefaultOptions)
+		s.testOKNonstandard(t, "tree", input, R{
+			Name: "a",
+			Next: &R{Name: "b", Raw: RawMsg(`{"name":"b"}`)},
+			Kids: []R{{Name: "c", Raw: RawMsg(`{"name":"c"}`)}},
+			Raw:  RawMsg(input),
+		})
+	})
+
+	t.Run("rewritten_input", func(t *testing.T) {
+		// The raw bytes are the original input, not the rewritten one.
+		s := newTestSetup[S](t, jscandec.DecodeOptions{
+			AllowUnquotedKeys: true,
+			AllowLeadingZeros: true,
+		})
+		s.testOKNonstandard(t, "verbatim", `{a:007}`, S{A: 7, Raw: RawMsg(`{a:007}`)})
+	})
+
+	t.Run("err_unsupported_type", func(t *testing.T) {
+		type S struct {
+			Raw string `json:",raw"`
+		}
+		tok := jscan.NewTokenizer[string](1, 1)
+		dec, err := jscandec.NewDecoder[string, S](tok, jscandec.DefaultInitOptions)
+		require.Equal(t, jscandec.ErrRawTagOptionOnUnsupportedType, err)
+		require.Nil(t, dec)
+	})
+
+	t.Run("err_multiple", func(t *testing.T) {
+		type S struct {
+			Raw1 []byte `json:",raw"`
+			Raw2 []byte `json:",raw"`
+		}
+		tok := jscan.NewTokenizer[string](1, 1)
+		dec, err := jscandec.NewDecoder[string, S](tok, jscandec.DefaultInitOptions)
+		require.Equal(t, jscandec.ErrMultipleRawFields, err)
+		require.Nil(t, dec)
+	})
+}
+
+func TestDecodeStructPairsField(t *testing.T) {
+	type Pair struct {
+		Key   string
+		Value int
+	}
+	type S struct {
+		Pairs []Pair `json:"pairs,pairs"`
+		N     int    `json:"n"`
+	}
+	s := newTestSetup[S](t, *jscandec.DefaultOptions)
+	s.testOKNonstandard(t, "document_order", `{"pairs":{"b":2,"a":1},"n":3}`,
+		S{Pairs: []Pair{{Key: "b", Value: 2}, {Key: "a", Value: 1}}, N: 3})
+	s.testOKNonstandard(t, "duplicate_keys", `{"pairs":{"a":1,"a":2}}`,
+		S{Pairs: []Pair{{Key: "a", Value: 1}, {Key: "a", Value: 2}}})
+	s.testOKNonstandard(t, "escaped_key", `{"pairs":{"a\n":1}}`,
+		S{Pairs: []Pair{{Key: "a\n", Value: 1}}})
+	s.testOKNonstandard(t, "empty", `{"pairs":{}}`, S{Pairs: []Pair{}})
+	s.TestOK(t, "null", `{"pairs":null}`, S{})
+	s.testErrNonstandard(t, "array", `{"pairs":[{"Key":"a","Value":1}]}`,
+		9, jscandec.ErrUnexpectedValue)
+	s.testErr(t, "value_type_mismatch", `{"pairs":{"a":"1"}}`,
+		14, jscandec.ErrUnexpectedValue)
+
+	t.Run("composite_value", func(t *testing.T) {
+		type Pair struct {
+			Key   string
+			Value []string
+		}
+		type S struct {
+			Pairs []Pair `json:",pairs"`
+		}
+		s := newTestSetup[S](t, *jscandec.DefaultOptions)
+		s.testOKNonstandard(t, "slices", `{"Pairs":{"x":["a","b"],"y":null}}`,
+			S{Pairs: []Pair{{Key: "x", Value: []string{"a", "b"}}, {Key: "y"}}})
+	})
+
+	t.Run("without_option", func(t *testing.T) {
+		type S struct {
+			Pairs []Pair `json:"pairs"`
+		}
+		s := newTestSetup[S](t, *jscandec.DefaultOptions)
+		s.testErr(t, "object", `{"pairs":{"a":1}}`, 9, jscandec.ErrUnexpectedValue)
+	})
+
+	t.Run("err_unsupported_type", func(t *testing.T) {
+		type S struct {
+			Pairs []struct{ Name string } `json:",pairs"`
+		}
+		tok := jscan.NewTokenizer[string](1, 1)
+		dec, err := jscandec.NewDecoder[string, S](tok, jscandec.DefaultInitOptions)
+		require.Equal(t, jscandec.ErrPairsTagOptionOnUnsupportedType, err)
+		require.Nil(t, dec)
+	})
+}
+
+func TestDecodeStructBytesField(t *testing.T) {
+	type S struct {
+		Data []byte `json:"data,bytes"`
+		N    int    `json:"n"`
+	}
+	s := newTestSetup[S](t, *jscandec.DefaultOptions)
+	s.testOKNonstandard(t, "escaped", `{"data":"h\u00e9llo"}`,
+		S{Data: []byte("héllo")})
+	s.testOKNonstandard(t, "utf8", `{"data":"héllo","n":1}`,
+		S{Data: []byte("héllo"), N: 1})
+	s.testOKNonstandard(t, "not_base64", `{"data":"aGk="}`,
+		S{Data: []byte("aGk=")})
+	s.testOKNonstandard(t, "empty", `{"data":""}`, S{Data: []byte{}})
+	s.TestOK(t, "null", `{"data":null}`, S{})
+	s.testErrNonstandard(t, "array", `{"data":[104,105]}`,
+		8, jscandec.ErrUnexpectedValue)
+	s.testErr(t, "number", `{"data":1}`, 8, jscandec.ErrUnexpectedValue)
+
+	t.Run("copied", func(t *testing.T) {
+		tok := jscan.NewTokenizer[[]byte](16, 1024)
+		d, err := jscandec.NewDecoder[[]byte, S](tok, jscandec.DefaultInitOptions)
+		require.NoError(t, err)
+		in := []byte(`{"data":"abc"}`)
+		var v S
+		_, err = d.Decode(in, &v, jscandec.DefaultOptions)
+		require.NoError(t, err)
+		copy(in, `{"data":"xyz"}`)
+		require.Equal(t, []byte("abc"), v.Data)
+	})
+
+	t.Run("err_unsupported_type", func(t *testing.T) {
+		type S struct {
+			Data string `json:",bytes"`
+		}
+		tok := jscan.NewTokenizer[string](1, 1)
+		dec, err := jscandec.NewDecoder[string, S](tok, jscandec.DefaultInitOptions)
+		require.Equal(t, jscandec.ErrBytesTagOptionOnUnsupportedType, err)
+		require.Nil(t, dec)
+	})
+}
+
+func TestDecodeApplyMergePatch(t *testing.T) {
+	type Inner struct {
+		X int `json:"x"`
+		Y int `json:"y"`
+	}
+	type S struct {
+		Name  string                 `json:"name"`
+		Tags  []string               `json:"tags"`
+		Count int                    `json:"count"`
+		Inner *Inner                 `json:"inner"`
+		M     map[string]int         `json:"m"`
+		Opt   jscandec.Optional[int] `json:"opt"`
+		Any   any                    `json:"any"`
+	}
+	apply := func(t *testing.T, v *S, patch string) {
+		t.Helper()
+		d, err := jscandec.NewDecoder[string, S](
+			jscan.NewTokenizer[string](16, 1024), jscandec.DefaultInitOptions,
+		)
+		require.NoError(t, err)
+		errIndex, err := d.ApplyMergePatch(patch, v, jscandec.DefaultOptions)
+		require.NoError(t, err)
+		require.Equal(t, -1, errIndex)
+	}
+
+	t.Run("struct", func(t *testing.T) {
+		v := S{Name: "old", Tags: []string{"a", "b"}, Count: 3}
+		apply(t, &v, `{"name":"new","tags":null}`)
+		require.Equal(t, S{Name: "new", Count: 3}, v)
+	})
+
+	t.Run("nested", func(t *testing.T) {
+		v := S{
+			Inner: &Inner{X: 1, Y: 2},
+			M:     map[string]int{"a": 1, "b": 2},
+			Opt:   jscandec.Optional[int]{Set: true, Valid: true, Value: 5},
+			Any:   map[string]any{"a": 1.0, "b": map[string]any{"c": 2.0, "d": 3.0}},
+		}
+		apply(t, &v, `{"inner":{"y":9},"m":{"a":null,"c":3},"opt":null,`+
+			`"any":{"b":{"c":null,"e":[null]}}}`)
+		require.Equal(t, S{
+			Inner: &Inner{X: 1, Y: 9},
+			M:     map[string]int{"b": 2, "c": 3},
+			Any: map[string]any{
+				"a": 1.0, "b": map[string]any{"d": 3.0, "e": []any{nil}},
+			},
+		}, v)
+	})
+
+	t.Run("null", func(t *testing.T) {
+		v := S{Name: "old", Count: 3}
+		apply(t, &v, `null`)
+		require.Equal(t, S{}, v)
+	})
+}
+
+func TestDecodeApplyMergePatchNestedMap(t *testing.T) {
+	type M map[string]map[string]map[string]int
+	d, err := jscandec.NewDecoder[string, M](
+		jscan.NewTokenizer[string](16, 1024), jscandec.DefaultInitOptions,
+	)
+	require.NoError(t, err)
+	v := M{"a": {"b": {"x": 1, "y": 2}, "c": {"z": 3}}, "d": {}}
+	errIndex, err := d.ApplyMergePatch(
+		`{"a":{"b":{"x":null,"w":4},"c":null},"e":{"f":{"g":5}}}`,
+		&v, jscandec.DefaultOptions,
+	)
+	require.NoError(t, err)
+	require.Equal(t, -1, errIndex)
+	require.Equal(t, M{
+		"a": {"b": {"y": 2, "w": 4}},
+		"d": {},
+		"e": {"f": {"g": 5}},
+	}, v)
+}
+
+func TestMemReuse(t *testing.T) {
+	optsInit := jscandec.DefaultInitOptions
+	optsDec := jscandec.DefaultOptions
+
+	type S []string
+	t.Run("string", func(t *testing.T) {
+		tok := jscan.NewTokenizer[string](1, 16)
+		dec, err := jscandec.NewDecoder[string, S](tok, optsInit)
+		require.NoError(t, err)
+
+		var v1 S
+		_, err = dec.Decode(`["first","second","third"]`, &v1, optsDec)
+		require.NoError(t, err)
+		require.Equal(t, S{"first", "second", "third"}, v1)
+
+		var v2 S
+		_, err = dec.Decode(`["FIRST","SECOND","THIRD"]`, &v2, optsDec)
+		require.NoError(t, err)
+
+		require.Equal(t, S{"first", "second", "third"}, v1)
+		require.Equal(t, S{"FIRST", "SECOND", "THIRD"}, v2)
+	})
+}
+
+func TestDecoderClone(t *testing.T) {
+	type N struct {
+		Name string `json:"name"`
+		Next *N     `json:"next"`
+		List []N    `json:"list"`
+	}
+	tok := jscan.NewTokenizer[string](16, 1024)
+	d, err := jscandec.NewDecoder[string, N](tok, jscandec.DefaultInitOptions)
+	require.NoError(t, err)
+	c := d.Clone()
+
+	const input = `{"name":"a","next":{"name":"b"},"list":[{"name":"c","list":[]}]}`
+	expect := N{Name: "a", Next: &N{Name: "b"}, List: []N{{Name: "c", List: []N{}}}}
+
+	// Use the original and the clone concurrently to make sure
+	// they don't share any mutable state (run with -race).
+	var wg sync.WaitGroup
+	for _, dec := range []*jscandec.Decoder[string, N]{d, c} {
+		wg.Add(1)
+		go func(dec *jscandec.Decoder[string, N]) {
+			defer wg.Done()
+			for i := 0; i < 256; i++ {
+				var v N
+				if _, err := dec.Decode(input, &v, jscandec.DefaultOptions); err != nil {
+					t.Error(err)
+					return
+				}
+				if !reflect.DeepEqual(expect, v) {
+					t.Errorf("unexpected result: %#v", v)
+					return
+				}
+			}
+		}(dec)
+	}
+	wg.Wait()
+
+}
+
+func TestAnyDecoder(t *testing.T) {
+	type User struct {
+		ID   int    `json:"id"`
+		Name string `json:"name"`
+	}
+	type Order struct {
+		ID    string   `json:"id"`
+		Items []string `json:"items"`
+		User  *User    `json:"user"`
+	}
+	type N struct {
+		Name string `json:"name"`
+		Next *N     `json:"next"`
+	}
+	d := jscandec.NewAnyDecoder[string](
+		jscan.NewTokenizer[string](16, 1024), jscandec.DefaultInitOptions,
+	)
+
+	// Decode every type twice to make sure type stacks are reused.
+	for i := 0; i < 2; i++ {
+		var u User
+		_, err := d.Decode(`{"id":1,"name":"a"}`, &u, jscandec.DefaultOptions)
+		require.NoError(t, err)
+		require.Equal(t, User{ID: 1, Name: "a"}, u)
+
+		var o Order
+		_, err = d.Decode(
+			`{"id":"x","items":["a","b"],"user":{"id":2}}`, &o, jscandec.DefaultOptions,
+		)
+		require.NoError(t, err)
+		require.Equal(t, Order{ID: "x", Items: []string{"a", "b"}, User: &User{ID: 2}}, o)
+
+		var n N
+		_, err = d.Decode(`{"name":"a","next":{"name":"b"}}`, &n, jscandec.DefaultOptions)
+		require.NoError(t, err)
+		require.Equal(t, N{Name: "a", Next: &N{Name: "b"}}, n)
+
+		var m map[string]int
+		_, err = d.Decode(`{"a":1}`, &m, jscandec.DefaultOptions)
+		require.NoError(t, err)
+		require.Equal(t, map[string]int{"a": 1}, m)
+	}
+
+	t.Run("err_decode", func(t *testing.T) {
+		var u User
+		errIndex, err := d.Decode(`{"id":"1"}`, &u, jscandec.DefaultOptions)
+		require.ErrorIs(t, err, jscandec.ErrUnexpectedValue)
+		require.Equal(t, 6, errIndex)
+	})
+
+	t.Run("err_nil_dest", func(t *testing.T) {
+		_, err := d.Decode(`{}`, nil, jscandec.DefaultOptions)
+		require.ErrorIs(t, err, jscandec.ErrNilDest)
+		_, err = d.Decode(`{}`, (*User)(nil), jscandec.DefaultOptions)
+		require.ErrorIs(t, err, jscandec.ErrNilDest)
+	})
+
+	t.Run("err_non_pointer", func(t *testing.T) {
+		_, err := d.Decode(`{}`, User{}, jscandec.DefaultOptions)
+		require.ErrorIs(t, err, jscandec.ErrUnsupportedType)
+	})
+
+	t.Run("err_unsupported_type", func(t *testing.T) {
+		var c chan int
+		_, err := d.Decode(`1`, &c, jscandec.DefaultOptions)
+		require.ErrorIs(t, err, jscandec.ErrUnsupportedType)
+	})
+}
+
+func TestDecoderDescribeStack(t *testing.T) {
+	type N struct {
+		Name string `json:"name"`
+		Next *N     `json:"next"`
+		List []N    `json:"list"`
+	}
+	tok := jscan.NewTokenizer[string](16, 1024)
+	d, err := jscandec.NewDecoder[string, []N](tok, jscandec.DefaultInitOptions)
+	require.NoError(t, err)
+
+	require.Equal(t, reflect.TypeOf([]N(nil)), d.InputType())
+	require.Equal(t, []string{
+		"0: slice offset=0 parent=-",
+		`1: struct⟲ offset=0 parent=0 fields=["name":2 "next":3 "list":4]`,
+		"2: string offset=0 parent=1",
+		fmt.Sprintf("3: *⟲ offset=%d parent=1 recur=1", unsafe.Offsetof(N{}.Next)),
+		fmt.Sprintf("4: slice⟲ offset=%d parent=1 recur=1", unsafe.Offsetof(N{}.List)),
+	}, d.DescribeStack())
+}
+
+func TestJSONKindOf(t *testing.T) {
+	type S struct {
+		Name string `json:"name"`
+	}
+	type N struct {
+		Next *N `json:"next"`
+	}
+	require.Equal(t, "object", jscandec.JSONKindOf[S]())
+	require.Equal(t, "object", jscandec.JSONKindOf[N]())
+	require.Equal(t, "object", jscandec.JSONKindOf[struct{}]())
+	require.Equal(t, "object", jscandec.JSONKindOf[map[string]int]())
+	require.Equal(t, "object", jscandec.JSONKindOf[map[string]string]())
+	require.Equal(t, "array", jscandec.JSONKindOf[[]int]())
+	require.Equal(t, "array", jscandec.JSONKindOf[[]S]())
+	require.Equal(t, "array", jscandec.JSONKindOf[[2]string]())
+	require.Equal(t, "number", jscandec.JSONKindOf[int]())
+	require.Equal(t, "number", jscandec.JSONKindOf[float64]())
+	require.Equal(t, "number", jscandec.JSONKindOf[jscandec.Number]())
+	require.Equal(t, "string", jscandec.JSONKindOf[string]())
+	require.Equal(t, "string", jscandec.JSONKindOf[textUnmarshalerImpl]())
+	require.Equal(t, "bool", jscandec.JSONKindOf[bool]())
+	require.Equal(t, "any", jscandec.JSONKindOf[any]())
+	require.Equal(t, "any", jscandec.JSONKindOf[json.RawMessage]())
+
+	require.Equal(t, "nullable object", jscandec.JSONKindOf[*S]())
+	require.Equal(t, "nullable number", jscandec.JSONKindOf[*int]())
+	require.Equal(t, "nullable nullable array", jscandec.JSONKindOf[**[]int]())
+	require.Equal(t, "nullable string", jscandec.JSONKindOf[jscandec.Optional[string]]())
+
+	require.Equal(t, "", jscandec.JSONKindOf[chan int]())
+}
+
+func TestDecoderGrow(t *testing.T) {
+	type N struct{ Next *N }
+	const depth = 256
+	input := strings.Repeat(`{"Next":`, depth) + `null` + strings.Repeat(`}`, depth)
+
+	// Preallocate the destination to make sure no allocations are
+	// caused by the data itself.
+	var v N
+	for p, i := &v, 0; i < depth-1; i++ {
+		p.Next = new(N)
+		p = p.Next
+	}
+
+	tok := jscan.NewTokenizer[string](1, 1)
+	d, err := jscandec.NewDecoder[string, N](tok, jscandec.DefaultInitOptions)
+	require.NoError(t, err)
+	d.Grow(depth*3+1, depth)
+
+	decode := func() {
+		if _, err := d.Decode(input, &v, jscandec.DefaultOptions); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// testing.AllocsPerRun performs a warm-up run, therefore the very first
+	// call to Decode must be measured separately.
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	decode()
+	runtime.ReadMemStats(&after)
+	require.Zero(t, after.Mallocs-before.Mallocs, "allocations in first run")
+
+	require.Zero(t, testing.AllocsPerRun(16, decode))
+}
+
+func TestDecodeNoAlloc(t *testing.T) {
+	run := func(t *testing.T, decode func()) {
+		t.Helper()
+		// testing.AllocsPerRun performs a warm-up run, therefore the very
+		// first call to Decode must be measured separately.
+		var before, after runtime.MemStats
+		runtime.ReadMemStats(&before)
+		decode()
+		runtime.ReadMemStats(&after)
+		require.Zero(t, after.Mallocs-before.Mallocs, "allocations in first run")
+
+		require.Zero(t, testing.AllocsPerRun(16, decode))
+	}
+
+	t.Run("int", func(t *testing.T) {
+		d, err := jscandec.NewDecoder[string, int](
+			jscan.NewTokenizer[string](16, 64), jscandec.DefaultInitOptions,
+		)
+		require.NoError(t, err)
+		var v int
+		run(t, func() {
+			if _, err := d.Decode("42", &v, jscandec.DefaultOptions); err != nil {
+				t.Fatal(err)
+			}
+		})
+		require.Equal(t, 42, v)
+	})
+
+	t.Run("float64", func(t *testing.T) {
+		d, err := jscandec.NewDecoder[[]byte, float64](
+			jscan.NewTokenizer[[]byte](16, 64), jscandec.DefaultInitOptions,
+		)
+		require.NoError(t, err)
+		in := []byte("3.14")
+		var v float64
+		run(t, func() {
+			if _, err := d.Decode(in, &v, jscandec.DefaultOptions); err != nil {
+				t.Fatal(err)
+			}
+		})
+		require.Equal(t, 3.14, v)
+	})
+
+	t.Run("array_int", func(t *testing.T) {
+		d, err := jscandec.NewDecoder[string, [3]int](
+			jscan.NewTokenizer[string](16, 64), jscandec.DefaultInitOptions,
+		)
+		require.NoError(t, err)
+		var v [3]int
+		run(t, func() {
+			if _, err := d.Decode("[1,2,3]", &v, jscandec.DefaultOptions); err != nil {
+				t.Fatal(err)
+			}
+		})
+		require.Equal(t, [3]int{1, 2, 3}, v)
+	})
+
+	t.Run("struct", func(t *testing.T) {
+		type S struct {
+			ID    int     `json:"id"`
+			Ratio float32 `json:"ratio"`
+			OK    bool    `json:"ok"`
+		}
+		d, err := jscandec.NewDecoder[string, S](
+			jscan.NewTokenizer[string](16, 64), jscandec.DefaultInitOptions,
+		)
+		require.NoError(t, err)
+		var v S
+		run(t, func() {
+			_, err := d.Decode(
+				`{"id":7,"ratio":0.5,"ok":true}`, &v, jscandec.DefaultOptions,
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+		require.Equal(t, S{ID: 7, Ratio: 0.5, OK: true}, v)
+	})
+}
+
+func TestDecodeSlicePool(t *testing.T) {
+	type Tag struct {
+		Key   string `json:"key"`
+		Value string `json:"value"`
+	}
+	type Item struct {
+		ID   int   `json:"id"`
+		Tags []Tag `json:"tags"`
+	}
+	const input = `[
+		{"id":1,"tags":[{"key":"a"},{"key":"b","value":"x"}]},
+		{"id":2},
+		{"id":3,"tags":[{"value":"c"}]}
+	]`
+	expect := []Item{
+		{ID: 1, Tags: []Tag{{Key: "a"}, {Key: "b", Value: "x"}}},
+		{ID: 2},
+		{ID: 3, Tags: []Tag{{Value: "c"}}},
+	}
+	newDecoder := func(t *testing.T, slicePool bool) *jscandec.Decoder[string, []Item] {
+		t.Helper()
+		d, err := jscandec.NewDecoder[string, []Item](
+			jscan.NewTokenizer[string](16, 1024),
+			&jscandec.InitOptions{SlicePool: slicePool},
+		)
+		require.NoError(t, err)
+		return d
+	}
+
+	t.Run("allocs", func(t *testing.T) {
+		allocs := func(d *jscandec.Decoder[string, []Item]) float64 {
+			var v []Item
+			return testing.AllocsPerRun(16, func() {
+				v = nil // Decode into a new slice every time.
+				if _, err := d.Decode(input, &v, jscandec.DefaultOptions); err != nil {
+					t.Fatal(err)
+				}
+			})
+		}
+		require.Equal(t, float64(3), allocs(newDecoder(t, false)))
+		require.Zero(t, allocs(newDecoder(t, true)))
+	})
+
+	t.Run("reuse", func(t *testing.T) {
+		d := newDecoder(t, true)
+		for i := 0; i < 3; i++ {
+			var v []Item
+			_, err := d.Decode(input, &v, jscandec.DefaultOptions)
+			require.NoError(t, err)
+			runtime.GC() // Make sure GC is happy
+			require.Equal(t, expect, v)
+		}
+	})
+
+	t.Run("aliasing", func(t *testing.T) {
+		d := newDecoder(t, true)
+		var first, second []Item
+		_, err := d.Decode(input, &first, jscandec.DefaultOptions)
+		require.NoError(t, err)
+		_, err = d.Decode(`[{"id":4}]`, &second, jscandec.DefaultOptions)
+		require.NoError(t, err)
+		require.Equal(t, []Item{{ID: 4}}, second)
+		// The backing array of first is reused by the second call to Decode.
+		require.Same(t, &first[0], &second[0])
+		require.Equal(t, Item{ID: 4}, first[0])
+	})
+}
+
+func TestStreamDecoder(t *testing.T) {
+	type S struct {
+		Name string `json:"name"`
+		Tags []int  `json:"tags"`
+	}
+	newStream := func(t *testing.T) *jscandec.StreamDecoder[S] {
+		t.Helper()
+		tok := jscan.NewTokenizer[[]byte](16, 1024)
+		d, err := jscandec.NewDecoder[[]byte, S](tok, jscandec.DefaultInitOptions)
+		require.NoError(t, err)
+		return jscandec.NewStreamDecoder(d, jscandec.DefaultOptions)
+	}
+
+	t.Run("byte_by_byte", func(t *testing.T) {
+		// Brackets and escaped quotes inside of strings must not
+		// affect detecting the end of the value.
+		const input = ` {"name":"a\"}]{","tags":[1,2]}`
+		s := newStream(t)
+		for i := 0; i < len(input); i++ {
+			n, err := s.Write([]byte{input[i]})
+			require.NoError(t, err)
+			require.Equal(t, 1, n)
+			v, ok := s.Value()
+			if i < len(input)-1 {
+				require.False(t, ok, "complete at index %d", i)
+				require.Nil(t, v)
+				continue
+			}
+			require.True(t, ok)
+			require.Equal(t, &S{Name: `a"}]{`, Tags: []int{1, 2}}, v)
+		}
+	})
+
+	t.Run("multiple_values", func(t *testing.T) {
+		s := newStream(t)
+		_, err := s.Write([]byte(`{"name":"a"} {"name":"b","ta`))
+		require.NoError(t, err)
+		v, ok := s.Value()
+		require.True(t, ok)
+		require.Equal(t, &S{Name: "a"}, v)
+
+		require.NoError(t, s.Next())
+		_, ok = s.Value()
+		require.False(t, ok)
+		_, err = s.Write([]byte(`gs":[3]}{"name":"c"}`))
+		require.NoError(t, err)
+		v, ok = s.Value()
+		require.True(t, ok)
+		require.Equal(t, &S{Name: "b", Tags: []int{3}}, v)
+
+		// The next value is already complete.
+		require.NoError(t, s.Next())
+		v, ok = s.Value()
+		require.True(t, ok)
+		require.Equal(t, &S{Name: "c"}, v)
+
+		require.NoError(t, s.Next())
+		_, ok = s.Value()
+		require.False(t, ok)
+	})
+
+	t.Run("scalar", func(t *testing.T) {
+		tok := jscan.NewTokenizer[[]byte](16, 1024)
+		d, err := jscandec.NewDecoder[[]byte, int](tok, jscandec.DefaultInitOptions)
+		require.NoError(t, err)
+		s := jscandec.NewStreamDecoder(d, jscandec.DefaultOptions)
+		for _, chunk := range []string{"1", "2", "3"} {
+			_, err := s.Write([]byte(chunk))
+			require.NoError(t, err)
+			_, ok := s.Value()
+			require.False(t, ok)
+		}
+		_, err = s.Write([]byte("\n"))
+		require.NoError(t, err)
+		v, ok := s.Value()
+		require.True(t, ok)
+		require.Equal(t, 123, *v)
+	})
+
+	t.Run("error", func(t *testing.T) {
+		s := newStream(t)
+		_, err := s.Write([]byte(`{"name":`))
+		require.NoError(t, err)
+		n, err := s.Write([]byte(`42}`))
+		require.Equal(t, jscandec.ErrUnexpectedValue, err)
+		require.Equal(t, 3, n)
+		_, ok := s.Value()
+		require.False(t, ok)
+
+		_, err = s.Write([]byte(`{}`))
+		require.Equal(t, jscandec.ErrUnexpectedValue, err)
+		require.Equal(t, jscandec.ErrUnexpectedValue, s.Next())
+	})
+}
+
+func TestDecodeMulti(t *testing.T) {
+	type S struct {
+		A int    `json:"a"`
+		B string `json:"b"`
+	}
+	decodeAll := func(t *testing.T, input string) ([]S, int, error) {
+		t.Helper()
+		d, err := jscandec.NewDecoder[string, S](
+			jscan.NewTokenizer[string](16, 1024), jscandec.DefaultInitOptions,
+		)
+		require.NoError(t, err)
+		var values []S
+		errIndex, err := d.DecodeMulti(input, func(v *S) error {
+			values = append(values, *v)
+			return nil
+		}, jscandec.DefaultOptions)
+		return values, errIndex, err
+	}
+
+	t.Run("ndjson", func(t *testing.T) {
+		values, errIndex, err := decodeAll(t, "{\"a\":1}\n{\"a\":2}\n{\"a\":3}")
+		require.NoError(t, err)
+		require.Equal(t, -1, errIndex)
+		require.Equal(t, []S{{A: 1}, {A: 2}, {A: 3}}, values)
+	})
+
+	t.Run("blank_lines", func(t *testing.T) {
+		values, _, err := decodeAll(t, "\n\n{\"a\":1,\"b\":\"x\"}\n\n\r\n"+
+			"{\"a\":2}\n \t\n{\"b\":\"}\\\"{\"}\n\n")
+		require.NoError(t, err)
+		// The value is reset before decoding the next one.
+		require.Equal(t, []S{{A: 1, B: "x"}, {A: 2}, {B: `}"{`}}, values)
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		values, errIndex, err := decodeAll(t, " \n ")
+		require.NoError(t, err)
+		require.Equal(t, -1, errIndex)
+		require.Nil(t, values)
+	})
+
+	t.Run("scalars", func(t *testing.T) {
+		d, err := jscandec.NewDecoder[[]byte, int](
+			jscan.NewTokenizer[[]byte](16, 1024), jscandec.DefaultInitOptions,
+		)
+		require.NoError(t, err)
+		var values []int
+		_, err = d.DecodeMulti([]byte("1 2\n3"), func(v *int) error {
+			values = append(values, *v)
+			return nil
+		}, jscandec.DefaultOptions)
+		require.NoError(t, err)
+		require.Equal(t, []int{1, 2, 3}, values)
+	})
+
+	t.Run("adjacent_containers", func(t *testing.T) {
+		// Like in the encoding/json Decoder objects don't need separators.
+		values, _, err := decodeAll(t, "{\"a\":1}{\"a\":2} {\"a\":3}")
+		require.NoError(t, err)
+		require.Equal(t, []S{{A: 1}, {A: 2}, {A: 3}}, values)
+	})
+
+	t.Run("err_no_separator", func(t *testing.T) {
+		values, errIndex, err := decodeAll(t, "{\"a\":1}\n{\"a\":2}\"x\"")
+		code, ok := jscandec.SyntaxErrorCode(err)
+		require.True(t, ok)
+		require.Equal(t, jscan.ErrorCodeUnexpectedToken, code)
+		require.Equal(t, 15, errIndex)
+		require.Equal(t, []S{{A: 1}}, values)
+
+		d, err := jscandec.NewDecoder[[]byte, int](
+			jscan.NewTokenizer[[]byte](16, 1024), jscandec.DefaultInitOptions,
+		)
+		require.NoError(t, err)
+		for _, td := range []struct {
+			input       string
+			expectIndex int
+		}{
+			{"1{}", 1},
+			{"1 2[]", 3},
+			{"\"1\"2", 3},
+		} {
+			var values []int
+			errIndex, err := d.DecodeMulti([]byte(td.input), func(v *int) error {
+				values = append(values, *v)
+				return nil
+			}, jscandec.DefaultOptions)
+			code, ok := jscandec.SyntaxErrorCode(err)
+			require.True(t, ok, td.input)
+			require.Equal(t, jscan.ErrorCodeUnexpectedToken, code, td.input)
+			require.Equal(t, td.expectIndex, errIndex, td.input)
+		}
+	})
+
+	t.Run("err_truncated", func(t *testing.T) {
+		values, errIndex, err := decodeAll(t, "{\"a\":1}\n{\"a\":")
+		code, ok := jscandec.SyntaxErrorCode(err)
+		require.True(t, ok)
+		require.Equal(t, jscan.ErrorCodeUnexpectedEOF, code)
+		require.Equal(t, 13, errIndex)
+		require.Equal(t, []S{{A: 1}}, values)
+	})
+
+	t.Run("err_value", func(t *testing.T) {
+		values, errIndex, err := decodeAll(t, "{\"a\":1}\n{\"a\":\"x\"}")
+		require.Equal(t, jscandec.ErrUnexpectedValue, err)
+		require.Equal(t, 13, errIndex)
+		require.Equal(t, []S{{A: 1}}, values)
+	})
+
+	t.Run("err_yield", func(t *testing.T) {
+		d, err := jscandec.NewDecoder[string, S](
+			jscan.NewTokenizer[string](16, 1024), jscandec.DefaultInitOptions,
+		)
+		require.NoError(t, err)
+		errStop := errors.New("stop")
+		calls := 0
+		errIndex, err := d.DecodeMulti("{\"a\":1}\n{\"a\":2}\n{\"a\":3}",
+			func(v *S) error {
+				if calls++; v.A == 2 {
+					return errStop
+				}
+				return nil
+			}, jscandec.DefaultOptions)
+		require.Equal(t, errStop, err)
+		require.Equal(t, 8, errIndex)
+		require.Equal(t, 2, calls)
+	})
+}
+
+func TestDecodeArrayToChan(t *testing.T) {
+	type S struct {
+		A int   `json:"a"`
+		B []int `json:"b"`
+	}
+	decodeAll := func(t *testing.T, input string) ([]S, int, error) {
+		t.Helper()
+		d, err := jscandec.NewDecoder[string, S](
+			jscan.NewTokenizer[string](16, 1024), jscandec.DefaultInitOptions,
+		)
+		require.NoError(t, err)
+		ch := make(chan S)
+		var values []S
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			for v := range ch {
+				values = append(values, v)
+			}
+		}()
+		errIndex, err := d.DecodeArrayToChan(input, ch, jscandec.DefaultOptions)
+		close(ch)
+		<-done
+		return values, errIndex, err
+	}
+
+	t.Run("objects", func(t *testing.T) {
+		values, errIndex, err := decodeAll(t, `[{"a":1},{"a":2}]`)
+		require.NoError(t, err)
+		require.Equal(t, -1, errIndex)
+		require.Equal(t, []S{{A: 1}, {A: 2}}, values)
+	})
+
+	t.Run("copies", func(t *testing.T) {
+		values, _, err := decodeAll(t, " [ {\"a\":1,\"b\":[1,2]} ,\n{\"b\":[3]} ] ")
+		require.NoError(t, err)
+		// Elements don't share state with preceding ones.
+		require.Equal(t, []S{{A: 1, B: []int{1, 2}}, {B: []int{3}}}, values)
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		values, errIndex, err := decodeAll(t, ` [ ] `)
+		require.NoError(t, err)
+		require.Equal(t, -1, errIndex)
+		require.Nil(t, values)
+	})
+
+	t.Run("err_not_array", func(t *testing.T) {
+		values, errIndex, err := decodeAll(t, ` {"a":1}`)
+		require.Equal(t, jscandec.ErrUnexpectedValue, err)
+		require.Equal(t, 1, errIndex)
+		require.Nil(t, values)
+	})
+
+	t.Run("err_value", func(t *testing.T) {
+		values, errIndex, err := decodeAll(t, `[{"a":1},{"a":"x"}]`)
+		require.Equal(t, jscandec.ErrUnexpectedValue, err)
+		require.Equal(t, 14, errIndex)
+		require.Equal(t, []S{{A: 1}}, values)
+	})
+
+	t.Run("err_truncated", func(t *testing.T) {
+		values, errIndex, err := decodeAll(t, `[{"a":1},`)
+		code, ok := jscandec.SyntaxErrorCode(err)
+		require.True(t, ok)
+		require.Equal(t, jscan.ErrorCodeUnexpectedEOF, code)
+		require.Equal(t, 9, errIndex)
+		require.Equal(t, []S{{A: 1}}, values)
+	})
+
+	t.Run("err_separator", func(t *testing.T) {
+		_, errIndex, err := decodeAll(t, `[{"a":1} {"a":2}]`)
+		code, ok := jscandec.SyntaxErrorCode(err)
+		require.True(t, ok)
+		require.Equal(t, jscan.ErrorCodeUnexpectedToken, code)
+		require.Equal(t, 9, errIndex)
+	})
+}
+
+func TestDecodeArrayIndexed(t *testing.T) {
+	type Record struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+		Tags []int  `json:"tags"`
+	}
+	d, err := jscandec.NewDecoder[string, Record](
+		jscan.NewTokenizer[string](16, 1024), jscandec.DefaultInitOptions,
+	)
+	require.NoError(t, err)
+
+	t.Run("objects", func(t *testing.T) {
+		var m map[string]Record
+		errIndex, err := jscandec.DecodeArrayIndexed(d,
+			` [{"id":"a","name":"A"}, {"tags":[1],"name":"B","id":"b"}] `,
+			"id", &m, jscandec.DefaultOptions)
+		require.NoError(t, err)
+		require.Equal(t, -1, errIndex)
+		require.Equal(t, map[string]Record{
+			"a": {ID: "a", Name: "A"},
+			"b": {ID: "b", Name: "B", Tags: []int{1}},
+		}, m)
+	})
+
+	t.Run("duplicate_last_wins", func(t *testing.T) {
+		m := map[string]Record{"x": {ID: "x"}}
+		_, err := jscandec.DecodeArrayIndexed(d,
+			`[{"id":"a","name":"1"},{"id":"a","name":"2"}]`,
+			"id", &m, jscandec.DefaultOptions)
+		require.NoError(t, err)
+		require.Equal(t, map[string]Record{
+			"x": {ID: "x"},
+			"a": {ID: "a", Name: "2"},
+		}, m)
+	})
+
+	t.Run("case_insensitive", func(t *testing.T) {
+		var m map[string]Record
+		_, err := jscandec.DecodeArrayIndexed(d, `[{"ID":"a"}]`,
+			"id", &m, jscandec.DefaultOptions)
+		require.NoError(t, err)
+		require.Equal(t, map[string]Record{"a": {ID: "a"}}, m)
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		var m map[string]Record
+		_, err := jscandec.DecodeArrayIndexed(d, `[]`, "id", &m, jscandec.DefaultOptions)
+		require.NoError(t, err)
+		require.Equal(t, map[string]Record{}, m)
+	})
+
+	t.Run("int_key", func(t *testing.T) {
+		type Item struct {
+			N int `json:"n"`
+		}
+		d, err := jscandec.NewDecoder[[]byte, Item](
+			jscan.NewTokenizer[[]byte](16, 1024), jscandec.DefaultInitOptions,
+		)
+		require.NoError(t, err)
+		var m map[int]Item
+		_, err = jscandec.DecodeArrayIndexed(d, []byte(`[{"n":2},{"n":1}]`),
+			"n", &m, jscandec.DefaultOptions)
+		require.NoError(t, err)
+		require.Equal(t, map[int]Item{1: {N: 1}, 2: {N: 2}}, m)
+	})
+
+	t.Run("err_missing_key", func(t *testing.T) {
+		var m map[string]Record
+		errIndex, err := jscandec.DecodeArrayIndexed(d,
+			`[{"id":"a"},{"name":"id","tags":[]}]`,
+			"id", &m, jscandec.DefaultOptions)
+		require.ErrorIs(t, err, jscandec.ErrMissingKeyField)
+		require.Equal(t, 12, errIndex)
+		require.Equal(t, map[string]Record{"a": {ID: "a"}}, m)
+	})
+
+	t.Run("err_null_element", func(t *testing.T) {
+		var m map[string]Record
+		errIndex, err := jscandec.DecodeArrayIndexed(d, `[null]`,
+			"id", &m, jscandec.DefaultOptions)
+		require.ErrorIs(t, err, jscandec.ErrMissingKeyField)
+		require.Equal(t, 1, errIndex)
+	})
+
+	t.Run("err_value", func(t *testing.T) {
+		var m map[string]Record
+		errIndex, err := jscandec.DecodeArrayIndexed(d, `[{"id":1}]`,
+			"id", &m, jscandec.DefaultOptions)
+		require.ErrorIs(t, err, jscandec.ErrUnexpectedValue)
+		require.Equal(t, 7, errIndex)
+	})
+
+	t.Run("err_unknown_field", func(t *testing.T) {
+		var m map[string]Record
+		_, err := jscandec.DecodeArrayIndexed(d, `[]`, "key", &m, jscandec.DefaultOptions)
+		require.ErrorIs(t, err, jscandec.ErrUnsupportedType)
+	})
+
+	t.Run("err_key_type", func(t *testing.T) {
+		var m map[int]Record
+		_, err := jscandec.DecodeArrayIndexed(d, `[]`, "id", &m, jscandec.DefaultOptions)
+		require.ErrorIs(t, err, jscandec.ErrUnsupportedType)
+	})
+}
+
+func TestDecodeHexIntegers(t *testing.T) {
+	t.Run("uint8", func(t *testing.T) {
+		s := newTestSetup[uint8](t, jscandec.DecodeOptions{AllowHexIntegers: true})
+		s.testOKNonstandard(t, "0xFF", `0xFF`, 255)
+		s.testOKNonstandard(t, "0xff", `0xff`, 255)
+		s.testOKNonstandard(t, "0X0f", ` 0X0f `, 15)
+		s.testOKNonstandard(t, "decimal", `255`, 255)
+
+		// Top-level values are wrapped because encoding/json.Decoder
+		// would stop reading at "x" without returning an error.
+		type T struct{ V uint8 }
+		st := newTestSetup[T](t, jscandec.DecodeOptions{AllowHexIntegers: true})
+		st.testErr(t, "overflow", `{"V":0x100}`, 5, jscandec.ErrIntegerOverflow)
+		st.testErr(t, "negative", `{"V":-0x1}`, 5, jscandec.ErrUnexpectedValue)
+	})
+	t.Run("slice_int64", func(t *testing.T) {
+		type T = []int64
+		s := newTestSetup[T](t, jscandec.DecodeOptions{AllowHexIntegers: true})
+		s.testOKNonstandard(t, "min_max", `[-0x8000000000000000,0x7fffffffffffffff]`,
+			T{math.MinInt64, math.MaxInt64})
+		s.testOKNonstandard(t, "-0x0", `[-0x0]`, T{0})
+		s.testErr(t, "overflow", `[0x8000000000000000]`, 1, jscandec.ErrIntegerOverflow)
+		s.testErr(t, "overflow_big", `[0x1ffffffffffffffff]`, 1, jscandec.ErrIntegerOverflow)
+	})
+	t.Run("slice_uint16", func(t *testing.T) {
+		type T = []uint16
+		s := newTestSetup[T](t, jscandec.DecodeOptions{AllowHexIntegers: true})
+		s.testOKNonstandard(t, "mixed", `[1,0x10,0xFFFF]`,
+			T{1, 16, 0xFFFF})
+		s.testErr(t, "overflow_index", `[0x1, 0x10000]`, 6, jscandec.ErrIntegerOverflow)
+		// Error indexes after a rewritten literal refer to the original input.
+		s.testErr(t, "index_after_literal", `[0xFFFF, "x"]`, 9, jscandec.ErrUnexpectedValue)
+	})
+	t.Run("string_tag", func(t *testing.T) {
+		type T struct {
+			U8  uint8 `json:",string"`
+			I64 int64 `json:",string"`
+		}
+		s := newTestSetup[T](t, jscandec.DecodeOptions{AllowHexIntegers: true})
+		s.testOKNonstandard(t, "hex", `{"U8":"0xFF","I64":"-0x1F"}`, T{U8: 255, I64: -31})
+		s.testErr(t, "overflow", `{"U8":"0x100"}`, 6, jscandec.ErrUnexpectedValue)
+	})
+	t.Run("string_unaffected", func(t *testing.T) {
+		s := newTestSetup[[]string](t, jscandec.DecodeOptions{AllowHexIntegers: true})
+		s.TestOK(t, "hex_in_string", `["0xFF", "-0x1"]`, []string{"0xFF", "-0x1"})
+	})
+	t.Run("non_integer", func(t *testing.T) {
+		// Hexadecimal literals are only accepted by integer types.
+		type T struct {
+			F32 float32
+			F64 float64
+			Any any
+			Num jscandec.Number
+			Rat *big.Rat
+		}
+		s := newTestSetup[T](t, jscandec.DecodeOptions{AllowHexIntegers: true})
+		s.testErr(t, "float32", `{"F32":0x10}`, 7, jscandec.ErrUnexpectedValue)
+		s.testErr(t, "float64", `{"F64":0x10}`, 7, jscandec.ErrUnexpectedValue)
+		s.testErr(t, "any", `{"Any":0x10}`, 7, jscandec.ErrUnexpectedValue)
+		s.testErr(t, "any_nested", `{"Any":[1,{"x":-0x10}]}`, 15, jscandec.ErrUnexpectedValue)
+		s.testErr(t, "number", `{"Num":0x10}`, 7, jscandec.ErrUnexpectedValue)
+		s.testErr(t, "big_rat", `{"Rat":0x10}`, 7, jscandec.ErrUnexpectedValue)
+		s.testOKNonstandard(t, "decimal", `{"F64":16,"Any":16,"Num":16}`,
+			T{F64: 16, Any: float64(16), Num: "16"})
+
+		sf64 := newTestSetup[[]float64](t, jscandec.DecodeOptions{AllowHexIntegers: true})
+		sf64.testErr(t, "slice_float64", `[1, 0x10]`, 4, jscandec.ErrUnexpectedValue)
+		sf32 := newTestSetup[[]float32](t, jscandec.DecodeOptions{AllowHexIntegers: true})
+		sf32.testErr(t, "slice_float32", `[1, 0x10]`, 4, jscandec.ErrUnexpectedValue)
+		af64 := newTestSetup[[2]float64](t, jscandec.DecodeOptions{AllowHexIntegers: true})
+		af64.testErr(t, "array_float64", `[1, 0x10]`, 4, jscandec.ErrUnexpectedValue)
+		sm := newTestSetup[map[string]any](t, jscandec.DecodeOptions{AllowHexIntegers: true})
+		sm.testErr(t, "map_any", `{"a":1,"b":0xF}`, 11, jscandec.ErrUnexpectedValue)
+	})
+	t.Run("disabled_by_default", func(t *testing.T) {
+		s := newTestSetup[[]uint8](t, *jscandec.DefaultOptions)
+		s.testErrCheck(t, "0xFF", `[0xFF]`, func(t *testing.T, errIndex int, err error) {
+			require.Error(t, err)
+			require.Equal(t, 2, errIndex)
+		})
+		type T struct {
+			U8 uint8 `json:",string"`
+		}
+		st := newTestSetup[T](t, *jscandec.DefaultOptions)
+		st.testErr(t, "string_tag", `{"U8":"0xFF"}`, 6, jscandec.ErrUnexpectedValue)
+	})
+}
+
+func TestDecodeUnquotedKeys(t *testing.T) {
+	opts := jscandec.DecodeOptions{AllowUnquotedKeys: true}
+	t.Run("struct", func(t *testing.T) {
+		type T struct {
+			Name string `json:"name"`
+			Age  int    `json:"age"`
+		}
+		s := newTestSetup[T](t, opts)
+		s.testOKNonstandard(t, "unquoted", `{name:"x",age:3}`, T{Name: "x", Age: 3})
+		s.testOKNonstandard(t, "whitespace", "{ name : \"x\" ,\n\tage\t: 3 }",
+			T{Name: "x", Age: 3})
+		s.testOKNonstandard(t, "mixed", `{"name":"x",age:3}`, T{Name: "x", Age: 3})
+		s.testOKNonstandard(t, "case_insensitive", `{NAME:"x"}`, T{Name: "x"})
+		s.testOKNonstandard(t, "string_untouched", `{name:"a:b,c:d",age:1}`,
+			T{Name: "a:b,c:d", Age: 1})
+		s.testOKNonstandard(t, "identifier_chars", `{_x$1:1,name:"x"}`, T{Name: "x"})
+		// Error indexes after a rewritten key refer to the original input.
+		s.testErr(t, "index_after_key", `{name:"x",age:"3"}`, 14, jscandec.ErrUnexpectedValue)
+	})
+	t.Run("map_string_any", func(t *testing.T) {
+		type T = map[string]any
+		s := newTestSetup[T](t, opts)
+		s.testOKNonstandard(t, "unquoted", `{name:"x",age:3}`, T{"name": "x", "age": 3.0})
+		s.testOKNonstandard(t, "nested", `{a:{b:[{c:null}]}}`, T{
+			"a": map[string]any{"b": []any{map[string]any{"c": nil}}},
+		})
+		s.testOKNonstandard(t, "keywords", `{true:1,null:2}`, T{"true": 1.0, "null": 2.0})
+	})
+	t.Run("values_unaffected", func(t *testing.T) {
+		type T = []any
+		s := newTestSetup[T](t, opts)
+		s.testOKNonstandard(t, "literals", `[true,false,null]`, T{true, false, nil})
+		s.testErrCheck(t, "identifier_value", `[name]`,
+			func(t *testing.T, errIndex int, err error) {
+				_, ok := jscandec.SyntaxErrorCode(err)
+				require.True(t, ok)
+				require.Equal(t, 1, errIndex)
+			})
+	})
+	t.Run("disabled", func(t *testing.T) {
+		type T struct {
+			Name string `json:"name"`
+		}
+		s := newTestSetup[T](t, *jscandec.DefaultOptions)
+		s.testErrCheck(t, "unquoted", `{name:"x"}`,
+			func(t *testing.T, errIndex int, err error) {
+				_, ok := jscandec.SyntaxErrorCode(err)
+				require.True(t, ok)
+				require.Equal(t, 1, errIndex)
+			})
+	})
+}
+
+func TestDecodeLeadingZeros(t *testing.T) {
+	type T struct {
+		I   int         `json:"i"`
+		U8  uint8       `json:"u8"`
+		F   float64     `json:"f"`
+		N   json.Number `json:"n"`
+		IS  int         `json:"is,string"`
+		U8S uint8       `json:"u8s,string"`
+	}
+
+	t.Run("default", func(t *testing.T) {
+		s := newTestSetup[T](t, *jscandec.DefaultOptions)
+		s.TestOK(t, "0", `{"i":0,"is":"0"}`, T{})
+		s.TestOK(t, "-0", `{"i":-0,"is":"-0"}`, T{})
+		for _, input := range []string{`{"i":01}`, `{"i":007}`, `{"f":00.5}`} {
+			s.testErrCheck(t, input, input, func(t *testing.T, errIndex int, err error) {
+				var errSyntax jscan.Error[string]
+				var errSyntaxBytes jscan.Error[[]byte]
+				require.True(t, errors.As(err, &errSyntax) || errors.As(err, &errSyntaxBytes))
+				require.Equal(t, 6, errIndex)
+			})
+		}
+		// encoding/json accepts leading zeros in fields with the string tag option.
+		s.TestOK(t, "string_01", `{"is":"01","u8s":"01"}`, T{IS: 1, U8S: 1})
+		s.TestOK(t, "string_-007", `{"is":"-007"}`, T{IS: -7})
+		// Quoted values of type Number are left unchanged.
+		s.testOKNonstandard(t, "number_str_01", `{"n":"01"}`, T{N: "01"})
+	})
+
+	t.Run("allow", func(t *testing.T) {
+		s := newTestSetup[T](t, jscandec.DecodeOptions{AllowLeadingZeros: true})
+		s.TestOK(t, "0", `{"i":0,"is":"0"}`, T{})
+		s.TestOK(t, "-0", `{"i":-0,"is":"-0"}`, T{})
+		s.TestOK(t, "string_01", `{"is":"01","u8s":"01"}`, T{IS: 1, U8S: 1})
+		s.testOKNonstandard(t, "01", `{"i":01}`, T{I: 1})
+		s.testOKNonstandard(t, "007", `{"i":007}`, T{I: 7})
+		s.testOKNonstandard(t, "-007", `{"i":-007}`, T{I: -7})
+		s.testOKNonstandard(t, "-00", `{"i":-00}`, T{})
+		s.testOKNonstandard(t, "uint", `{"u8":00255}`, T{U8: 255})
+		s.testOKNonstandard(t, "float", `{"f":-00.5}`, T{F: -0.5})
+		s.testOKNonstandard(t, "number", `{"n":007}`, T{N: "7"})
+		s.testOKNonstandard(t, "number_str", `{"n":"007"}`, T{N: "7"})
+		// Error indexes refer to the original input.
+		s.testErr(t, "negative_uint", `{"i":01,"u8":-0001}`, 13, jscandec.ErrUnexpectedValue)
+		s.testErr(t, "overflow", `{"i":01,"u8":0256}`, 13, jscandec.ErrIntegerOverflow)
+	})
+
+	t.Run("allow_strings_unaffected", func(t *testing.T) {
+		s := newTestSetup[[]string](t, jscandec.DecodeOptions{AllowLeadingZeros: true})
+		s.TestOK(t, "strings", `["007", "-01"]`, []string{"007", "-01"})
+	})
+}
+
+func TestDecodeUnderscoreInNumbers(t *testing.T) {
+	type T struct {
+		I   int         `json:"i"`
+		U8  uint8       `json:"u8"`
+		F   float64     `json:"f"`
+		N   json.Number `json:"n"`
+		A   any         `json:"a"`
+		IS  int         `json:"is,string"`
+		FS  float64     `json:"fs,string"`
+		STR string      `json:"str"`
+	}
+	errSyntax := func(expectIndex int) func(t *testing.T, errIndex int, err error) {
+		return func(t *testing.T, errIndex int, err error) {
+			var errSyntax jscan.Error[string]
+			var errSyntaxBytes jscan.Error[[]byte]
+			require.True(t, errors.As(err, &errSyntax) || errors.As(err, &errSyntaxBytes))
+			require.Equal(t, expectIndex, errIndex)
+		}
+	}
+
+	t.Run("default", func(t *testing.T) {
+		s := newTestSetup[T](t, *jscandec.DefaultOptions)
+		s.testErrCheck(t, "int", `{"i":1_000}`, errSyntax(6))
+		s.testErrCheck(t, "float", `{"f":3_000.5}`, errSyntax(6))
+		s.testErr(t, "string_tag", `{"is":"1_000"}`, 6, jscandec.ErrUnexpectedValue)
+		s.testErr(t, "string_tag_trailing", `{"is":"1abc"}`, 6, jscandec.ErrUnexpectedValue)
+		s.testErr(t, "string_tag_float", `{"fs":"3_000.5"}`, 6, jscandec.ErrUnexpectedValue)
+	})
+
+	t.Run("allow", func(t *testing.T) {
+		s := newTestSetup[T](t, jscandec.DecodeOptions{AllowUnderscoreInNumbers: true})
+		s.TestOK(t, "no_underscores", `{"i":1000,"f":3000.5}`, T{I: 1000, F: 3000.5})
+		s.testOKNonstandard(t, "int", `{"i":1_000}`, T{I: 1000})
+		s.testOKNonstandard(t, "int_negative", `{"i":-1_000_000}`, T{I: -1000000})
+		s.testOKNonstandard(t, "uint", `{"u8":2_55}`, T{U8: 255})
+		s.testOKNonstandard(t, "float", `{"f":3_000.5}`, T{F: 3000.5})
+		s.testOKNonstandard(t, "float_exponent", `{"f":1_0.2_5e1_0}`, T{F: 10.25e10})
+		s.testOKNonstandard(t, "number", `{"n":1_000.0_1}`, T{N: "1000.01"})
+		s.testOKNonstandard(t, "any", `{"a":[1_000,-2_5.5]}`, T{A: []any{1000.0, -25.5}})
+		s.testOKNonstandard(t, "string_tag", `{"is":"-1_000","fs":"3_000.5"}`,
+			T{IS: -1000, FS: 3000.5})
+		s.testOKNonstandard(t, "number_str", `{"n":"1_0"}`, T{N: "10"})
+		s.TestOK(t, "string_unaffected", `{"str":"1_000"}`, T{STR: "1_000"})
+
+		// Underscores must separate digits.
+		s.testErrCheck(t, "double", `{"i":1__0}`, errSyntax(6))
+		s.testErrCheck(t, "trailing", `{"i":1_}`, errSyntax(6))
+		s.testErrCheck(t, "leading", `{"i":_1}`, errSyntax(5))
+		s.testErrCheck(t, "before_fraction", `{"f":1_.5}`, errSyntax(6))
+		s.testErr(t, "string_tag_double", `{"is":"1__0"}`, 6, jscandec.ErrUnexpectedValue)
+		// Error indexes refer to the original input.
+		s.testErr(t, "overflow", `{"i":1_0,"u8":2_56}`, 14, jscandec.ErrIntegerOverflow)
+	})
+
+	t.Run("allow_leading_zeros", func(t *testing.T) {
+		s := newTestSetup[T](t, jscandec.DecodeOptions{
+			AllowUnderscoreInNumbers: true,
+			AllowLeadingZeros:        true,
+		})
+		s.testOKNonstandard(t, "int", `{"i":00_1}`, T{I: 1})
+	})
+}
+
+func TestDecodeIntegersAsInt64(t *testing.T) {
+	s := newTestSetup[any](t, jscandec.DecodeOptions{IntegersAsInt64: true})
+	s.testOKNonstandard(t, "int", `42`, int64(42))
+	s.testOKNonstandard(t, "negative", `-42`, int64(-42))
+	s.testOKNonstandard(t, "int64_min", `-9223372036854775808`, int64(math.MinInt64))
+	s.testOKNonstandard(t, "int64_max", `9223372036854775807`, int64(math.MaxInt64))
+	s.testOKNonstandard(t, "fraction", `42.5`, float64(42.5))
+	s.testOKNonstandard(t, "exponent", `1e2`, float64(100))
+	s.testOKNonstandard(t, "overflow", `9223372036854775808`, float64(9223372036854775808))
+	s.testOKNonstandard(t, "composite", `{"a":[1,2.5,{"b":3}],"c":null}`,
+		map[string]any{"a": []any{int64(1), 2.5, map[string]any{"b": int64(3)}}, "c": nil})
+
+	type S struct {
+		A any            `json:"a"`
+		M map[string]any `json:"m"`
+	}
+	ss := newTestSetup[S](t, jscandec.DecodeOptions{IntegersAsInt64: true})
+	ss.testOKNonstandard(t, "struct", `{"a":1,"m":{"x":2,"y":2.5}}`,
+		S{A: int64(1), M: map[string]any{"x": int64(2), "y": 2.5}})
+
+	// Disabled by default
+	sd := newTestSetup[any](t, *jscandec.DefaultOptions)
+	sd.TestOK(t, "default", `[42,42.5]`, []any{float64(42), 42.5})
+}
+
+func TestDecodeUseNumberDepth(t *testing.T) {
+	type N = jscandec.Number
+	type M = map[string]any
+	const input = `[1,{"a":2.5,"b":[3]}]`
+
+	t.Run("any", func(t *testing.T) {
+		for _, td := range []struct {
+			depth  int
+			expect any
+		}{
+			{depth: 1, expect: []any{1.0, M{"a": 2.5, "b": []any{3.0}}}},
+			{depth: 2, expect: []any{N("1"), M{"a": 2.5, "b": []any{3.0}}}},
+			{depth: 3, expect: []any{N("1"), M{"a": N("2.5"), "b": []any{3.0}}}},
+			{depth: 4, expect: []any{N("1"), M{"a": N("2.5"), "b": []any{N("3")}}}},
+		} {
+			s := newTestSetup[any](t, jscandec.DecodeOptions{UseNumberDepth: td.depth})
+			s.testOKNonstandard(t, fmt.Sprintf("depth_%d", td.depth), input, td.expect)
+		}
+		s := newTestSetup[any](t, jscandec.DecodeOptions{UseNumberDepth: 1})
+		s.testOKNonstandard(t, "scalar", `12345678901234567890`, N("12345678901234567890"))
+	})
+
+	t.Run("slice", func(t *testing.T) {
+		s := newTestSetup[[]any](t, jscandec.DecodeOptions{UseNumberDepth: 1})
+		s.testOKNonstandard(t, "top_level", `[1,-2.5e3,[3]]`,
+			[]any{N("1"), N("-2.5e3"), []any{3.0}})
+	})
+
+	t.Run("struct", func(t *testing.T) {
+		type S struct {
+			ID   any `json:"id"`
+			Data any `json:"data"`
+		}
+		s := newTestSetup[S](t, jscandec.DecodeOptions{UseNumberDepth: 1})
+		s.testOKNonstandard(t, "lossless_id",
+			`{"id":12345678901234567890,"data":{"x":1.5,"y":[2]}}`,
+			S{ID: N("12345678901234567890"), Data: M{"x": 1.5, "y": []any{2.0}}})
+	})
+
+	t.Run("integers_as_int64", func(t *testing.T) {
+		s := newTestSetup[[]any](t, jscandec.DecodeOptions{
+			UseNumberDepth:  1,
+			IntegersAsInt64: true,
+		})
+		s.testOKNonstandard(t, "deeper", `[1,[2,2.5]]`, []any{N("1"), []any{int64(2), 2.5}})
+	})
+}
+
+func TestDecodeRequireCanonicalNumbers(t *testing.T) {
+	opts := jscandec.DecodeOptions{RequireCanonicalNumbers: true}
+
+	t.Run("float64", func(t *testing.T) {
+		s := newTestSetup[float64](t, opts)
+		for _, input := range []string{
+			`0`, `1`, `-1`, `100`, `1.5`, `-0.5`, `0.000001`, `1e-7`, `-2.5e-10`,
+			`1e21`, `1.5e21`, `123456789012345678901`, `1e308`,
+		} {
+			var expect float64
+			require.NoError(t, json.Unmarshal([]byte(input), &expect))
+			s.TestOK(t, input, input, expect)
+		}
+		for _, input := range []string{
+			`-0`, `-0.0`, `1.0`, `1.50`, `1e0`, `1e2`, `1e20`, `1e-6`,
+			`1E21`, `1e+21`, `1e021`, `12e21`, `0.1e22`, `0.0000001`,
+			`1000000000000000000000`,
+		} {
+			s.testErrNonstandard(t, input, input, 0, jscandec.ErrNonCanonicalNumber)
+		}
+	})
+
+	t.Run("int", func(t *testing.T) {
+		s := newTestSetup[int](t, opts)
+		s.TestOK(t, "1", `1`, 1)
+		s.testErrNonstandard(t, "1.0", `1.0`, 0, jscandec.ErrNonCanonicalNumber)
+		s.testErrNonstandard(t, "1e2", `1e2`, 0, jscandec.ErrNonCanonicalNumber)
+
+		o := opts
+		o.AllowFloatAsInt = true
+		s = newTestSetup[int](t, o)
+		s.testErrNonstandard(t, "float_as_int", `1e2`, 0, jscandec.ErrNonCanonicalNumber)
+	})
+
+	t.Run("composite", func(t *testing.T) {
+		type S struct {
+			A []any `json:"a"`
+		}
+		s := newTestSetup[S](t, opts)
+		s.TestOK(t, "canonical", `{"a":[1,2.5,{"b":1e-7}],"x":3}`,
+			S{A: []any{1.0, 2.5, map[string]any{"b": 1e-7}}})
+		s.testErrNonstandard(t, "nested", `{"a":[1,{"b":1.0}]}`,
+			13, jscandec.ErrNonCanonicalNumber)
+		s.testErrNonstandard(t, "skipped", `{"unknown":1.0}`,
+			11, jscandec.ErrNonCanonicalNumber)
+		// Numbers in strings aren't number literals.
+		s.TestOK(t, "string", `{"a":["1.0"]}`, S{A: []any{"1.0"}})
+	})
+}
+
+func TestDecodeAllowFloatAsInt(t *testing.T) {
+	opts := jscandec.DecodeOptions{AllowFloatAsInt: true}
+
+	t.Run("int8", func(t *testing.T) {
+		s := newTestSetup[int8](t, opts)
+		s.testOKNonstandard(t, "1e2", `1e2`, 100)
+		s.testOKNonstandard(t, "250e-1", `250e-1`, 25)
+		s.testOKNonstandard(t, "2.5e1", `2.5e1`, 25)
+		s.testOKNonstandard(t, "negative", `-1.28e2`, -128)
+		s.testOKNonstandard(t, "fraction", `12.0`, 12)
+		s.testErrNonstandard(t, "non_integral", `1.5e0`, 0, jscandec.ErrUnexpectedValue)
+		s.testErrNonstandard(t, "overflow", `1.28e2`, 0, jscandec.ErrIntegerOverflow)
+		s.testErrNonstandard(t, "underflow", `-1.29e2`, 0, jscandec.ErrIntegerOverflow)
+	})
+
+	t.Run("int", func(t *testing.T) {
+		s := newTestSetup[int](t, opts)
+		s.testOKNonstandard(t, "1e2", `1e2`, 100)
+		s.testOKNonstandard(t, "250e-1", `250e-1`, 25)
+		s.testOKNonstandard(t, "negative", `-1E3`, -1000)
+		s.testOKNonstandard(t, "integer", `42`, 42)
+		s.testErrNonstandard(t, "non_integral", `1.5e0`, 0, jscandec.ErrUnexpectedValue)
+		s.testErrNonstandard(t, "overflow", `1e30`, 0, jscandec.ErrIntegerOverflow)
+		s.testErrNonstandard(t, "out_of_float64_range", `1e400`,
+			0, jscandec.ErrIntegerOverflow)
+	})
+
+	t.Run("int32", func(t *testing.T) {
+		s := newTestSetup[int32](t, opts)
+		s.testOKNonstandard(t, "1e2", `1e2`, 100)
+		s.testErrNonstandard(t, "overflow", `1e30`, 0, jscandec.ErrIntegerOverflow)
+	})
+
+	t.Run("int64", func(t *testing.T) {
+		// Beyond 2^53 float64 can't represent all integers,
+		// the decimal digits are checked instead.
+		s := newTestSetup[int64](t, opts)
+		s.testOKNonstandard(t, "2^53+1", `9007199254740993.0`, 9007199254740993)
+		s.testOKNonstandard(t, "exponent_0", `1234567890123456789e0`,
+			1234567890123456789)
+		s.testOKNonstandard(t, "max", `9223372036854775807.0`, math.MaxInt64)
+		s.testOKNonstandard(t, "min", `-9223372036854775808.0`, math.MinInt64)
+		s.testOKNonstandard(t, "trailing_zeros", `12300.000e-2`, 123)
+		s.testErrNonstandard(t, "non_integral_fraction", `1.00000000000000000001`,
+			0, jscandec.ErrUnexpectedValue)
+		s.testErrNonstandard(t, "non_integral_tiny", `1e-400`,
+			0, jscandec.ErrUnexpectedValue)
+		s.testErrNonstandard(t, "overflow", `9223372036854775808.0`,
+			0, jscandec.ErrIntegerOverflow)
+	})
+
+	t.Run("uint64", func(t *testing.T) {
+		s := newTestSetup[uint64](t, opts)
+		s.testOKNonstandard(t, "1e2", `1e2`, 100)
+		s.testOKNonstandard(t, "250e-1", `250e-1`, 25)
+		s.testOKNonstandard(t, "1e19", `1e19`, 1e19)
+		s.testOKNonstandard(t, "max", `18446744073709551615.0`, math.MaxUint64)
+		s.testErrNonstandard(t, "max_overflow", `18446744073709551616.0`,
+			0, jscandec.ErrIntegerOverflow)
+		s.testErrNonstandard(t, "non_integral", `1.5e0`, 0, jscandec.ErrUnexpectedValue)
+		s.testErrNonstandard(t, "negative", `-1e2`, 0, jscandec.ErrIntegerOverflow)
+		s.testErrNonstandard(t, "overflow", `1.8446744073709552e19`,
+			0, jscandec.ErrIntegerOverflow)
+	})
+
+	t.Run("slices", func(t *testing.T) {
+		si := newTestSetup[[]int](t, opts)
+		si.testOKNonstandard(t, "int", `[1e2,250e-1,3]`, []int{100, 25, 3})
+		si.testErrNonstandard(t, "int_non_integral", `[1,1.5e0]`,
+			3, jscandec.ErrUnexpectedValue)
+		s8 := newTestSetup[[]int8](t, opts)
+		s8.testOKNonstandard(t, "int8", `[1e2,-2.5e1]`, []int8{100, -25})
+		s8.testErrNonstandard(t, "int8_overflow", `[1e2,1e3]`,
+			5, jscandec.ErrIntegerOverflow)
+		su := newTestSetup[[]uint64](t, opts)
+		su.testOKNonstandard(t, "uint64", `[1e2,2.5e1]`, []uint64{100, 25})
+	})
+
+	t.Run("struct", func(t *testing.T) {
+		type T struct {
+			I8  int8   `json:"i8"`
+			I32 int32  `json:"i32"`
+			U64 uint64 `json:"u64"`
+		}
+		s := newTestSetup[T](t, opts)
+		s.testOKNonstandard(t, "ok", `{"i8":1e2,"i32":250e-1,"u64":2.5e1}`,
+			T{I8: 100, I32: 25, U64: 25})
+		s.testErrNonstandard(t, "overflow", `{"i32":1e30}`,
+			7, jscandec.ErrIntegerOverflow)
+	})
+
+	t.Run("default", func(t *testing.T) {
+		s := newTestSetup[int](t, *jscandec.DefaultOptions)
+		s.testErr(t, "exponent", `1e2`, 0, jscandec.ErrUnexpectedValue)
+		s.testErr(t, "fraction", `1.0`, 0, jscandec.ErrUnexpectedValue)
+		ss := newTestSetup[[]int](t, *jscandec.DefaultOptions)
+		ss.testErr(t, "slice", `[1,1e2]`, 3, jscandec.ErrUnexpectedValue)
+	})
+}
+
+func TestDecodeStringTransform(t *testing.T) {
+	type S struct {
+		Str    string            `json:"str"`
+		Ptr    *string           `json:"ptr"`
+		Slice  []string          `json:"slice"`
+		Array  [2]string         `json:"array"`
+		Map    map[string]string `json:"map"`
+		MapInt map[string]int    `json:"mapint"`
+		Any    any               `json:"any"`
+		Tagged string            `json:"tagged,string"`
+		Number json.Number       `json:"number"`
+		Text   textUnmarshalerImpl
+	}
+	const input = `{
+		"str": " a ",
+		"ptr": " b ",
+		"slice": [" c ", null, "d "],
+		"array": [" e", "f"],
+		"map": {" k ": " v "},
+		"mapint": {" k ": 1},
+		"any": {" k ": [" v "]},
+		"tagged": "\" t \"",
+		"number": "1e2",
+		"Text": " x "
+	}`
+	ptr := func(s string) *string { return &s }
+	transform := func(s string) string { return strings.ToUpper(strings.TrimSpace(s)) }
+
+	t.Run("values", func(t *testing.T) {
+		s := newTestSetup[S](t, jscandec.DecodeOptions{
+			StringTransform: transform,
+		})
+		s.testOKNonstandard(t, "trim", input, S{
+			Str:    "A",
+			Ptr:    ptr("B"),
+			Slice:  []string{"C", "", "D"},
+			Array:  [2]string{"E", "F"},
+			Map:    map[string]string{" k ": "V"},
+			MapInt: map[string]int{" k ": 1},
+			Any:    map[string]any{" k ": []any{" v "}},
+			Tagged: " t ",
+			Number: "1e2",
+			Text:   textUnmarshalerImpl{Value: " x "},
+		})
+	})
+
+	t.Run("map_keys_and_any", func(t *testing.T) {
+		s := newTestSetup[S](t, jscandec.DecodeOptions{
+			StringTransform:        transform,
+			StringTransformMapKeys: true,
+			StringTransformAny:     true,
+		})
+		s.testOKNonstandard(t, "trim", input, S{
+			Str:    "A",
+			Ptr:    ptr("B"),
+			Slice:  []string{"C", "", "D"},
+			Array:  [2]string{"E", "F"},
+			Map:    map[string]string{"K": "V"},
+			MapInt: map[string]int{"K": 1},
+			Any:    map[string]any{"K": []any{"V"}},
+			Tagged: " t ",
+			Number: "1e2",
+			Text:   textUnmarshalerImpl{Value: " x "},
+		})
+	})
+
+	t.Run("rest", func(t *testing.T) {
+		type R struct {
+			Rest map[string]any `json:",rest"`
+		}
+		s := newTestSetup[R](t, jscandec.DecodeOptions{
+			StringTransform:        strings.ToUpper,
+			StringTransformMapKeys: true,
+			StringTransformAny:     true,
+		})
+		s.testOKNonstandard(t, "upper", `{"a":"b","c":{"d":"e"}}`, R{
+			Rest: map[string]any{"A": "B", "C": map[string]any{"D": "E"}},
+		})
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		s := newTestSetup[[]string](t, *jscandec.DefaultOptions)
+		s.TestOK(t, "untransformed", `[" a "]`, []string{" a "})
+	})
+}
+
+func TestDecodeRuneSliceFromString(t *testing.T) {
+	t.Run("enabled", func(t *testing.T) {
+		s := newTestSetup[[]rune](t, jscandec.DecodeOptions{RuneSliceFromString: true})
+		s.testOKNonstandard(t, "string", `"h\u00e9llo"`, []rune("héllo"))
+		s.testOKNonstandard(t, "utf8", `"héllo 👋"`, []rune("héllo 👋"))
+		s.testOKNonstandard(t, "empty", `""`, []rune{})
+		s.TestOK(t, "numbers", `[104,105]`, []rune{104, 105})
+		s.TestOK(t, "null", `null`, []rune(nil))
+
+		type S struct {
+			R []rune `json:"r"`
+		}
+		ss := newTestSetup[S](t, jscandec.DecodeOptions{RuneSliceFromString: true})
+		ss.testOKNonstandard(t, "field", `{"r":"ab"}`, S{R: []rune{'a', 'b'}})
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		s := newTestSetup[[]int32](t, *jscandec.DefaultOptions)
+		s.TestOK(t, "numbers", `[104,105]`, []int32{104, 105})
+		s.testErr(t, "string", `"hi"`, 0, jscandec.ErrUnexpectedValue)
+	})
+}
+
+func TestDecodeExtendedBoolStrings(t *testing.T) {
+	options := jscandec.DecodeOptions{ExtendedBoolStrings: true}
+	spellings := []struct {
+		Input  string
+		Expect bool
+	}{
+		{`"yes"`, true}, {`"no"`, false},
+		{`"on"`, true}, {`"off"`, false},
+		{`"1"`, true}, {`"0"`, false},
+		{`"true"`, true}, {`"false"`, false},
+	}
+
+	t.Run("bool", func(t *testing.T) {
+		s := newTestSetup[bool](t, options)
+		for _, sp := range spellings {
+			s.testOKNonstandard(t, sp.Input, sp.Input, sp.Expect)
+		}
+		s.TestOK(t, "literal", `true`, true)
+		s.testErrNonstandard(t, "maybe", `"maybe"`, 0, jscandec.ErrUnexpectedValue)
+		s.testErrNonstandard(t, "uppercase", `"YES"`, 0, jscandec.ErrUnexpectedValue)
+	})
+
+	t.Run("string_tag", func(t *testing.T) {
+		type S struct {
+			B bool `json:"b,string"`
+		}
+		s := newTestSetup[S](t, options)
+		for _, sp := range spellings {
+			s.testOKNonstandard(t, sp.Input, `{"b":`+sp.Input+`}`, S{B: sp.Expect})
+		}
+		s.testErrNonstandard(t, "maybe", `{"b":"maybe"}`, 5, jscandec.ErrUnexpectedValue)
+	})
+
+	t.Run("flat_struct_slice", func(t *testing.T) {
+		type F struct {
+			ID int  `json:"id"`
+			B  bool `json:"b"`
+		}
+		s := newTestSetup[[]F](t, options)
+		s.testOKNonstandard(t, "yes_off", `[{"b":"yes"},{"id":1,"b":"off"}]`,
+			[]F{{B: true}, {ID: 1}})
+		s.testErrNonstandard(t, "maybe", `[{"b":"maybe"}]`, 6, jscandec.ErrUnexpectedValue)
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		s := newTestSetup[bool](t, *jscandec.DefaultOptions)
+		s.testErr(t, "yes", `"yes"`, 0, jscandec.ErrUnexpectedValue)
+
+		type S struct {
+			B bool `json:"b,string"`
+		}
+		ss := newTestSetup[S](t, *jscandec.DefaultOptions)
+		ss.TestOK(t, "true", `{"b":"true"}`, S{B: true})
+		ss.testErr(t, "yes", `{"b":"yes"}`, 5, jscandec.ErrUnexpectedValue)
+	})
+}
+
+func TestDecodeFlatStructSlice(t *testing.T) {
+	type F struct {
+		B   bool    `json:"b"`
+		S   string  `json:"s"`
+		I   int     `json:"i"`
+		I8  int8    `json:"i8"`
+		U16 uint16  `json:"u16"`
+		F32 float32 `json:"f32"`
+		F64 float64 `json:"f64"`
+	}
+	s := newTestSetup[[]F](t, *jscandec.DefaultOptions)
+	s.TestOK(t, "empty", `[]`, []F{})
+	s.TestOK(t, "null", `null`, []F(nil))
+	s.TestOK(t, "field_order", `[
+		{"b":true,"s":"a","i":1,"i8":-2,"u16":3,"f32":1.5,"f64":2.5},
+		{"f64":2.5,"f32":1.5,"u16":3,"i8":-2,"i":1,"s":"a","b":true}
+	]`, []F{
+		{B: true, S: "a", I: 1, I8: -2, U16: 3, F32: 1.5, F64: 2.5},
+		{B: true, S: "a", I: 1, I8: -2, U16: 3, F32: 1.5, F64: 2.5},
+	})
+	s.TestOK(t, "missing_fields", `[{},{"s":"x"},null,{"i":1}]`,
+		[]F{{}, {S: "x"}, {}, {I: 1}})
+	s.TestOK(t, "unknown_fields", `[
+		{"x":1,"s":"a","y":{"z":[1,{"s":"no"}]},"w":[],"i":2,"v":"s"}
+	]`, []F{{S: "a", I: 2}})
+	s.TestOK(t, "null_fields", `[{"b":null,"s":null,"i":null,"f64":null}]`, []F{{}})
+	s.TestOK(t, "duplicate_keys", `[{"i":1,"i":2}]`, []F{{I: 2}})
+	s.TestOK(t, "case_insensitive", `[{"S":"a","I8":1}]`, []F{{S: "a", I8: 1}})
+	s.TestOK(t, "escaped_key_and_value", `[{"\u0073":"\u0061\n"}]`, []F{{S: "a\n"}})
+	s.TestOK(t, "integer_into_float", `[{"f32":16777217,"f64":1}]`,
+		[]F{{F32: 16777217, F64: 1}})
+	s.TestOK(t, "exponent_into_float", `[{"f32":1e2,"f64":-2.5e-1}]`,
+		[]F{{F32: 100, F64: -0.25}})
+	s.TestOKPrepare(t, "reuse", `[{"i":1},{"s":"b"}]`, Test[[]F]{
+		PrepareJscan: func() []F { return []F{{S: "x", I: 5}, {S: "y", I: 6}} },
+		Expect:       []F{{S: "x", I: 1}, {S: "b", I: 6}},
+	})
+
+	s.testErr(t, "element_not_object", `[{},1]`, 4, jscandec.ErrUnexpectedValue)
+	s.testErr(t, "element_array", `[[]]`, 1, jscandec.ErrUnexpectedValue)
+	s.testErr(t, "bool_into_string", `[{"s":true}]`, 6, jscandec.ErrUnexpectedValue)
+	s.testErr(t, "string_into_int", `[{"i":"1"}]`, 6, jscandec.ErrUnexpectedValue)
+	s.testErr(t, "number_into_int", `[{"i":1.5}]`, 6, jscandec.ErrUnexpectedValue)
+	s.testErr(t, "negative_uint", `[{"u16":-1}]`, 8, jscandec.ErrUnexpectedValue)
+	s.testErr(t, "overflow_int8", `[{"i8":128}]`, 7, jscandec.ErrIntegerOverflow)
+	s.testErr(t, "overflow_uint16", `[{"u16":65536}]`, 8, jscandec.ErrIntegerOverflow)
+	s.testErr(t, "object_into_int", `[{"i":{}}]`, 6, jscandec.ErrUnexpectedValue)
+
+	t.Run("disallow_unknown_fields", func(t *testing.T) {
+		s := newTestSetup[[]F](t, jscandec.DecodeOptions{DisallowUnknownFields: true})
+		s.TestOK(t, "known", `[{"s":"a"}]`, []F{{S: "a"}})
+		s.testErr(t, "unknown", `[{"s":"a","x":1}]`, 10, jscandec.ErrUnknownField)
+	})
+
+	t.Run("case_sensitive", func(t *testing.T) {
+		s := newTestSetup[[]F](t, jscandec.DecodeOptions{
+			DisableCaseInsensitiveMatching: true,
+		})
+		s.testOKNonstandard(t, "ignored", `[{"S":"a","s":"b","I":1}]`, []F{{S: "b"}})
+	})
+
+	t.Run("allow_float_as_int", func(t *testing.T) {
+		s := newTestSetup[[]F](t, jscandec.DecodeOptions{AllowFloatAsInt: true})
+		s.testOKNonstandard(t, "exponent", `[{"i":1e2,"i8":2.5e1}]`, []F{{I: 100, I8: 25}})
+		s.testErrNonstandard(t, "overflow", `[{"i8":1e3}]`, 7, jscandec.ErrIntegerOverflow)
+	})
+
+	t.Run("field_offsets", func(t *testing.T) {
+		// FieldOffsets falls back to the generic path.
+		tok := jscan.NewTokenizer[string](16, 1024)
+		d, err := jscandec.NewDecoder[string, []F](tok, jscandec.DefaultInitOptions)
+		require.NoError(t, err)
+		var offsets map[string]int
+		var v []F
+		_, err = d.Decode(`[{"s":"a"},{"i":1}]`, &v, &jscandec.DecodeOptions{
+			FieldOffsets: &offsets,
+		})
+		require.NoError(t, err)
+		require.Equal(t, []F{{S: "a"}, {I: 1}}, v)
+		require.Equal(t, map[string]int{"/0/s": 6, "/1/i": 16}, offsets)
+	})
+
+	t.Run("nested", func(t *testing.T) {
+		type Outer struct {
+			Items []F            `json:"items"`
+			ByKey map[string][]F `json:"by_key"`
+			Name  string         `json:"name"`
+		}
+		s := newTestSetup[Outer](t, *jscandec.DefaultOptions)
+		s.TestOK(t, "nested", `{"items":[{"i":1},{"s":"a"}],"by_key":{"k":[{"b":true}]},"name":"n"}`,
+			Outer{
+				Items: []F{{I: 1}, {S: "a"}},
+				ByKey: map[string][]F{"k": {{B: true}}},
+				Name:  "n",
+			})
+	})
+}
+
+func TestDecodeOptional(t *testing.T) {
+	type T struct {
+		F jscandec.Optional[int] `json:"f"`
+	}
+	s := newTestSetup[T](t, *jscandec.DefaultOptions)
+	s.TestOKPrepare(t, "absent", `{}`, Test[T]{
+		Expect: T{},
+	})
+	s.TestOKPrepare(t, "null", `{"f":null}`, Test[T]{
+		Expect: T{F: jscandec.Optional[int]{Set: true}},
+	})
+	s.TestOKPrepare(t, "present", `{"f":5}`, Test[T]{
+		Expect: T{F: jscandec.Optional[int]{Set: true, Valid: true, Value: 5}},
+	})
+	s.TestOKPrepare(t, "absent_keeps_value", `{}`, Test[T]{
+		PrepareJscan: func() T {
+			return T{F: jscandec.Optional[int]{Set: true, Valid: true, Value: 5}}
+		},
+		Expect: T{F: jscandec.Optional[int]{Set: true, Valid: true, Value: 5}},
+	})
+	s.TestOKPrepare(t, "null_resets_value", `{"f":null}`, Test[T]{
+		PrepareJscan: func() T {
+			return T{F: jscandec.Optional[int]{Set: true, Valid: true, Value: 5}}
+		},
+		Expect: T{F: jscandec.Optional[int]{Set: true}},
+	})
+	s.testErr(t, "wrong_type", `{"f":"5"}`, 5, jscandec.ErrUnexpectedValue)
+
+	t.Run("composite", func(t *testing.T) {
+		type S struct {
+			A string `json:"a"`
+		}
+		type T struct {
+			P jscandec.Optional[*int]            `json:"p"`
+			S jscandec.Optional[S]               `json:"s"`
+			L jscandec.Optional[[]S]             `json:"l"`
+			M jscandec.Optional[map[string]bool] `json:"m"`
+			N string                             `json:"n"`
+		}
+		s := newTestSetup[T](t, *jscandec.DefaultOptions)
+		i := 1
+		s.TestOKPrepare(t, "present", `{
+			"p":1,"s":{"a":"x"},"l":[{"a":"y"},{}],"m":{"k":true},"n":"z"
+		}`, Test[T]{
+			Expect: T{
+				P: jscandec.Optional[*int]{Set: true, Valid: true, Value: &i},
+				S: jscandec.Optional[S]{Set: true, Valid: true, Value: S{A: "x"}},
+				L: jscandec.Optional[[]S]{
+					Set: true, Valid: true, Value: []S{{A: "y"}, {}},
+				},
+				M: jscandec.Optional[map[string]bool]{
+					Set: true, Valid: true, Value: map[string]bool{"k": true},
+				},
+				N: "z",
+			},
 		})
-	s.TestOK(t, "array", `{"string":"c","json":[1,2, 3],"text":"","tail":[1,2]}`,
-		S{
-			String: "c",
-			JSON:   jsonUnmarshalerImpl{Value: `[1,2, 3]`},
-			Text:   textUnmarshalerImpl{Value: ""},
-			Tail:   []int{1, 2},
+		s.TestOKPrepare(t, "null", `{"p":null,"s":null,"l":null,"m":null,"n":"z"}`,
+			Test[T]{
+				Expect: T{
+					P: jscandec.Optional[*int]{Set: true},
+					S: jscandec.Optional[S]{Set: true},
+					L: jscandec.Optional[[]S]{Set: true},
+					M: jscandec.Optional[map[string]bool]{Set: true},
+					N: "z",
+				},
+			})
+	})
+
+	t.Run("container_elements", func(t *testing.T) {
+		type T struct {
+			L []jscandec.Optional[int]          `json:"l"`
+			M map[string]jscandec.Optional[int] `json:"m"`
+		}
+		s := newTestSetup[T](t, *jscandec.DefaultOptions)
+		s.TestOKPrepare(t, "mixed", `{"l":[1,null,3],"m":{"a":null,"b":2}}`, Test[T]{
+			Expect: T{
+				L: []jscandec.Optional[int]{
+					{Set: true, Valid: true, Value: 1},
+					{Set: true},
+					{Set: true, Valid: true, Value: 3},
+				},
+				M: map[string]jscandec.Optional[int]{
+					"a": {Set: true},
+					"b": {Set: true, Valid: true, Value: 2},
+				},
+			},
 		})
-	s.TestOK(t, "object", `{"string":"d","json":{"foo":["bar", null]},"tail":[1,2]}`,
-		S{
-			String: "d",
-			JSON:   jsonUnmarshalerImpl{Value: `{"foo":["bar", null]}`},
-			Text:   textUnmarshalerImpl{Value: ""},
-			Tail:   []int{1, 2},
+	})
+
+	t.Run("top_level", func(t *testing.T) {
+		type T = jscandec.Optional[string]
+		s := newTestSetup[T](t, *jscandec.DefaultOptions)
+		s.TestOKPrepare(t, "null", `null`, Test[T]{Expect: T{Set: true}})
+		s.TestOKPrepare(t, "string", `"x"`, Test[T]{
+			Expect: T{Set: true, Valid: true, Value: "x"},
 		})
+	})
 }
 
-func TestDecodeJSONUnmarshalerErr(t *testing.T) {
-	s := newTestSetup[unmarshalerImplErr](t, *jscandec.DefaultOptions)
-	s.testErrCheck(t, "integer", `123`, func(t *testing.T, errIndex int, err error) {
-		require.Equal(t, errUnmarshalerImpl, err)
+func TestDecodeStringed(t *testing.T) {
+	t.Run("int", func(t *testing.T) {
+		type T = []jscandec.Stringed[int]
+		s := newTestSetup[T](t, *jscandec.DefaultOptions)
+		s.TestOK(t, "valid", `["1","-2"]`, T{{1}, {-2}})
+		s.TestOK(t, "empty", `[]`, T{})
+		s.TestOK(t, "null", `null`, T(nil))
+		s.testErr(t, "float", `["1.5"]`, 1, jscandec.ErrUnexpectedValue)
+		s.testErr(t, "number", `[1]`, 1, jscandec.ErrUnexpectedValue)
+		s.testErr(t, "overflow", `["99999999999999999999"]`,
+			1, jscandec.ErrUnexpectedValue)
 	})
 
-	s2 := newTestSetup[map[textUnmarshalerImplErr]struct{}](t, *jscandec.DefaultOptions)
-	s2.testErrCheck(t, "map", `{"x":"y"}`, func(t *testing.T, errIndex int, err error) {
-		require.Equal(t, errTextUnmarshalerImpl, err)
+	t.Run("bool", func(t *testing.T) {
+		type T = []jscandec.Stringed[bool]
+		s := newTestSetup[T](t, *jscandec.DefaultOptions)
+		s.TestOK(t, "valid", `["true","false"]`, T{{true}, {false}})
+		s.testErr(t, "invalid", `["yes"]`, 1, jscandec.ErrUnexpectedValue)
+		s.testErr(t, "bool", `[true]`, 1, jscandec.ErrUnexpectedValue)
 	})
 
-	type S struct{ Unmarshaler textUnmarshalerImplErr }
-	s3 := newTestSetup[S](t, *jscandec.DefaultOptions)
-	s3.testErrCheck(t, "struct_field", `{"Unmarshaler":"abc"}`,
+	t.Run("float64", func(t *testing.T) {
+		type T = [2]jscandec.Stringed[float64]
+		s := newTestSetup[T](t, *jscandec.DefaultOptions)
+		s.TestOK(t, "valid", `["1.5","-2e3"]`, T{{1.5}, {-2e3}})
+	})
+
+	t.Run("map_value", func(t *testing.T) {
+		type T = map[string]jscandec.Stringed[uint8]
+		s := newTestSetup[T](t, *jscandec.DefaultOptions)
+		s.TestOK(t, "valid", `{"a":"255"}`, T{"a": {255}})
+		s.testErr(t, "overflow", `{"a":"256"}`, 5, jscandec.ErrUnexpectedValue)
+	})
+
+	t.Run("struct_field", func(t *testing.T) {
+		type T struct {
+			IDs []jscandec.Stringed[int64] `json:"ids"`
+		}
+		s := newTestSetup[T](t, *jscandec.DefaultOptions)
+		s.TestOK(t, "valid", `{"ids":["1","2"]}`, T{IDs: []jscandec.Stringed[int64]{{1}, {2}}})
+	})
+
+	t.Run("unsupported", func(t *testing.T) {
+		_, err := jscandec.NewDecoder[string, jscandec.Stringed[[]int]](
+			jscan.NewTokenizer[string](16, 64), jscandec.DefaultInitOptions,
+		)
+		require.ErrorIs(t, err, jscandec.ErrUnsupportedType)
+	})
+}
+
+func TestDecodeValidateUTF8(t *testing.T) {
+	type T struct {
+		S string         `json:"s"`
+		A any            `json:"a"`
+		M map[string]int `json:"m"`
+	}
+	s := newTestSetup[T](t, jscandec.DecodeOptions{ValidateUTF8: true})
+	s.TestOK(t, "multi_byte", `{"s":"привет, 世界 🌍","a":"ß","m":{"ключ":1}}`)
+	s.TestOK(t, "escaped", `{"s":"\u00e9\ud83c\udf0d","a":"\ud800"}`)
+	s.testErrNonstandard(t, "invalid_byte", "{\"s\":\"ab\xffc\"}",
+		5, jscandec.ErrInvalidUTF8)
+	s.testErrNonstandard(t, "truncated_sequence", "{\"s\":\"ok\",\"a\":\"\xe4\xb8\"}",
+		14, jscandec.ErrInvalidUTF8)
+	s.testErrNonstandard(t, "surrogate_encoded", "{\"s\":\"\xed\xa0\x80\"}",
+		5, jscandec.ErrInvalidUTF8)
+	s.testErrNonstandard(t, "nested_any", "{\"a\":[\"ok\",{\"b\":\"\xff\"}]}",
+		16, jscandec.ErrInvalidUTF8)
+
+	// Only string values decoded into strings are validated.
+	s.testOKNonstandard(t, "map_key", "{\"m\":{\"\xff\":1}}",
+		T{M: map[string]int{"\xff": 1}})
+	s.testOKNonstandard(t, "unknown_field", "{\"x\":[\"\xc0\xaf\"],\"s\":\"ok\"}",
+		T{S: "ok"})
+
+	// Syntax errors take precedence over invalid UTF-8.
+	s.testErrCheck(t, "syntax_error", "{\"s\":\"\xff\",}",
 		func(t *testing.T, errIndex int, err error) {
-			require.Equal(t, errTextUnmarshalerImpl, err)
+			_, ok := jscandec.SyntaxErrorCode(err)
+			require.True(t, ok)
+			require.Equal(t, 9, errIndex)
 		})
-}
 
-func TestDecodeSyntaxErrorUnexpectedEOF(t *testing.T) {
-	tokenizerString := jscan.NewTokenizer[string](16, 1024)
-	d, err := jscandec.NewDecoder[string, []int](
-		tokenizerString, jscandec.DefaultInitOptions,
-	)
-	require.NoError(t, err)
-	var v []int
-	_, err = d.Decode(`[1,2,3`, &v, jscandec.DefaultOptions)
-	var jscanErr jscan.Error[string]
-	require.True(t, errors.As(err, &jscanErr))
-	require.Equal(t, jscan.ErrorCodeUnexpectedEOF, jscanErr.Code)
+	t.Run("disabled", func(t *testing.T) {
+		// Invalid bytes are preserved by default.
+		s := newTestSetup[T](t, *jscandec.DefaultOptions)
+		s.testOKNonstandard(t, "invalid_byte", "{\"s\":\"ab\xffc\"}", T{S: "ab\xffc"})
+	})
 }
 
-func BenchmarkSmall(b *testing.B) {
-	in := []byte(`[[true],[false,false,false,false],[],[],[true]]`) // 18 tokens
-
-	b.Run("jscan", func(b *testing.B) {
-		tok := jscan.NewTokenizer[[]byte](8, 64)
-		d, err := jscandec.NewDecoder[[]byte, [][]bool](tok, jscandec.DefaultInitOptions)
-		if err != nil {
-			b.Fatalf("initializing decoder: %v", err)
-		}
-		b.ResetTimer()
-		for n := 0; n < b.N; n++ {
-			var v [][]bool
-			if _, err := d.Decode(in, &v, jscandec.DefaultOptions); err != nil {
-				b.Fatal(err)
-			}
+func TestDecodeDisallowEmptyString(t *testing.T) {
+	type T struct {
+		Name   string            `json:"name"`
+		Tags   []string          `json:"tags"`
+		Labels map[string]string `json:"labels"`
+		Any    any               `json:"any"`
+		Nested struct {
+			Name string `json:"name"`
+		} `json:"nested"`
+	}
+	s := newTestSetup[T](t, jscandec.DecodeOptions{DisallowEmptyString: true})
+	s.TestOK(t, "non_empty", `{"name":"x"}`, T{Name: "x"})
+	s.TestOK(t, "null", `{"name":null}`, T{})
+	s.TestOK(t, "unaffected", `{"tags":[""],"labels":{"k":""},"any":""}`, T{
+		Tags: []string{""}, Labels: map[string]string{"k": ""}, Any: "",
+	})
+	s.testErrNonstandard(t, "empty", `{"name":""}`, 8, jscandec.ErrEmptyString)
+	s.testErrNonstandard(t, "nested", `{"nested":{"name":""}}`,
+		18, jscandec.ErrEmptyString)
+
+	t.Run("flat_struct_slice", func(t *testing.T) {
+		type F struct {
+			ID   int    `json:"id"`
+			Name string `json:"name"`
 		}
+		s := newTestSetup[[]F](t, jscandec.DecodeOptions{DisallowEmptyString: true})
+		s.TestOK(t, "non_empty", `[{"id":1,"name":"x"}]`, []F{{ID: 1, Name: "x"}})
+		s.testErrNonstandard(t, "empty", `[{"name":"a"},{"name":""}]`,
+			22, jscandec.ErrEmptyString)
 	})
-	b.Run("jsoniter", func(b *testing.B) {
-		for n := 0; n < b.N; n++ {
-			var v [][]bool
-			if err := jsoniter.Unmarshal(in, &v); err != nil {
-				b.Fatal(err)
-			}
-		}
+
+	t.Run("disabled", func(t *testing.T) {
+		s := newTestSetup[T](t, *jscandec.DefaultOptions)
+		s.TestOK(t, "empty", `{"name":""}`, T{})
 	})
 }
 
-func skipIfNot64bitSystem(t *testing.T) {
-	if uintptr(8) != unsafe.Sizeof(int(0)) {
-		t.Skip("this test must run on a 64-bit system")
+func TestDecodeDisallowNullForContainers(t *testing.T) {
+	type T struct {
+		Slice    []int          `json:"slice"`
+		Map      map[string]int `json:"map"`
+		Array    [3]int         `json:"array"`
+		PtrSlice *[]int         `json:"ptr_slice"`
+		Any      any            `json:"any"`
+		Int      int            `json:"int"`
 	}
-}
+	options := jscandec.DecodeOptions{DisallowNullForContainers: true}
+	s := newTestSetup[T](t, options)
+	s.TestOK(t, "non_null", `{"slice":[1],"map":{"a":1},"array":[1,2,3]}`, T{
+		Slice: []int{1}, Map: map[string]int{"a": 1}, Array: [3]int{1, 2, 3},
+	})
+	s.TestOK(t, "ptr_slice", `{"ptr_slice":null}`, T{})
+	s.TestOK(t, "any", `{"any":null}`, T{})
+	s.TestOK(t, "scalar", `{"int":null}`, T{})
+	s.testErrNonstandard(t, "slice", `{"slice":null}`, 9, jscandec.ErrUnexpectedValue)
+	s.testErrNonstandard(t, "map", `{"map":null}`, 7, jscandec.ErrUnexpectedValue)
+	s.testErrNonstandard(t, "array", `{"array":null}`, 9, jscandec.ErrUnexpectedValue)
 
-// jsonUnmarshalerImpl implements encoding/json.Unmarshaler.
-type jsonUnmarshalerImpl struct{ Value string }
+	t.Run("top_level", func(t *testing.T) {
+		s := newTestSetup[[]int](t, options)
+		s.testErrNonstandard(t, "slice", `null`, 0, jscandec.ErrUnexpectedValue)
+		s.TestOK(t, "slice_of_ints", `[null]`, []int{0})
 
-func (impl *jsonUnmarshalerImpl) UnmarshalJSON(data []byte) error {
-	impl.Value = string(data)
-	return nil
+		sm := newTestSetup[map[string]int](t, options)
+		sm.testErrNonstandard(t, "map", `null`, 0, jscandec.ErrUnexpectedValue)
+
+		sa := newTestSetup[[3]int](t, options)
+		sa.testErrNonstandard(t, "array", `null`, 0, jscandec.ErrUnexpectedValue)
+
+		sp := newTestSetup[*[]int](t, options)
+		sp.TestOK(t, "ptr_slice", `null`, (*[]int)(nil))
+
+		ss := newTestSetup[[][]int](t, options)
+		ss.testErrNonstandard(t, "nested", `[[1],null]`, 5, jscandec.ErrUnexpectedValue)
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		s := newTestSetup[T](t, *jscandec.DefaultOptions)
+		s.TestOK(t, "null", `{"slice":null,"map":null,"array":null}`, T{})
+	})
 }
 
-// unmarshalerImplErr implements encoding/json.Unmarshaler and always returns an error.
-type unmarshalerImplErr struct{ Value string }
+func TestDecodeObjectAsSingletonSlice(t *testing.T) {
+	type S struct {
+		A    int   `json:"a"`
+		Tags []int `json:"tags"`
+	}
+	options := jscandec.DecodeOptions{ObjectAsSingletonSlice: true}
+	s := newTestSetup[[]S](t, options)
+	s.testOKNonstandard(t, "object", `{"a":1}`, []S{{A: 1}})
+	s.testOKNonstandard(t, "empty_object", `{}`, []S{{}})
+	s.testOKNonstandard(t, "nested_slice", `{"a":1,"tags":[1,2]}`,
+		[]S{{A: 1, Tags: []int{1, 2}}})
+	s.testErrNonstandard(t, "err_in_object", `{"a":"x"}`,
+		5, jscandec.ErrUnexpectedValue)
+	s.TestOK(t, "array", `[{"a":1},{"a":2}]`, []S{{A: 1}, {A: 2}})
+	s.TestOK(t, "null", `null`, []S(nil))
 
-func (impl *unmarshalerImplErr) UnmarshalJSON(data []byte) error {
-	return errUnmarshalerImpl
+	t.Run("field", func(t *testing.T) {
+		type T struct {
+			Items []S `json:"items"`
+			After int `json:"after"`
+		}
+		s := newTestSetup[T](t, options)
+		s.testOKNonstandard(t, "object", `{"items":{"a":1},"after":2}`,
+			T{Items: []S{{A: 1}}, After: 2})
+		s.TestOK(t, "array", `{"items":[{"a":1}],"after":2}`,
+			T{Items: []S{{A: 1}}, After: 2})
+	})
+
+	t.Run("pointer_elements", func(t *testing.T) {
+		s := newTestSetup[[]*S](t, options)
+		s.testOKNonstandard(t, "object", `{"a":1}`, []*S{{A: 1}})
+		s.TestOK(t, "null", `null`, []*S(nil))
+	})
+
+	t.Run("map_elements", func(t *testing.T) {
+		s := newTestSetup[[]map[string]int](t, options)
+		s.testOKNonstandard(t, "object", `{"a":1}`, []map[string]int{{"a": 1}})
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		s := newTestSetup[[]S](t, *jscandec.DefaultOptions)
+		s.testErr(t, "object", `{"a":1}`, 0, jscandec.ErrUnexpectedValue)
+		s.TestOK(t, "array", `[{"a":1}]`, []S{{A: 1}})
+	})
 }
 
-var errUnmarshalerImpl = errors.New("unmarshalerImplErr test error")
+func TestDecodeDuplicateKeyStrategy(t *testing.T) {
+	type T struct {
+		Foo    int   `json:"foo"`
+		Bar    []int `json:"bar"`
+		Nested *T    `json:"nested"`
+	}
+	options := func(s jscandec.DuplicateKeyStrategy) jscandec.DecodeOptions {
+		return jscandec.DecodeOptions{DuplicateKeyStrategy: s}
+	}
 
-// textUnmarshalerImpl implements encoding/json.Unmarshaler.
-type textUnmarshalerImpl struct{ Value string }
+	t.Run("last", func(t *testing.T) {
+		s := newTestSetup[T](t, options(jscandec.DuplicateKeyLast))
+		s.TestOK(t, "scalar", `{"foo":1,"foo":2}`, T{Foo: 2})
+		s.TestOK(t, "slice", `{"bar":[1],"bar":[2,3]}`, T{Bar: []int{2, 3}})
+	})
+
+	t.Run("first", func(t *testing.T) {
+		s := newTestSetup[T](t, options(jscandec.DuplicateKeyFirst))
+		s.testOKNonstandard(t, "scalar", `{"foo":1,"foo":2}`, T{Foo: 1})
+		s.testOKNonstandard(t, "slice", `{"bar":[1],"foo":0,"bar":[2,3]}`,
+			T{Bar: []int{1}})
+		s.testOKNonstandard(t, "case_insensitive", `{"foo":1,"FOO":2}`, T{Foo: 1})
+		s.testOKNonstandard(t, "nested",
+			`{"foo":1,"nested":{"foo":2,"foo":3},"foo":4}`,
+			T{Foo: 1, Nested: &T{Foo: 2}})
+		s.TestOK(t, "nested_not_duplicate", `{"nested":{"foo":2},"foo":1}`,
+			T{Foo: 1, Nested: &T{Foo: 2}})
+	})
+
+	t.Run("error", func(t *testing.T) {
+		s := newTestSetup[T](t, options(jscandec.DuplicateKeyError))
+		s.TestOK(t, "no_duplicates", `{"foo":1,"bar":[],"nested":{"foo":1}}`,
+			T{Foo: 1, Bar: []int{}, Nested: &T{Foo: 1}})
+		s.testErrNonstandard(t, "scalar", `{"foo":1,"foo":2}`,
+			9, jscandec.ErrDuplicateKey)
+		s.testErrNonstandard(t, "after_composite",
+			`{"nested":{"bar":[1]},"foo":1,"nested":null}`,
+			30, jscandec.ErrDuplicateKey)
+		s.testErrNonstandard(t, "nested", `{"nested":{"foo":1,"foo":2}}`,
+			19, jscandec.ErrDuplicateKey)
+	})
+
+	t.Run("flat_struct_slice", func(t *testing.T) {
+		type F struct {
+			Foo int    `json:"foo"`
+			Bar string `json:"bar"`
+		}
+		s := newTestSetup[[]F](t, options(jscandec.DuplicateKeyFirst))
+		s.testOKNonstandard(t, "first", `[{"foo":1},{"foo":2,"bar":"x","foo":3}]`,
+			[]F{{Foo: 1}, {Foo: 2, Bar: "x"}})
+		s.testOKNonstandard(t, "first_composite", `[{"foo":1,"foo":[2],"bar":"x"}]`,
+			[]F{{Foo: 1, Bar: "x"}})
 
-func (impl *textUnmarshalerImpl) UnmarshalText(text []byte) error {
-	impl.Value = string(text)
-	return nil
+		s = newTestSetup[[]F](t, options(jscandec.DuplicateKeyError))
+		s.testErrNonstandard(t, "error", `[{"foo":1},{"foo":2,"foo":3}]`,
+			20, jscandec.ErrDuplicateKey)
+	})
 }
 
-// textUnmarshalerImplErr implements encoding/json.Unmarshaler.
-type textUnmarshalerImplErr struct{ Value string }
+func TestDecodeIgnoreFields(t *testing.T) {
+	type T struct {
+		Name    string `json:"name"`
+		IsAdmin bool   `json:"isAdmin"`
+		Sub     *T     `json:"sub"`
+	}
+	ignore := map[string]bool{"isAdmin": true}
+
+	s := newTestSetup[T](t, jscandec.DecodeOptions{IgnoreFields: ignore})
+	s.testOKNonstandard(t, "ignored", `{"name":"x","isAdmin":true}`, T{Name: "x"})
+	s.testOKNonstandard(t, "case_insensitive", `{"ISADMIN":true,"name":"x"}`, T{Name: "x"})
+	s.testOKNonstandard(t, "composite_value",
+		`{"isAdmin":{"a":[1,{}]},"name":"x"}`, T{Name: "x"})
+	s.testOKNonstandard(t, "nested",
+		`{"sub":{"isAdmin":true,"name":"y"},"name":"x"}`,
+		T{Name: "x", Sub: &T{Name: "y"}})
+	s.TestOK(t, "absent", `{"name":"x"}`, T{Name: "x"})
+
+	t.Run("disallow_unknown_fields", func(t *testing.T) {
+		s := newTestSetup[T](t, jscandec.DecodeOptions{
+			IgnoreFields:          ignore,
+			DisallowUnknownFields: true,
+		})
+		s.testOKNonstandard(t, "ignored", `{"name":"x","isAdmin":true}`, T{Name: "x"})
+		s.testErr(t, "unknown", `{"name":"x","unknown":true}`,
+			12, jscandec.ErrUnknownField)
+	})
 
-func (impl *textUnmarshalerImplErr) UnmarshalText(text []byte) error {
-	return errTextUnmarshalerImpl
+	t.Run("flat_struct_slice", func(t *testing.T) {
+		type F struct {
+			Name    string `json:"name"`
+			IsAdmin bool   `json:"isAdmin"`
+		}
+		s := newTestSetup[[]F](t, jscandec.DecodeOptions{IgnoreFields: ignore})
+		s.testOKNonstandard(t, "ignored",
+			`[{"name":"a","isAdmin":true},{"isAdmin":[true],"name":"b"}]`,
+			[]F{{Name: "a"}, {Name: "b"}})
+	})
 }
 
-var errTextUnmarshalerImpl = errors.New("textUnmarshalerImplErr test error")
+func TestDecodeMaxAllocBytes(t *testing.T) {
+	t.Run("slice", func(t *testing.T) {
+		type T = []int64
+		s := newTestSetup[T](t, jscandec.DecodeOptions{MaxAllocBytes: 32})
+		s.testOKNonstandard(t, "within", `[1,2,3,4]`, T{1, 2, 3, 4})
+		s.testErrNonstandard(t, "exceeded", `[1,2,3,4,5]`,
+			0, jscandec.ErrAllocBudgetExceeded)
+	})
+	t.Run("map", func(t *testing.T) {
+		type T = map[string]int64
+		// Each entry accounts for the size of a string key and an int64 value
+		// plus the length of the key if it's copied, which it is for []byte.
+		s := newTestSetup[T](t, jscandec.DecodeOptions{MaxAllocBytes: 50})
+		s.testOKNonstandard(t, "within", `{"a":1,"b":2}`, T{"a": 1, "b": 2})
+		s.testErrNonstandard(t, "exceeded", `{"a":1,"b":2,"c":3}`,
+			0, jscandec.ErrAllocBudgetExceeded)
+	})
+	t.Run("any", func(t *testing.T) {
+		type T = []any
+		s := newTestSetup[T](t, jscandec.DecodeOptions{MaxAllocBytes: 64})
+		s.testOKNonstandard(t, "within", `[[1,2],[]]`, T{[]any{1.0, 2.0}, []any{}})
+		s.testErrNonstandard(t, "exceeded", `[[1,2,3],[]]`,
+			1, jscandec.ErrAllocBudgetExceeded)
+	})
+	t.Run("whole_document", func(t *testing.T) {
+		type T struct {
+			A []int32 `json:"a"`
+			P *int64  `json:"p"`
+		}
+		// 4*4 bytes for the slice and 8 bytes for the pointer.
+		s := newTestSetup[T](t, jscandec.DecodeOptions{MaxAllocBytes: 24})
+		i := int64(5)
+		s.testOKNonstandard(t, "within", `{"a":[1,2,3,4],"p":5}`,
+			T{A: []int32{1, 2, 3, 4}, P: &i})
+		s.testErrNonstandard(t, "exceeded", `{"a":[1,2,3,4,5],"p":5}`,
+			21, jscandec.ErrAllocBudgetExceeded)
+	})
+	t.Run("string", func(t *testing.T) {
+		type T struct {
+			S string `json:"s"`
+		}
+		// Escaped strings are copied regardless of S.
+		long := `{"s":"` + strings.Repeat("x", 16) + `\n"}`
+		s := newTestSetup[T](t, jscandec.DecodeOptions{MaxAllocBytes: 16})
+		s.testOKNonstandard(t, "within", `{"s":"abc\n"}`, T{S: "abc\n"})
+		s.testErrNonstandard(t, "exceeded", long, 5, jscandec.ErrAllocBudgetExceeded)
+
+		t.Run("unescaped", func(t *testing.T) {
+			// Unescaped strings are only copied if S is []byte.
+			in := `{"s":"` + strings.Repeat("x", 17) + `"}`
+			var v T
+			errIndex, err := s.decoderBytes.Decode([]byte(in), &v, s.decodeOptions)
+			require.ErrorIs(t, err, jscandec.ErrAllocBudgetExceeded)
+			require.Equal(t, 5, errIndex)
+			_, err = s.decoderString.Decode(in, &v, s.decodeOptions)
+			require.NoError(t, err)
+			require.Equal(t, strings.Repeat("x", 17), v.S)
+		})
+	})
+	t.Run("strings", func(t *testing.T) {
+		long := `"` + strings.Repeat("x", 16) + `\n"`
+		t.Run("slice", func(t *testing.T) {
+			s := newTestSetup[[]string](t, jscandec.DecodeOptions{
+				MaxAllocBytes: unsafe.Sizeof("") + 16,
+			})
+			s.testErrNonstandard(t, "exceeded", `[`+long+`]`,
+				1, jscandec.ErrAllocBudgetExceeded)
+		})
+		t.Run("map_key", func(t *testing.T) {
+			s := newTestSetup[map[string]int](t, jscandec.DecodeOptions{
+				MaxAllocBytes: unsafe.Sizeof("") + unsafe.Sizeof(int(0)) + 16,
+			})
+			s.testErrNonstandard(t, "exceeded", `{`+long+`:1}`,
+				1, jscandec.ErrAllocBudgetExceeded)
+		})
+		t.Run("any", func(t *testing.T) {
+			s := newTestSetup[any](t, jscandec.DecodeOptions{MaxAllocBytes: 16})
+			s.testErrNonstandard(t, "exceeded", long,
+				0, jscandec.ErrAllocBudgetExceeded)
+		})
+	})
+	t.Run("unlimited", func(t *testing.T) {
+		s := newTestSetup[[]int64](t, *jscandec.DefaultOptions)
+		s.TestOK(t, "large", `[1,2,3,4,5,6,7,8,9,10]`)
+	})
+}
 
-func TestErrStringTagOptionOnUnsupportedType(t *testing.T) {
-	type S struct {
-		//lint:ignore SA5008 the JSON string option is used intentionally
-		Unsupported map[string]string `json:",string"` //nolint:staticcheck
+func TestDecodeMapCapacityFactor(t *testing.T) {
+	t.Run("map", func(t *testing.T) {
+		type T = map[string]int64
+		s := newTestSetup[T](t, jscandec.DecodeOptions{MapCapacityFactor: 2})
+		s.testOKNonstandard(t, "ok", `{"a":1,"b":2}`, T{"a": 1, "b": 2})
+	})
+	t.Run("map_string_string", func(t *testing.T) {
+		type T = map[string]string
+		s := newTestSetup[T](t, jscandec.DecodeOptions{MapCapacityFactor: 2})
+		s.testOKNonstandard(t, "ok", `{"a":"1","b":null}`, T{"a": "1", "b": ""})
+	})
+	t.Run("any", func(t *testing.T) {
+		s := newTestSetup[any](t, jscandec.DecodeOptions{MapCapacityFactor: 2})
+		s.testOKNonstandard(t, "ok", `{"a":{"b":1}}`,
+			map[string]any{"a": map[string]any{"b": 1.0}})
+	})
+	t.Run("alloc_budget", func(t *testing.T) {
+		type T = map[string]int64
+		// Each entry accounts for the size of a string key and an int64 value,
+		// the over-allocated capacity is accounted for too.
+		// The key is copied if S is []byte.
+		s := newTestSetup[T](t, jscandec.DecodeOptions{
+			MaxAllocBytes:     49,
+			MapCapacityFactor: 2,
+		})
+		s.testOKNonstandard(t, "within", `{"a":1}`, T{"a": 1})
+		s.testErrNonstandard(t, "exceeded", `{"a":1,"b":2}`,
+			0, jscandec.ErrAllocBudgetExceeded)
+	})
+	t.Run("less_than_one", func(t *testing.T) {
+		type T = map[string]int64
+		// Factors below 1 don't under-allocate.
+		// The keys are copied if S is []byte.
+		s := newTestSetup[T](t, jscandec.DecodeOptions{
+			MaxAllocBytes:     50,
+			MapCapacityFactor: 0.5,
+		})
+		s.testOKNonstandard(t, "within", `{"a":1,"b":2}`, T{"a": 1, "b": 2})
+		s.testErrNonstandard(t, "exceeded", `{"a":1,"b":2,"c":3}`,
+			0, jscandec.ErrAllocBudgetExceeded)
+	})
+}
+
+func TestDecodeAnyLimits(t *testing.T) {
+	deep := func(depth int) string {
+		return strings.Repeat(`[`, depth) + strings.Repeat(`]`, depth)
+	}
+	wide := func(elements int) string {
+		return `[` + strings.TrimSuffix(strings.Repeat(`0,`, elements), `,`) + `]`
 	}
 
-	testIn := `{"unsupported":{"not":"okay"}}`
-	var v S
-	require.NoError(t, json.Unmarshal([]byte(testIn), &v))
+	t.Run("max_depth", func(t *testing.T) {
+		s := newTestSetup[any](t, jscandec.DecodeOptions{MaxDepth: 3})
+		s.testOKNonstandard(t, "within", `[[{"a":[]}]]`,
+			[]any{[]any{map[string]any{"a": []any{}}}})
+		s.testOKNonstandard(t, "scalar", `1`, 1.0)
+		s.testErrNonstandard(t, "array", deep(4), 0, jscandec.ErrMaxDepthExceeded)
+		s.testErrNonstandard(t, "object", `[{"a":{"b":{}}}]`,
+			0, jscandec.ErrMaxDepthExceeded)
+		s.testErrNonstandard(t, "very_deep", deep(10_000),
+			0, jscandec.ErrMaxDepthExceeded)
+	})
+	t.Run("max_depth_map", func(t *testing.T) {
+		type T = map[string]any
+		// The depth is counted from the map values.
+		s := newTestSetup[T](t, jscandec.DecodeOptions{MaxDepth: 2})
+		s.testOKNonstandard(t, "within", `{"a":[[1]]}`, T{"a": []any{[]any{1.0}}})
+		s.testErrNonstandard(t, "exceeded", `{"a":[[[1]]]}`,
+			5, jscandec.ErrMaxDepthExceeded)
+	})
+	t.Run("max_depth_struct_field", func(t *testing.T) {
+		type T struct {
+			A any `json:"a"`
+		}
+		s := newTestSetup[T](t, jscandec.DecodeOptions{MaxDepth: 1})
+		s.testOKNonstandard(t, "within", `{"a":{"b":1}}`, T{A: map[string]any{"b": 1.0}})
+		s.testErrNonstandard(t, "exceeded", `{"a":{"b":{}}}`,
+			5, jscandec.ErrMaxDepthExceeded)
+	})
+	t.Run("max_array_elements", func(t *testing.T) {
+		s := newTestSetup[any](t, jscandec.DecodeOptions{MaxArrayElements: 3})
+		s.testOKNonstandard(t, "within", `[[0,0,0],{"a":[0,0,0]}]`,
+			[]any{[]any{0.0, 0.0, 0.0}, map[string]any{"a": []any{0.0, 0.0, 0.0}}})
+		s.testErrNonstandard(t, "top_level", wide(4), 0, jscandec.ErrLimitExceeded)
+		s.testErrNonstandard(t, "nested", `[{"a":[0,0,0,0]}]`,
+			0, jscandec.ErrLimitExceeded)
+		s.testErrNonstandard(t, "very_wide", wide(100_000),
+			0, jscandec.ErrLimitExceeded)
+	})
+	t.Run("max_array_elements_slice", func(t *testing.T) {
+		type T = []any
+		// Only arrays decoded into any are limited.
+		s := newTestSetup[T](t, jscandec.DecodeOptions{MaxArrayElements: 2})
+		s.testOKNonstandard(t, "within", `[[0,0],0,0]`, T{[]any{0.0, 0.0}, 0.0, 0.0})
+		s.testErrNonstandard(t, "exceeded", `[[0,0,0]]`, 1, jscandec.ErrLimitExceeded)
+	})
+	t.Run("joint", func(t *testing.T) {
+		s := newTestSetup[any](t, jscandec.DecodeOptions{
+			MaxDepth: 2, MaxArrayElements: 2,
+		})
+		s.testOKNonstandard(t, "within", `[[0,0],[]]`, []any{[]any{0.0, 0.0}, []any{}})
+		s.testErrNonstandard(t, "depth", `[[[]]]`, 0, jscandec.ErrMaxDepthExceeded)
+		s.testErrNonstandard(t, "elements", `[[],[],[]]`, 0, jscandec.ErrLimitExceeded)
+	})
+	t.Run("unlimited", func(t *testing.T) {
+		s := newTestSetup[any](t, *jscandec.DefaultOptions)
+		s.TestOK(t, "deep", deep(512))
+		s.TestOK(t, "wide", wide(1024))
+	})
+}
 
-	t.Run("check_disabled", func(t *testing.T) {
-		tok := jscan.NewTokenizer[string](1, 1)
-		dec, err := jscandec.NewDecoder[string, S](tok, jscandec.DefaultInitOptions)
-		require.NoError(t, err)
-		require.NotNil(t, dec)
-		var v S
-		_, err = dec.Decode(testIn, &v, jscandec.DefaultOptions)
-		require.NoError(t, err)
+func TestDecodeMaxStringLength(t *testing.T) {
+	options := jscandec.DecodeOptions{MaxStringLength: 3}
+
+	t.Run("field", func(t *testing.T) {
+		type T struct {
+			S string `json:"s"`
+		}
+		s := newTestSetup[T](t, options)
+		s.testOKNonstandard(t, "boundary", `{"s":"abc"}`, T{S: "abc"})
+		s.testOKNonstandard(t, "escaped", `{"s":"a\n\t"}`, T{S: "a\n\t"})
+		s.testErrNonstandard(t, "exceeded", `{"s":"abcd"}`, 5, jscandec.ErrLimitExceeded)
+		s.testErrNonstandard(t, "exceeded_utf8", `{"s":"äbc"}`, 5, jscandec.ErrLimitExceeded)
 	})
 
-	t.Run("err", func(t *testing.T) {
-		tok := jscan.NewTokenizer[string](1, 1)
-		opt := *jscandec.DefaultInitOptions
-		opt.DisallowStringTagOptOnUnsupportedTypes = true
-		dec, err := jscandec.NewDecoder[string, S](tok, &opt)
-		require.Equal(t, err, jscandec.ErrStringTagOptionOnUnsupportedType)
-		require.Nil(t, dec)
+	t.Run("slice", func(t *testing.T) {
+		s := newTestSetup[[]string](t, options)
+		s.testOKNonstandard(t, "boundary", `["abc",""]`, []string{"abc", ""})
+		s.testErrNonstandard(t, "exceeded", `["abc","abcd"]`, 7, jscandec.ErrLimitExceeded)
+
+		sa := newTestSetup[[2]string](t, options)
+		sa.testErrNonstandard(t, "array", `["a","abcd"]`, 5, jscandec.ErrLimitExceeded)
+	})
+
+	t.Run("flat_struct_slice", func(t *testing.T) {
+		type F struct {
+			ID int    `json:"id"`
+			S  string `json:"s"`
+		}
+		s := newTestSetup[[]F](t, options)
+		s.testOKNonstandard(t, "boundary", `[{"s":"abc"}]`, []F{{S: "abc"}})
+		s.testErrNonstandard(t, "exceeded", `[{"s":"abc"},{"s":"abcd"}]`,
+			18, jscandec.ErrLimitExceeded)
+	})
+
+	t.Run("map", func(t *testing.T) {
+		s := newTestSetup[map[string]string](t, options)
+		s.testOKNonstandard(t, "boundary", `{"abc":"abc"}`, map[string]string{"abc": "abc"})
+		s.testErrNonstandard(t, "key", `{"abcd":""}`, 1, jscandec.ErrLimitExceeded)
+		s.testErrNonstandard(t, "value", `{"a":"abcd"}`, 5, jscandec.ErrLimitExceeded)
+
+		si := newTestSetup[map[string]int](t, options)
+		si.testErrNonstandard(t, "key_generic", `{"a":1,"abcd":2}`,
+			7, jscandec.ErrLimitExceeded)
+	})
+
+	t.Run("any", func(t *testing.T) {
+		s := newTestSetup[any](t, options)
+		s.testOKNonstandard(t, "boundary", `{"abc":["abc"]}`,
+			map[string]any{"abc": []any{"abc"}})
+		s.testErrNonstandard(t, "string", `"abcd"`, 0, jscandec.ErrLimitExceeded)
+		s.testErrNonstandard(t, "element", `[1,"abcd"]`, 3, jscandec.ErrLimitExceeded)
+		s.testErrNonstandard(t, "key", `{"a":{"abcd":1}}`, 6, jscandec.ErrLimitExceeded)
+	})
+
+	t.Run("unlimited", func(t *testing.T) {
+		s := newTestSetup[[]string](t, *jscandec.DefaultOptions)
+		s.TestOK(t, "long", `["`+strings.Repeat("a", 4096)+`"]`)
 	})
 }
 
-func TestMemReuse(t *testing.T) {
-	optsInit := jscandec.DefaultInitOptions
-	optsDec := jscandec.DefaultOptions
+func TestDecodeMaxDecodedArrayElements(t *testing.T) {
+	options := jscandec.DecodeOptions{MaxDecodedArrayElements: 10}
+
+	t.Run("int", func(t *testing.T) {
+		var input strings.Builder
+		expect := make([]int, 10)
+		input.WriteByte('[')
+		for i := 0; i < 1000; i++ {
+			if i > 0 {
+				input.WriteByte(',')
+			}
+			input.WriteString(strconv.Itoa(i * 2))
+			if i < len(expect) {
+				expect[i] = i * 2
+			}
+		}
+		input.WriteByte(']')
+
+		s := newTestSetup[[]int](t, options)
+		s.testOKNonstandard(t, "1000", input.String(), expect)
+		s.testOKNonstandard(t, "shorter", `[1,2,3]`, []int{1, 2, 3})
+		s.testOKNonstandard(t, "empty", `[]`, []int{})
+		s.testOKNonstandard(t, "skip_unchecked",
+			`[0,1,2,3,4,5,6,7,8,9,"x",{"a":[1]},[]]`,
+			[]int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9})
+		s.testErrNonstandard(t, "within", `[0,"x"]`, 3, jscandec.ErrUnexpectedValue)
+	})
+
+	options.MaxDecodedArrayElements = 2
 
-	type S []string
 	t.Run("string", func(t *testing.T) {
-		tok := jscan.NewTokenizer[string](1, 16)
-		dec, err := jscandec.NewDecoder[string, S](tok, optsInit)
-		require.NoError(t, err)
+		s := newTestSetup[[]string](t, options)
+		s.testOKNonstandard(t, "exceeded", `["a","b","c"]`, []string{"a", "b"})
+	})
 
-		var v1 S
-		_, err = dec.Decode(`["first","second","third"]`, &v1, optsDec)
-		require.NoError(t, err)
-		require.Equal(t, S{"first", "second", "third"}, v1)
+	t.Run("generic", func(t *testing.T) {
+		type S struct {
+			ID   int   `json:"id"`
+			Tags []int `json:"tags"`
+		}
+		s := newTestSetup[[]S](t, options)
+		s.testOKNonstandard(t, "exceeded",
+			`[{"id":1,"tags":[1,2,3]},{"id":2},{"id":3,"tags":[]}]`,
+			[]S{{ID: 1, Tags: []int{1, 2}}, {ID: 2}})
 
-		var v2 S
-		_, err = dec.Decode(`["FIRST","SECOND","THIRD"]`, &v2, optsDec)
+		sn := newTestSetup[[][]bool](t, options)
+		sn.testOKNonstandard(t, "nested",
+			`[[true,false,true],[false],[true]]`,
+			[][]bool{{true, false}, {false}})
+	})
+
+	t.Run("flat_struct", func(t *testing.T) {
+		type F struct {
+			ID int `json:"id"`
+		}
+		s := newTestSetup[[]F](t, options)
+		s.testOKNonstandard(t, "exceeded", `[{"id":1},null,{"id":3}]`,
+			[]F{{ID: 1}, {}})
+	})
+
+	t.Run("json_unmarshaler", func(t *testing.T) {
+		s := newTestSetup[[]json.RawMessage](t, options)
+		s.testOKNonstandard(t, "exceeded", `[1,{"a":[2]},3]`,
+			[]json.RawMessage{json.RawMessage(`1`), json.RawMessage(`{"a":[2]}`)})
+	})
+
+	t.Run("recursive", func(t *testing.T) {
+		type N struct {
+			Name string `json:"name"`
+			List []N    `json:"list"`
+		}
+		s := newTestSetup[N](t, options)
+		s.testOKNonstandard(t, "exceeded",
+			`{"list":[{"name":"a","list":[{},{},{}]},{"name":"b"},{"name":"c"}]}`,
+			N{List: []N{{Name: "a", List: []N{{}, {}}}, {Name: "b"}}})
+	})
+
+	t.Run("reuse", func(t *testing.T) {
+		d, err := jscandec.NewDecoder[string, []int](
+			jscan.NewTokenizer[string](16, 1024), jscandec.DefaultInitOptions,
+		)
 		require.NoError(t, err)
+		v := make([]int, 5, 8)
+		_, err = d.Decode(`[1,2,3,4]`, &v, &options)
+		require.NoError(t, err)
+		require.Equal(t, []int{1, 2}, v)
+		require.Equal(t, 8, cap(v))
+	})
 
-		require.Equal(t, S{"first", "second", "third"}, v1)
-		require.Equal(t, S{"FIRST", "SECOND", "THIRD"}, v2)
+	t.Run("any", func(t *testing.T) {
+		// Arrays decoded into any are unaffected.
+		s := newTestSetup[any](t, options)
+		s.testOKNonstandard(t, "unaffected", `[1,2,3]`, []any{1.0, 2.0, 3.0})
 	})
 }
 
@@ -3694,6 +9824,22 @@ func TestDecodeNumber(t *testing.T) {
 	testOKFloat64("str_float64_pi", `"3.14159"`, 3.14159)
 }
 
+func TestDecodeEncodingJSONNumber(t *testing.T) {
+	type S struct{ N json.Number }
+	s := newTestSetup[S](t, *jscandec.DefaultOptions)
+	s.TestOK(t, "int", `{"n":42}`, S{N: "42"})
+	s.TestOK(t, "str_int", `{"n":"42"}`, S{N: "42"})
+	s.TestOK(t, "float", `{"n":-3.14e10}`, S{N: "-3.14e10"})
+	s.TestOK(t, "str_float", `{"n":"-3.14e10"}`, S{N: "-3.14e10"})
+	s.TestOK(t, "null", `{"n":null}`, S{})
+
+	s.testErr(t, "str_invalid", `{"n":"abc"}`, 5, jscandec.ErrUnexpectedValue)
+	s.testErr(t, "bool", `{"n":true}`, 5, jscandec.ErrUnexpectedValue)
+
+	sl := newTestSetup[[]json.Number](t, *jscandec.DefaultOptions)
+	sl.TestOK(t, "slice", `[1,"2",3.5,null]`, []json.Number{"1", "2", "3.5", ""})
+}
+
 func TestDisableFieldNameUnescaping(t *testing.T) {
 	type S struct {
 		ID string `json:"id"`
@@ -3780,6 +9926,48 @@ func TestDisableCaseInsensitiveMatching(t *testing.T) {
 	})
 }
 
+func TestPrecomputeExactOnly(t *testing.T) {
+	type S struct {
+		ID   string `json:"id"`
+		Name string
+	}
+	optsInit := &jscandec.InitOptions{PrecomputeExactOnly: true}
+	tokenizer := jscan.NewTokenizer[string](16, 1024)
+	d, err := jscandec.NewDecoder[string, S](tokenizer, optsInit)
+	require.NoError(t, err)
+
+	for _, td := range []struct {
+		name, input string
+		expect      S
+	}{
+		{"exact_match", `{"id":"ok","Name":"ok"}`, S{ID: "ok", Name: "ok"}},
+		{"no_match_upper", `{"ID":"not ok","NAME":"not ok"}`, S{}},
+		{"no_match_mixed", `{"Id":"not ok","name":"not ok"}`, S{}},
+		{"partial", `{"ID":"not ok","Name":"ok"}`, S{Name: "ok"}},
+	} {
+		t.Run(td.name, func(t *testing.T) {
+			var v S
+			_, err := d.Decode(td.input, &v, jscandec.DefaultOptions)
+			require.NoError(t, err)
+			require.Equal(t, td.expect, v)
+
+			// Clones must preserve the option.
+			v = S{}
+			_, err = d.Clone().Decode(td.input, &v, jscandec.DefaultOptions)
+			require.NoError(t, err)
+			require.Equal(t, td.expect, v)
+		})
+	}
+
+	t.Run("unknown_field", func(t *testing.T) {
+		var v S
+		errIndex, err := d.Decode(`{"id":"ok","ID":"not ok"}`, &v,
+			&jscandec.DecodeOptions{DisallowUnknownFields: true})
+		require.Equal(t, jscandec.ErrUnknownField, err)
+		require.Equal(t, 11, errIndex)
+	})
+}
+
 // TestDisableCaseInsensitiveMatchingNoTag is same as TestDisableCaseInsensitiveMatching
 // but with no struct tags involved.
 func TestDisableCaseInsensitiveMatchingNoTag(t *testing.T) {
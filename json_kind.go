@@ -0,0 +1,106 @@
+package jscandec
+
+import "reflect"
+
+// JSON kinds returned by JSONKindOf.
+const (
+	JSONKindObject = "object"
+	JSONKindArray  = "array"
+	JSONKindString = "string"
+	JSONKindNumber = "number"
+	JSONKindBool   = "bool"
+
+	// JSONKindAny is the kind of types accepting values of any kind,
+	// such as `any` and types implementing json.Unmarshaler.
+	JSONKindAny = "any"
+
+	// JSONKindNullablePrefix prefixes the kind of the value
+	// of pointers and Optional values, which are nil and absent for null
+	// respectively, such as "nullable object" for `*struct{}`.
+	JSONKindNullablePrefix = "nullable "
+)
+
+// JSONKindOf returns the kind of JSON value a decoder created with
+// DefaultInitOptions expects at the top level for T, which is one of
+// the JSONKind constants, optionally prefixed with JSONKindNullablePrefix.
+// Null is accepted for all kinds, yet only nullable kinds are set to nil.
+// Returns an empty string if T is unsupported (see ErrUnsupportedType).
+func JSONKindOf[T any]() string {
+	stack, err := appendTypeToStack[string](
+		nil, reflect.TypeOf((*T)(nil)).Elem(), DefaultInitOptions,
+	)
+	if err != nil {
+		return ""
+	}
+	return jsonKind(stack, 0)
+}
+
+// jsonKind returns the kind of JSON value expected by the frame at index i.
+func jsonKind[S []byte | string](stack []stackFrame[S], i int) string {
+	switch stack[i].Type {
+	case ExpectTypePtr, ExpectTypeOptional:
+		// The value frame is guaranteed to follow the frame.
+		return JSONKindNullablePrefix + jsonKind(stack, i+1)
+	case ExpectTypePtrRecur:
+		// Only structs are recursive.
+		return JSONKindNullablePrefix + JSONKindObject
+	case ExpectTypeMap,
+		ExpectTypeMapStringString,
+		ExpectTypeMapStringRawMessage,
+		ExpectTypeMapNested,
+		ExpectTypeMapRecur,
+		ExpectTypeSlicePairs,
+		ExpectTypeStruct,
+		ExpectTypeStructRecur,
+		ExpectTypeEmptyStruct:
+		return JSONKindObject
+	case ExpectTypeArray,
+		ExpectTypeArrayLen0,
+		ExpectTypeArrayBool,
+		ExpectTypeArrayStr,
+		ExpectTypeArrayFloat32,
+		ExpectTypeArrayFloat64,
+		ExpectTypeSlice,
+		ExpectTypeSliceRecur,
+		ExpectTypeSliceEmptyStruct,
+		ExpectTypeSliceBool,
+		ExpectTypeSliceString,
+		ExpectTypeSliceInt,
+		ExpectTypeSliceInt8,
+		ExpectTypeSliceInt16,
+		ExpectTypeSliceInt32,
+		ExpectTypeSliceInt64,
+		ExpectTypeSliceUint,
+		ExpectTypeSliceUint8,
+		ExpectTypeSliceUint16,
+		ExpectTypeSliceUint32,
+		ExpectTypeSliceUint64,
+		ExpectTypeSliceFloat32,
+		ExpectTypeSliceFloat64,
+		ExpectTypeSliceTime,
+		ExpectTypeContainer:
+		return JSONKindArray
+	case ExpectTypeNumber,
+		ExpectTypeBigRat,
+		ExpectTypeFloat32,
+		ExpectTypeFloat64,
+		ExpectTypeInt,
+		ExpectTypeInt8,
+		ExpectTypeInt16,
+		ExpectTypeInt32,
+		ExpectTypeInt64,
+		ExpectTypeUint,
+		ExpectTypeUint8,
+		ExpectTypeUint16,
+		ExpectTypeUint32,
+		ExpectTypeUint64:
+		return JSONKindNumber
+	case ExpectTypeBool:
+		return JSONKindBool
+	case ExpectTypeAny, ExpectTypeInterface, ExpectTypeJSONUnmarshaler:
+		return JSONKindAny
+	}
+	// Strings, text unmarshalers, errors and types with
+	// the `string` or `bytes` tag option.
+	return JSONKindString
+}
@@ -0,0 +1,149 @@
+package jscandec
+
+import (
+	"encoding"
+	"reflect"
+	"strconv"
+	"unsafe"
+
+	"github.com/romshark/jscan-experimental-decoder/internal/unescape"
+
+	"github.com/romshark/jscan/v2"
+)
+
+// ApplyMergePatch applies the JSON merge patch (RFC 7386) s onto the existing
+// value of t. Unlike Decode, which only overwrites the values present in s,
+// ApplyMergePatch follows the merge patch semantics:
+//
+//   - A null member removes the target value. Map entries are deleted while
+//     struct fields and other values are reset to their zero value
+//     (Optional fields become absent).
+//   - An object member is merged into the existing struct, map, pointer or
+//     `any` value recursively.
+//   - Any other member, including arrays, replaces the target value.
+//
+// Option MergeDuplicateKeysIntoSlice is ignored.
+func (d *Decoder[S, T]) ApplyMergePatch(
+	s S, t *T, options *DecodeOptions,
+) (errIndex int, err error) {
+	o := *options
+	o.mergePatch, o.MergeDuplicateKeysIntoSlice = true, false
+	return d.Decode(s, t, &o)
+}
+
+// isMergePatchMember returns true if the value at frame si is either
+// the root value or a member of an object.
+func (d *Decoder[S, T]) isMergePatchMember(si uint32) bool {
+	if d.mergePatchMapFrame(si) != noParentFrame {
+		return true
+	}
+	if len(d.stackExp[si].RecursionStack) > 0 {
+		// A recursive struct nested in a recursive pointer or slice.
+		return false
+	}
+	pi := d.stackExp[si].ParentFrameIndex
+	if pi == noParentFrame {
+		return true
+	}
+	switch d.stackExp[pi].Type {
+	case ExpectTypeStruct, ExpectTypeStructRecur:
+		return true
+	}
+	return false
+}
+
+// mergePatchMapFrame returns the index of the map frame the value at frame si
+// is an entry of, or noParentFrame if it's not a map entry.
+func (d *Decoder[S, T]) mergePatchMapFrame(si uint32) uint32 {
+	if l := len(d.stackExp[si].RecursionStack); l > 0 {
+		if c := d.stackExp[si].RecursionStack[l-1].ContainerFrame; d.stackExp[c].Type ==
+			ExpectTypeMapRecur {
+			return c
+		}
+		return noParentFrame
+	}
+	if pi := d.stackExp[si].ParentFrameIndex; pi != noParentFrame &&
+		d.stackExp[pi].Type == ExpectTypeMap {
+		return pi
+	}
+	return noParentFrame
+}
+
+// mergePatchRemove removes the value at frame si pointed to by p.
+// tokens[ti] is the null value token.
+func (d *Decoder[S, T]) mergePatchRemove(
+	s S, tokens []jscan.Token[S], ti int, si uint32, p unsafe.Pointer,
+	options *DecodeOptions,
+) error {
+	mi := d.mergePatchMapFrame(si)
+	if mi == noParentFrame {
+		typedmemclr(d.stackExp[si].Typ, p)
+		return nil
+	}
+	f := &d.stackExp[mi]
+	k, err := mergePatchMapKey(
+		f.RType.Key(), s[tokens[ti-1].Index+1:tokens[ti-1].End-1], options,
+	)
+	if err != nil {
+		return err
+	}
+	m := reflect.NewAt(f.RType, unsafe.Pointer(uintptr(f.Dest)+f.Offset)).Elem()
+	m.SetMapIndex(k, reflect.Value{})
+	return nil
+}
+
+// mergePatchMapKey returns the map key of type t for the escaped key
+// literal key. The key was already decoded successfully when the map entry
+// was assigned, hence only the key types supported by maps are handled.
+func mergePatchMapKey[S []byte | string](
+	t reflect.Type, key S, options *DecodeOptions,
+) (reflect.Value, error) {
+	if reflect.PointerTo(t).Implements(tpTextUnmarshaler) {
+		v := reflect.New(t)
+		u := v.Interface().(encoding.TextUnmarshaler)
+		if err := u.UnmarshalText(unescape.Valid[S, []byte](key)); err != nil {
+			return reflect.Value{}, err
+		}
+		return v.Elem(), nil
+	}
+	v := reflect.New(t).Elem()
+	switch t.Kind() {
+	case reflect.String:
+		v.SetString(options.transformMapKey(unescape.Valid[S, string](key)))
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(string(key), 10, t.Bits())
+		if err != nil {
+			return reflect.Value{}, ErrUnexpectedValue
+		}
+		v.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := strconv.ParseUint(string(key), 10, t.Bits())
+		if err != nil {
+			return reflect.Value{}, ErrUnexpectedValue
+		}
+		v.SetUint(u)
+	default:
+		return reflect.Value{}, ErrUnexpectedValue
+	}
+	return v, nil
+}
+
+// mergePatchAny merges the decoded patch into target following RFC 7386.
+func mergePatchAny(target, patch any) any {
+	p, ok := patch.(map[string]any)
+	if !ok {
+		return patch
+	}
+	t, ok := target.(map[string]any)
+	if !ok {
+		t = make(map[string]any, len(p))
+	}
+	for k, v := range p {
+		if v == nil {
+			delete(t, k)
+			continue
+		}
+		t[k] = mergePatchAny(t[k], v)
+	}
+	return t
+}
@@ -0,0 +1,109 @@
+package jscandec
+
+import "github.com/romshark/jscan/v2"
+
+// DecodeMulti decodes a sequence of JSON values separated by whitespace,
+// such as newline-delimited JSON (NDJSON), calling yield for every value.
+// Like in the encoding/json Decoder, objects and arrays may directly follow
+// another object or array (`{}{}`). Any other value not separated from
+// the preceding one by whitespace (such as `1{}` or `{}1`) makes DecodeMulti
+// return a jscan error with code jscan.ErrorCodeUnexpectedToken at its index
+// before the preceding value is decoded.
+// The value passed to yield is reused and reset to the zero value of T before
+// each value is decoded, so it must not be retained after yield returns.
+// DecodeMulti stops at the first error returned by yield and returns it
+// together with the index of the value in s.
+// A value that fails to decode is reported like by Decode with the error index
+// relative to s. A truncated trailing value makes DecodeMulti return a
+// jscan error with code jscan.ErrorCodeUnexpectedEOF (see SyntaxErrorCode).
+func (d *Decoder[S, T]) DecodeMulti(
+	s S, yield func(*T) error, options *DecodeOptions,
+) (errIndex int, err error) {
+	var v T
+	for i := 0; ; {
+		start := skipSpace(s, i)
+		if start >= len(s) {
+			return -1, nil
+		}
+		end := valueEnd(s, start)
+		if end < len(s) && skipSpace(s, end) == end &&
+			!(isContainerStart(s[start]) && isContainerStart(s[end])) {
+			return end, syntaxError(s, end, jscan.ErrorCodeUnexpectedToken)
+		}
+
+		var zero T
+		v = zero
+		if errIndex, err = d.Decode(s[start:end], &v, options); err != nil {
+			if errTok, ok := err.(jscan.Error[S]); ok {
+				errTok.Src, errTok.Index = s, errTok.Index+start
+				err = errTok
+			}
+			return errIndex + start, err
+		}
+		if err = yield(&v); err != nil {
+			return start, err
+		}
+		i = end
+	}
+}
+
+func isContainerStart(c byte) bool { return c == '{' || c == '[' }
+
+func skipSpace[S []byte | string](s S, i int) int {
+	for ; i < len(s); i++ {
+		switch s[i] {
+		case ' ', '\t', '\r', '\n':
+		default:
+			return i
+		}
+	}
+	return i
+}
+
+// valueEnd returns the index following the end of the top-level value
+// starting at index start in s, or len(s) if the value is truncated.
+// The value itself isn't validated.
+func valueEnd[S []byte | string](s S, start int) int {
+	depth, inString, escaped := 0, false, false
+	switch s[start] {
+	case '{', '[':
+		depth = 1
+	case '"':
+		inString = true
+	default:
+		// Scalars end at the next whitespace or structural character.
+		for i := start + 1; i < len(s); i++ {
+			switch s[i] {
+			case ' ', '\t', '\r', '\n', '{', '}', '[', ']', ',', ':', '"':
+				return i
+			}
+		}
+		return len(s)
+	}
+	for i := start + 1; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case inString:
+			switch {
+			case escaped:
+				escaped = false
+			case c == '\\':
+				escaped = true
+			case c == '"':
+				inString = false
+				if depth == 0 {
+					return i + 1
+				}
+			}
+		case c == '"':
+			inString = true
+		case c == '{' || c == '[':
+			depth++
+		case c == '}' || c == ']':
+			if depth--; depth == 0 {
+				return i + 1
+			}
+		}
+	}
+	return len(s)
+}
@@ -0,0 +1,147 @@
+package jscandec
+
+import (
+	"reflect"
+	"unsafe"
+
+	"github.com/romshark/jscan-experimental-decoder/internal/unescape"
+
+	"github.com/romshark/jscan/v2"
+)
+
+// nestedMapLevel is a map of a nested map chain (see ExpectTypeMapNested).
+type nestedMapLevel struct {
+	Typ   *typ
+	RType reflect.Type
+
+	// EntrySize is the size of a key and a value of the map.
+	EntrySize uintptr
+}
+
+// nestedMapLevels returns the levels of t and the type of the values of the
+// innermost map if t is a chain of at least two nested maps with string keys
+// ending in a scalar value type (see isFlatScalar).
+// Otherwise returns nil.
+func nestedMapLevels[S []byte | string](
+	t reflect.Type, options *InitOptions,
+) ([]nestedMapLevel, ExpectType) {
+	var levels []nestedMapLevel
+	for ; t.Kind() == reflect.Map; t = t.Elem() {
+		if _, ok := options.ContainerFactories[t]; ok {
+			return nil, 0
+		}
+		if len(levels) > 0 && (determineJSONUnmarshalerSupport(t) != interfaceSupportNone ||
+			determineTextUnmarshalerSupport(t) != interfaceSupportNone) {
+			return nil, 0
+		}
+		if k := t.Key(); k.Kind() != reflect.String ||
+			determineTextUnmarshalerSupport(k) != interfaceSupportNone ||
+			isEnum(options, k) {
+			return nil, 0
+		}
+		levels = append(levels, nestedMapLevel{
+			Typ:       getTyp(t),
+			RType:     t,
+			EntrySize: t.Key().Size() + t.Elem().Size(),
+		})
+	}
+	if len(levels) < 2 {
+		return nil, 0
+	}
+	// Unmarshaler implementations and other special types
+	// are resolved by the frame of the value type.
+	leaf, err := appendTypeToStack[S](nil, t, options)
+	if err != nil || len(leaf) != 1 || !leaf[0].Type.isFlatScalar() {
+		return nil, 0
+	}
+	return levels, leaf[0].Type
+}
+
+// decodeNestedMap decodes the object at tokens[ti] into the map at p
+// that is the first of levels, recursing into the objects of the nested maps
+// without going through the frames of the generic path.
+// Existing maps at p are reused, nested maps of existing entries are replaced
+// like in encoding/json unless merge patching.
+// Returns the index of the token following the object.
+func (d *Decoder[S, T]) decodeNestedMap(
+	s S, tokens []jscan.Token[S], ti int, levels []nestedMapLevel, leaf ExpectType,
+	p unsafe.Pointer, options *DecodeOptions, budget *allocBudget,
+) (next, errIndex int, err error) {
+	m := *(*unsafe.Pointer)(p)
+	if m == nil {
+		capacity := options.mapCapacity(tokens[ti].Elements)
+		if !budget.alloc(uintptr(capacity) * levels[0].EntrySize) {
+			return 0, tokens[ti].Index, ErrAllocBudgetExceeded
+		}
+		m = makemap(levels[0].Typ, capacity)
+		*(*unsafe.Pointer)(p) = m
+	}
+	end := tokens[ti].End
+	for ti++; ti < end; {
+		if stringTooLong(s, tokens[ti], options) {
+			return 0, tokens[ti].Index, ErrLimitExceeded
+		}
+		if !allocString(budget, s, tokens[ti]) {
+			return 0, tokens[ti].Index, ErrAllocBudgetExceeded
+		}
+		key := options.transformMapKey(
+			unescape.Valid[S, string](s[tokens[ti].Index+1 : tokens[ti].End-1]),
+		)
+		tokVal := tokens[ti+1]
+		if tokVal.Type == jscan.TokenTypeNull && options.mergePatch {
+			// A null member removes the entry.
+			rt := levels[0].RType
+			reflect.NewAt(rt, p).Elem().SetMapIndex(
+				reflect.ValueOf(key).Convert(rt.Key()), reflect.Value{},
+			)
+			ti += 2
+			continue
+		}
+		pv := mapassign_faststr(levels[0].Typ, m, key)
+
+		if len(levels) == 1 {
+			if leaf == ExpectTypeStr && tokVal.Type == jscan.TokenTypeString {
+				// Unlike for struct fields DisallowEmptyString doesn't apply.
+				if stringTooLong(s, tokVal, options) {
+					return 0, tokVal.Index, ErrLimitExceeded
+				}
+				if invalidUTF8(s, tokVal, options) {
+					return 0, tokVal.Index, ErrInvalidUTF8
+				}
+				if !allocString(budget, s, tokVal) {
+					return 0, tokVal.Index, ErrAllocBudgetExceeded
+				}
+				*(*string)(pv) = options.transformValue(
+					unescape.Valid[S, string](s[tokVal.Index+1 : tokVal.End-1]),
+				)
+			} else if err := d.decodeScalar(s, tokVal, leaf, pv, options, budget); err != nil {
+				return 0, tokVal.Index, err
+			}
+			ti += 2
+			continue
+		}
+
+		switch tokVal.Type {
+		case jscan.TokenTypeNull:
+			if options.DisallowNullForContainers {
+				return 0, tokVal.Index, ErrUnexpectedValue
+			}
+			*(*unsafe.Pointer)(pv) = nil
+			ti += 2
+		case jscan.TokenTypeObject:
+			if !options.mergePatch {
+				// Don't reuse the map of an existing key.
+				*(*unsafe.Pointer)(pv) = nil
+			}
+			ti, errIndex, err = d.decodeNestedMap(
+				s, tokens, ti+1, levels[1:], leaf, pv, options, budget,
+			)
+			if err != nil {
+				return 0, errIndex, err
+			}
+		default:
+			return 0, tokVal.Index, ErrUnexpectedValue
+		}
+	}
+	return end + 1, 0, nil
+}
@@ -0,0 +1,37 @@
+package jscandec
+
+import (
+	"encoding/json"
+	"reflect"
+)
+
+// Optional distinguishes an absent field from a field that is
+// explicitly null and a field that has a value.
+// Decoding an object without the key leaves Optional unchanged,
+// null sets Set but leaves Valid false and resets Value to its zero value,
+// any other value sets both Set and Valid and is decoded into Value.
+type Optional[T any] struct {
+	Set   bool // Set is true if the key is present.
+	Valid bool // Valid is true if the value isn't null.
+	Value T
+}
+
+// optionalHeader is the layout of the fields every Optional[T] begins with.
+type optionalHeader struct{ Set, Valid bool }
+
+func (Optional[T]) isOptional() {}
+
+var tpOptional = reflect.TypeOf((*interface{ isOptional() })(nil)).Elem()
+
+// UnmarshalJSON implements encoding/json.Unmarshaler
+// for compatibility with encoding/json.
+func (o *Optional[T]) UnmarshalJSON(data []byte) error {
+	o.Set = true
+	if string(data) == "null" {
+		var zero T
+		o.Valid, o.Value = false, zero
+		return nil
+	}
+	o.Valid = true
+	return json.Unmarshal(data, &o.Value)
+}
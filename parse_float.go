@@ -0,0 +1,165 @@
+package jscandec
+
+import "math"
+
+// float64Pow10 holds all powers of ten exactly representable as float64.
+var float64Pow10 = [...]float64{
+	1e0, 1e1, 1e2, 1e3, 1e4, 1e5, 1e6, 1e7, 1e8, 1e9, 1e10,
+	1e11, 1e12, 1e13, 1e14, 1e15, 1e16, 1e17, 1e18, 1e19, 1e20,
+	1e21, 1e22,
+}
+
+// parseFloat64Exact parses the JSON number s without going through
+// strconv.ParseFloat if the result is guaranteed to be exact, which is the case
+// for numbers of up to 15 significant digits and a decimal exponent within
+// [-22, 22] since both the mantissa and the power of ten are then exactly
+// representable as float64 and a single multiplication or division is
+// correctly rounded. Returns false if s doesn't qualify, in which case
+// it must be parsed by strconv.ParseFloat.
+func parseFloat64Exact[S []byte | string](s S) (float64, bool) {
+	i, neg := 0, false
+	if len(s) > 0 && s[0] == '-' {
+		i, neg = 1, true
+	}
+	var mantissa uint64
+	digits, exp := 0, 0
+	for ; i < len(s) && s[i] >= '0' && s[i] <= '9'; i++ {
+		if mantissa == 0 && s[i] == '0' {
+			continue
+		}
+		mantissa = mantissa*10 + uint64(s[i]-'0')
+		digits++
+	}
+	if i < len(s) && s[i] == '.' {
+		for i++; i < len(s) && s[i] >= '0' && s[i] <= '9'; i++ {
+			exp--
+			if mantissa == 0 && s[i] == '0' {
+				continue
+			}
+			mantissa = mantissa*10 + uint64(s[i]-'0')
+			digits++
+		}
+	}
+	if digits > 15 {
+		return 0, false
+	}
+	if i < len(s) && (s[i] == 'e' || s[i] == 'E') {
+		i++
+		expNeg := false
+		if i < len(s) && (s[i] == '-' || s[i] == '+') {
+			expNeg = s[i] == '-'
+			i++
+		}
+		e, expDigits := 0, 0
+		for ; i < len(s) && s[i] >= '0' && s[i] <= '9'; i++ {
+			if expDigits++; expDigits > 3 {
+				return 0, false
+			}
+			e = e*10 + int(s[i]-'0')
+		}
+		if expNeg {
+			e = -e
+		}
+		exp += e
+	}
+	if i != len(s) {
+		// Unexpected characters, such as digit separators.
+		return 0, false
+	}
+	f := float64(mantissa)
+	switch {
+	case mantissa == 0:
+	case exp >= 0 && exp < len(float64Pow10):
+		f *= float64Pow10[exp]
+	case exp < 0 && -exp < len(float64Pow10):
+		f /= float64Pow10[-exp]
+	default:
+		return 0, false
+	}
+	if neg {
+		f = -f
+	}
+	return f, true
+}
+
+// parseFloat64Fast parses the JSON number s with a single multiplication
+// or division of its leading 19 significant digits by a power of ten,
+// which is faster than strconv.ParseFloat for long literals but isn't
+// guaranteed to be correctly rounded. The result is off by at most a few
+// units in the last place. Returns false if s has a decimal exponent
+// outside of [-300, 300] or overflows, in which case it must be parsed
+// by strconv.ParseFloat.
+func parseFloat64Fast[S []byte | string](s S) (float64, bool) {
+	i, neg := 0, false
+	if len(s) > 0 && s[0] == '-' {
+		i, neg = 1, true
+	}
+	var mantissa uint64
+	digits, exp := 0, 0
+	for ; i < len(s) && s[i] >= '0' && s[i] <= '9'; i++ {
+		if mantissa == 0 && s[i] == '0' {
+			continue
+		}
+		if digits >= 19 {
+			// Truncate the digit, it's below the float64 precision.
+			exp++
+			continue
+		}
+		mantissa = mantissa*10 + uint64(s[i]-'0')
+		digits++
+	}
+	if i < len(s) && s[i] == '.' {
+		for i++; i < len(s) && s[i] >= '0' && s[i] <= '9'; i++ {
+			if mantissa == 0 && s[i] == '0' {
+				exp--
+				continue
+			}
+			if digits >= 19 {
+				continue
+			}
+			exp--
+			mantissa = mantissa*10 + uint64(s[i]-'0')
+			digits++
+		}
+	}
+	if i < len(s) && (s[i] == 'e' || s[i] == 'E') {
+		i++
+		expNeg := false
+		if i < len(s) && (s[i] == '-' || s[i] == '+') {
+			expNeg = s[i] == '-'
+			i++
+		}
+		e, expDigits := 0, 0
+		for ; i < len(s) && s[i] >= '0' && s[i] <= '9'; i++ {
+			if expDigits++; expDigits > 3 {
+				return 0, false
+			}
+			e = e*10 + int(s[i]-'0')
+		}
+		if expNeg {
+			e = -e
+		}
+		exp += e
+	}
+	if i != len(s) {
+		// Unexpected characters, such as digit separators.
+		return 0, false
+	}
+	f := float64(mantissa)
+	switch {
+	case mantissa == 0:
+	case exp >= 0 && exp <= 300:
+		f *= math.Pow10(exp)
+	case exp < 0 && exp >= -300:
+		f /= math.Pow10(-exp)
+	default:
+		return 0, false
+	}
+	if math.IsInf(f, 0) {
+		return 0, false
+	}
+	if neg {
+		f = -f
+	}
+	return f, true
+}
@@ -0,0 +1,79 @@
+package jscandec
+
+import (
+	"encoding/json"
+	"unsafe"
+
+	"github.com/romshark/jscan-experimental-decoder/internal/unescape"
+
+	"github.com/romshark/jscan/v2"
+)
+
+// decodeMapStringRawMessage decodes the object at tokens[ti] into
+// the `map[string]json.RawMessage` at p. The values are the verbatim
+// source of the member values, including `null`, and are copied unless
+// options.RawMessageNoCopy is enabled and S is []byte.
+// Returns the index of the token following the object.
+func decodeMapStringRawMessage[S []byte | string](
+	s S, tokens []jscan.Token[S], ti int, p unsafe.Pointer,
+	options *DecodeOptions, budget *allocBudget,
+) (next, errIndex int, err error) {
+	m := *(*map[string]json.RawMessage)(p)
+	if options.mergePatch && m != nil {
+		// Merge into the existing map.
+	} else if tokens[ti].Elements == 0 {
+		m = make(map[string]json.RawMessage, 0)
+	} else {
+		capacity := options.mapCapacity(tokens[ti].Elements)
+		if !budget.alloc(uintptr(capacity) *
+			(unsafe.Sizeof("") + unsafe.Sizeof(json.RawMessage(nil)))) {
+			return 0, tokens[ti].Index, ErrAllocBudgetExceeded
+		}
+		m = make(map[string]json.RawMessage, capacity)
+	}
+	_, noCopy := any(s).([]byte)
+	noCopy = noCopy && options.RawMessageNoCopy
+
+	end := tokens[ti].End
+	for ti++; ti < end; {
+		if stringTooLong(s, tokens[ti], options) {
+			return 0, tokens[ti].Index, ErrLimitExceeded
+		}
+		if !allocString(budget, s, tokens[ti]) {
+			return 0, tokens[ti].Index, ErrAllocBudgetExceeded
+		}
+		key := options.transformMapKey(
+			unescape.Valid[S, string](s[tokens[ti].Index+1 : tokens[ti].End-1]),
+		)
+		tokVal := tokens[ti+1]
+		var raw S
+		switch tokVal.Type {
+		case jscan.TokenTypeObject, jscan.TokenTypeArray:
+			raw = s[tokVal.Index : tokens[tokVal.End].Index+1]
+			ti = tokVal.End + 1
+		case jscan.TokenTypeNull:
+			if options.mergePatch {
+				delete(m, key)
+				ti += 2
+				continue
+			}
+			fallthrough
+		default:
+			raw = s[tokVal.Index:tokVal.End]
+			ti += 2
+		}
+		if noCopy {
+			// Alias the input, limit the capacity to prevent appends
+			// to the message from overwriting the input.
+			b := *(*[]byte)(unsafe.Pointer(&raw))
+			m[key] = b[:len(b):len(b)]
+			continue
+		}
+		if !budget.alloc(uintptr(len(raw))) {
+			return 0, tokVal.Index, ErrAllocBudgetExceeded
+		}
+		m[key] = append(json.RawMessage(nil), raw...)
+	}
+	*(*map[string]json.RawMessage)(p) = m
+	return end + 1, 0, nil
+}
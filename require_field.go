@@ -0,0 +1,57 @@
+package jscandec
+
+import (
+	"github.com/romshark/jscan-experimental-decoder/internal/unescape"
+
+	"github.com/romshark/jscan/v2"
+)
+
+// RequiredField is a discriminator field the top-level JSON object
+// must contain (see InitOptions.RequireField).
+type RequiredField struct {
+	// Name is the name of the field, which is matched exactly.
+	Name string
+
+	// Value is the string value the field must have.
+	Value string
+}
+
+// hasRequiredField returns true if the top-level value at tokens[0] is an
+// object with a member named f.Name with the string value f.Value.
+// Names and values are compared unescaped.
+func hasRequiredField[S []byte | string](
+	s S, tokens []jscan.Token[S], f RequiredField,
+) bool {
+	if tokens[0].Type != jscan.TokenTypeObject {
+		return false
+	}
+	for i, end := 1, tokens[0].End; i < end; {
+		key, val := tokens[i], tokens[i+1]
+		if val.Type == jscan.TokenTypeString &&
+			equalUnescaped(s[key.Index+1:key.End-1], f.Name) &&
+			equalUnescaped(s[val.Index+1:val.End-1], f.Value) {
+			return true
+		}
+		switch val.Type {
+		case jscan.TokenTypeObject, jscan.TokenTypeArray:
+			i = val.End + 1
+		default:
+			i += 2
+		}
+	}
+	return false
+}
+
+// equalUnescaped returns true if the contents of the valid JSON string s
+// are equal to v once unescaped.
+func equalUnescaped[S []byte | string](s S, v string) bool {
+	if string(s) == v {
+		return true
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' {
+			return unescape.Valid[S, string](s) == v
+		}
+	}
+	return false
+}
@@ -0,0 +1,27 @@
+package jscandec
+
+import (
+	"fmt"
+
+	"github.com/romshark/jscan/v2"
+)
+
+// checkRequired returns ErrMissingRequiredField naming the first field of
+// the struct frame si with the `required:"true"` struct tag that has none
+// of its names present as keys of the object at tokens[ti].
+// Keys with a null value count as present. Nested structs are only checked
+// if their object is present. Merge patches require no fields.
+func (d *Decoder[S, T]) checkRequired(
+	s S, tokens []jscan.Token[S], ti int, si uint32, options *DecodeOptions,
+) error {
+	if options.mergePatch {
+		return nil
+	}
+	for _, frameIndex := range d.stackExp[si].Required {
+		if !d.hasField(s, tokens, ti, si, frameIndex, options) {
+			return fmt.Errorf("%w: %s", ErrMissingRequiredField,
+				fieldName(d.stackExp[si].Fields, frameIndex))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,41 @@
+package jscandec
+
+import "unsafe"
+
+// slicePool retains the backing array of a slice frame across calls to Decode
+// when InitOptions.SlicePool is enabled. Within a single call to Decode the
+// backing array is handed out in consecutive chunks, one per decoded slice.
+type slicePool struct {
+	Data unsafe.Pointer
+	Cap  uintptr
+
+	// Used is the number of elements handed out during the current
+	// call to Decode and is reset when Decode returns.
+	Used uintptr
+
+	// Dirty is the number of elements that were handed out at least once
+	// since Data was allocated and must be zeroed before being reused.
+	Dirty uintptr
+}
+
+// get returns a zeroed backing array for n elements of type elemTyp
+// that are elemSize bytes each, reallocating the pool if it's exhausted.
+func (p *slicePool) get(elemTyp *typ, elemSize, n uintptr) unsafe.Pointer {
+	if p.Cap-p.Used < n {
+		c := p.Cap * 2
+		if c < n {
+			c = n
+		}
+		// The previous backing array is left to the slices referring to it.
+		*p = slicePool{Data: newarray(elemTyp, c), Cap: c}
+	}
+	dp := unsafe.Add(p.Data, p.Used*elemSize)
+	for i := p.Used; i < p.Used+n && i < p.Dirty; i++ {
+		typedmemclr(elemTyp, unsafe.Add(p.Data, i*elemSize))
+	}
+	p.Used += n
+	if p.Used > p.Dirty {
+		p.Dirty = p.Used
+	}
+	return dp
+}
@@ -0,0 +1,45 @@
+package jscandec
+
+const (
+	smallIntAnyMin = -128
+	smallIntAnyMax = 255
+)
+
+// smallIntAny holds preboxed float64 values for all integers in
+// [smallIntAnyMin, smallIntAnyMax]. Assigning a float64 to an `any` allocates
+// while assigning a value from this table doesn't. The boxed values are
+// immutable and can therefore be shared across all decoders.
+var smallIntAny = func() (a [smallIntAnyMax - smallIntAnyMin + 1]any) {
+	for i := range a {
+		a[i] = float64(i + smallIntAnyMin)
+	}
+	return a
+}()
+
+// boxSmallInt returns the integer literal s as a preboxed float64
+// if it's within [smallIntAnyMin, smallIntAnyMax].
+// s must be a valid JSON integer literal. Returns false if s is out of range
+// or if it's "-0", which must be decoded as negative zero.
+func boxSmallInt[S []byte | string](s S) (any, bool) {
+	if len(s) > len("-128") {
+		return nil, false
+	}
+	i, neg := 0, s[0] == '-'
+	if neg {
+		i = 1
+	}
+	n := 0
+	for ; i < len(s); i++ {
+		n = n*10 + int(s[i]-'0')
+	}
+	if neg {
+		if n == 0 {
+			return nil, false
+		}
+		n = -n
+	}
+	if n < smallIntAnyMin || n > smallIntAnyMax {
+		return nil, false
+	}
+	return smallIntAny[n-smallIntAnyMin], true
+}
@@ -0,0 +1,149 @@
+package jscandec
+
+// StreamDecoder decodes a JSON value received in chunks of arbitrary size,
+// such as from a network connection, without requiring the whole input
+// upfront. Chunks are buffered until a complete top-level value is available,
+// which is then decoded into a value of type T.
+//
+// Top-level numbers and literals (like `42` or `true`) are only complete
+// once followed by whitespace or another value since otherwise their end
+// can't be told apart from a split across chunks.
+//
+// DecodeOptions.RawMessageNoCopy must not be used with StreamDecoder
+// since the internal buffer is reused by Next.
+type StreamDecoder[T any] struct {
+	decoder *Decoder[[]byte, T]
+	options *DecodeOptions
+	value   T
+	err     error
+	buf     []byte
+
+	// scanned is the index in buf up to which the input was scanned.
+	scanned int
+
+	// end is the index in buf at which the complete value ends,
+	// or -1 if the value is not yet complete.
+	end int
+
+	depth    int
+	started  bool
+	scalar   bool
+	inString bool
+	escaped  bool
+}
+
+// NewStreamDecoder creates a new stream decoder using decoder to decode
+// the values with options. decoder must not be used concurrently
+// while the stream decoder is in use.
+func NewStreamDecoder[T any](
+	decoder *Decoder[[]byte, T], options *DecodeOptions,
+) *StreamDecoder[T] {
+	return &StreamDecoder[T]{decoder: decoder, options: options, end: -1}
+}
+
+// Write appends chunk p to the buffered input and decodes the value once
+// it's complete. Any input following a complete value is retained for Next.
+// Write returns the error of the decoder if the value fails to decode,
+// after which all subsequent calls to Write return the same error.
+func (d *StreamDecoder[T]) Write(p []byte) (n int, err error) {
+	if d.err != nil {
+		return 0, d.err
+	}
+	d.buf = append(d.buf, p...)
+	if d.end < 0 {
+		d.scan()
+	}
+	return len(p), d.err
+}
+
+// Value returns the decoded value and true once the value is complete,
+// otherwise returns nil and false.
+func (d *StreamDecoder[T]) Value() (*T, bool) {
+	if d.end < 0 || d.err != nil {
+		return nil, false
+	}
+	return &d.value, true
+}
+
+// Next discards the decoded value and starts decoding the next value
+// from the input buffered after it. Returns the error of the decoder
+// if the next value is already complete and fails to decode.
+// Next is a no-op if the current value isn't yet complete.
+func (d *StreamDecoder[T]) Next() error {
+	if d.err != nil {
+		return d.err
+	}
+	if d.end < 0 {
+		return nil
+	}
+	n := copy(d.buf, d.buf[d.end:])
+	d.buf = d.buf[:n]
+	var zero T
+	d.value, d.scanned, d.end = zero, 0, -1
+	d.depth, d.started, d.scalar, d.inString, d.escaped = 0, false, false, false, false
+	d.scan()
+	return d.err
+}
+
+// scan continues scanning the buffered input for the end of the current
+// top-level value and decodes it once found.
+func (d *StreamDecoder[T]) scan() {
+	i := d.scanned
+	for ; i < len(d.buf); i++ {
+		c := d.buf[i]
+		switch {
+		case !d.started:
+			switch c {
+			case ' ', '\t', '\r', '\n':
+				continue
+			case '{', '[':
+				d.depth = 1
+			case '"':
+				d.inString = true
+			default:
+				d.scalar = true
+			}
+			d.started = true
+		case d.inString:
+			switch {
+			case d.escaped:
+				d.escaped = false
+			case c == '\\':
+				d.escaped = true
+			case c == '"':
+				d.inString = false
+				if d.depth == 0 {
+					d.complete(i + 1)
+					return
+				}
+			}
+		case d.scalar:
+			switch c {
+			case ' ', '\t', '\r', '\n', '{', '}', '[', ']', ',', ':', '"':
+				d.complete(i)
+				return
+			}
+		default:
+			switch c {
+			case '"':
+				d.inString = true
+			case '{', '[':
+				d.depth++
+			case '}', ']':
+				if d.depth--; d.depth == 0 {
+					d.complete(i + 1)
+					return
+				}
+			}
+		}
+	}
+	d.scanned = i
+}
+
+// complete decodes the value ending at index end of the buffered input.
+func (d *StreamDecoder[T]) complete(end int) {
+	d.end, d.scanned = end, end
+	if _, err := d.decoder.Decode(d.buf[:end], &d.value, d.options); err != nil {
+		d.err = err
+	}
+}
@@ -0,0 +1,33 @@
+package jscandec
+
+import (
+	"encoding/json"
+	"reflect"
+)
+
+// Stringed decodes Value of a boolean, numeric or string type T
+// from a JSON string containing the value like the `string` tag option,
+// which encoding/json doesn't apply to the elements of slices, arrays
+// and maps. For example, `["1","-2"]` is decoded into `[]Stringed[int]`
+// as {{1}, {-2}}. Null leaves Value unchanged.
+// NewDecoder returns ErrUnsupportedType for any other type T.
+type Stringed[T any] struct {
+	Value T
+}
+
+func (Stringed[T]) isStringed() {}
+
+var tpStringed = reflect.TypeOf((*interface{ isStringed() })(nil)).Elem()
+
+// UnmarshalJSON implements encoding/json.Unmarshaler
+// for compatibility with encoding/json.
+func (s *Stringed[T]) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		return nil
+	}
+	var str string
+	if err := json.Unmarshal(data, &str); err != nil {
+		return err
+	}
+	return json.Unmarshal([]byte(str), &s.Value)
+}
@@ -0,0 +1,191 @@
+package jscandec
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// structFields returns the fields of struct type t visible to encoding/json
+// in the order of their declaration. Fields of embedded structs without a name
+// in their tag are promoted with Offset and Index relative to t.
+//
+// Like in encoding/json unexported non-embedded fields and fields tagged
+// with `json:"-"` are ignored, a field at a shallower embedding depth shadows
+// deeper fields of the same name and fields of the same name at the same depth
+// conflict and are all ignored unless exactly one of them has its name
+// specified by a tag.
+//
+// Returns ErrUnsupportedType if a field is promoted through an embedded
+// pointer since the pointer would have to be allocated on demand.
+func structFields(t reflect.Type, fallbackTagKeys []string) ([]reflect.StructField, error) {
+	type embedded struct {
+		typ        reflect.Type
+		index      []int
+		offset     uintptr
+		throughPtr bool
+	}
+	type field struct {
+		reflect.StructField
+		name       string
+		tagged     bool
+		throughPtr bool
+	}
+
+	var fields []field
+	visited := map[reflect.Type]bool{}
+	count, nextCount := map[reflect.Type]int{}, map[reflect.Type]int{t: 1}
+	for next := []embedded{{typ: t}}; len(next) > 0; {
+		current := next
+		next = nil
+		count, nextCount = nextCount, map[reflect.Type]int{}
+		for _, e := range current {
+			if visited[e.typ] {
+				continue
+			}
+			visited[e.typ] = true
+			for i := 0; i < e.typ.NumField(); i++ {
+				f := e.typ.Field(i)
+				ft := f.Type
+				if f.Anonymous {
+					if ft.Kind() == reflect.Pointer {
+						ft = ft.Elem()
+					}
+					if !f.IsExported() && ft.Kind() != reflect.Struct {
+						continue
+					}
+				} else if !f.IsExported() {
+					continue
+				}
+				name, tagged, ignore := fieldJSONName(f, fallbackTagKeys)
+				if ignore {
+					continue
+				}
+				index := append(slices.Clip(e.index), i)
+				throughPtr := e.throughPtr
+				if tagged || !f.Anonymous || ft.Kind() != reflect.Struct {
+					f.Index, f.Offset = index, f.Offset+e.offset
+					fields = append(fields, field{f, name, tagged, throughPtr})
+					if count[e.typ] > 1 {
+						// The same struct type is embedded multiple times at
+						// this depth, duplicate the field to make it conflict.
+						fields = append(fields, fields[len(fields)-1])
+					}
+					continue
+				}
+				if nextCount[ft]++; nextCount[ft] == 1 {
+					next = append(next, embedded{
+						typ:        ft,
+						index:      index,
+						offset:     e.offset + f.Offset,
+						throughPtr: throughPtr || f.Type.Kind() == reflect.Pointer,
+					})
+				}
+			}
+		}
+	}
+
+	// Keep only the dominant field of every name.
+	slices.SortStableFunc(fields, func(a, b field) int {
+		if c := strings.Compare(a.name, b.name); c != 0 {
+			return c
+		}
+		if c := len(a.Index) - len(b.Index); c != 0 {
+			return c
+		}
+		if a.tagged != b.tagged {
+			if a.tagged {
+				return -1
+			}
+			return 1
+		}
+		return slices.Compare(a.Index, b.Index)
+	})
+	dominant := make([]field, 0, len(fields))
+	for i := 0; i < len(fields); {
+		j := i + 1
+		for j < len(fields) && fields[j].name == fields[i].name {
+			j++
+		}
+		if j-i > 1 && len(fields[i].Index) == len(fields[i+1].Index) &&
+			fields[i].tagged == fields[i+1].tagged {
+			// Conflicting fields at the same depth, ignore all of them.
+			i = j
+			continue
+		}
+		dominant = append(dominant, fields[i])
+		i = j
+	}
+	slices.SortFunc(dominant, func(a, b field) int {
+		return slices.Compare(a.Index, b.Index)
+	})
+
+	visible := make([]reflect.StructField, len(dominant))
+	for i, f := range dominant {
+		if f.throughPtr {
+			return nil, fmt.Errorf(
+				"%w: field %s of %v promoted through embedded pointer",
+				ErrUnsupportedType, f.Name, t,
+			)
+		}
+		visible[i] = f.StructField
+	}
+	return visible, nil
+}
+
+// fieldJSONName returns the name of struct field f, which is specified either
+// by the json struct tag, by one of the fallback tags or is the Go field name.
+// tagged is true if the name is specified by a tag. ignore is true if the field
+// is ignored by the json struct tag.
+func fieldJSONName(
+	f reflect.StructField, fallbackTagKeys []string,
+) (name string, tagged, ignore bool) {
+	if n := fallbackTagName(f.Tag, fallbackTagKeys); n != "" {
+		name, tagged = n, true
+	}
+	if jsonTag := f.Tag.Get("json"); jsonTag != "" {
+		if i := strings.IndexByte(jsonTag, ','); i != -1 {
+			jsonTag = jsonTag[:i]
+		}
+		switch jsonTag {
+		case "":
+			// No name specified in the tag.
+		case "-":
+			return "", false, true
+		default:
+			name, tagged = jsonTag, true
+		}
+	}
+	if name == "" {
+		name = f.Name
+	}
+	return name, tagged, false
+}
+
+// appendFieldAliases appends the alternative names defined by the `jsonalias`
+// struct tag (such as `jsonalias:"oldName,legacy"`) to the fields of the
+// struct frame at index si. Every entry of aliases holds the comma-separated
+// names of the tag in place of a name.
+// Aliases are matched like names, yet only after all names since they're
+// appended at the end, which makes a field named like an alias of another
+// field take precedence.
+func appendFieldAliases[S []byte | string](
+	stack []stackFrame[S], si uint32, aliases []fieldStackFrame,
+) {
+	f := &stack[si]
+	for _, a := range aliases {
+		for _, name := range strings.Split(a.Name, ",") {
+			if name == "" || slices.ContainsFunc(f.Fields, func(fl fieldStackFrame) bool {
+				return fl.Name == name
+			}) {
+				continue
+			}
+			f.Fields = append(f.Fields, fieldStackFrame{
+				Name:       name,
+				FrameIndex: a.FrameIndex,
+			})
+			f.FieldAliases++
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package jscandec
+
+import (
+	"time"
+	"unsafe"
+
+	"github.com/romshark/jscan-experimental-decoder/internal/atoi"
+	"github.com/romshark/jscan-experimental-decoder/internal/unescape"
+
+	"github.com/romshark/jscan/v2"
+)
+
+// TimeEpoch defines the unit of integer time.Time values
+// (see DecodeOptions.TimeEpoch).
+type TimeEpoch uint8
+
+const (
+	// TimeEpochNone rejects integer time.Time values,
+	// which is the default behavior of encoding/json.
+	TimeEpochNone TimeEpoch = iota
+
+	// TimeEpochSeconds decodes integer time.Time values as Unix seconds.
+	TimeEpochSeconds
+
+	// TimeEpochMillis decodes integer time.Time values as Unix milliseconds.
+	TimeEpochMillis
+)
+
+var layoutsRFC3339 = []string{time.RFC3339}
+
+// decodesTime returns true if time.Time values must be decoded by decodeTime
+// instead of time.Time.UnmarshalJSON.
+func (o *DecodeOptions) decodesTime() bool {
+	return len(o.TimeLayouts) > 0 || o.TimeLayout != "" || o.TimeEpoch != TimeEpochNone
+}
+
+// timeLayouts returns the layouts to try in order when decoding
+// time.Time values: TimeLayouts if set, otherwise TimeLayout
+// and time.RFC3339 if neither is set.
+func (o *DecodeOptions) timeLayouts() []string {
+	if len(o.TimeLayouts) > 0 {
+		return o.TimeLayouts
+	}
+	if o.TimeLayout == "" {
+		return layoutsRFC3339
+	}
+	// Avoid allocating a slice for every value.
+	return unsafe.Slice(&o.TimeLayout, 1)
+}
+
+// decodeTime decodes the string or integer token tok into a time.Time.
+// Strings are parsed with each of layouts in order until one succeeds.
+// If all layouts fail the error of the first one is returned.
+func decodeTime[S []byte | string](
+	s S, tok jscan.Token[S], layouts []string, epoch TimeEpoch,
+) (time.Time, error) {
+	switch tok.Type {
+	case jscan.TokenTypeString:
+		v := unescape.Valid[S, string](s[tok.Index+1 : tok.End-1])
+		var errFirst error
+		for _, layout := range layouts {
+			t, err := time.Parse(layout, v)
+			if err == nil {
+				return t, nil
+			}
+			if errFirst == nil {
+				errFirst = err
+			}
+		}
+		return time.Time{}, errFirst
+	case jscan.TokenTypeInteger:
+		if epoch == TimeEpochNone {
+			break
+		}
+		n, overflow := atoi.I64(s[tok.Index:tok.End])
+		if overflow {
+			return time.Time{}, ErrIntegerOverflow
+		}
+		if epoch == TimeEpochMillis {
+			return time.UnixMilli(n).UTC(), nil
+		}
+		return time.Unix(n, 0).UTC(), nil
+	}
+	return time.Time{}, ErrUnexpectedValue
+}
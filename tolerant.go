@@ -0,0 +1,439 @@
+package jscandec
+
+import (
+	"math/big"
+	"sort"
+	"strconv"
+	"unsafe"
+
+	"github.com/romshark/jscan-experimental-decoder/internal/atoi"
+)
+
+// rewriteSpan maps a rewritten section of the input to its original section.
+type rewriteSpan struct {
+	// Index and End define the section in the rewritten input.
+	Index, End int
+
+	// OrigIndex and OrigEnd define the section in the original input.
+	OrigIndex, OrigEnd int
+
+	// Hex is true if the section was rewritten from a hexadecimal integer.
+	Hex bool
+}
+
+// originalIndex translates index i of the rewritten input
+// to the index in the original input.
+func originalIndex(spans []rewriteSpan, i int) int {
+	delta := 0
+	for _, sp := range spans {
+		if i < sp.Index {
+			break
+		}
+		if i < sp.End {
+			// Inside of a rewritten section, point at its start.
+			return sp.OrigIndex
+		}
+		delta = sp.OrigEnd - sp.End
+	}
+	return i + delta
+}
+
+// hexLiteral returns true if the token at index i of the rewritten input
+// was rewritten from a hexadecimal integer literal.
+func hexLiteral(spans []rewriteSpan, i int) bool {
+	j := sort.Search(len(spans), func(j int) bool { return spans[j].Index >= i })
+	return j < len(spans) && spans[j].Index == i && spans[j].Hex
+}
+
+// rewriteNonstandard rewrites the non-standard number literals and object keys
+// permitted by options into standard JSON, which jscan can tokenize:
+//
+//   - hexadecimal integers (like 0x1F) are replaced by their decimal
+//     representation if options.AllowHexIntegers is enabled.
+//   - redundant leading zeros (like in 007) are removed
+//     if options.AllowLeadingZeros is enabled.
+//   - unquoted identifier keys (like in {name:"x"}) are quoted
+//     if options.AllowUnquotedKeys is enabled.
+//   - underscores separating digits (like in 1_000) are removed
+//     if options.AllowUnderscoreInNumbers is enabled.
+//
+// Strings are never rewritten. Returns s as is if nothing needs to be rewritten,
+// otherwise returns a rewritten copy and the rewritten sections
+// appended to spans[:0].
+func rewriteNonstandard[S []byte | string](
+	s S, spans []rewriteSpan, options *DecodeOptions,
+) (S, []rewriteSpan) {
+	spans = spans[:0]
+	var b []byte // Allocated on first rewrite
+	copied := 0  // Index in s up to which the input was copied to b
+	rewrite := func(start, end int, replacement S) {
+		if b == nil {
+			b = make([]byte, 0, len(s)+len(replacement))
+		}
+		b = append(b, s[copied:start]...)
+		spans = append(spans, rewriteSpan{
+			Index:     len(b),
+			End:       len(b) + len(replacement),
+			OrigIndex: start,
+			OrigEnd:   end,
+		})
+		b = append(b, replacement...)
+		copied = end
+	}
+	for i := 0; i < len(s); i++ {
+		if options.AllowUnderscoreInNumbers &&
+			(s[i] == '-' || isDigit(s[i])) &&
+			(i == 0 || isValueDelimiter(s[i-1])) {
+			if end, ok := digitSeparatedNumberEnd(s, i); ok {
+				n := removeDigitSeparators(s[i:end])
+				if options.AllowLeadingZeros {
+					n = trimLeadingZeros(n)
+				}
+				rewrite(i, end, n)
+				i = end - 1
+				continue
+			}
+		}
+		switch s[i] {
+		case '"':
+			// Skip over string
+			for i++; i < len(s) && s[i] != '"'; i++ {
+				if s[i] == '\\' {
+					i++
+				}
+			}
+		case '-', '0':
+			if i > 0 && !isValueDelimiter(s[i-1]) {
+				// Not the start of a number
+				continue
+			}
+			end := i
+			if s[end] == '-' {
+				end++
+			}
+			if end+1 >= len(s) || s[end] != '0' {
+				continue
+			}
+			switch c := s[end+1]; {
+			case options.AllowHexIntegers && (c == 'x' || c == 'X') &&
+				end+2 < len(s) && isHexDigit(s[end+2]):
+				for end += 2; end < len(s) && isHexDigit(s[end]); end++ {
+				}
+				decimal, _ := hexToDecimal(s[i:end])
+				rewrite(i, end, decimal)
+				spans[len(spans)-1].Hex = true
+				i = end - 1
+			case options.AllowLeadingZeros && c >= '0' && c <= '9':
+				// Rewrite the sign and the first significant digit
+				// to make errors point at the start of the number.
+				for end+1 < len(s) && s[end] == '0' && s[end+1] >= '0' && s[end+1] <= '9' {
+					end++
+				}
+				rewrite(i, end+1, trimLeadingZeros(s[i:end+1]))
+				i = end
+			}
+		default:
+			if !options.AllowUnquotedKeys || !isIdentifierStart(s[i]) ||
+				(i > 0 && isIdentifierPart(s[i-1])) {
+				continue
+			}
+			end := i + 1
+			for end < len(s) && isIdentifierPart(s[end]) {
+				end++
+			}
+			if !isUnquotedKey(s, i, end) {
+				// Not a key, leave it to the tokenizer.
+				i = end - 1
+				continue
+			}
+			rewrite(i, end, S(append(append([]byte{'"'}, s[i:end]...), '"')))
+			i = end - 1
+		}
+	}
+	if b == nil {
+		return s, spans
+	}
+	b = append(b, s[copied:]...)
+	return S(b), spans
+}
+
+// trimLeadingZeros removes redundant leading zeros from the number literal s
+// such that 007 becomes 7 and -00.5 becomes -0.5.
+func trimLeadingZeros[S []byte | string](s S) S {
+	digits := s
+	if len(digits) > 0 && digits[0] == '-' {
+		digits = digits[1:]
+	}
+	z := 0
+	for z+1 < len(digits) && digits[z] == '0' &&
+		digits[z+1] >= '0' && digits[z+1] <= '9' {
+		z++
+	}
+	if z == 0 {
+		return s
+	}
+	if len(digits) == len(s) {
+		return digits[z:]
+	}
+	return S(append([]byte{'-'}, digits[z:]...))
+}
+
+// digitSeparatedNumberEnd returns the end index of the number literal
+// starting at s[i] and true if it contains underscores, all of which
+// separate two digits (like in 1_000 or 3_000.5). Returns false otherwise.
+func digitSeparatedNumberEnd[S []byte | string](s S, i int) (end int, ok bool) {
+	end = i
+	if s[end] == '-' {
+		end++
+	}
+	for ; end < len(s); end++ {
+		switch c := s[end]; {
+		case isDigit(c), c == '.', c == 'e', c == 'E':
+		case c == '+', c == '-':
+			if s[end-1] != 'e' && s[end-1] != 'E' {
+				return end, ok
+			}
+		case c == '_':
+			if !isDigit(s[end-1]) || end+1 >= len(s) || !isDigit(s[end+1]) {
+				// Leave the invalid literal to the tokenizer.
+				return end, false
+			}
+			ok = true
+		default:
+			return end, ok
+		}
+	}
+	return end, ok
+}
+
+// removeDigitSeparators returns s without the underscores separating digits
+// such that 1_000 becomes 1000. Returns s as is if it contains no underscores
+// or if any of them doesn't separate two digits.
+func removeDigitSeparators[S []byte | string](s S) S {
+	n := 0
+	for i := 0; i < len(s); i++ {
+		if s[i] != '_' {
+			continue
+		}
+		if i < 1 || i+1 >= len(s) || !isDigit(s[i-1]) || !isDigit(s[i+1]) {
+			return s
+		}
+		n++
+	}
+	if n == 0 {
+		return s
+	}
+	b := make([]byte, 0, len(s)-n)
+	for i := 0; i < len(s); i++ {
+		if s[i] != '_' {
+			b = append(b, s[i])
+		}
+	}
+	return S(b)
+}
+
+// hexToDecimal converts the hexadecimal integer literal s (like 0x1F or -0X1f)
+// to its decimal representation. Returns ok=false if s isn't a valid
+// hexadecimal integer literal.
+func hexToDecimal[S []byte | string](s S) (decimal S, ok bool) {
+	digits := s
+	if len(digits) > 0 && digits[0] == '-' {
+		digits = digits[1:]
+	}
+	if len(digits) < 3 || digits[0] != '0' || (digits[1] != 'x' && digits[1] != 'X') {
+		return decimal, false
+	}
+	digits = digits[2:]
+	for i := 0; i < len(digits); i++ {
+		if !isHexDigit(digits[i]) {
+			return decimal, false
+		}
+	}
+
+	b := make([]byte, 0, len(digits)*2)
+	if s[0] == '-' {
+		b = append(b, '-')
+	}
+	if len(digits) <= 16 {
+		// Guaranteed to fit uint64
+		var v uint64
+		for i := 0; i < len(digits); i++ {
+			v = v<<4 | uint64(hexDigitValue(digits[i]))
+		}
+		if v == 0 {
+			// Avoid producing -0
+			b = b[:0]
+		}
+		return S(strconv.AppendUint(b, v, 10)), true
+	}
+	var v big.Int
+	v.SetString(string(digits), 16)
+	return S(v.Append(b, 10)), true
+}
+
+// isNonIntegerNumber returns true for the non-integer types integer tokens
+// are decoded into, which don't accept hexadecimal integer literals.
+func (t ExpectType) isNonIntegerNumber() bool {
+	switch t {
+	case ExpectTypeAny,
+		ExpectTypeFloat32,
+		ExpectTypeFloat64,
+		ExpectTypeNumber,
+		ExpectTypeBigRat:
+		return true
+	}
+	return false
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isHexDigit(c byte) bool {
+	return (c >= '0' && c <= '9') || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
+}
+
+func hexDigitValue(c byte) byte {
+	switch {
+	case c >= 'a':
+		return c - 'a' + 10
+	case c >= 'A':
+		return c - 'A' + 10
+	}
+	return c - '0'
+}
+
+// floatAsInt converts the number literal s with a fraction or an exponent
+// (like 1e3 or 2.5e1) to integer type I. Returns ErrUnexpectedValue if the
+// number isn't integral and ErrIntegerOverflow if it overflows I.
+// Integrality is checked on the decimal digits of s rather than on its
+// float64 approximation, which can't represent all integers above 2^53.
+func floatAsInt[
+	I int | int8 | int16 | int32 | int64 | uint | uint8 | uint16 | uint32 | uint64,
+	S []byte | string,
+](s S) (I, error) {
+	neg := s[0] == '-'
+	if neg {
+		s = s[1:]
+	}
+	mantissa, exp := s, 0
+	for i := 0; i < len(s); i++ {
+		if s[i] != 'e' && s[i] != 'E' {
+			continue
+		}
+		mantissa = s[:i]
+		e := s[i+1:]
+		expNeg := e[0] == '-'
+		if e[0] == '-' || e[0] == '+' {
+			e = e[1:]
+		}
+		for j := 0; j < len(e) && exp <= len(s)+20; j++ {
+			// Any exponent beyond the length of s plus the 20 digits of
+			// the largest uint64 either overflows or isn't integral.
+			exp = exp*10 + int(e[j]-'0')
+		}
+		if expNeg {
+			exp = -exp
+		}
+		break
+	}
+	intPart, frac := mantissa, mantissa[len(mantissa):]
+	for i := 0; i < len(mantissa); i++ {
+		if mantissa[i] == '.' {
+			intPart, frac = mantissa[:i], mantissa[i+1:]
+			break
+		}
+	}
+	digit := func(i int) byte {
+		if i < len(intPart) {
+			return intPart[i]
+		}
+		return frac[i-len(intPart)]
+	}
+
+	// The value is the significant digits [first, last] times 10^exp.
+	n := len(intPart) + len(frac)
+	first := 0
+	for first < n && digit(first) == '0' {
+		first++
+	}
+	if first == n {
+		return 0, nil // Zero, such as 0.0 or -0e5
+	}
+	last := n - 1
+	for digit(last) == '0' {
+		last--
+	}
+	exp += n - 1 - last - len(frac)
+	if exp < 0 {
+		return 0, ErrUnexpectedValue
+	}
+	if last-first+1+exp > 20 {
+		return 0, ErrIntegerOverflow
+	}
+	var buf [21]byte // Sign and up to 20 digits
+	b := buf[:0]
+	if neg {
+		b = append(b, '-')
+	}
+	for i := first; i <= last; i++ {
+		b = append(b, digit(i))
+	}
+	for ; exp > 0; exp-- {
+		b = append(b, '0')
+	}
+
+	var z I
+	bits := int(unsafe.Sizeof(z) * 8)
+	if z-1 < z { // Signed
+		v, overflow := atoi.I64(b)
+		if l := int64(1) << (bits - 1); overflow || (bits < 64 && (v < -l || v >= l)) {
+			return 0, ErrIntegerOverflow
+		}
+		return I(v), nil
+	}
+	if neg {
+		return 0, ErrIntegerOverflow
+	}
+	v, overflow := atoi.U64(b)
+	if overflow || (bits < 64 && v >= uint64(1)<<bits) {
+		return 0, ErrIntegerOverflow
+	}
+	return I(v), nil
+}
+
+// isUnquotedKey returns true if the identifier s[start:end] is preceded
+// by either '{' or ',' and followed by ':', ignoring whitespace.
+func isUnquotedKey[S []byte | string](s S, start, end int) bool {
+	for end < len(s) && isWhitespace(s[end]) {
+		end++
+	}
+	if end >= len(s) || s[end] != ':' {
+		return false
+	}
+	for start--; start >= 0 && isWhitespace(s[start]); start-- {
+	}
+	return start >= 0 && (s[start] == '{' || s[start] == ',')
+}
+
+func isIdentifierStart(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || c == '_' || c == '$'
+}
+
+func isIdentifierPart(c byte) bool {
+	return isIdentifierStart(c) || (c >= '0' && c <= '9')
+}
+
+func isWhitespace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\r' || c == '\n'
+}
+
+// isValueDelimiter returns true for characters that can
+// precede a value in valid JSON.
+func isValueDelimiter(c byte) bool {
+	switch c {
+	case ' ', '\t', '\r', '\n', '[', ',', ':':
+		return true
+	}
+	return false
+}
@@ -0,0 +1,106 @@
+package jscandec
+
+import (
+	"encoding"
+	"encoding/json"
+	"reflect"
+	"unsafe"
+
+	"github.com/romshark/jscan-experimental-decoder/internal/unescape"
+
+	"github.com/romshark/jscan/v2"
+)
+
+// decodeTextUnmarshalerSlice decodes the elements of the array at tokens[ti]
+// into the slice data dp of the slice frame si, which is a slice of a type
+// implementing encoding.TextUnmarshaler.
+// Compared to the generic path it avoids the frame transitions and the
+// reflect.NewAt call for every element. The interface value is created once
+// for the first element and its data word is then moved along the contiguous
+// backing array.
+// Only the first elems elements are decoded.
+// Returns the index of the token following the array.
+func (d *Decoder[S, T]) decodeTextUnmarshalerSlice(
+	s S, tokens []jscan.Token[S], ti int, si uint32, dp unsafe.Pointer, elems uintptr,
+	options *DecodeOptions,
+) (next, errIndex int, err error) {
+	elemFrame := &d.stackExp[si+1]
+	u := reflect.NewAt(elemFrame.RType, dp).Interface().(encoding.TextUnmarshaler)
+	h := (*ifaceHeader)(unsafe.Pointer(&u))
+	size := elemFrame.Size
+	end := tokens[ti].End
+	for ti++; elems > 0; ti, dp, elems = ti+1, unsafe.Add(dp, size), elems-1 {
+		switch tokens[ti].Type {
+		case jscan.TokenTypeNull:
+			// Skip
+		case jscan.TokenTypeString:
+			h.Data = dp
+			tb := unescape.Valid[S, []byte](s[tokens[ti].Index+1 : tokens[ti].End-1])
+			if err := u.UnmarshalText(tb); err != nil {
+				return 0, tokens[ti].Index, err
+			}
+		case jscan.TokenTypeInteger, jscan.TokenTypeNumber,
+			jscan.TokenTypeTrue, jscan.TokenTypeFalse:
+			if !options.TextUnmarshalerAcceptsScalars {
+				return 0, tokens[ti].Index, ErrUnexpectedValue
+			}
+			h.Data = dp
+			tb := []byte(s[tokens[ti].Index:tokens[ti].End])
+			if err := u.UnmarshalText(tb); err != nil {
+				return 0, tokens[ti].Index, err
+			}
+		default:
+			return 0, tokens[ti].Index, ErrUnexpectedValue
+		}
+	}
+	return end + 1, 0, nil
+}
+
+// decodeJSONUnmarshalerSlice decodes the elements of the array at tokens[ti]
+// into the slice data dp of the slice frame si, which is a slice of a type
+// implementing json.Unmarshaler.
+// Like decodeTextUnmarshalerSlice it creates the interface value once and
+// passes the raw span of every element to UnmarshalJSON, including null.
+// Only the first elems elements are decoded.
+// Returns the index of the token following the array.
+func (d *Decoder[S, T]) decodeJSONUnmarshalerSlice(
+	s S, tokens []jscan.Token[S], ti int, si uint32, dp unsafe.Pointer,
+	elems uintptr, options *DecodeOptions,
+) (next, errIndex int, err error) {
+	elemFrame := &d.stackExp[si+1]
+	noCopy := false
+	if options.RawMessageNoCopy && elemFrame.RType == tpRawMessage {
+		var z S
+		_, noCopy = any(z).([]byte)
+	}
+	u := reflect.NewAt(elemFrame.RType, dp).Interface().(json.Unmarshaler)
+	h := (*ifaceHeader)(unsafe.Pointer(&u))
+	size := elemFrame.Size
+	end := tokens[ti].End
+	for ti++; elems > 0; dp, elems = unsafe.Add(dp, size), elems-1 {
+		var raw S
+		tkIndex := tokens[ti].Index
+		switch tokens[ti].Type {
+		case jscan.TokenTypeObject, jscan.TokenTypeArray:
+			// Composite value
+			raw = s[tokens[ti].Index : tokens[tokens[ti].End].Index+1]
+			ti = tokens[ti].End + 1
+		default:
+			// Non-composite value
+			raw = s[tokens[ti].Index:tokens[ti].End]
+			ti++
+		}
+		if noCopy {
+			// Alias the input, limit the capacity to prevent appends
+			// to the message from overwriting the input.
+			b := *(*[]byte)(unsafe.Pointer(&raw))
+			*(*json.RawMessage)(dp) = b[:len(b):len(b)]
+			continue
+		}
+		h.Data = dp
+		if err := u.UnmarshalJSON([]byte(raw)); err != nil {
+			return 0, tkIndex, err
+		}
+	}
+	return end + 1, 0, nil
+}
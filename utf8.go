@@ -0,0 +1,41 @@
+package jscandec
+
+import (
+	"unicode/utf8"
+
+	"github.com/romshark/jscan/v2"
+)
+
+// invalidUTF8 returns true if options.ValidateUTF8 is enabled and
+// the contents of the string token tok contain invalid UTF-8.
+// Escape sequences always unescape to valid UTF-8 and are skipped.
+func invalidUTF8[S []byte | string](
+	s S, tok jscan.Token[S], options *DecodeOptions,
+) bool {
+	if !options.ValidateUTF8 {
+		return false
+	}
+	for i, end := tok.Index+1, tok.End-1; i < end; i++ {
+		switch c := s[i]; {
+		case c == '\\':
+			i++
+		case c >= utf8.RuneSelf:
+			r, size := decodeRune(s[i:end])
+			if r == utf8.RuneError && size == 1 {
+				return true
+			}
+			i += size - 1
+		}
+	}
+	return false
+}
+
+func decodeRune[S []byte | string](s S) (r rune, size int) {
+	switch s := any(s).(type) {
+	case []byte:
+		return utf8.DecodeRune(s)
+	case string:
+		return utf8.DecodeRuneInString(s)
+	}
+	panic("unreachable")
+}
@@ -0,0 +1,74 @@
+package jscandec
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// Warning is a recoverable issue reported through DecodeOptions.Warnings.
+type Warning struct {
+	// Index is the index of the value or key in the input.
+	Index int
+
+	// Err is the recoverable error,
+	// such as ErrUnknownField or ErrImpreciseNumber.
+	Err error
+}
+
+func (w *Warning) Error() string {
+	return fmt.Sprintf("at index %d: %v", w.Index, w.Err)
+}
+
+func (w *Warning) Unwrap() error { return w.Err }
+
+// warn appends a warning to o.Warnings.
+func (o *DecodeOptions) warn(index int, err error) {
+	*o.Warnings = append(*o.Warnings, &Warning{Index: index, Err: err})
+}
+
+// unknownField reports the unknown field at index as a warning if o.Warnings
+// is set and returns nil. Otherwise returns err if enabled.
+// composite must be true if the value of the field is an object or an array.
+func (o *DecodeOptions) unknownField(index int, composite bool) error {
+	var err error
+	if o.DisallowUnknownFields {
+		err = ErrUnknownField
+	} else if o.RejectUnknownObjectValues && composite {
+		err = ErrUnknownCompositeField
+	}
+	if o.Warnings == nil {
+		return err
+	}
+	if err == nil {
+		err = ErrUnknownField
+	}
+	o.warn(index, err)
+	return nil
+}
+
+// checkPrecision reports the integer literal tv at index as a warning
+// with ErrImpreciseNumber if o.Warnings is set and tv isn't exactly
+// representable by v, which is the float it was parsed into.
+func checkPrecision[S []byte | string](
+	o *DecodeOptions, index int, tv S, v float64,
+) {
+	if o.Warnings == nil {
+		return
+	}
+	var buf [32]byte
+	// Integral floats are formatted exactly with a precision of 0.
+	f := strconv.AppendFloat(buf[:0], v, 'f', 0, 64)
+	if string(f) != string(tv) {
+		o.warn(index, ErrImpreciseNumber)
+	}
+}
+
+// translateWarnings translates the indices of the warnings from index from
+// in the rewritten input back to the original input.
+func translateWarnings(o *DecodeOptions, from int, spans []rewriteSpan) {
+	for _, w := range (*o.Warnings)[from:] {
+		if w, ok := w.(*Warning); ok {
+			w.Index = originalIndex(spans, w.Index)
+		}
+	}
+}